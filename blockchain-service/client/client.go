@@ -0,0 +1,232 @@
+// Package client is a Go client for the blockchain-service HTTP API.
+//
+//	c, err := client.New("http://localhost:8082", client.WithSessionToken(token))
+//	err = c.StoreMetadata(ctx, metadata)
+//	metadata, err := c.GetMetadata(ctx, fileID)
+//
+// Idempotent calls are retried with backoff; failures are returned as *Error,
+// which errors.Is matches against ErrForbidden etc.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/shared/httpretry"
+)
+
+// FileMetadata is the on-chain record of a file.
+type FileMetadata = domain.FileMetadata
+
+// TransactionStatus is the state of a submitted transaction.
+type TransactionStatus = domain.TransactionStatus
+
+// Transaction states in TransactionStatus.Status.
+const (
+	TxPending   = domain.TxPending
+	TxSucceeded = domain.TxSucceeded
+	TxReverted  = domain.TxReverted
+)
+
+type Client struct {
+	baseURL      *url.URL
+	httpClient   *http.Client
+	sessionToken string
+	retry        RetryPolicy
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends requests with httpClient instead of http.DefaultClient, e.g. to set
+// timeouts, proxies or tracing. Writes wait for the transaction receipt, so allow for
+// the block time in any Timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithSessionToken authenticates requests as the wallet signed in with Sign-In With Ethereum.
+func WithSessionToken(token string) Option {
+	return func(c *Client) { c.sessionToken = token }
+}
+
+// RetryPolicy controls how idempotent requests are retried after network errors
+// and 429, 502, 503 or 504 responses.
+type RetryPolicy = httpretry.Policy
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given.
+var DefaultRetryPolicy = httpretry.DefaultPolicy
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// New returns a client for the service at baseURL, e.g. "http://localhost:8082".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{baseURL: u, httpClient: http.DefaultClient, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c, nil
+}
+
+// StoreMetadata records metadata on chain, owned by the signed-in wallet, and sets
+// metadata.TransactionHash. It submits a transaction and is therefore never retried.
+func (c *Client) StoreMetadata(ctx context.Context, metadata *FileMetadata) error {
+	body, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/store", nil, body, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var stored struct {
+		TransactionHash string `json:"transactionHash"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stored); err != nil {
+		return fmt.Errorf("failed to decode store response: %w", err)
+	}
+	metadata.TransactionHash = stored.TransactionHash
+	return nil
+}
+
+// GetMetadata returns the metadata of fileID.
+func (c *Client) GetMetadata(ctx context.Context, fileID string) (*FileMetadata, error) {
+	var metadata FileMetadata
+	if err := c.getJSON(ctx, "/metadata", url.Values{"fileID": {fileID}}, &metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// UpdateMetadata sets the deleted flag of fileID. Setting the flag twice has the same
+// effect as setting it once, so the call is retried.
+func (c *Client) UpdateMetadata(ctx context.Context, fileID string, isDeleted bool) error {
+	body, err := json.Marshal(map[string]bool{"isDeleted": isDeleted})
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodPut, "/update", url.Values{"fileID": {fileID}}, body, true)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListOwnedMetadata returns the metadata of every file owned by the signed-in wallet.
+func (c *Client) ListOwnedMetadata(ctx context.Context) ([]FileMetadata, error) {
+	var files []FileMetadata
+	if err := c.getJSON(ctx, "/files", nil, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// TransactionStatus reports whether the transaction hash is pending, succeeded or reverted.
+func (c *Client) TransactionStatus(ctx context.Context, hash string) (*TransactionStatus, error) {
+	var status TransactionStatus
+	if err := c.getJSON(ctx, "/tx", url.Values{"hash": {hash}}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out any) error {
+	resp, err := c.do(ctx, http.MethodGet, path, query, nil, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.sessionToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.sessionToken)
+	}
+	return req, nil
+}
+
+// do performs a request and turns error responses into *Error. Idempotent requests are
+// retried according to the retry policy; the body is resent on every attempt.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte, idempotent bool) (*http.Response, error) {
+	attempts := 1
+	if idempotent {
+		attempts = c.retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := c.newRequest(ctx, method, path, query, body)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && (!httpretry.RetryableStatus(resp.StatusCode) || attempt >= attempts) {
+			if resp.StatusCode >= 400 {
+				defer resp.Body.Close()
+				return nil, errorFromResponse(resp)
+			}
+			return resp, nil
+		}
+		if err != nil && (ctx.Err() != nil || attempt >= attempts) {
+			return nil, err
+		}
+
+		wait := c.retry.Delay(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
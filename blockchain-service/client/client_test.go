@@ -0,0 +1,147 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"decentralstore/blockchain-service/client"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetry = client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+func newClient(t *testing.T, handler http.HandlerFunc, opts ...client.Option) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := client.New(server.URL, append([]client.Option{fastRetry}, opts...)...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestStoreMetadata(t *testing.T) {
+	var calls atomic.Int32
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/store", r.URL.Path)
+		assert.Equal(t, "Bearer session-1", r.Header.Get("Authorization"))
+		var metadata client.FileMetadata
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&metadata))
+		assert.Equal(t, "f1", metadata.ID)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, client.WithSessionToken("session-1"))
+
+	err := c.StoreMetadata(context.Background(), &client.FileMetadata{ID: "f1", Name: "a.txt"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load(), "transactions are not retried")
+}
+
+func TestGetMetadata_RetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		assert.Equal(t, "f1", r.URL.Query().Get("fileID"))
+		w.Write([]byte(`{"id":"f1","name":"a.txt","owner":"0xabc"}`))
+	})
+
+	metadata, err := c.GetMetadata(context.Background(), "f1")
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", metadata.Name)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestUpdateMetadata_ResendsBodyOnRetry(t *testing.T) {
+	var calls atomic.Int32
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"isDeleted":true}`, string(body))
+		if calls.Load() < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"message":"Metadata updated successfully"}`))
+	})
+
+	require.NoError(t, c.UpdateMetadata(context.Background(), "f1", true))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"code":"forbidden","message":"caller is not the owner of this file","requestId":"req-3"}}`))
+	})
+
+	err := c.UpdateMetadata(context.Background(), "f1", true)
+	assert.ErrorIs(t, err, client.ErrForbidden)
+
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "caller is not the owner of this file", apiErr.Message)
+	assert.Equal(t, "req-3", apiErr.RequestID)
+}
+
+func TestErrorDetails(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":"invalid_request","message":"bad","details":[{"field":"query.hash","message":"must match pattern"}]}}`))
+	})
+
+	_, err := c.GetMetadata(context.Background(), "f1")
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, []client.Detail{{Field: "query.hash", Message: "must match pattern"}}, apiErr.Details)
+}
+
+func TestListOwnedMetadata(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/files", r.URL.Path)
+		w.Write([]byte(`[{"id":"f1"},{"id":"f2"}]`))
+	})
+
+	files, err := c.ListOwnedMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestStoreMetadata_SetsTransactionHash(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message":"Metadata stored successfully","transactionHash":"0xabc"}`))
+	})
+
+	metadata := &client.FileMetadata{ID: "f1"}
+	require.NoError(t, c.StoreMetadata(context.Background(), metadata))
+	assert.Equal(t, "0xabc", metadata.TransactionHash)
+}
+
+func TestTransactionStatus(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tx", r.URL.Path)
+		assert.Equal(t, "0xabc", r.URL.Query().Get("hash"))
+		w.Write([]byte(`{"hash":"0xabc","status":"succeeded","blockNumber":7,"confirmations":2}`))
+	})
+
+	status, err := c.TransactionStatus(context.Background(), "0xabc")
+	require.NoError(t, err)
+	assert.Equal(t, client.TxSucceeded, status.Status)
+	assert.Equal(t, uint64(7), status.BlockNumber)
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"decentralstore/shared/httperr"
+)
+
+// Error codes the service returns in Error.Code.
+const (
+	CodeBadRequest       = httperr.CodeBadRequest
+	CodeInvalidRequest   = httperr.CodeInvalidRequest
+	CodeUnauthorized     = httperr.CodeUnauthorized
+	CodeInvalidSignature = httperr.CodeInvalidSignature
+	CodeSignatureExpired = httperr.CodeSignatureExpired
+	CodeNonceMismatch    = httperr.CodeNonceMismatch
+	CodeForbidden        = httperr.CodeForbidden
+	CodeNotFound         = httperr.CodeNotFound
+	CodeMethodNotAllowed = httperr.CodeMethodNotAllowed
+	CodeConflict         = httperr.CodeConflict
+	CodeRateLimited      = httperr.CodeRateLimited
+	CodeInternal         = httperr.CodeInternal
+	CodeNotImplemented   = httperr.CodeNotImplemented
+	CodeUnavailable      = httperr.CodeUnavailable
+)
+
+// Sentinel errors for use with errors.Is; they match any *Error with the same Code.
+var (
+	ErrInvalidRequest   = &Error{Code: CodeInvalidRequest}
+	ErrUnauthorized     = &Error{Code: CodeUnauthorized}
+	ErrForbidden        = &Error{Code: CodeForbidden}
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrInvalidSignature = &Error{Code: CodeInvalidSignature}
+	ErrNonceMismatch    = &Error{Code: CodeNonceMismatch}
+	ErrRateLimited      = &Error{Code: CodeRateLimited}
+)
+
+// Error is an error response of the service.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	// RequestID identifies the request in the service logs.
+	RequestID string
+	// Details lists what was wrong with a request rejected with CodeInvalidRequest.
+	Details []Detail
+}
+
+// Detail is one problem with a rejected request, e.g. a missing parameter.
+type Detail = httperr.Detail
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("blockchain-service: %s (%d %s)", e.Message, e.StatusCode, e.Code)
+	if e.RequestID != "" {
+		msg += ", request " + e.RequestID
+	}
+	return msg
+}
+
+// Is reports whether target is an *Error with the same Code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// errorFromResponse reads the error envelope of resp. Responses that are not
+// enveloped, e.g. from a proxy, keep their body as the message.
+func errorFromResponse(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var envelope httperr.Envelope
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error.Code != "" {
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Code:       envelope.Error.Code,
+			Message:    envelope.Error.Message,
+			RequestID:  envelope.Error.RequestID,
+			Details:    envelope.Error.Details,
+		}
+	}
+
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Code:       httperr.CodeForStatus(resp.StatusCode),
+		Message:    message,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"decentralstore/blockchain-service/internal/api"
+	"decentralstore/blockchain-service/internal/apispec"
+	"decentralstore/blockchain-service/internal/auth"
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/eip712"
+	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/shared/health"
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/openapi"
+	"decentralstore/webhook"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// このファイルはmain_test.goより前に実行されるよう、名前の順序に依存している
+// （TestMissingContractAddressはプロセスを終了させるため）
+
+var testDomain = eip712.Domain{
+	Name:              "DecentralStore",
+	Version:           "1",
+	ChainID:           big.NewInt(1337),
+	VerifyingContract: common.HexToAddress("0x1234567890123456789012345678901234567890"),
+}
+
+// fakeChain is an in-memory contract and node that mines every transaction immediately.
+type fakeChain struct {
+	mu     sync.Mutex
+	files  map[string]domain.FileMetadata
+	nonces map[common.Address]uint64
+	blocks map[common.Hash]uint64
+	head   uint64
+	events event.Feed
+	// history keeps every event sent, for FilterMetadata.
+	history []*domain.MetadataEvent
+	// watching receives a value whenever WatchMetadata subscribes.
+	watching chan struct{}
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		files:    make(map[string]domain.FileMetadata),
+		nonces:   make(map[common.Address]uint64),
+		blocks:   make(map[common.Hash]uint64),
+		watching: make(chan struct{}, 1),
+	}
+}
+
+func (c *fakeChain) mine() *types.Transaction {
+	c.head++
+	tx := types.NewTx(&types.LegacyTx{Nonce: c.head})
+	c.blocks[tx.Hash()] = c.head
+	return tx
+}
+
+func (c *fakeChain) store(metadata *domain.FileMetadata) *types.Transaction {
+	c.mu.Lock()
+	c.files[metadata.ID] = *metadata
+	tx := c.mine()
+	metadataEvent := &domain.MetadataEvent{Kind: domain.MetadataStored, FileID: metadata.ID, Owner: metadata.Owner, BlockNumber: c.head, TransactionHash: tx.Hash().Hex()}
+	c.history = append(c.history, metadataEvent)
+	c.mu.Unlock()
+	c.events.Send(metadataEvent)
+	return tx
+}
+
+func (c *fakeChain) update(fileID string, isDeleted bool) *types.Transaction {
+	c.mu.Lock()
+	owner := c.files[fileID].Owner
+	tx := c.mine()
+	metadataEvent := &domain.MetadataEvent{Kind: domain.MetadataUpdated, FileID: fileID, Owner: owner, IsDeleted: isDeleted, BlockNumber: c.head, TransactionHash: tx.Hash().Hex()}
+	c.history = append(c.history, metadataEvent)
+	c.mu.Unlock()
+	c.events.Send(metadataEvent)
+	return tx
+}
+
+func (c *fakeChain) StoreMetadata(ctx context.Context, metadata *domain.FileMetadata, opts *bind.TransactOpts) (*types.Transaction, error) {
+	return c.store(metadata), nil
+}
+
+func (c *fakeChain) GetMetadata(ctx context.Context, fileID string) (*domain.FileMetadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	metadata, ok := c.files[fileID]
+	if !ok {
+		return nil, domain.ErrMetadataNotFound
+	}
+	return &metadata, nil
+}
+
+func (c *fakeChain) UpdateMetadata(ctx context.Context, fileID string, isDeleted bool, opts *bind.TransactOpts) (*types.Transaction, error) {
+	return c.update(fileID, isDeleted), nil
+}
+
+func (c *fakeChain) ListFileIDsByOwner(ctx context.Context, owner common.Address) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id, metadata := range c.files {
+		if common.HexToAddress(metadata.Owner) == owner {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (c *fakeChain) WaitForTransaction(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return c.TransactionReceipt(ctx, txHash)
+}
+
+func (c *fakeChain) Nonce(ctx context.Context, owner common.Address) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nonces[owner], nil
+}
+
+func (c *fakeChain) StoreMetadataWithSig(ctx context.Context, metadata *domain.FileMetadata, deadline uint64, signature []byte, opts *bind.TransactOpts) (*types.Transaction, error) {
+	c.mu.Lock()
+	c.nonces[common.HexToAddress(metadata.Owner)]++
+	c.mu.Unlock()
+	return c.store(metadata), nil
+}
+
+func (c *fakeChain) UpdateMetadataWithSig(ctx context.Context, owner common.Address, fileID string, isDeleted bool, deadline uint64, signature []byte, opts *bind.TransactOpts) (*types.Transaction, error) {
+	c.mu.Lock()
+	c.nonces[owner]++
+	c.mu.Unlock()
+	return c.update(fileID, isDeleted), nil
+}
+
+func (c *fakeChain) WatchMetadata(ctx context.Context, sink chan<- *domain.MetadataEvent) (event.Subscription, error) {
+	sub := c.events.Subscribe(sink)
+	select {
+	case c.watching <- struct{}{}:
+	default:
+	}
+	return sub, nil
+}
+
+func (c *fakeChain) FilterMetadata(ctx context.Context, fromBlock uint64) ([]*domain.MetadataEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var events []*domain.MetadataEvent
+	for _, metadataEvent := range c.history {
+		if metadataEvent.BlockNumber >= fromBlock {
+			events = append(events, metadataEvent)
+		}
+	}
+	return events, nil
+}
+
+func (c *fakeChain) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block, ok := c.blocks[hash]
+	if !ok {
+		return nil, false, ethereum.NotFound
+	}
+	return types.NewTx(&types.LegacyTx{Nonce: block}), false, nil
+}
+
+func (c *fakeChain) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block, ok := c.blocks[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: new(big.Int).SetUint64(block), GasUsed: 21000}, nil
+}
+
+func (c *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head, nil
+}
+
+// contractServer serves the real routes and checks every response against the
+// OpenAPI document, recording which operations were exercised.
+type contractServer struct {
+	*httptest.Server
+	doc    *openapi.Document
+	events *api.EventsHandler
+
+	mu        sync.Mutex
+	exercised map[string]bool
+}
+
+func newContractServer(t *testing.T, validateRequests bool) *contractServer {
+	t.Helper()
+	chain := newFakeChain()
+	authenticator := newTestAuthenticator()
+	webhooks := webhook.NewDispatcher(webhook.NewMemoryStore(), webhook.Options{AllowPrivateAddresses: true})
+	t.Cleanup(func() { webhooks.Close(context.Background()) })
+	events := api.NewEventsHandler(usecase.NewBlockchainService(chain, nil))
+	routes := newRouter(Handlers{
+		Blockchain:  api.NewBlockchainHandler(usecase.NewBlockchainService(chain, webhooks)),
+		Auth:        api.NewAuthHandler(authenticator),
+		Relay:       api.NewRelayHandler(usecase.NewRelayerService(chain, testDomain, webhooks)),
+		Transaction: api.NewTransactionHandler(usecase.NewTransactionService(chain)),
+		Webhooks:    api.NewWebhookHandler(webhooks),
+		Events:      events,
+		Ready:       health.NewChecker(time.Second).Ready,
+	}, RouterOptions{Authenticator: authenticator, ValidateRequests: validateRequests})
+
+	s := &contractServer{doc: apispec.MustLoad(), events: events, exercised: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, r)
+
+		if err := s.doc.ValidateResponse(r.Method, r.URL.Path, rr.Code, rr.Header(), rr.Body.Bytes()); err != nil {
+			t.Errorf("Response does not match the OpenAPI document: %v", err)
+		}
+		s.mu.Lock()
+		s.exercised[r.Method+" "+r.URL.Path] = true
+		s.mu.Unlock()
+
+		for name, values := range rr.Header() {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(rr.Code)
+		w.Write(rr.Body.Bytes())
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// call sends a request, optionally with a session token, and decodes the response into out if given.
+func (s *contractServer) call(t *testing.T, method, path, token string, body any, wantStatus int, out any) {
+	t.Helper()
+	var reader *strings.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = strings.NewReader("")
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		data, _ := json.Marshal(b)
+		reader = strings.NewReader(string(data))
+	}
+	req, _ := http.NewRequest(method, s.URL+path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: expected status %d; got %v", method, path, wantStatus, resp.Status)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+}
+
+// signIn runs the SIWE flow for key and returns the session token.
+func (s *contractServer) signIn(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	var nonce struct {
+		Nonce string `json:"nonce"`
+	}
+	s.call(t, "GET", "/auth/nonce", "", nil, http.StatusOK, &nonce)
+
+	message := (&auth.SIWEMessage{
+		Domain:    "localhost:8082",
+		Address:   crypto.PubkeyToAddress(key.PublicKey),
+		Statement: "Sign in to DecentralStore",
+		URI:       "http://localhost:8082",
+		Version:   "1",
+		ChainID:   1337,
+		Nonce:     nonce.Nonce,
+		IssuedAt:  time.Now().UTC().Truncate(time.Second),
+	}).String()
+	var session struct {
+		Token string `json:"token"`
+	}
+	s.call(t, "POST", "/auth/verify", "", map[string]string{
+		"message":   message,
+		"signature": sign(t, key, accounts.TextHash([]byte(message))),
+	}, http.StatusOK, &session)
+	return session.Token
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, digest []byte) string {
+	t.Helper()
+	sig, err := crypto.Sign(digest, key)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig)
+}
+
+func TestOpenAPI_Contract(t *testing.T) {
+	s := newContractServer(t, false)
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+
+	token := s.signIn(t, key)
+	s.call(t, "POST", "/auth/verify", "", map[string]string{"message": "hello", "signature": "0x00"}, http.StatusUnauthorized, nil)
+	s.call(t, "POST", "/auth/verify", "", "{", http.StatusBadRequest, nil)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+	var subscription struct {
+		ID string `json:"id"`
+	}
+	s.call(t, "POST", "/webhooks", token, map[string]any{"url": receiver.URL}, http.StatusCreated, &subscription)
+	s.call(t, "POST", "/webhooks", token, map[string]any{"url": "ftp://example.com"}, http.StatusBadRequest, nil)
+	s.call(t, "GET", "/webhooks", token, nil, http.StatusOK, nil)
+	s.call(t, "GET", "/webhooks", "", nil, http.StatusUnauthorized, nil)
+
+	metadata := map[string]any{"id": "file-1", "name": "a.txt", "size": 12, "cid": "QmTest", "uploadedAt": time.Now()}
+	var stored struct {
+		TransactionHash string `json:"transactionHash"`
+	}
+	s.call(t, "POST", "/store", token, metadata, http.StatusCreated, &stored)
+	s.call(t, "POST", "/store", "", metadata, http.StatusUnauthorized, nil)
+	s.call(t, "GET", "/metadata?fileID=file-1", "", nil, http.StatusOK, nil)
+	s.call(t, "GET", "/metadata", "", nil, http.StatusBadRequest, nil)
+	s.call(t, "GET", "/metadata?fileID=missing", "", nil, http.StatusInternalServerError, nil)
+	s.call(t, "PUT", "/update?fileID=file-1", token, map[string]bool{"isDeleted": true}, http.StatusOK, nil)
+	s.call(t, "PUT", "/update?fileID=file-1", "", map[string]bool{"isDeleted": true}, http.StatusUnauthorized, nil)
+	s.call(t, "GET", "/files", token, nil, http.StatusOK, nil)
+	s.call(t, "GET", "/files", "", nil, http.StatusUnauthorized, nil)
+
+	s.call(t, "GET", "/tx?hash="+stored.TransactionHash, "", nil, http.StatusOK, nil)
+	s.call(t, "GET", "/tx?hash="+common.Hash{1}.Hex(), "", nil, http.StatusNotFound, nil)
+	s.call(t, "GET", "/tx?hash=0x12", "", nil, http.StatusBadRequest, nil)
+
+	s.call(t, "GET", "/relay/domain", "", nil, http.StatusOK, nil)
+	s.call(t, "GET", "/relay/nonce?owner="+owner.Hex(), "", nil, http.StatusOK, nil)
+	s.call(t, "GET", "/relay/nonce?owner=me", "", nil, http.StatusBadRequest, nil)
+
+	deadline := uint64(time.Now().Add(time.Hour).Unix())
+	storeRequest := domain.RelayStoreRequest{Owner: owner.Hex(), ID: "file-2", Name: "b.txt", Size: 3, CID: "QmOther", Nonce: 0, Deadline: deadline}
+	storeRequest.Signature = sign(t, key, eip712.Digest(testDomain, eip712.StoreMetadata{
+		Owner: owner, FileID: storeRequest.ID, Name: storeRequest.Name, Size: uint64(storeRequest.Size),
+		CID: storeRequest.CID, Nonce: storeRequest.Nonce, Deadline: deadline,
+	}.Hash()).Bytes())
+	s.call(t, "POST", "/relay/store", "", storeRequest, http.StatusCreated, nil)
+	s.call(t, "POST", "/relay/store", "", storeRequest, http.StatusConflict, nil)
+
+	updateRequest := domain.RelayUpdateRequest{Owner: owner.Hex(), FileID: "file-2", IsDeleted: true, Nonce: 1, Deadline: deadline}
+	updateRequest.Signature = sign(t, key, eip712.Digest(testDomain, eip712.UpdateMetadata{
+		Owner: owner, FileID: updateRequest.FileID, IsDeleted: true, Nonce: 1, Deadline: deadline,
+	}.Hash()).Bytes())
+	s.call(t, "POST", "/relay/update", "", updateRequest, http.StatusOK, nil)
+	updateRequest.IsDeleted = false
+	s.call(t, "POST", "/relay/update", "", updateRequest, http.StatusUnauthorized, nil)
+
+	var deliveries []struct {
+		ID string `json:"id"`
+	}
+	s.call(t, "GET", "/webhooks/deliveries?subscriptionId="+subscription.ID, token, nil, http.StatusOK, &deliveries)
+	s.call(t, "GET", "/webhooks/deliveries?subscriptionId=missing", token, nil, http.StatusNotFound, nil)
+	if len(deliveries) == 0 {
+		t.Fatal("Expected webhook deliveries for the metadata events")
+	}
+	s.call(t, "POST", "/webhooks/deliveries/replay?id="+deliveries[0].ID, token, nil, http.StatusAccepted, nil)
+	s.call(t, "POST", "/webhooks/deliveries/replay", token, nil, http.StatusBadRequest, nil)
+	s.call(t, "DELETE", "/webhooks?id="+subscription.ID, token, nil, http.StatusNoContent, nil)
+	s.call(t, "DELETE", "/webhooks?id="+subscription.ID, token, nil, http.StatusNotFound, nil)
+
+	for _, path := range []string{"/metrics", "/healthz", "/readyz", "/openapi.json"} {
+		s.call(t, "GET", path, "", nil, http.StatusOK, nil)
+	}
+	// The contract server buffers responses, so end event streams right after their headers.
+	s.events.Shutdown()
+	s.call(t, "GET", "/events?owner="+owner.Hex(), "", nil, http.StatusOK, nil)
+	s.call(t, "GET", "/events?owner=me", "", nil, http.StatusBadRequest, nil)
+	s.call(t, "GET", "/events?lastEventId=latest", "", nil, http.StatusBadRequest, nil)
+	s.call(t, "POST", "/auth/logout", token, nil, http.StatusNoContent, nil)
+	s.call(t, "POST", "/auth/logout", "", nil, http.StatusUnauthorized, nil)
+
+	var missing []string
+	for path, item := range s.doc.Paths {
+		for method := range item.Operations() {
+			if !s.exercised[method+" "+path] {
+				missing = append(missing, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("Operations not exercised by the contract test: %v", missing)
+	}
+}
+
+func TestWebhooks_MetadataEvents(t *testing.T) {
+	s := newContractServer(t, false)
+	key, _ := crypto.GenerateKey()
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	token := s.signIn(t, key)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	events := make(chan received, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		events <- received{header: r.Header, body: body}
+	}))
+	defer receiver.Close()
+
+	var subscription struct {
+		Secret string `json:"secret"`
+	}
+	s.call(t, "POST", "/webhooks", token, map[string]any{"url": receiver.URL, "events": []string{"metadata.anchored", "metadata.confirmed"}}, http.StatusCreated, &subscription)
+
+	var stored struct {
+		TransactionHash string `json:"transactionHash"`
+	}
+	metadata := map[string]any{"id": "file-1", "name": "a.txt", "size": 12, "cid": "QmTest", "uploadedAt": time.Now()}
+	s.call(t, "POST", "/store", token, metadata, http.StatusCreated, &stored)
+
+	// Deliveries run concurrently, so the two events may arrive in either order.
+	got := map[string]domain.TransactionEvent{}
+	for len(got) < 2 {
+		var delivery received
+		select {
+		case delivery = <-events:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected two webhooks; got %v", got)
+		}
+		if err := webhook.Verify(subscription.Secret, delivery.header.Get(webhook.HeaderSignature), delivery.body, time.Minute, time.Now()); err != nil {
+			t.Errorf("Invalid webhook signature: %v", err)
+		}
+		var event struct {
+			Type string                  `json:"type"`
+			Data domain.TransactionEvent `json:"data"`
+		}
+		json.Unmarshal(delivery.body, &event)
+		got[event.Type] = event.Data
+	}
+
+	for _, eventType := range []string{domain.EventMetadataAnchored, domain.EventMetadataConfirmed} {
+		event, ok := got[eventType]
+		if !ok {
+			t.Errorf("Expected a %s webhook; got %v", eventType, got)
+			continue
+		}
+		if event.Operation != domain.OperationStore || event.FileID != "file-1" || event.Owner != owner.Hex() ||
+			event.TransactionHash != stored.TransactionHash {
+			t.Errorf("Unexpected %s event: %+v", eventType, event)
+		}
+	}
+	if got[domain.EventMetadataConfirmed].BlockNumber == 0 {
+		t.Errorf("Expected the block number in the confirmed event; got %+v", got[domain.EventMetadataConfirmed])
+	}
+}
+
+// TestOpenAPI_DocumentsEveryRoute compares the routes registered in newRouter with
+// the paths of the document.
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse main.go: %v", err)
+	}
+	routes := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+			return true
+		}
+		if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			path, _ := strconv.Unquote(lit.Value)
+			routes[path] = true
+		}
+		return true
+	})
+
+	doc := apispec.MustLoad()
+	for path := range routes {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("Route %s is not in the OpenAPI document", path)
+		}
+	}
+	for path := range doc.Paths {
+		if !routes[path] {
+			t.Errorf("Path %s of the OpenAPI document is not a route", path)
+		}
+	}
+}
+
+func TestOpenAPI_ValidateRequests(t *testing.T) {
+	s := newContractServer(t, true)
+
+	req, _ := http.NewRequest("GET", s.URL+"/tx?hash=0x12", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope httperr.Envelope
+	json.NewDecoder(resp.Body).Decode(&envelope)
+	if resp.StatusCode != http.StatusBadRequest || envelope.Error.Code != httperr.CodeInvalidRequest {
+		t.Fatalf("Expected an invalid_request error; got %v %+v", resp.Status, envelope.Error)
+	}
+	if got := fmt.Sprint(envelope.Error.Details); !strings.Contains(got, "query.hash") {
+		t.Errorf("Expected a detail for query.hash; got %s", got)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"decentralstore/blockchain-service/internal/api"
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/shared/health"
+)
+
+// sseEvent is one event read from a text/event-stream body.
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readSSEEvent returns the next event of the stream, skipping comments and retry fields.
+func readSSEEvent(t *testing.T, reader *bufio.Reader) (sseEvent, error) {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return event, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if event.Event != "" {
+				return event, nil
+			}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Event = value
+		case "data":
+			event.Data = value
+		}
+	}
+}
+
+func TestEvents_ResumeFromLastEventID(t *testing.T) {
+	chain := newFakeChain()
+	events := api.NewEventsHandler(usecase.NewBlockchainService(chain, nil))
+	server := httptest.NewServer(newRouter(Handlers{
+		Events: events,
+		Ready:  health.NewChecker(time.Second).Ready,
+	}, RouterOptions{Authenticator: newTestAuthenticator()}))
+	defer server.Close()
+
+	owner := "0x1234567890123456789012345678901234567890"
+	chain.store(&domain.FileMetadata{ID: "file-1", Owner: owner})                                        // 1-0
+	chain.store(&domain.FileMetadata{ID: "file-2", Owner: "0x0000000000000000000000000000000000000bad"}) // 2-0
+	chain.update("file-1", true)                                                                         // 3-0
+
+	req, _ := http.NewRequest("GET", server.URL+"/events?owner="+owner, nil)
+	req.Header.Set("Last-Event-ID", "1-0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /events failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected Content-Type text/event-stream; got %q", contentType)
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	missed, err := readSSEEvent(t, reader)
+	if err != nil {
+		t.Fatalf("Failed to read the missed event: %v", err)
+	}
+	if missed.ID != "3-0" || missed.Event != "MetadataUpdated" {
+		t.Errorf("Expected MetadataUpdated 3-0 first; got %s %s", missed.Event, missed.ID)
+	}
+	var data domain.MetadataEvent
+	if err := json.Unmarshal([]byte(missed.Data), &data); err != nil {
+		t.Fatalf("Failed to decode event data %q: %v", missed.Data, err)
+	}
+	if data.FileID != "file-1" || !data.IsDeleted {
+		t.Errorf("Unexpected event data: %+v", data)
+	}
+
+	<-chain.watching
+	chain.store(&domain.FileMetadata{ID: "file-3", Owner: owner})
+	live, err := readSSEEvent(t, reader)
+	if err != nil {
+		t.Fatalf("Failed to read the live event: %v", err)
+	}
+	if live.ID != "4-0" || live.Event != "MetadataStored" {
+		t.Errorf("Expected MetadataStored 4-0; got %s %s", live.Event, live.ID)
+	}
+
+	events.Shutdown()
+	if _, err := readSSEEvent(t, reader); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the stream to end on shutdown; got %v", err)
+	}
+}
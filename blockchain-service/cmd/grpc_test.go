@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"net"
+	"testing"
+	"time"
+
+	"decentralstore/blockchain-service/internal/auth"
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/blockchain-service/rpc/blockchainpb"
+	"decentralstore/shared/httperr"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newTestGRPCClient serves the gRPC API over an in-memory listener backed by chain.
+func newTestGRPCClient(t *testing.T, chain *fakeChain, authenticator *auth.Authenticator) blockchainpb.BlockchainServiceClient {
+	t.Helper()
+
+	ln := bufconn.Listen(1 << 20)
+	server := newGRPCServer(usecase.NewBlockchainService(chain, nil), authenticator)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return blockchainpb.NewBlockchainServiceClient(conn)
+}
+
+// withSession signs key in and returns a context that sends the session token.
+func withSession(t *testing.T, authenticator *auth.Authenticator, key *ecdsa.PrivateKey) context.Context {
+	t.Helper()
+	nonce, err := authenticator.Nonce()
+	if err != nil {
+		t.Fatalf("Failed to issue nonce: %v", err)
+	}
+	message := (&auth.SIWEMessage{
+		Domain:    "localhost:8082",
+		Address:   crypto.PubkeyToAddress(key.PublicKey),
+		Statement: "Sign in to DecentralStore",
+		URI:       "http://localhost:8082",
+		Version:   "1",
+		ChainID:   1337,
+		Nonce:     nonce,
+		IssuedAt:  time.Now().UTC().Truncate(time.Second),
+	}).String()
+	session, err := authenticator.SignIn(message, sign(t, key, accounts.TextHash([]byte(message))))
+	if err != nil {
+		t.Fatalf("Failed to sign in: %v", err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+session.Token)
+}
+
+// assertGRPCError checks the gRPC code and the envelope code carried in the ErrorInfo detail.
+func assertGRPCError(t *testing.T, err error, wantCode codes.Code, wantReason string) {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != wantCode {
+		t.Fatalf("Expected code %v; got %v (%v)", wantCode, st.Code(), err)
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason == wantReason {
+			return
+		}
+	}
+	t.Errorf("Expected reason %q in %v", wantReason, st.Details())
+}
+
+func newTestAuthenticator() *auth.Authenticator {
+	return auth.NewAuthenticator("localhost:8082", 1337, auth.NewMemoryNonceStore(time.Minute), auth.NewMemorySessionStore(time.Hour))
+}
+
+func TestGRPC_StoreGetUpdate(t *testing.T) {
+	chain, authenticator := newFakeChain(), newTestAuthenticator()
+	client := newTestGRPCClient(t, chain, authenticator)
+	owner, _ := crypto.GenerateKey()
+	ctx := withSession(t, authenticator, owner)
+
+	file := &blockchainpb.FileMetadata{Id: "file-1", Name: "a.txt", Size: 12, Cid: "QmTest", UploadedAt: timestamppb.Now()}
+	stored, err := client.Store(ctx, &blockchainpb.StoreRequest{Metadata: file})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if stored.GetTransactionHash() == "" || stored.GetBlockNumber() == 0 {
+		t.Errorf("Expected the transaction of the store; got %v", stored)
+	}
+
+	got, err := client.Get(context.Background(), &blockchainpb.GetRequest{FileId: "file-1"})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.GetOwner() != crypto.PubkeyToAddress(owner.PublicKey).Hex() || got.GetCid() != "QmTest" {
+		t.Errorf("Unexpected metadata: %v", got)
+	}
+
+	other, _ := crypto.GenerateKey()
+	_, err = client.Update(withSession(t, authenticator, other), &blockchainpb.UpdateRequest{FileId: "file-1", IsDeleted: true})
+	assertGRPCError(t, err, codes.PermissionDenied, httperr.CodeForbidden)
+
+	if _, err := client.Update(ctx, &blockchainpb.UpdateRequest{FileId: "file-1", IsDeleted: true}); err != nil {
+		t.Errorf("Update failed: %v", err)
+	}
+}
+
+func TestGRPC_RequiresSession(t *testing.T) {
+	client := newTestGRPCClient(t, newFakeChain(), newTestAuthenticator())
+	file := &blockchainpb.FileMetadata{Id: "file-1", Name: "a.txt"}
+
+	_, err := client.Store(context.Background(), &blockchainpb.StoreRequest{Metadata: file})
+	assertGRPCError(t, err, codes.Unauthenticated, httperr.CodeUnauthorized)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer expired")
+	_, err = client.Store(ctx, &blockchainpb.StoreRequest{Metadata: file})
+	assertGRPCError(t, err, codes.Unauthenticated, httperr.CodeUnauthorized)
+
+	_, err = client.Get(context.Background(), &blockchainpb.GetRequest{})
+	assertGRPCError(t, err, codes.InvalidArgument, httperr.CodeBadRequest)
+}
+
+func TestGRPC_WatchMetadata(t *testing.T) {
+	chain, authenticator := newFakeChain(), newTestAuthenticator()
+	client := newTestGRPCClient(t, chain, authenticator)
+	owner, _ := crypto.GenerateKey()
+	ctx := withSession(t, authenticator, owner)
+
+	watchCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchMetadata(watchCtx, &blockchainpb.WatchMetadataRequest{Owner: crypto.PubkeyToAddress(owner.PublicKey).Hex()})
+	if err != nil {
+		t.Fatalf("WatchMetadata failed: %v", err)
+	}
+	<-chain.watching
+
+	chain.store(&domain.FileMetadata{ID: "someone-else", Owner: "0x0000000000000000000000000000000000000bad"})
+	if _, err := client.Store(ctx, &blockchainpb.StoreRequest{Metadata: &blockchainpb.FileMetadata{Id: "file-1", Name: "a.txt"}}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := client.Update(ctx, &blockchainpb.UpdateRequest{FileId: "file-1", IsDeleted: true}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	for _, want := range []blockchainpb.MetadataEvent_Kind{blockchainpb.MetadataEvent_KIND_STORED, blockchainpb.MetadataEvent_KIND_UPDATED} {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if event.GetKind() != want || event.GetFileId() != "file-1" {
+			t.Errorf("Expected a %v event of file-1; got %v", want, event)
+		}
+	}
+
+	invalid, err := client.WatchMetadata(context.Background(), &blockchainpb.WatchMetadataRequest{Owner: "me"})
+	if err == nil {
+		_, err = invalid.Recv()
+	}
+	assertGRPCError(t, err, codes.InvalidArgument, httperr.CodeBadRequest)
+}
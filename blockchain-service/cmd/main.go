@@ -2,86 +2,368 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"math/big"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"decentralstore/blockchain-service/internal/api"
+	"decentralstore/blockchain-service/internal/apispec"
+	"decentralstore/blockchain-service/internal/auth"
+	"decentralstore/blockchain-service/internal/config"
+	"decentralstore/blockchain-service/internal/eip712"
+	"decentralstore/blockchain-service/internal/healthcheck"
 	"decentralstore/blockchain-service/internal/infrastructure"
+	"decentralstore/blockchain-service/internal/metrics"
 	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/blockchain-service/rpc/blockchainpb"
+	"decentralstore/shared/health"
+	"decentralstore/shared/logging"
+	"decentralstore/shared/ratelimit"
+	"decentralstore/shared/servermetrics"
+	"decentralstore/shared/tracing"
+	"decentralstore/webhook"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	log.Println("Starting Blockchain Service...")
+	// 設定の読み込み
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
 
-	// 環境変数の取得
-	ethereumRPCURL := os.Getenv("ETHEREUM_RPC_URL")
-	if ethereumRPCURL == "" {
-		log.Println("ETHEREUM_RPC_URL not set, using default: http://localhost:8545")
-		ethereumRPCURL = "http://localhost:8545"
+	// ログの初期化
+	levelVar, err := logging.Setup(os.Stdout, cfg.Log.Level)
+	if err != nil {
+		fatal("Failed to configure logging", err)
 	}
+	slog.Info("Starting Blockchain Service")
+	slog.Info("Effective configuration", "config", cfg.String())
 
-	contractAddress := os.Getenv("CONTRACT_ADDRESS")
-	if contractAddress == "" {
-		log.Fatal("CONTRACT_ADDRESS environment variable is not set")
+	// トレーシングの初期化
+	shutdownTracing, err := tracing.Setup(context.Background(), "blockchain-service")
+	if err != nil {
+		fatal("Failed to configure tracing", err)
 	}
 
 	// Ethereumクライアントの初期化
-	ethereumClient, err := infrastructure.NewEthereumClient(ethereumRPCURL)
+	ethereumClient, err := infrastructure.NewEthereumClient(cfg.Ethereum.RPCURL)
 	if err != nil {
-		log.Fatalf("Failed to create Ethereum client: %v", err)
+		fatal("Failed to create Ethereum client", err)
 	}
 	defer ethereumClient.Close()
 
 	// スマートコントラクトの初期化
-	contract, err := infrastructure.NewFileMetadataContract(common.HexToAddress(contractAddress), ethereumClient)
+	contract, err := infrastructure.NewFileMetadataContract(common.HexToAddress(cfg.Contract.Address), ethereumClient)
 	if err != nil {
-		log.Fatalf("Failed to create smart contract instance: %v", err)
+		fatal("Failed to create smart contract instance", err)
+	}
+
+	chainID := cfg.ChainID()
+
+	// レディネスチェックの設定
+	checker := health.NewChecker(5 * time.Second)
+	checker.Add("chainId", healthcheck.ChainID(ethereumClient, chainID))
+	checker.Add("latestBlock", healthcheck.BlockAge(ethereumClient, cfg.Readiness.MaxBlockAge))
+	checker.Add("contract", healthcheck.ContractCode(ethereumClient, contract.Address()))
+
+	// サービスアカウント（ガス代の支払い元）の設定
+	if cfg.Signer.PrivateKey != "" {
+		transactor, err := newTransactor(cfg.Signer.PrivateKey, chainID)
+		if err != nil {
+			fatal("Failed to load service account", err)
+		}
+		contract.SetTransactor(transactor)
+		slog.Info("Relaying transactions from service account", "address", transactor.From.Hex())
+		checker.Add("signerBalance", healthcheck.Balance(ethereumClient, transactor.From, cfg.MinBalance()))
+	} else {
+		slog.Warn("signer.privateKey not set, transactions will not be signed")
 	}
 
+	// Redisの初期化（設定されていればレプリカ間でセッション、レート制限とWebhookを共有する）
+	var redisClient *redis.Client
+	if cfg.Redis.URL != "" {
+		redisClient = redis.NewClient(&redis.Options{Addr: cfg.Redis.URL})
+		defer redisClient.Close()
+	} else {
+		slog.Warn("redis.url not set, sessions, rate limits and webhooks are kept per replica")
+	}
+
+	// Webhookの初期化
+	webhooks := newWebhooks(redisClient)
+
 	// ユースケースの初期化
-	blockchainService := usecase.NewBlockchainService(contract)
+	blockchainService := usecase.WithTracing(usecase.NewBlockchainService(contract, webhooks))
+	relayerService := usecase.WithRelayerTracing(usecase.NewRelayerService(contract, eip712.Domain{
+		Name:              "DecentralStore",
+		Version:           "1",
+		ChainID:           chainID,
+		VerifyingContract: contract.Address(),
+	}, webhooks))
 
-	// ハンドラーの初期化
-	handler := api.NewBlockchainHandler(blockchainService)
+	// SIWE認証の初期化
+	authenticator := newAuthenticator(cfg, redisClient)
 
-	// ルーターの設定
-	mux := http.NewServeMux()
-	mux.HandleFunc("/store", handler.StoreMetadata)
-	mux.HandleFunc("/metadata", handler.GetMetadata)
-	mux.HandleFunc("/update", handler.UpdateMetadata)
+	// レート制限の初期化
+	rateLimits, err := cfg.RateLimits()
+	if err != nil {
+		fatal("Failed to configure rate limits", err)
+	}
+	rateLimiter := newRateLimiter(redisClient, rateLimits)
+
+	// ハンドラーの初期化とルーターの設定
+	events := api.NewEventsHandler(blockchainService)
+	router := newRouter(Handlers{
+		Blockchain:  api.NewBlockchainHandler(blockchainService),
+		Auth:        api.NewAuthHandler(authenticator),
+		Relay:       api.NewRelayHandler(relayerService),
+		Transaction: api.NewTransactionHandler(usecase.NewTransactionService(ethereumClient)),
+		Webhooks:    api.NewWebhookHandler(webhooks),
+		Events:      events,
+		Ready:       checker.Ready,
+	}, RouterOptions{
+		Authenticator:    authenticator,
+		RateLimiter:      rateLimiter,
+		ValidateRequests: cfg.Server.ValidateRequests,
+	})
 
 	// HTTPサーバーの設定
 	server := &http.Server{
-		Addr:    ":8082",
-		Handler: mux,
+		Addr:    cfg.Server.Addr,
+		Handler: router,
 	}
+	// SSEストリームはShutdownで終了させる（クライアントはLast-Event-IDで再接続する）
+	server.RegisterOnShutdown(events.Shutdown)
 
 	// サーバーを非同期で起動
 	go func() {
-		log.Printf("Server is listening on %s", server.Addr)
+		slog.Info("Server is listening", "addr", server.Addr)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			fatal("Server failed to start", err)
 		}
 	}()
 
+	// gRPCサーバーを非同期で起動
+	grpcServer := newGRPCServer(blockchainService, authenticator)
+	if cfg.Server.GRPCAddr != "" {
+		grpcLn, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+		if err != nil {
+			fatal("Failed to listen for gRPC", err)
+		}
+		go func() {
+			slog.Info("gRPC server is listening", "addr", grpcLn.Addr().String())
+			if err := grpcServer.Serve(grpcLn); err != nil {
+				fatal("gRPC server failed to start", err)
+			}
+		}()
+	}
+
+	// SIGHUPで設定を再読み込み
+	reloadOnSIGHUP(cfg, os.Args[1:], func(cfg *config.Config) {
+		level, _ := logging.ParseLevel(cfg.Log.Level)
+		levelVar.Set(level)
+		limits, _ := cfg.RateLimits()
+		rateLimiter.SetLimits(limits)
+	})
+
 	// シグナル処理
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// グレースフルシャットダウン
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("Server forced to shutdown", err)
+	}
+	stopGRPC(ctx, grpcServer)
+	if err := webhooks.Close(ctx); err != nil {
+		slog.Error("Webhook deliveries did not finish", "error", err)
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+
+	slog.Info("Server exiting")
+}
+
+// Handlers are the HTTP handlers mounted by newRouter.
+type Handlers struct {
+	Blockchain  *api.BlockchainHandler
+	Auth        *api.AuthHandler
+	Relay       *api.RelayHandler
+	Transaction *api.TransactionHandler
+	Webhooks    *api.WebhookHandler
+	Events      *api.EventsHandler
+	Ready       http.HandlerFunc
+}
+
+// RouterOptions holds the middleware settings of newRouter.
+type RouterOptions struct {
+	// Authenticator resolves session tokens to wallets.
+	Authenticator *auth.Authenticator
+	// RateLimiter limits requests per client; nil disables rate limiting.
+	RateLimiter *ratelimit.Limiter
+	// ValidateRequests rejects requests that do not match the OpenAPI document with a 400.
+	ValidateRequests bool
+}
+
+// newRouter mounts the API routes and wraps them in the tracing, logging, metrics
+// and session middlewares.
+func newRouter(h Handlers, opts RouterOptions) http.Handler {
+	rateLimiter := opts.RateLimiter
+	spec := apispec.MustLoad()
 
-	log.Println("Server exiting")
+	mux := http.NewServeMux()
+	mux.Handle("/store", rateLimiter.Wrap(ratelimit.ClassWrite, http.HandlerFunc(h.Blockchain.StoreMetadata)))
+	mux.HandleFunc("/metadata", h.Blockchain.GetMetadata)
+	mux.Handle("/update", rateLimiter.Wrap(ratelimit.ClassWrite, http.HandlerFunc(h.Blockchain.UpdateMetadata)))
+	mux.HandleFunc("/files", h.Blockchain.ListOwnedMetadata)
+	mux.HandleFunc("/tx", h.Transaction.Status)
+	mux.Handle("/auth/nonce", rateLimiter.Wrap(ratelimit.ClassAuth, http.HandlerFunc(h.Auth.Nonce)))
+	mux.Handle("/auth/verify", rateLimiter.Wrap(ratelimit.ClassAuth, http.HandlerFunc(h.Auth.Verify)))
+	mux.HandleFunc("/auth/logout", h.Auth.Logout)
+	mux.HandleFunc("/relay/domain", h.Relay.Domain)
+	mux.HandleFunc("/relay/nonce", h.Relay.Nonce)
+	mux.Handle("/relay/store", rateLimiter.Wrap(ratelimit.ClassWrite, http.HandlerFunc(h.Relay.StoreMetadata)))
+	mux.Handle("/relay/update", rateLimiter.Wrap(ratelimit.ClassWrite, http.HandlerFunc(h.Relay.UpdateMetadata)))
+	mux.HandleFunc("/webhooks", h.Webhooks.Webhooks)
+	mux.HandleFunc("/webhooks/deliveries", h.Webhooks.Deliveries)
+	mux.HandleFunc("/webhooks/deliveries/replay", h.Webhooks.Replay)
+	mux.HandleFunc("/events", h.Events.Stream)
+	mux.Handle("/metrics", servermetrics.Handler())
+	mux.HandleFunc("/healthz", health.Live)
+	mux.HandleFunc("/readyz", h.Ready)
+	mux.Handle("/openapi.json", spec)
+
+	var handler http.Handler = mux
+	if opts.ValidateRequests {
+		handler = spec.Middleware(handler)
+	}
+	handler = opts.Authenticator.Middleware(handler)
+	return tracing.Middleware(mux, logging.Middleware(mux, metrics.Requests.Instrument(mux, handler)))
+}
+
+// newGRPCServer returns the gRPC API, which shares the usecase and sessions with the HTTP routes.
+func newGRPCServer(service usecase.BlockchainService, authenticator *auth.Authenticator) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(authenticator.StreamServerInterceptor()),
+	)
+	blockchainpb.RegisterBlockchainServiceServer(server, api.NewBlockchainServer(service))
+	return server
+}
+
+// stopGRPC lets running calls finish until ctx is done and then cancels them, which
+// also ends WatchMetadata streams.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+// fatal logs err and exits; deferred functions do not run.
+func fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
+}
+
+// reloadOnSIGHUP reloads the configuration whenever the process receives SIGHUP. Settings
+// marked reloadable are copied into cfg and passed to apply; other changes are only logged
+// because they need a restart. A configuration that fails validation is ignored.
+func reloadOnSIGHUP(cfg *config.Config, args []string, apply func(*config.Config)) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			next, err := config.Load(args)
+			if err != nil {
+				slog.Error("Failed to reload configuration", "error", err)
+				continue
+			}
+			for _, change := range cfg.Diff(next) {
+				if change.Reloadable {
+					slog.Info("Configuration changed", "setting", change.Path)
+				} else {
+					slog.Warn("Configuration change needs a restart", "setting", change.Path)
+				}
+			}
+			cfg.ApplyReloadable(next)
+			apply(cfg)
+		}
+	}()
+}
+
+// nonceTTL is how long a SIWE nonce can be used after it is issued.
+const nonceTTL = 5 * time.Minute
+
+// newAuthenticator builds the SIWE authenticator. With Redis, nonces and sessions are shared
+// by all replicas, so a client may sign in and use its session on any of them.
+func newAuthenticator(cfg *config.Config, redisClient *redis.Client) *auth.Authenticator {
+	if redisClient == nil {
+		return auth.NewAuthenticator(cfg.SIWE.Domain, cfg.SIWE.ChainID,
+			auth.NewMemoryNonceStore(nonceTTL), auth.NewMemorySessionStore(cfg.Session.TTL))
+	}
+	return auth.NewAuthenticator(cfg.SIWE.Domain, cfg.SIWE.ChainID,
+		auth.NewRedisNonceStore(redisClient, nonceTTL), auth.NewRedisSessionStore(redisClient, cfg.Session.TTL))
+}
+
+// newWebhooks builds the webhook dispatcher. With Redis the subscriptions and the delivery
+// log are shared by all replicas and survive restarts.
+func newWebhooks(redisClient *redis.Client) *webhook.Dispatcher {
+	var store webhook.Store = webhook.NewMemoryStore()
+	if redisClient != nil {
+		store = webhook.NewRedisStore(redisClient)
+	}
+	return webhook.NewDispatcher(store, webhook.Options{})
+}
+
+// newRateLimiter builds the per-client limiter. With Redis the buckets are shared by all replicas.
+func newRateLimiter(redisClient *redis.Client, limits map[string]ratelimit.Limit) *ratelimit.Limiter {
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if redisClient != nil {
+		store = ratelimit.NewRedisStore(redisClient)
+	}
+	return ratelimit.NewLimiter(store, limits, rateLimitKey)
+}
+
+// rateLimitKey accounts signed-in requests to the wallet and all others to the client IP.
+func rateLimitKey(r *http.Request) string {
+	if wallet, ok := auth.WalletFromContext(r.Context()); ok {
+		return "wallet:" + wallet.Hex()
+	}
+	return "ip:" + ratelimit.ClientIP(r)
+}
+
+// newTransactor loads the hex-encoded private key of the account that pays for relayed transactions.
+func newTransactor(hexKey string, chainID *big.Int) (*bind.TransactOpts, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewKeyedTransactorWithChainID(key, chainID)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -22,6 +23,10 @@ func TestMain(m *testing.M) {
 }
 
 func setup() {
+	// go testのフラグをmainが設定フラグとして解釈しないようにする
+	flag.Parse()
+	os.Args = os.Args[:1]
+
 	// テストに必要な環境変数を設定
 	os.Setenv("ETHEREUM_RPC_URL", "http://localhost:8545")
 	os.Setenv("CONTRACT_ADDRESS", "0x1234567890123456789012345678901234567890")
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"decentralstore/blockchain-service/internal/auth"
+	"decentralstore/shared/httperr"
+)
+
+type AuthHandler struct {
+	authenticator *auth.Authenticator
+}
+
+func NewAuthHandler(authenticator *auth.Authenticator) *AuthHandler {
+	return &AuthHandler{authenticator: authenticator}
+}
+
+// Nonce issues a nonce for the client to embed in its SIWE message.
+func (h *AuthHandler) Nonce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	nonce, err := h.authenticator.Nonce()
+	if err != nil {
+		httperr.Error(w, r, "Failed to issue nonce", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"nonce": nonce})
+}
+
+// Verify checks a signed SIWE message and returns a session token for the wallet.
+func (h *AuthHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Message   string `json:"message"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Message == "" || request.Signature == "" {
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.authenticator.SignIn(request.Message, request.Signature)
+	if err != nil {
+		httperr.Error(w, r, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// Logout revokes the caller's session token.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, ok := auth.BearerToken(r)
+	if !ok {
+		httperr.Error(w, r, "Missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	h.authenticator.SignOut(token)
+	w.WriteHeader(http.StatusNoContent)
+}
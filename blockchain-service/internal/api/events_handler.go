@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/logging"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// keepAliveInterval is how often an idle stream sends a comment, so that proxies keep the
+// connection open.
+const keepAliveInterval = 15 * time.Second
+
+// sseEventNames are the SSE event names of MetadataEvent kinds, after the contract events.
+var sseEventNames = map[string]string{
+	domain.MetadataStored:  "MetadataStored",
+	domain.MetadataUpdated: "MetadataUpdated",
+}
+
+// EventsHandler streams the contract's metadata events as Server-Sent Events.
+type EventsHandler struct {
+	service usecase.BlockchainService
+
+	shutdownOnce sync.Once
+	shutdown     chan struct{}
+}
+
+func NewEventsHandler(service usecase.BlockchainService) *EventsHandler {
+	return &EventsHandler{service: service, shutdown: make(chan struct{})}
+}
+
+// Shutdown ends all open streams. Clients reconnect with Last-Event-ID and miss nothing.
+func (h *EventsHandler) Shutdown() {
+	h.shutdownOnce.Do(func() { close(h.shutdown) })
+}
+
+// Stream sends the MetadataStored and MetadataUpdated events matching the owner and fileID
+// query parameters. The ID of each event is its "<block>-<logIndex>" position; a client that
+// reconnects with it in Last-Event-ID (or ?lastEventId=) first receives the events it missed.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter := domain.MetadataEventFilter{
+		Owner:  r.URL.Query().Get("owner"),
+		FileID: r.URL.Query().Get("fileID"),
+	}
+	if filter.Owner != "" && !common.IsHexAddress(filter.Owner) {
+		httperr.Error(w, r, "Invalid owner address", http.StatusBadRequest)
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	if lastEventID != "" {
+		position, err := domain.ParseEventPosition(lastEventID)
+		if err != nil {
+			httperr.Error(w, r, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		filter.After = &position
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	events := make(chan *domain.MetadataEvent)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- h.service.WatchMetadata(ctx, filter, events)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher := http.NewResponseController(w)
+	fmt.Fprintf(w, "retry: %d\n\n", (3 * time.Second).Milliseconds())
+	if err := flusher.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case metadataEvent := <-events:
+			data, err := json.Marshal(metadataEvent)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", metadataEvent.Position(), sseEventNames[metadataEvent.Kind], data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case err := <-watchErr:
+			if err != nil && r.Context().Err() == nil {
+				// ストリーム開始後はステータスを変更できないため、エラーをイベントとして送る
+				data, _ := json.Marshal(httperr.Envelope{Error: httperr.Body{
+					Code:      errorCode(err),
+					Message:   errorMessage(err, "Failed to watch metadata"),
+					RequestID: logging.RequestIDFromContext(r.Context()),
+				}})
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+				flusher.Flush()
+			}
+			return
+		case <-h.shutdown:
+			return
+		}
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/blockchain-service/rpc/blockchainpb"
+	"decentralstore/shared/grpcerr"
+	"decentralstore/shared/httperr"
+
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// BlockchainServer serves the BlockchainService gRPC API over the same usecase as BlockchainHandler.
+type BlockchainServer struct {
+	blockchainpb.UnimplementedBlockchainServiceServer
+	service usecase.BlockchainService
+}
+
+func NewBlockchainServer(service usecase.BlockchainService) *BlockchainServer {
+	return &BlockchainServer{service: service}
+}
+
+func (s *BlockchainServer) Store(ctx context.Context, req *blockchainpb.StoreRequest) (*blockchainpb.StoreResponse, error) {
+	if req.GetMetadata() == nil {
+		return nil, grpcerr.Error(http.StatusBadRequest, httperr.CodeBadRequest, "Missing metadata")
+	}
+
+	metadata := metadataFromProto(req.GetMetadata())
+	if err := s.service.StoreMetadata(ctx, metadata); err != nil {
+		return nil, grpcError(err, "Failed to store metadata")
+	}
+
+	resp := &blockchainpb.StoreResponse{TransactionHash: metadata.TransactionHash}
+	if metadata.BlockNumber != nil {
+		resp.BlockNumber = metadata.BlockNumber.Uint64()
+	}
+	return resp, nil
+}
+
+func (s *BlockchainServer) Get(ctx context.Context, req *blockchainpb.GetRequest) (*blockchainpb.FileMetadata, error) {
+	if req.GetFileId() == "" {
+		return nil, grpcerr.Error(http.StatusBadRequest, httperr.CodeBadRequest, "Missing fileID parameter")
+	}
+
+	metadata, err := s.service.GetMetadata(ctx, req.GetFileId())
+	if err != nil {
+		return nil, grpcError(err, "Failed to get metadata")
+	}
+	return metadataToProto(metadata), nil
+}
+
+func (s *BlockchainServer) Update(ctx context.Context, req *blockchainpb.UpdateRequest) (*blockchainpb.UpdateResponse, error) {
+	if req.GetFileId() == "" {
+		return nil, grpcerr.Error(http.StatusBadRequest, httperr.CodeBadRequest, "Missing fileID parameter")
+	}
+
+	if err := s.service.UpdateMetadata(ctx, req.GetFileId(), req.GetIsDeleted()); err != nil {
+		return nil, grpcError(err, "Failed to update metadata")
+	}
+	return &blockchainpb.UpdateResponse{}, nil
+}
+
+// WatchMetadata streams the contract's metadata events until the caller disconnects.
+func (s *BlockchainServer) WatchMetadata(req *blockchainpb.WatchMetadataRequest, stream blockchainpb.BlockchainService_WatchMetadataServer) error {
+	if req.GetOwner() != "" && !common.IsHexAddress(req.GetOwner()) {
+		return grpcerr.Error(http.StatusBadRequest, httperr.CodeBadRequest, "Invalid owner address")
+	}
+	filter := domain.MetadataEventFilter{Owner: req.GetOwner(), FileID: req.GetFileId()}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	events := make(chan *domain.MetadataEvent)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- s.service.WatchMetadata(ctx, filter, events)
+	}()
+
+	for {
+		select {
+		case metadataEvent := <-events:
+			if err := stream.Send(eventToProto(metadataEvent)); err != nil {
+				return err
+			}
+		case err := <-watchErr:
+			if err == nil || stream.Context().Err() != nil {
+				return nil
+			}
+			return grpcError(err, "Failed to watch metadata")
+		}
+	}
+}
+
+func metadataFromProto(m *blockchainpb.FileMetadata) *domain.FileMetadata {
+	return &domain.FileMetadata{
+		ID:              m.GetId(),
+		Name:            m.GetName(),
+		Size:            m.GetSize(),
+		CID:             m.GetCid(),
+		UploadedAt:      m.GetUploadedAt().AsTime(),
+		DownloadKeyword: m.GetDownloadKeyword(),
+		DeleteKeyword:   m.GetDeleteKeyword(),
+	}
+}
+
+func metadataToProto(metadata *domain.FileMetadata) *blockchainpb.FileMetadata {
+	m := &blockchainpb.FileMetadata{
+		Id:              metadata.ID,
+		Name:            metadata.Name,
+		Size:            metadata.Size,
+		Cid:             metadata.CID,
+		UploadedAt:      timestamppb.New(metadata.UploadedAt),
+		DownloadKeyword: metadata.DownloadKeyword,
+		DeleteKeyword:   metadata.DeleteKeyword,
+		Owner:           metadata.Owner,
+		TransactionHash: metadata.TransactionHash,
+	}
+	if metadata.BlockNumber != nil {
+		m.BlockNumber = metadata.BlockNumber.Uint64()
+	}
+	return m
+}
+
+func eventToProto(metadataEvent *domain.MetadataEvent) *blockchainpb.MetadataEvent {
+	kind := blockchainpb.MetadataEvent_KIND_UNSPECIFIED
+	switch metadataEvent.Kind {
+	case domain.MetadataStored:
+		kind = blockchainpb.MetadataEvent_KIND_STORED
+	case domain.MetadataUpdated:
+		kind = blockchainpb.MetadataEvent_KIND_UPDATED
+	}
+	return &blockchainpb.MetadataEvent{
+		Kind:            kind,
+		FileId:          metadataEvent.FileID,
+		Owner:           metadataEvent.Owner,
+		IsDeleted:       metadataEvent.IsDeleted,
+		BlockNumber:     metadataEvent.BlockNumber,
+		TransactionHash: metadataEvent.TransactionHash,
+	}
+}
+
+// grpcError is the gRPC counterpart of writeError.
+func grpcError(err error, fallback string) error {
+	return grpcerr.Error(statusFromError(err), errorCode(err), errorMessage(err, fallback))
+}
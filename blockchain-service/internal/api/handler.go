@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"decentralstore/blockchain-service/internal/domain"
 	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/shared/httperr"
 )
 
 type BlockchainHandler struct {
@@ -18,40 +20,44 @@ func NewBlockchainHandler(service usecase.BlockchainService) *BlockchainHandler
 
 func (h *BlockchainHandler) StoreMetadata(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var metadata domain.FileMetadata
 	if err := json.NewDecoder(r.Body).Decode(&metadata); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.service.StoreMetadata(r.Context(), &metadata); err != nil {
-		http.Error(w, "Failed to store metadata", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to store metadata")
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Metadata stored successfully"})
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":         "Metadata stored successfully",
+		"transactionHash": metadata.TransactionHash,
+	})
 }
 
 func (h *BlockchainHandler) GetMetadata(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	fileID := r.URL.Query().Get("fileID")
 	if fileID == "" {
-		http.Error(w, "Missing fileID parameter", http.StatusBadRequest)
+		httperr.Error(w, r, "Missing fileID parameter", http.StatusBadRequest)
 		return
 	}
 
 	metadata, err := h.service.GetMetadata(r.Context(), fileID)
 	if err != nil {
-		http.Error(w, "Failed to get metadata", http.StatusInternalServerError)
+		httperr.Error(w, r, "Failed to get metadata", http.StatusInternalServerError)
 		return
 	}
 
@@ -61,13 +67,13 @@ func (h *BlockchainHandler) GetMetadata(w http.ResponseWriter, r *http.Request)
 
 func (h *BlockchainHandler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	fileID := r.URL.Query().Get("fileID")
 	if fileID == "" {
-		http.Error(w, "Missing fileID parameter", http.StatusBadRequest)
+		httperr.Error(w, r, "Missing fileID parameter", http.StatusBadRequest)
 		return
 	}
 
@@ -75,15 +81,80 @@ func (h *BlockchainHandler) UpdateMetadata(w http.ResponseWriter, r *http.Reques
 		IsDeleted bool `json:"isDeleted"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if err := h.service.UpdateMetadata(r.Context(), fileID, updateRequest.IsDeleted); err != nil {
-		http.Error(w, "Failed to update metadata", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to update metadata")
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Metadata updated successfully"})
 }
+
+// ListOwnedMetadata returns the metadata of every file owned by the signed-in wallet.
+func (h *BlockchainHandler) ListOwnedMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	files, err := h.service.ListOwnedMetadata(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to list metadata")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files)
+}
+
+// statusFromError maps domain errors returned by the service to HTTP status codes.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrInvalidSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrSignatureExpired):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrNonceMismatch):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrTransactionNotFound), errors.Is(err, domain.ErrMetadataNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorCode returns the envelope code of a domain error.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrInvalidSignature):
+		return httperr.CodeInvalidSignature
+	case errors.Is(err, domain.ErrSignatureExpired):
+		return httperr.CodeSignatureExpired
+	case errors.Is(err, domain.ErrNonceMismatch):
+		return httperr.CodeNonceMismatch
+	default:
+		return httperr.CodeForStatus(statusFromError(err))
+	}
+}
+
+// writeError replies with the status, code and message of a service error.
+func writeError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	httperr.Write(w, r, statusFromError(err), errorCode(err), errorMessage(err, fallback))
+}
+
+// errorMessage exposes domain error messages to the client and hides internal failures behind fallback.
+func errorMessage(err error, fallback string) string {
+	if statusFromError(err) == http.StatusInternalServerError {
+		return fallback
+	}
+	return err.Error()
+}
@@ -34,6 +34,16 @@ func (m *MockBlockchainService) UpdateMetadata(ctx context.Context, fileID strin
 	return args.Error(0)
 }
 
+func (m *MockBlockchainService) ListOwnedMetadata(ctx context.Context) ([]*domain.FileMetadata, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.FileMetadata), args.Error(1)
+}
+
+func (m *MockBlockchainService) WatchMetadata(ctx context.Context, filter domain.MetadataEventFilter, sink chan<- *domain.MetadataEvent) error {
+	args := m.Called(ctx, filter, sink)
+	return args.Error(0)
+}
+
 func TestStoreMetadata(t *testing.T) {
 	mockService := new(MockBlockchainService)
 	handler := NewBlockchainHandler(mockService)
@@ -101,6 +111,37 @@ func TestUpdateMetadata(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestUpdateMetadata_NotOwner(t *testing.T) {
+	mockService := new(MockBlockchainService)
+	handler := NewBlockchainHandler(mockService)
+
+	mockService.On("UpdateMetadata", mock.Anything, "testID", true).Return(domain.ErrForbidden)
+
+	body, _ := json.Marshal(map[string]bool{"isDeleted": true})
+	req, _ := http.NewRequest("PUT", "/update?fileID=testID", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+
+	handler.UpdateMetadata(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListOwnedMetadata_Unauthenticated(t *testing.T) {
+	mockService := new(MockBlockchainService)
+	handler := NewBlockchainHandler(mockService)
+
+	mockService.On("ListOwnedMetadata", mock.Anything).Return([]*domain.FileMetadata(nil), domain.ErrUnauthenticated)
+
+	req, _ := http.NewRequest("GET", "/files", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ListOwnedMetadata(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
 func TestMethodNotAllowed(t *testing.T) {
 	mockService := new(MockBlockchainService)
 	handler := NewBlockchainHandler(mockService)
@@ -113,6 +154,7 @@ func TestMethodNotAllowed(t *testing.T) {
 		{"StoreMetadata", "GET", handler.StoreMetadata},
 		{"GetMetadata", "POST", handler.GetMetadata},
 		{"UpdateMetadata", "GET", handler.UpdateMetadata},
+		{"ListOwnedMetadata", "POST", handler.ListOwnedMetadata},
 	}
 
 	for _, tc := range testCases {
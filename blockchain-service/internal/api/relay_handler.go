@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/shared/httperr"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type RelayHandler struct {
+	relayer usecase.RelayerService
+}
+
+func NewRelayHandler(relayer usecase.RelayerService) *RelayHandler {
+	return &RelayHandler{relayer: relayer}
+}
+
+// Domain returns the EIP-712 domain that clients must use when signing relayed requests.
+func (h *RelayHandler) Domain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.relayer.Domain())
+}
+
+// Nonce returns the owner's next relay nonce.
+func (h *RelayHandler) Nonce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	owner := r.URL.Query().Get("owner")
+	if !common.IsHexAddress(owner) {
+		httperr.Error(w, r, "Missing or invalid owner parameter", http.StatusBadRequest)
+		return
+	}
+
+	nonce, err := h.relayer.Nonce(r.Context(), common.HexToAddress(owner))
+	if err != nil {
+		httperr.Error(w, r, "Failed to get nonce", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]uint64{"nonce": nonce})
+}
+
+func (h *RelayHandler) StoreMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request domain.RelayStoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || !common.IsHexAddress(request.Owner) || request.Signature == "" {
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	txHash, err := h.relayer.RelayStoreMetadata(r.Context(), &request)
+	if err != nil {
+		writeError(w, r, err, "Failed to relay metadata")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"transactionHash": txHash.Hex()})
+}
+
+func (h *RelayHandler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request domain.RelayUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || !common.IsHexAddress(request.Owner) || request.FileID == "" || request.Signature == "" {
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	txHash, err := h.relayer.RelayUpdateMetadata(r.Context(), &request)
+	if err != nil {
+		writeError(w, r, err, "Failed to relay metadata update")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"transactionHash": txHash.Hex()})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"decentralstore/blockchain-service/internal/usecase"
+	"decentralstore/shared/httperr"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+type TransactionHandler struct {
+	service usecase.TransactionService
+}
+
+func NewTransactionHandler(service usecase.TransactionService) *TransactionHandler {
+	return &TransactionHandler{service: service}
+}
+
+// Status returns whether the transaction ?hash= is pending, succeeded or reverted.
+func (h *TransactionHandler) Status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	raw, err := hexutil.Decode(r.URL.Query().Get("hash"))
+	if err != nil || len(raw) != common.HashLength {
+		httperr.Error(w, r, "Invalid hash parameter", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.service.TransactionStatus(r.Context(), common.BytesToHash(raw))
+	if err != nil {
+		writeError(w, r, err, "Failed to get transaction status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"decentralstore/blockchain-service/internal/auth"
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/shared/httperr"
+	"decentralstore/webhook"
+)
+
+// WebhookHandler manages the webhook subscriptions of the signed-in wallet.
+type WebhookHandler struct {
+	dispatcher *webhook.Dispatcher
+}
+
+func NewWebhookHandler(dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{dispatcher: dispatcher}
+}
+
+type createdSubscriptionResponse struct {
+	*webhook.Subscription
+	Secret string `json:"secret"`
+}
+
+// Webhooks creates (POST), lists (GET) or deletes (DELETE ?id=) subscriptions.
+func (h *WebhookHandler) Webhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost, http.MethodGet, http.MethodDelete:
+	default:
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	wallet, ok := auth.WalletFromContext(r.Context())
+	if !ok {
+		writeError(w, r, domain.ErrUnauthenticated, "Sign-in required")
+		return
+	}
+	owner := wallet.Hex()
+
+	switch r.Method {
+	case http.MethodPost:
+		h.subscribe(w, r, owner)
+	case http.MethodGet:
+		h.listSubscriptions(w, r, owner)
+	case http.MethodDelete:
+		h.unsubscribe(w, r, owner)
+	}
+}
+
+func (h *WebhookHandler) subscribe(w http.ResponseWriter, r *http.Request, owner string) {
+	var request struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.dispatcher.Subscribe(r.Context(), owner, request.URL, request.Events)
+	if err != nil {
+		httperr.Error(w, r, webhookErrorMessage(err, "Failed to create webhook"), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdSubscriptionResponse{Subscription: sub, Secret: sub.Secret})
+}
+
+func (h *WebhookHandler) listSubscriptions(w http.ResponseWriter, r *http.Request, owner string) {
+	subs, err := h.dispatcher.Subscriptions(r.Context(), owner)
+	if err != nil {
+		httperr.Error(w, r, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+func (h *WebhookHandler) unsubscribe(w http.ResponseWriter, r *http.Request, owner string) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		httperr.Error(w, r, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dispatcher.Unsubscribe(r.Context(), owner, id); err != nil {
+		httperr.Error(w, r, webhookErrorMessage(err, "Failed to delete webhook"), webhookErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries returns the delivery log of a subscription (?subscriptionId=), newest first.
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	wallet, ok := auth.WalletFromContext(r.Context())
+	if !ok {
+		writeError(w, r, domain.ErrUnauthenticated, "Sign-in required")
+		return
+	}
+	owner := wallet.Hex()
+
+	subscriptionID := r.URL.Query().Get("subscriptionId")
+	if subscriptionID == "" {
+		httperr.Error(w, r, "Missing subscriptionId parameter", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.dispatcher.Deliveries(r.Context(), owner, subscriptionID)
+	if err != nil {
+		httperr.Error(w, r, webhookErrorMessage(err, "Failed to list deliveries"), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// Replay sends the event of a delivery (?id=) again and returns the new delivery.
+func (h *WebhookHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	wallet, ok := auth.WalletFromContext(r.Context())
+	if !ok {
+		writeError(w, r, domain.ErrUnauthenticated, "Sign-in required")
+		return
+	}
+	owner := wallet.Hex()
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		httperr.Error(w, r, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.dispatcher.Replay(r.Context(), owner, id)
+	if err != nil {
+		httperr.Error(w, r, webhookErrorMessage(err, "Failed to replay delivery"), webhookErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrInvalidSubscription):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func webhookErrorMessage(err error, fallback string) string {
+	if webhookErrorStatus(err) == http.StatusInternalServerError {
+		return fallback
+	}
+	return err.Error()
+}
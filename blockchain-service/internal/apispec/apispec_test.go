@@ -0,0 +1,96 @@
+package apispec_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"decentralstore/blockchain-service/internal/apispec"
+	"decentralstore/shared/httperr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	doc, err := apispec.Load()
+	require.NoError(t, err)
+
+	for path, item := range doc.Paths {
+		for method, op := range item.Operations() {
+			assert.NotEmpty(t, op.OperationID, "%s %s", method, path)
+		}
+	}
+}
+
+func validate(t *testing.T, req *http.Request) []httperr.Detail {
+	t.Helper()
+	called := false
+	handler := apispec.MustLoad().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code == http.StatusOK {
+		assert.True(t, called)
+		return nil
+	}
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.False(t, called)
+	var envelope httperr.Envelope
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&envelope))
+	assert.Equal(t, httperr.CodeInvalidRequest, envelope.Error.Code)
+	return envelope.Error.Details
+}
+
+func TestMiddleware(t *testing.T) {
+	hash := "0x" + strings.Repeat("ab", 32)
+	jsonRequest := func(method, target, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want []httperr.Detail
+	}{
+		{"valid query", httptest.NewRequest(http.MethodGet, "/tx?hash="+hash, nil), nil},
+		{"missing query", httptest.NewRequest(http.MethodGet, "/metadata", nil),
+			[]httperr.Detail{{Field: "query.fileID", Message: "is required"}}},
+		{"pattern", httptest.NewRequest(http.MethodGet, "/tx?hash=0x1234", nil),
+			[]httperr.Detail{{Field: "query.hash", Message: "must match ^0x[0-9a-fA-F]{64}$"}}},
+		{"valid body", jsonRequest(http.MethodPut, "/update?fileID=f1", `{"isDeleted": true}`), nil},
+		{"body type", jsonRequest(http.MethodPut, "/update?fileID=f1", `{"isDeleted": "yes"}`),
+			[]httperr.Detail{{Field: "body.isDeleted", Message: "must be a boolean"}}},
+		{"missing body", jsonRequest(http.MethodPut, "/update?fileID=f1", ""),
+			[]httperr.Detail{{Field: "body", Message: "is required"}}},
+		{"malformed body", jsonRequest(http.MethodPost, "/auth/verify", "{"),
+			[]httperr.Detail{{Field: "body", Message: "is not valid JSON"}}},
+		{"undocumented path", httptest.NewRequest(http.MethodGet, "/unknown", nil), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validate(t, tt.req))
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := apispec.MustLoad()
+	header := http.Header{"Content-Type": {"application/json"}}
+
+	assert.NoError(t, doc.ValidateResponse(http.MethodGet, "/metadata", http.StatusOK, header,
+		[]byte(`{"id": "f1", "name": "a.txt", "size": 1, "cid": "Qm", "uploadedAt": "2024-01-01T00:00:00Z", "owner": "0x1", "blockNumber": null}`)))
+
+	err := doc.ValidateResponse(http.MethodGet, "/tx", http.StatusOK, header, []byte(`{"hash": "0x1", "status": "lost", "confirmations": 0}`))
+	assert.ErrorContains(t, err, "body.hash must match")
+	assert.ErrorContains(t, err, "body.status must be one of")
+
+	err = doc.ValidateResponse(http.MethodPost, "/auth/logout", http.StatusNoContent, nil, []byte("bye"))
+	assert.ErrorContains(t, err, "documented without a body")
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"decentralstore/shared/httperr"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrInvalidNonce     = errors.New("siwe: nonce is unknown, expired or already used")
+	ErrDomainMismatch   = errors.New("siwe: domain does not match this service")
+	ErrChainMismatch    = errors.New("siwe: chain ID does not match this service")
+	ErrMessageExpired   = errors.New("siwe: message has expired")
+	ErrMessageNotYet    = errors.New("siwe: message is not yet valid")
+	ErrInvalidSignature = errors.New("siwe: signature does not match the message address")
+)
+
+// Session is the result of a successful sign-in.
+type Session struct {
+	Token     string         `json:"token"`
+	Address   common.Address `json:"address"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+}
+
+// Authenticator implements the Sign-In With Ethereum flow and session lookup.
+type Authenticator struct {
+	domain   string
+	chainID  uint64
+	nonces   NonceStore
+	sessions SessionStore
+	now      func() time.Time
+}
+
+// NewAuthenticator creates an Authenticator that accepts SIWE messages for domain.
+// A chainID of zero disables the chain ID check.
+func NewAuthenticator(domain string, chainID uint64, nonces NonceStore, sessions SessionStore) *Authenticator {
+	return &Authenticator{
+		domain:   domain,
+		chainID:  chainID,
+		nonces:   nonces,
+		sessions: sessions,
+		now:      time.Now,
+	}
+}
+
+// Nonce issues a nonce to be embedded in the next SIWE message.
+func (a *Authenticator) Nonce() (string, error) {
+	return a.nonces.Issue()
+}
+
+// SignIn verifies a signed SIWE message and starts a session for its address.
+func (a *Authenticator) SignIn(message, signature string) (*Session, error) {
+	msg, err := ParseSIWEMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Domain != a.domain {
+		return nil, ErrDomainMismatch
+	}
+	if a.chainID != 0 && msg.ChainID != a.chainID {
+		return nil, ErrChainMismatch
+	}
+
+	now := a.now()
+	if msg.ExpirationTime != nil && !now.Before(*msg.ExpirationTime) {
+		return nil, ErrMessageExpired
+	}
+	if msg.NotBefore != nil && now.Before(*msg.NotBefore) {
+		return nil, ErrMessageNotYet
+	}
+
+	signer, err := RecoverPersonalSigner([]byte(message), signature)
+	if err != nil {
+		return nil, err
+	}
+	if signer != msg.Address {
+		return nil, ErrInvalidSignature
+	}
+
+	// 署名の検証後にノンスを消費し、不正な署名でノンスが失われないようにする
+	if !a.nonces.Consume(msg.Nonce) {
+		return nil, ErrInvalidNonce
+	}
+
+	token, expiresAt, err := a.sessions.Create(msg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &Session{Token: token, Address: msg.Address, ExpiresAt: expiresAt}, nil
+}
+
+// SignOut revokes the session token.
+func (a *Authenticator) SignOut(token string) {
+	a.sessions.Revoke(token)
+}
+
+// Middleware attaches the wallet of a valid bearer token to the request context.
+// Requests without a token pass through unauthenticated; invalid tokens are rejected.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := BearerToken(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		address, ok := a.sessions.Lookup(token)
+		if !ok {
+			httperr.Error(w, r, "Invalid or expired session", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithWallet(r.Context(), address)))
+	})
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, bool) {
+	return bearerToken(r.Header.Get("Authorization"))
+}
+
+func bearerToken(header string) (string, bool) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// RecoverPersonalSigner recovers the address that produced an EIP-191 personal_sign signature over data.
+func RecoverPersonalSigner(data []byte, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	// ウォレットは V を 27/28 で返すが、crypto パッケージは 0/1 を期待する
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(accounts.TextHash(data), sig)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signPersonal(t *testing.T, message string) (common.Address, string) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+
+	return crypto.PubkeyToAddress(key.PublicKey), hexutil.Encode(sig)
+}
+
+func newTestAuthenticator() *Authenticator {
+	return NewAuthenticator("example.com", 1337, NewMemoryNonceStore(time.Minute), NewMemorySessionStore(time.Hour))
+}
+
+func buildMessage(t *testing.T, a *Authenticator, address common.Address) *SIWEMessage {
+	nonce, err := a.Nonce()
+	require.NoError(t, err)
+
+	return &SIWEMessage{
+		Domain:    "example.com",
+		Address:   address,
+		Statement: "Sign in to DecentralStore",
+		URI:       "https://example.com/login",
+		Version:   "1",
+		ChainID:   1337,
+		Nonce:     nonce,
+		IssuedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestParseSIWEMessage_RoundTrip(t *testing.T) {
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg := &SIWEMessage{
+		Domain:         "example.com",
+		Address:        common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Statement:      "Sign in",
+		URI:            "https://example.com",
+		Version:        "1",
+		ChainID:        1,
+		Nonce:          "abc123",
+		IssuedAt:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ExpirationTime: &expires,
+		Resources:      []string{"ipfs://Qm1", "https://example.com/tos"},
+	}
+
+	parsed, err := ParseSIWEMessage(msg.String())
+
+	require.NoError(t, err)
+	assert.Equal(t, msg, parsed)
+}
+
+func TestParseSIWEMessage_Invalid(t *testing.T) {
+	_, err := ParseSIWEMessage("hello\nworld")
+	assert.Error(t, err)
+}
+
+func TestAuthenticator_SignIn(t *testing.T) {
+	a := newTestAuthenticator()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	text := buildMessage(t, a, address).String()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(text)), key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+
+	session, err := a.SignIn(text, hexutil.Encode(sig))
+
+	require.NoError(t, err)
+	assert.Equal(t, address, session.Address)
+	assert.NotEmpty(t, session.Token)
+
+	_, err = a.SignIn(text, hexutil.Encode(sig))
+	assert.ErrorIs(t, err, ErrInvalidNonce, "nonce must not be reusable")
+}
+
+func TestAuthenticator_SignIn_WrongSigner(t *testing.T) {
+	a := newTestAuthenticator()
+
+	claimed := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	text := buildMessage(t, a, claimed).String()
+	_, sig := signPersonal(t, text)
+
+	_, err := a.SignIn(text, sig)
+
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestAuthenticator_SignIn_DomainMismatch(t *testing.T) {
+	a := newTestAuthenticator()
+
+	msg := buildMessage(t, a, common.Address{})
+	msg.Domain = "evil.example"
+	_, sig := signPersonal(t, msg.String())
+
+	_, err := a.SignIn(msg.String(), sig)
+
+	assert.ErrorIs(t, err, ErrDomainMismatch)
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	a := newTestAuthenticator()
+	address := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	token, _, err := a.sessions.Create(address)
+	require.NoError(t, err)
+
+	var got common.Address
+	var authenticated bool
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, authenticated = WalletFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/files", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.True(t, authenticated)
+	assert.Equal(t, address, got)
+
+	authenticated = false
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/files", nil))
+	assert.False(t, authenticated)
+
+	req = httptest.NewRequest("GET", "/files", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type walletKey struct{}
+
+// WithWallet returns a context carrying the authenticated wallet address.
+func WithWallet(ctx context.Context, address common.Address) context.Context {
+	return context.WithValue(ctx, walletKey{}, address)
+}
+
+// WalletFromContext returns the authenticated wallet address, if any.
+func WalletFromContext(ctx context.Context) (common.Address, bool) {
+	address, ok := ctx.Value(walletKey{}).(common.Address)
+	return address, ok
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"decentralstore/shared/grpcerr"
+	"decentralstore/shared/httperr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware for unary calls.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticateGRPC(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the gRPC counterpart of Middleware for streaming calls.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticateGRPC(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticateGRPC attaches the wallet of the bearer token in the "authorization" metadata
+// to ctx. Like Middleware, calls without a token pass through and invalid tokens are rejected.
+func (a *Authenticator) authenticateGRPC(ctx context.Context) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return ctx, nil
+	}
+	token, ok := bearerToken(values[0])
+	if !ok {
+		return ctx, nil
+	}
+
+	address, ok := a.sessions.Lookup(token)
+	if !ok {
+		return nil, grpcerr.Error(http.StatusUnauthorized, httperr.CodeUnauthorized, "Invalid or expired session")
+	}
+	return WithWallet(ctx, address), nil
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisClient is the subset of the Redis client used by the Redis stores.
+type RedisClient interface {
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// RedisNonceStore keeps nonces in Redis so that any replica accepts a nonce issued by
+// another. Redis expires them after the TTL:
+//
+//	siwe:nonce:<nonce> -> "1"
+type RedisNonceStore struct {
+	client RedisClient
+	ttl    time.Duration
+}
+
+func NewRedisNonceStore(client RedisClient, ttl time.Duration) *RedisNonceStore {
+	return &RedisNonceStore{client: client, ttl: ttl}
+}
+
+func (s *RedisNonceStore) Issue() (string, error) {
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	if err := s.client.Set(context.Background(), nonceKey(nonce), "1", s.ttl).Err(); err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+// Consume deletes the nonce; only the caller whose DEL removed it may use it.
+func (s *RedisNonceStore) Consume(nonce string) bool {
+	deleted, err := s.client.Del(context.Background(), nonceKey(nonce)).Result()
+	if err != nil {
+		slog.Warn("Failed to consume SIWE nonce", "error", err)
+		return false
+	}
+	return deleted == 1
+}
+
+// RedisSessionStore keeps sessions in Redis so that all replicas share them. Redis
+// expires them after the TTL:
+//
+//	session:<token> -> wallet address
+type RedisSessionStore struct {
+	client RedisClient
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewRedisSessionStore(client RedisClient, ttl time.Duration) *RedisSessionStore {
+	return &RedisSessionStore{client: client, ttl: ttl, now: time.Now}
+}
+
+func (s *RedisSessionStore) Create(address common.Address) (string, time.Time, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := s.now().Add(s.ttl)
+	if err := s.client.Set(context.Background(), sessionKey(token), address.Hex(), s.ttl).Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+func (s *RedisSessionStore) Lookup(token string) (common.Address, bool) {
+	value, err := s.client.Get(context.Background(), sessionKey(token)).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.Warn("Failed to look up session", "error", err)
+		}
+		return common.Address{}, false
+	}
+	if !common.IsHexAddress(value) {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(value), true
+}
+
+func (s *RedisSessionStore) Revoke(token string) {
+	if err := s.client.Del(context.Background(), sessionKey(token)).Err(); err != nil {
+		slog.Warn("Failed to revoke session", "error", err)
+	}
+}
+
+func nonceKey(nonce string) string {
+	return "siwe:nonce:" + nonce
+}
+
+func sessionKey(token string) string {
+	return "session:" + token
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis is an in-memory RedisClient that ignores expirations.
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string]string)}
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = fmt.Sprint(value)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (f *fakeRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := f.values[key]; ok {
+			delete(f.values, key)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func TestRedisStores_SharedByReplicas(t *testing.T) {
+	client := newFakeRedis()
+	newReplica := func() *Authenticator {
+		return NewAuthenticator("example.com", 1337, NewRedisNonceStore(client, time.Minute), NewRedisSessionStore(client, time.Hour))
+	}
+	issuer, verifier := newReplica(), newReplica()
+
+	// A nonce issued by one replica signs in on another, once.
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	text := buildMessage(t, issuer, crypto.PubkeyToAddress(key.PublicKey)).String()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(text)), key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+
+	session, err := verifier.SignIn(text, hexutil.Encode(sig))
+	require.NoError(t, err)
+	_, err = issuer.SignIn(text, hexutil.Encode(sig))
+	assert.ErrorIs(t, err, ErrInvalidNonce)
+
+	// The session works on every replica until it is revoked.
+	var got bool
+	handler := issuer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, got = WalletFromContext(r.Context())
+	}))
+	req := httptest.NewRequest("GET", "/files", nil)
+	req.Header.Set("Authorization", "Bearer "+session.Token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.True(t, got)
+
+	verifier.SignOut(session.Token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
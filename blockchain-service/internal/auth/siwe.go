@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// SIWEMessage is a Sign-In With Ethereum message as defined by EIP-4361.
+type SIWEMessage struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        uint64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// ParseSIWEMessage parses the plain-text EIP-4361 representation that the wallet signed.
+func ParseSIWEMessage(text string) (*SIWEMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) < 2 {
+		return nil, errors.New("siwe: message is too short")
+	}
+
+	msg := &SIWEMessage{}
+
+	domain, ok := strings.CutSuffix(lines[0], siweHeaderSuffix)
+	if !ok || domain == "" {
+		return nil, errors.New("siwe: invalid header line")
+	}
+	msg.Domain = domain
+
+	if !common.IsHexAddress(lines[1]) {
+		return nil, fmt.Errorf("siwe: invalid address %q", lines[1])
+	}
+	msg.Address = common.HexToAddress(lines[1])
+
+	// The address is followed by an empty line and an optional statement paragraph.
+	i := 2
+	if i >= len(lines) || lines[i] != "" {
+		return nil, errors.New("siwe: expected empty line after address")
+	}
+	i++
+	if i < len(lines) && lines[i] != "" && !strings.HasPrefix(lines[i], "URI: ") {
+		msg.Statement = lines[i]
+		i++
+		if i >= len(lines) || lines[i] != "" {
+			return nil, errors.New("siwe: expected empty line after statement")
+		}
+		i++
+	}
+
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		if line == "Resources:" {
+			for i++; i < len(lines) && strings.HasPrefix(lines[i], "- "); i++ {
+				msg.Resources = append(msg.Resources, strings.TrimPrefix(lines[i], "- "))
+			}
+			i--
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("siwe: invalid field line %q", line)
+		}
+		if err := msg.setField(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if msg.URI == "" || msg.Version == "" || msg.Nonce == "" || msg.IssuedAt.IsZero() || msg.ChainID == 0 {
+		return nil, errors.New("siwe: missing required field")
+	}
+	if msg.Version != "1" {
+		return nil, fmt.Errorf("siwe: unsupported version %q", msg.Version)
+	}
+
+	return msg, nil
+}
+
+func (m *SIWEMessage) setField(key, value string) error {
+	var err error
+	switch key {
+	case "URI":
+		m.URI = value
+	case "Version":
+		m.Version = value
+	case "Chain ID":
+		m.ChainID, err = strconv.ParseUint(value, 10, 64)
+	case "Nonce":
+		m.Nonce = value
+	case "Issued At":
+		m.IssuedAt, err = time.Parse(time.RFC3339, value)
+	case "Expiration Time":
+		var t time.Time
+		t, err = time.Parse(time.RFC3339, value)
+		m.ExpirationTime = &t
+	case "Not Before":
+		var t time.Time
+		t, err = time.Parse(time.RFC3339, value)
+		m.NotBefore = &t
+	case "Request ID":
+		m.RequestID = value
+	default:
+		return fmt.Errorf("siwe: unknown field %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("siwe: invalid %s: %w", key, err)
+	}
+	return nil
+}
+
+// String renders the message in the EIP-4361 text format that wallets sign.
+func (m *SIWEMessage) String() string {
+	var b strings.Builder
+	b.WriteString(m.Domain + siweHeaderSuffix + "\n")
+	b.WriteString(m.Address.Hex() + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n\n")
+	}
+	b.WriteString("URI: " + m.URI + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString("Chain ID: " + strconv.FormatUint(m.ChainID, 10) + "\n")
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339))
+	if m.ExpirationTime != nil {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if m.NotBefore != nil {
+		b.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		b.WriteString("\nRequest ID: " + m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, r := range m.Resources {
+			b.WriteString("\n- " + r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceStore issues single-use nonces for SIWE messages.
+type NonceStore interface {
+	// Issue returns a fresh nonce that can be consumed once before it expires.
+	Issue() (string, error)
+	// Consume reports whether nonce was issued and is still valid, and invalidates it.
+	Consume(nonce string) bool
+}
+
+// SessionStore maps opaque bearer tokens to authenticated wallet addresses.
+type SessionStore interface {
+	// Create starts a session for address and returns its token and expiry.
+	Create(address common.Address) (string, time.Time, error)
+	// Lookup returns the wallet address of a valid session token.
+	Lookup(token string) (common.Address, bool)
+	// Revoke ends the session identified by token.
+	Revoke(token string)
+}
+
+// MemoryNonceStore keeps nonces in process memory, so only the replica that issued a
+// nonce accepts it.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewMemoryNonceStore(ttl time.Duration) *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time), ttl: ttl, now: time.Now}
+}
+
+func (s *MemoryNonceStore) Issue() (string, error) {
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for n, expiresAt := range s.nonces {
+		if !now.Before(expiresAt) {
+			delete(s.nonces, n)
+		}
+	}
+	s.nonces[nonce] = now.Add(s.ttl)
+	return nonce, nil
+}
+
+func (s *MemoryNonceStore) Consume(nonce string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.nonces[nonce]
+	if !ok {
+		return false
+	}
+	delete(s.nonces, nonce)
+	return s.now().Before(expiresAt)
+}
+
+type session struct {
+	address   common.Address
+	expiresAt time.Time
+}
+
+// MemorySessionStore keeps sessions in process memory, so only the replica that
+// created a session knows its token.
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]session
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+func NewMemorySessionStore(ttl time.Duration) *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]session), ttl: ttl, now: time.Now}
+}
+
+func (s *MemorySessionStore) Create(address common.Address) (string, time.Time, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for t, sess := range s.sessions {
+		if !now.Before(sess.expiresAt) {
+			delete(s.sessions, t)
+		}
+	}
+	expiresAt := now.Add(s.ttl)
+	s.sessions[token] = session{address: address, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+func (s *MemorySessionStore) Lookup(token string) (common.Address, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[token]
+	if !ok || !s.now().Before(sess.expiresAt) {
+		return common.Address{}, false
+	}
+	return sess.address, true
+}
+
+func (s *MemorySessionStore) Revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
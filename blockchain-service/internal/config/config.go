@@ -0,0 +1,184 @@
+// Package config loads the blockchain-service configuration from a YAML file, environment
+// variables and command-line flags, in increasing order of precedence.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"decentralstore/shared/configloader"
+	"decentralstore/shared/logging"
+	"decentralstore/shared/ratelimit"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type Config struct {
+	Server struct {
+		Addr string `yaml:"addr" env:"HTTP_ADDR"`
+		// ValidateRequests rejects requests that do not match the OpenAPI document.
+		ValidateRequests bool `yaml:"validateRequests" env:"VALIDATE_REQUESTS"`
+		// GRPCAddr is the listen address of the gRPC API; empty disables it.
+		GRPCAddr string `yaml:"grpcAddr" env:"GRPC_ADDR"`
+	} `yaml:"server"`
+
+	Ethereum struct {
+		RPCURL  string `yaml:"rpcUrl" env:"ETHEREUM_RPC_URL"`
+		ChainID uint64 `yaml:"chainId" env:"CHAIN_ID"`
+	} `yaml:"ethereum"`
+
+	Contract struct {
+		Address string `yaml:"address" env:"CONTRACT_ADDRESS"`
+	} `yaml:"contract"`
+
+	Signer struct {
+		// PrivateKey is the hex-encoded key of the account that pays for relayed
+		// transactions; without it transactions are not signed.
+		PrivateKey string `yaml:"privateKey" env:"SIGNER_PRIVATE_KEY" secret:"true"`
+		// MinBalance in wei below which the service reports itself not ready.
+		MinBalance string `yaml:"minBalance" env:"SIGNER_MIN_BALANCE"`
+	} `yaml:"signer"`
+
+	SIWE struct {
+		Domain string `yaml:"domain" env:"SIWE_DOMAIN"`
+		// ChainID restricts sign-in messages to one chain; 0 accepts any.
+		ChainID uint64 `yaml:"chainId" env:"SIWE_CHAIN_ID"`
+	} `yaml:"siwe"`
+
+	Session struct {
+		TTL time.Duration `yaml:"ttl" env:"SESSION_TTL"`
+	} `yaml:"session"`
+
+	Redis struct {
+		// URL is optional; without it sessions and rate limits are kept per replica.
+		URL string `yaml:"url" env:"REDIS_URL"`
+	} `yaml:"redis"`
+
+	Log struct {
+		Level string `yaml:"level" env:"LOG_LEVEL" reload:"true"`
+	} `yaml:"log"`
+
+	RateLimit struct {
+		Write string `yaml:"write" env:"RATE_LIMIT_WRITE" reload:"true"`
+		Auth  string `yaml:"auth" env:"RATE_LIMIT_AUTH" reload:"true"`
+	} `yaml:"rateLimit"`
+
+	Readiness struct {
+		// MaxBlockAge is how old the latest block may be; 0 only reports its age.
+		MaxBlockAge time.Duration `yaml:"maxBlockAge" env:"READY_MAX_BLOCK_AGE"`
+	} `yaml:"readiness"`
+}
+
+// Default returns the configuration used when no source sets a value.
+func Default() *Config {
+	cfg := &Config{}
+	cfg.Server.Addr = ":8082"
+	cfg.Server.GRPCAddr = ":9082"
+	cfg.Ethereum.RPCURL = "http://localhost:8545"
+	cfg.Ethereum.ChainID = 1337
+	cfg.Signer.MinBalance = "10000000000000000" // 0.01 ETH
+	cfg.SIWE.Domain = "localhost:8082"
+	cfg.Session.TTL = 24 * time.Hour
+	cfg.Log.Level = "info"
+	cfg.RateLimit.Write = "30/m"
+	cfg.RateLimit.Auth = "30/m"
+	cfg.Readiness.MaxBlockAge = 10 * time.Minute
+	return cfg
+}
+
+// Load builds the configuration from the defaults, the YAML file named by -config or
+// CONFIG_FILE, the environment and args, and validates it.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	if err := configloader.Load(cfg, args, "CONFIG_FILE"); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr must not be empty"))
+	}
+	if c.Ethereum.RPCURL == "" {
+		errs = append(errs, errors.New("ethereum.rpcUrl must not be empty"))
+	}
+	if c.Ethereum.ChainID == 0 {
+		errs = append(errs, errors.New("ethereum.chainId must not be 0"))
+	}
+	switch {
+	case c.Contract.Address == "":
+		errs = append(errs, errors.New("contract.address (CONTRACT_ADDRESS) is not set"))
+	case !common.IsHexAddress(c.Contract.Address):
+		errs = append(errs, fmt.Errorf("contract.address %q is not an address", c.Contract.Address))
+	}
+	if _, ok := new(big.Int).SetString(c.Signer.MinBalance, 10); !ok {
+		errs = append(errs, fmt.Errorf("signer.minBalance %q is not an amount in wei", c.Signer.MinBalance))
+	}
+	if c.SIWE.Domain == "" {
+		errs = append(errs, errors.New("siwe.domain must not be empty"))
+	}
+	if c.Session.TTL <= 0 {
+		errs = append(errs, errors.New("session.ttl must be positive"))
+	}
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if _, err := c.RateLimits(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Readiness.MaxBlockAge < 0 {
+		errs = append(errs, errors.New("readiness.maxBlockAge must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// ChainID returns the chain transactions are signed for.
+func (c *Config) ChainID() *big.Int {
+	return new(big.Int).SetUint64(c.Ethereum.ChainID)
+}
+
+// MinBalance returns the lowest signer balance in wei the service is ready with.
+func (c *Config) MinBalance() *big.Int {
+	balance, _ := new(big.Int).SetString(c.Signer.MinBalance, 10)
+	return balance
+}
+
+// RateLimits returns the per-class request limits.
+func (c *Config) RateLimits() (map[string]ratelimit.Limit, error) {
+	specs := map[string]string{
+		ratelimit.ClassWrite: c.RateLimit.Write,
+		ratelimit.ClassAuth:  c.RateLimit.Auth,
+	}
+	limits := make(map[string]ratelimit.Limit, len(specs))
+	for class, spec := range specs {
+		limit, err := ratelimit.ParseLimit(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rateLimit.%s: %w", class, err)
+		}
+		limits[class] = limit
+	}
+	return limits, nil
+}
+
+// String returns the effective configuration as YAML with secrets redacted.
+func (c *Config) String() string {
+	return configloader.RedactedYAML(c)
+}
+
+// Diff lists the settings that differ in next.
+func (c *Config) Diff(next *Config) []configloader.Change {
+	return configloader.Diff(c, next)
+}
+
+// ApplyReloadable copies the settings that may change at runtime from next.
+func (c *Config) ApplyReloadable(next *Config) {
+	configloader.MergeReloadable(c, next)
+}
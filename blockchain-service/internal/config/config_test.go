@@ -0,0 +1,74 @@
+package config_test
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"decentralstore/blockchain-service/internal/config"
+	"decentralstore/shared/configloader"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const contractAddress = "0x1234567890123456789012345678901234567890"
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+contract:
+  address: "`+contractAddress+`"
+ethereum:
+  chainId: 5
+session:
+  ttl: 1h
+`), 0o600))
+	t.Setenv("CHAIN_ID", "11155111")
+
+	cfg, err := config.Load([]string{"-config", path, "-session.ttl", "30m"})
+	require.NoError(t, err)
+	assert.Equal(t, contractAddress, cfg.Contract.Address)
+	assert.Equal(t, big.NewInt(11155111), cfg.ChainID(), "env overrides the file")
+	assert.Equal(t, 30*time.Minute, cfg.Session.TTL, "flags override the file")
+	assert.Equal(t, ":8082", cfg.Server.Addr)
+	assert.Equal(t, big.NewInt(1e16), cfg.MinBalance())
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := config.Load(nil)
+	assert.ErrorContains(t, err, "CONTRACT_ADDRESS")
+
+	_, err = config.Load([]string{"-contract.address", "0x12", "-signer.minBalance", "0.5", "-rateLimit.auth", "5/d"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "contract.address")
+	assert.Contains(t, err.Error(), "signer.minBalance")
+	assert.Contains(t, err.Error(), "rateLimit.auth")
+}
+
+func TestConfig_StringRedactsSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Signer.PrivateKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+	out := cfg.String()
+	assert.NotContains(t, out, cfg.Signer.PrivateKey)
+	assert.Contains(t, out, "privateKey: '[REDACTED]'")
+}
+
+func TestConfig_DiffAndApplyReloadable(t *testing.T) {
+	cfg := config.Default()
+	next := config.Default()
+	next.Ethereum.RPCURL = "http://geth:8545"
+	next.RateLimit.Auth = "5/m"
+
+	assert.ElementsMatch(t, []configloader.Change{
+		{Path: "ethereum.rpcUrl", Reloadable: false},
+		{Path: "rateLimit.auth", Reloadable: true},
+	}, cfg.Diff(next))
+
+	cfg.ApplyReloadable(next)
+	assert.Equal(t, "http://localhost:8545", cfg.Ethereum.RPCURL)
+	assert.Equal(t, "5/m", cfg.RateLimit.Auth)
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrUnauthenticated is returned when an operation requires a signed-in wallet.
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden is returned when the signed-in wallet does not own the metadata.
+	ErrForbidden = errors.New("caller is not the owner of this file")
+	// ErrMetadataNotFound is returned when no metadata has been stored for a fileID.
+	ErrMetadataNotFound = errors.New("metadata not found")
+)
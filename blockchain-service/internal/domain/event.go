@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kinds of MetadataEvent.
+const (
+	MetadataStored  = "stored"
+	MetadataUpdated = "updated"
+)
+
+// MetadataEvent is a metadata change emitted by the contract.
+type MetadataEvent struct {
+	Kind        string `json:"kind"`
+	FileID      string `json:"fileId"`
+	Owner       string `json:"owner"`
+	IsDeleted   bool   `json:"isDeleted"`
+	BlockNumber uint64 `json:"blockNumber"`
+	// LogIndex is the position of the event's log in its block.
+	LogIndex        uint   `json:"logIndex"`
+	TransactionHash string `json:"transactionHash"`
+}
+
+// Position returns where the event was emitted in the chain.
+func (e *MetadataEvent) Position() EventPosition {
+	return EventPosition{Block: e.BlockNumber, LogIndex: e.LogIndex}
+}
+
+// ErrInvalidEventPosition is returned by ParseEventPosition.
+var ErrInvalidEventPosition = errors.New("invalid event position")
+
+// EventPosition orders contract events by block number and log index. Its string form,
+// "<block>-<logIndex>", is used as the ID of streamed events.
+type EventPosition struct {
+	Block    uint64
+	LogIndex uint
+}
+
+func (p EventPosition) String() string {
+	return fmt.Sprintf("%d-%d", p.Block, p.LogIndex)
+}
+
+// After reports whether p comes later in the chain than other.
+func (p EventPosition) After(other EventPosition) bool {
+	if p.Block != other.Block {
+		return p.Block > other.Block
+	}
+	return p.LogIndex > other.LogIndex
+}
+
+// ParseEventPosition parses the "<block>-<logIndex>" form of an EventPosition.
+func ParseEventPosition(s string) (EventPosition, error) {
+	block, index, ok := strings.Cut(s, "-")
+	if !ok {
+		return EventPosition{}, ErrInvalidEventPosition
+	}
+	blockNumber, err := strconv.ParseUint(block, 10, 64)
+	if err != nil {
+		return EventPosition{}, ErrInvalidEventPosition
+	}
+	logIndex, err := strconv.ParseUint(index, 10, 0)
+	if err != nil {
+		return EventPosition{}, ErrInvalidEventPosition
+	}
+	return EventPosition{Block: blockNumber, LogIndex: uint(logIndex)}, nil
+}
+
+// MetadataEventFilter selects metadata events; empty fields match every event.
+type MetadataEventFilter struct {
+	Owner  string
+	FileID string
+	// After, if set, only matches events emitted after this position. Watchers that set it
+	// first receive the earlier events they missed.
+	After *EventPosition
+}
+
+// Matches reports whether event passes the filter.
+func (f MetadataEventFilter) Matches(event *MetadataEvent) bool {
+	if f.Owner != "" && !strings.EqualFold(f.Owner, event.Owner) {
+		return false
+	}
+	if f.After != nil && !event.Position().After(*f.After) {
+		return false
+	}
+	return f.FileID == "" || f.FileID == event.FileID
+}
+
+// Lifecycle events of metadata transactions, delivered to the owner's webhooks.
+const (
+	// EventMetadataAnchored is sent when a transaction has been submitted.
+	EventMetadataAnchored = "metadata.anchored"
+	// EventMetadataConfirmed and EventMetadataReverted are sent once it has been mined.
+	EventMetadataConfirmed = "metadata.confirmed"
+	EventMetadataReverted  = "metadata.reverted"
+)
+
+// Operations of a TransactionEvent.
+const (
+	OperationStore  = "store"
+	OperationUpdate = "update"
+)
+
+// TransactionEvent is the data of a metadata lifecycle event.
+type TransactionEvent struct {
+	Operation string `json:"operation"`
+	FileID    string `json:"fileId"`
+	Owner     string `json:"owner"`
+	// IsDeleted is the new deleted flag of an update.
+	IsDeleted       bool   `json:"isDeleted"`
+	Relayed         bool   `json:"relayed"`
+	TransactionHash string `json:"transactionHash"`
+	// BlockNumber is set once the transaction has been mined.
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+}
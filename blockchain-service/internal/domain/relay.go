@@ -0,0 +1,41 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrSignatureExpired is returned when a relayed request is submitted after its deadline.
+	ErrSignatureExpired = errors.New("signed request has expired")
+	// ErrInvalidSignature is returned when the typed-data signature was not made by the owner.
+	ErrInvalidSignature = errors.New("signature does not match the owner")
+	// ErrNonceMismatch is returned when the request nonce is not the owner's next nonce.
+	ErrNonceMismatch = errors.New("nonce does not match the owner's next nonce")
+)
+
+// RelayStoreRequest is an EIP-712 signed StoreMetadata message submitted for gasless relaying.
+type RelayStoreRequest struct {
+	Owner           string `json:"owner"`
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Size            int64  `json:"size"`
+	CID             string `json:"cid"`
+	DownloadKeyword string `json:"downloadKeyword"`
+	DeleteKeyword   string `json:"deleteKeyword"`
+	Nonce           uint64 `json:"nonce"`
+	Deadline        uint64 `json:"deadline"`
+	Signature       string `json:"signature"`
+}
+
+// Metadata returns the file metadata described by the request.
+func (r *RelayStoreRequest) Metadata() *FileMetadata {
+	return NewFileMetadata(r.ID, r.Name, r.CID, r.DownloadKeyword, r.DeleteKeyword, r.Owner, r.Size)
+}
+
+// RelayUpdateRequest is an EIP-712 signed UpdateMetadata message submitted for gasless relaying.
+type RelayUpdateRequest struct {
+	Owner     string `json:"owner"`
+	FileID    string `json:"fileId"`
+	IsDeleted bool   `json:"isDeleted"`
+	Nonce     uint64 `json:"nonce"`
+	Deadline  uint64 `json:"deadline"`
+	Signature string `json:"signature"`
+}
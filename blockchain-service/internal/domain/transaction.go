@@ -0,0 +1,27 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrTransactionNotFound is returned when the node does not know a transaction hash.
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrTransactionReverted is returned when a submitted transaction was mined but reverted.
+	ErrTransactionReverted = errors.New("transaction reverted")
+)
+
+// Transaction states reported by TransactionStatus.
+const (
+	TxPending   = "pending"
+	TxSucceeded = "succeeded"
+	TxReverted  = "reverted"
+)
+
+// TransactionStatus is the state of a submitted transaction.
+type TransactionStatus struct {
+	Hash   string `json:"hash"`
+	Status string `json:"status"`
+	// BlockNumber, GasUsed and Confirmations are set once the transaction is mined.
+	BlockNumber   uint64 `json:"blockNumber,omitempty"`
+	GasUsed       uint64 `json:"gasUsed,omitempty"`
+	Confirmations uint64 `json:"confirmations"`
+}
@@ -0,0 +1,139 @@
+// Package eip712 hashes the typed-data messages that file owners sign to let the
+// service relay metadata transactions on their behalf.
+package eip712
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Type strings as they must be declared in the verifying contract.
+const (
+	DomainType         = "EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"
+	StoreMetadataType  = "StoreMetadata(address owner,string fileId,string name,uint256 size,string cid,string downloadKeyword,string deleteKeyword,uint256 nonce,uint256 deadline)"
+	UpdateMetadataType = "UpdateMetadata(address owner,string fileId,bool isDeleted,uint256 nonce,uint256 deadline)"
+)
+
+var (
+	domainTypeHash         = crypto.Keccak256Hash([]byte(DomainType))
+	storeMetadataTypeHash  = crypto.Keccak256Hash([]byte(StoreMetadataType))
+	updateMetadataTypeHash = crypto.Keccak256Hash([]byte(UpdateMetadataType))
+)
+
+var ErrInvalidSignature = errors.New("eip712: invalid signature")
+
+// Domain is the EIP-712 domain that binds signatures to one contract on one chain.
+type Domain struct {
+	Name              string         `json:"name"`
+	Version           string         `json:"version"`
+	ChainID           *big.Int       `json:"chainId"`
+	VerifyingContract common.Address `json:"verifyingContract"`
+}
+
+// Separator returns the domain separator hash.
+func (d Domain) Separator() common.Hash {
+	return crypto.Keccak256Hash(
+		domainTypeHash.Bytes(),
+		hashString(d.Name),
+		hashString(d.Version),
+		encodeUint(d.ChainID),
+		encodeAddress(d.VerifyingContract),
+	)
+}
+
+// StoreMetadata is the typed message authorizing the relayer to store file metadata.
+type StoreMetadata struct {
+	Owner           common.Address
+	FileID          string
+	Name            string
+	Size            uint64
+	CID             string
+	DownloadKeyword string
+	DeleteKeyword   string
+	Nonce           uint64
+	Deadline        uint64
+}
+
+// Hash returns the EIP-712 struct hash of the message.
+func (m StoreMetadata) Hash() common.Hash {
+	return crypto.Keccak256Hash(
+		storeMetadataTypeHash.Bytes(),
+		encodeAddress(m.Owner),
+		hashString(m.FileID),
+		hashString(m.Name),
+		encodeUint(new(big.Int).SetUint64(m.Size)),
+		hashString(m.CID),
+		hashString(m.DownloadKeyword),
+		hashString(m.DeleteKeyword),
+		encodeUint(new(big.Int).SetUint64(m.Nonce)),
+		encodeUint(new(big.Int).SetUint64(m.Deadline)),
+	)
+}
+
+// UpdateMetadata is the typed message authorizing the relayer to update file metadata.
+type UpdateMetadata struct {
+	Owner     common.Address
+	FileID    string
+	IsDeleted bool
+	Nonce     uint64
+	Deadline  uint64
+}
+
+// Hash returns the EIP-712 struct hash of the message.
+func (m UpdateMetadata) Hash() common.Hash {
+	isDeleted := big.NewInt(0)
+	if m.IsDeleted {
+		isDeleted = big.NewInt(1)
+	}
+	return crypto.Keccak256Hash(
+		updateMetadataTypeHash.Bytes(),
+		encodeAddress(m.Owner),
+		hashString(m.FileID),
+		encodeUint(isDeleted),
+		encodeUint(new(big.Int).SetUint64(m.Nonce)),
+		encodeUint(new(big.Int).SetUint64(m.Deadline)),
+	)
+}
+
+// Digest returns the hash that is signed: keccak256("\x19\x01" ‖ domainSeparator ‖ structHash).
+func Digest(domain Domain, structHash common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, domain.Separator().Bytes(), structHash.Bytes())
+}
+
+// RecoverSigner returns the address that signed digest. The signature is the
+// 65-byte hex string returned by eth_signTypedData_v4.
+func RecoverSigner(digest common.Hash, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(digest.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+func hashString(s string) []byte {
+	return crypto.Keccak256([]byte(s))
+}
+
+func encodeUint(v *big.Int) []byte {
+	if v == nil {
+		v = new(big.Int)
+	}
+	return math.U256Bytes(new(big.Int).Set(v))
+}
+
+func encodeAddress(a common.Address) []byte {
+	return common.LeftPadBytes(a.Bytes(), 32)
+}
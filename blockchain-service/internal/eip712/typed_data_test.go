@@ -0,0 +1,141 @@
+package eip712
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDomain = Domain{
+	Name:              "DecentralStore",
+	Version:           "1",
+	ChainID:           big.NewInt(1337),
+	VerifyingContract: common.HexToAddress("0x1234567890123456789012345678901234567890"),
+}
+
+func apiDomain() apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:              testDomain.Name,
+		Version:           testDomain.Version,
+		ChainId:           (*math.HexOrDecimal256)(testDomain.ChainID),
+		VerifyingContract: testDomain.VerifyingContract.Hex(),
+	}
+}
+
+var domainFields = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+func TestStoreMetadata_MatchesReferenceImplementation(t *testing.T) {
+	msg := StoreMetadata{
+		Owner:           common.HexToAddress("0x00000000000000000000000000000000000000aa"),
+		FileID:          "file-1",
+		Name:            "report.pdf",
+		Size:            1024,
+		CID:             "QmTest",
+		DownloadKeyword: "dl",
+		DeleteKeyword:   "del",
+		Nonce:           3,
+		Deadline:        1700000000,
+	}
+
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": domainFields,
+			"StoreMetadata": {
+				{Name: "owner", Type: "address"},
+				{Name: "fileId", Type: "string"},
+				{Name: "name", Type: "string"},
+				{Name: "size", Type: "uint256"},
+				{Name: "cid", Type: "string"},
+				{Name: "downloadKeyword", Type: "string"},
+				{Name: "deleteKeyword", Type: "string"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "StoreMetadata",
+		Domain:      apiDomain(),
+		Message: apitypes.TypedDataMessage{
+			"owner":           msg.Owner.Hex(),
+			"fileId":          msg.FileID,
+			"name":            msg.Name,
+			"size":            "1024",
+			"cid":             msg.CID,
+			"downloadKeyword": msg.DownloadKeyword,
+			"deleteKeyword":   msg.DeleteKeyword,
+			"nonce":           "3",
+			"deadline":        "1700000000",
+		},
+	}
+
+	expected, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+
+	assert.Equal(t, common.BytesToHash(expected), Digest(testDomain, msg.Hash()))
+}
+
+func TestUpdateMetadata_MatchesReferenceImplementation(t *testing.T) {
+	msg := UpdateMetadata{
+		Owner:     common.HexToAddress("0x00000000000000000000000000000000000000aa"),
+		FileID:    "file-1",
+		IsDeleted: true,
+		Nonce:     4,
+		Deadline:  1700000000,
+	}
+
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": domainFields,
+			"UpdateMetadata": {
+				{Name: "owner", Type: "address"},
+				{Name: "fileId", Type: "string"},
+				{Name: "isDeleted", Type: "bool"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "UpdateMetadata",
+		Domain:      apiDomain(),
+		Message: apitypes.TypedDataMessage{
+			"owner":     msg.Owner.Hex(),
+			"fileId":    msg.FileID,
+			"isDeleted": true,
+			"nonce":     "4",
+			"deadline":  "1700000000",
+		},
+	}
+
+	expected, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+
+	assert.Equal(t, common.BytesToHash(expected), Digest(testDomain, msg.Hash()))
+}
+
+func TestRecoverSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	digest := Digest(testDomain, UpdateMetadata{FileID: "file-1"}.Hash())
+	sig, err := crypto.Sign(digest.Bytes(), key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+
+	signer, err := RecoverSigner(digest, hexutil.Encode(sig))
+
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer)
+
+	_, err = RecoverSigner(digest, "0x1234")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,88 @@
+// Package healthcheck provides the readiness checks of blockchain-service's Ethereum node,
+// contract and signer.
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"decentralstore/shared/health"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ChainBackend is the part of the Ethereum client the readiness checks use.
+type ChainBackend interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// ChainID verifies that the node serves the expected chain.
+func ChainID(backend ChainBackend, expected *big.Int) health.Check {
+	return func(ctx context.Context) (string, error) {
+		chainID, err := backend.ChainID(ctx)
+		if err != nil {
+			return "", err
+		}
+		if chainID.Cmp(expected) != 0 {
+			return "", fmt.Errorf("node serves chain %s, expected %s", chainID, expected)
+		}
+		return "chain " + chainID.String(), nil
+	}
+}
+
+// BlockAge fails when the latest block is older than maxAge, which means the node
+// is syncing or lost its peers. A zero maxAge only reports the age.
+func BlockAge(backend ChainBackend, maxAge time.Duration) health.Check {
+	return func(ctx context.Context) (string, error) {
+		header, err := backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return "", err
+		}
+		age := time.Since(time.Unix(int64(header.Time), 0)).Truncate(time.Second)
+		detail := fmt.Sprintf("block %s, %s old", header.Number, age)
+		if maxAge > 0 && age > maxAge {
+			return detail, fmt.Errorf("latest block is older than %s", maxAge)
+		}
+		return detail, nil
+	}
+}
+
+// ContractCode verifies that contract is deployed on the chain.
+func ContractCode(backend ChainBackend, contract common.Address) health.Check {
+	return func(ctx context.Context) (string, error) {
+		code, err := backend.CodeAt(ctx, contract, nil)
+		if err != nil {
+			return "", err
+		}
+		if len(code) == 0 {
+			return "", fmt.Errorf("no contract code at %s", contract.Hex())
+		}
+		return fmt.Sprintf("%d bytes of code", len(code)), nil
+	}
+}
+
+// Balance verifies that account can still pay for gas.
+func Balance(backend ChainBackend, account common.Address, minimum *big.Int) health.Check {
+	return func(ctx context.Context) (string, error) {
+		balance, err := backend.BalanceAt(ctx, account, nil)
+		if err != nil {
+			return "", err
+		}
+		detail := formatEther(balance) + " ETH"
+		if balance.Cmp(minimum) < 0 {
+			return detail, fmt.Errorf("balance of %s is below %s ETH", account.Hex(), formatEther(minimum))
+		}
+		return detail, nil
+	}
+}
+
+func formatEther(wei *big.Int) string {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.Ether)).Text('f', 6)
+}
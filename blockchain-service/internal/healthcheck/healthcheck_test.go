@@ -0,0 +1,100 @@
+package healthcheck_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"decentralstore/blockchain-service/internal/healthcheck"
+	"decentralstore/shared/health"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBackend struct {
+	chainID   *big.Int
+	blockTime time.Time
+	code      []byte
+	balance   *big.Int
+	err       error
+}
+
+func (f *fakeBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return f.chainID, f.err
+}
+
+func (f *fakeBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(42), Time: uint64(f.blockTime.Unix())}, f.err
+}
+
+func (f *fakeBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return f.code, f.err
+}
+
+func (f *fakeBackend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return f.balance, f.err
+}
+
+var (
+	testContract = common.HexToAddress("0x1234567890123456789012345678901234567890")
+	testSigner   = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+)
+
+func newChecker(backend healthcheck.ChainBackend) *health.Checker {
+	checker := health.NewChecker(time.Second)
+	checker.Add("chain", healthcheck.ChainID(backend, big.NewInt(1337)))
+	checker.Add("block", healthcheck.BlockAge(backend, time.Minute))
+	checker.Add("contract", healthcheck.ContractCode(backend, testContract))
+	checker.Add("signer", healthcheck.Balance(backend, testSigner, big.NewInt(1e16)))
+	return checker
+}
+
+func TestReady_Healthy(t *testing.T) {
+	backend := &fakeBackend{
+		chainID:   big.NewInt(1337),
+		blockTime: time.Now(),
+		code:      []byte{0x60, 0x80},
+		balance:   big.NewInt(1e18),
+	}
+
+	rr := httptest.NewRecorder()
+	newChecker(backend).Ready(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report health.Report
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, "chain 1337", report.Checks["chain"].Detail)
+	assert.Equal(t, "1.000000 ETH", report.Checks["signer"].Detail)
+}
+
+func TestReady_Unhealthy(t *testing.T) {
+	backend := &fakeBackend{
+		chainID:   big.NewInt(1),
+		blockTime: time.Now().Add(-time.Hour),
+		code:      nil,
+		balance:   big.NewInt(1),
+	}
+
+	report := newChecker(backend).Run(context.Background())
+
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	for _, name := range []string{"chain", "block", "contract", "signer"} {
+		assert.Equal(t, health.StatusUnavailable, report.Checks[name].Status, name)
+	}
+	assert.Contains(t, report.Checks["contract"].Error, "no contract code")
+}
+
+func TestReady_NodeDown(t *testing.T) {
+	report := newChecker(&fakeBackend{err: errors.New("connection refused")}).Run(context.Background())
+
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["chain"].Error)
+}
@@ -2,13 +2,19 @@ package infrastructure
 
 import (
 	"context"
+	"errors"
 	"math/big"
+	"time"
+
+	"decentralstore/blockchain-service/internal/metrics"
+	"decentralstore/shared/tracing"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type EthereumClient struct {
@@ -24,57 +30,95 @@ func NewEthereumClient(url string) (*EthereumClient, error) {
 }
 
 func (ec *EthereumClient) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
-	return ec.client.CodeAt(ctx, contract, blockNumber)
+	ctx, done := observeRPC(ctx, "eth_getCode")
+	result, err := ec.client.CodeAt(ctx, contract, blockNumber)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
-	return ec.client.CallContract(ctx, call, blockNumber)
+	ctx, done := observeRPC(ctx, "eth_call")
+	result, err := ec.client.CallContract(ctx, call, blockNumber)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
-	return ec.client.PendingCodeAt(ctx, account)
+	ctx, done := observeRPC(ctx, "eth_getCode")
+	result, err := ec.client.PendingCodeAt(ctx, account)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
-	return ec.client.PendingNonceAt(ctx, account)
+	ctx, done := observeRPC(ctx, "eth_getTransactionCount")
+	result, err := ec.client.PendingNonceAt(ctx, account)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
-	return ec.client.SuggestGasPrice(ctx)
+	ctx, done := observeRPC(ctx, "eth_gasPrice")
+	result, err := ec.client.SuggestGasPrice(ctx)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) EstimateGas(ctx context.Context, call ethereum.CallMsg) (gas uint64, err error) {
-	return ec.client.EstimateGas(ctx, call)
+	ctx, done := observeRPC(ctx, "eth_estimateGas")
+	result, err := ec.client.EstimateGas(ctx, call)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	return ec.client.SendTransaction(ctx, tx)
+	ctx, done := observeRPC(ctx, "eth_sendRawTransaction")
+	err := ec.client.SendTransaction(ctx, tx)
+	return done(err)
 }
 
 func (ec *EthereumClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
-	return ec.client.FilterLogs(ctx, query)
+	ctx, done := observeRPC(ctx, "eth_getLogs")
+	result, err := ec.client.FilterLogs(ctx, query)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
-	return ec.client.SubscribeFilterLogs(ctx, query, ch)
+	ctx, done := observeRPC(ctx, "eth_subscribe")
+	result, err := ec.client.SubscribeFilterLogs(ctx, query, ch)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	return ec.client.TransactionReceipt(ctx, txHash)
+	ctx, done := observeRPC(ctx, "eth_getTransactionReceipt")
+	result, err := ec.client.TransactionReceipt(ctx, txHash)
+	return result, done(err)
+}
+
+func (ec *EthereumClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	ctx, done := observeRPC(ctx, "eth_getTransactionByHash")
+	tx, isPending, err := ec.client.TransactionByHash(ctx, hash)
+	return tx, isPending, done(err)
 }
 
 // 以下のメソッドは既存のものですが、bind.ContractBackendインターフェースの完全な実装のために必要です
 
 func (ec *EthereumClient) BlockNumber(ctx context.Context) (uint64, error) {
-	return ec.client.BlockNumber(ctx)
+	ctx, done := observeRPC(ctx, "eth_blockNumber")
+	result, err := ec.client.BlockNumber(ctx)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
-	return ec.client.BalanceAt(ctx, account, blockNumber)
+	ctx, done := observeRPC(ctx, "eth_getBalance")
+	result, err := ec.client.BalanceAt(ctx, account, blockNumber)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
-	return ec.client.HeaderByNumber(ctx, number)
+	ctx, done := observeRPC(ctx, "eth_getBlockByNumber")
+	result, err := ec.client.HeaderByNumber(ctx, number)
+	return result, done(err)
+}
+
+func (ec *EthereumClient) ChainID(ctx context.Context) (*big.Int, error) {
+	ctx, done := observeRPC(ctx, "eth_chainId")
+	result, err := ec.client.ChainID(ctx)
+	return result, done(err)
 }
 
 func (ec *EthereumClient) Close() {
@@ -83,7 +127,29 @@ func (ec *EthereumClient) Close() {
 
 // SuggestGasTipCap is required for EIP-1559 transactions
 func (ec *EthereumClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
-	return ec.client.SuggestGasTipCap(ctx)
+	ctx, done := observeRPC(ctx, "eth_maxPriorityFeePerGas")
+	result, err := ec.client.SuggestGasTipCap(ctx)
+	return result, done(err)
+}
+
+// observeRPC starts a span for a JSON-RPC call. The returned function records the
+// call's latency and failure in metrics and on the span, and returns err unchanged.
+// ethereum.NotFound is an expected answer, e.g. while polling for a pending receipt.
+func observeRPC(ctx context.Context, method string) (context.Context, func(error) error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, method, attribute.String("rpc.system", "jsonrpc"), attribute.String("rpc.method", method))
+	return ctx, func(err error) error {
+		metrics.RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		if errors.Is(err, ethereum.NotFound) {
+			tracing.End(span, nil)
+			return err
+		}
+		if err != nil {
+			metrics.RPCErrors.WithLabelValues(method).Inc()
+		}
+		tracing.End(span, err)
+		return err
+	}
 }
 
 // Ensure EthereumClient implements bind.ContractBackend
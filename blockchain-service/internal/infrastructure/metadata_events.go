@@ -0,0 +1,145 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"decentralstore/blockchain-service/internal/domain"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// metadataEventsABI describes the events of the FileMetadata contract.
+const metadataEventsABI = `[
+	{"type":"event","name":"MetadataStored","anonymous":false,"inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"fileId","type":"string","indexed":false}]},
+	{"type":"event","name":"MetadataUpdated","anonymous":false,"inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"fileId","type":"string","indexed":false},
+		{"name":"isDeleted","type":"bool","indexed":false}]}
+]`
+
+// metadataEventKinds maps the contract event names to MetadataEvent kinds.
+var metadataEventKinds = map[string]string{
+	"MetadataStored":  domain.MetadataStored,
+	"MetadataUpdated": domain.MetadataUpdated,
+}
+
+var metadataEvents = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(metadataEventsABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// metadataQuery selects the metadata event logs of the contract from fromBlock on.
+func (fmc *FileMetadataContract) metadataQuery(fromBlock *big.Int) ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		FromBlock: fromBlock,
+		Addresses: []common.Address{fmc.address},
+		Topics: [][]common.Hash{{
+			metadataEvents.Events["MetadataStored"].ID,
+			metadataEvents.Events["MetadataUpdated"].ID,
+		}},
+	}
+}
+
+// FilterMetadata returns the metadata events of the contract from fromBlock up to the
+// latest block, oldest first.
+func (fmc *FileMetadataContract) FilterMetadata(ctx context.Context, fromBlock uint64) ([]*domain.MetadataEvent, error) {
+	logs, err := fmc.backend.FilterLogs(ctx, fmc.metadataQuery(new(big.Int).SetUint64(fromBlock)))
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*domain.MetadataEvent, 0, len(logs))
+	for _, log := range logs {
+		metadataEvent, err := decodeMetadataEvent(log)
+		if err != nil {
+			return nil, err
+		}
+		if metadataEvent != nil {
+			events = append(events, metadataEvent)
+		}
+	}
+	return events, nil
+}
+
+// WatchMetadata forwards the MetadataStored and MetadataUpdated events of the contract to
+// sink until the subscription is unsubscribed or the log subscription fails. The backend
+// must support subscriptions, which usually means a websocket RPC endpoint.
+func (fmc *FileMetadataContract) WatchMetadata(ctx context.Context, sink chan<- *domain.MetadataEvent) (event.Subscription, error) {
+	logs := make(chan types.Log)
+	logSub, err := fmc.backend.SubscribeFilterLogs(ctx, fmc.metadataQuery(nil), logs)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer logSub.Unsubscribe()
+		for {
+			var log types.Log
+			select {
+			case log = <-logs:
+			case err := <-logSub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+
+			metadataEvent, err := decodeMetadataEvent(log)
+			if err != nil {
+				return err
+			}
+			if metadataEvent == nil {
+				continue
+			}
+			select {
+			case sink <- metadataEvent:
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// decodeMetadataEvent converts a contract log to a metadata event. It returns nil for logs
+// removed by a reorg and for logs of other events.
+func decodeMetadataEvent(log types.Log) (*domain.MetadataEvent, error) {
+	if log.Removed || len(log.Topics) < 2 {
+		return nil, nil
+	}
+	definition, err := metadataEvents.EventByID(log.Topics[0])
+	if err != nil {
+		return nil, nil
+	}
+
+	values, err := definition.Inputs.NonIndexed().Unpack(log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s log: %w", definition.Name, err)
+	}
+	metadataEvent := &domain.MetadataEvent{
+		Kind:            metadataEventKinds[definition.Name],
+		Owner:           common.BytesToAddress(log.Topics[1].Bytes()).Hex(),
+		BlockNumber:     log.BlockNumber,
+		LogIndex:        log.Index,
+		TransactionHash: log.TxHash.Hex(),
+	}
+	var ok bool
+	if metadataEvent.FileID, ok = values[0].(string); !ok {
+		return nil, fmt.Errorf("decode %s log: fileId is not a string", definition.Name)
+	}
+	if metadataEvent.Kind == domain.MetadataUpdated {
+		if metadataEvent.IsDeleted, ok = values[1].(bool); !ok {
+			return nil, fmt.Errorf("decode %s log: isDeleted is not a bool", definition.Name)
+		}
+	}
+	return metadataEvent, nil
+}
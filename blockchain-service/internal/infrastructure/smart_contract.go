@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sync"
 	"time"
 
 	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/metrics"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
@@ -14,9 +16,14 @@ import (
 )
 
 type FileMetadataContract struct {
-	address  common.Address
-	backend  bind.ContractBackend
-	contract boundContract
+	address    common.Address
+	backend    bind.ContractBackend
+	contract   boundContract
+	transactor *bind.TransactOpts
+
+	// pending maps submitted transactions to their contract method for receipt metrics.
+	mu      sync.Mutex
+	pending map[common.Hash]string
 }
 
 type boundContract interface {
@@ -29,8 +36,12 @@ type boundContract interface {
 		DeleteKeyword   string
 		Owner           common.Address
 	}, error)
-	StoreMetadata(opts *bind.TransactOpts, fileID string, name string, size uint64, cid string, downloadKeyword string, deleteKeyword string) (*types.Transaction, error)
+	StoreMetadata(opts *bind.TransactOpts, owner common.Address, fileID string, name string, size uint64, cid string, downloadKeyword string, deleteKeyword string) (*types.Transaction, error)
 	UpdateMetadata(opts *bind.TransactOpts, fileID string, isDeleted bool) (*types.Transaction, error)
+	GetFileIDsByOwner(opts *bind.CallOpts, owner common.Address) ([]string, error)
+	Nonces(opts *bind.CallOpts, owner common.Address) (*big.Int, error)
+	StoreMetadataWithSig(opts *bind.TransactOpts, owner common.Address, fileID string, name string, size uint64, cid string, downloadKeyword string, deleteKeyword string, deadline *big.Int, signature []byte) (*types.Transaction, error)
+	UpdateMetadataWithSig(opts *bind.TransactOpts, owner common.Address, fileID string, isDeleted bool, deadline *big.Int, signature []byte) (*types.Transaction, error)
 }
 
 func NewFileMetadataContract(address common.Address, backend bind.ContractBackend) (*FileMetadataContract, error) {
@@ -42,11 +53,32 @@ func NewFileMetadataContract(address common.Address, backend bind.ContractBacken
 		address:  address,
 		backend:  backend,
 		contract: contract,
+		pending:  make(map[common.Hash]string),
 	}, nil
 }
 
+// SetTransactor sets the service account used to sign transactions when callers pass nil options.
+func (fmc *FileMetadataContract) SetTransactor(opts *bind.TransactOpts) {
+	fmc.transactor = opts
+}
+
+// Address returns the address of the deployed contract.
+func (fmc *FileMetadataContract) Address() common.Address {
+	return fmc.address
+}
+
+func (fmc *FileMetadataContract) transactOpts(ctx context.Context, opts *bind.TransactOpts) *bind.TransactOpts {
+	if opts != nil || fmc.transactor == nil {
+		return opts
+	}
+	withCtx := *fmc.transactor
+	withCtx.Context = ctx
+	return &withCtx
+}
+
 func (fmc *FileMetadataContract) StoreMetadata(ctx context.Context, metadata *domain.FileMetadata, opts *bind.TransactOpts) (*types.Transaction, error) {
-	return fmc.contract.StoreMetadata(opts, metadata.ID, metadata.Name, uint64(metadata.Size), metadata.CID, metadata.DownloadKeyword, metadata.DeleteKeyword)
+	opts = fmc.transactOpts(ctx, opts)
+	return fmc.submitted("storeMetadata")(fmc.contract.StoreMetadata(opts, common.HexToAddress(metadata.Owner), metadata.ID, metadata.Name, uint64(metadata.Size), metadata.CID, metadata.DownloadKeyword, metadata.DeleteKeyword))
 }
 
 func (fmc *FileMetadataContract) GetMetadata(ctx context.Context, fileID string) (*domain.FileMetadata, error) {
@@ -55,6 +87,10 @@ func (fmc *FileMetadataContract) GetMetadata(ctx context.Context, fileID string)
 	if err != nil {
 		return nil, err
 	}
+	// 未登録のfileIDでは所有者がゼロアドレスの空の構造体が返る
+	if metadata.Owner == (common.Address{}) {
+		return nil, domain.ErrMetadataNotFound
+	}
 
 	return &domain.FileMetadata{
 		ID:              fileID,
@@ -68,8 +104,52 @@ func (fmc *FileMetadataContract) GetMetadata(ctx context.Context, fileID string)
 	}, nil
 }
 
+// ListFileIDsByOwner returns the IDs of all files recorded for owner.
+func (fmc *FileMetadataContract) ListFileIDsByOwner(ctx context.Context, owner common.Address) ([]string, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	return fmc.contract.GetFileIDsByOwner(opts, owner)
+}
+
 func (fmc *FileMetadataContract) UpdateMetadata(ctx context.Context, fileID string, isDeleted bool, opts *bind.TransactOpts) (*types.Transaction, error) {
-	return fmc.contract.UpdateMetadata(opts, fileID, isDeleted)
+	opts = fmc.transactOpts(ctx, opts)
+	return fmc.submitted("updateMetadata")(fmc.contract.UpdateMetadata(opts, fileID, isDeleted))
+}
+
+// Nonce returns the owner's next nonce for signed (relayed) requests.
+func (fmc *FileMetadataContract) Nonce(ctx context.Context, owner common.Address) (uint64, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	nonce, err := fmc.contract.Nonces(opts, owner)
+	if err != nil {
+		return 0, err
+	}
+	return nonce.Uint64(), nil
+}
+
+// StoreMetadataWithSig submits an owner-signed StoreMetadata message. The contract recovers
+// the signer from the EIP-712 signature and records it as the owner, while gas is paid by opts.
+func (fmc *FileMetadataContract) StoreMetadataWithSig(ctx context.Context, metadata *domain.FileMetadata, deadline uint64, signature []byte, opts *bind.TransactOpts) (*types.Transaction, error) {
+	opts = fmc.transactOpts(ctx, opts)
+	return fmc.submitted("storeMetadataWithSig")(fmc.contract.StoreMetadataWithSig(opts, common.HexToAddress(metadata.Owner), metadata.ID, metadata.Name, uint64(metadata.Size), metadata.CID, metadata.DownloadKeyword, metadata.DeleteKeyword, new(big.Int).SetUint64(deadline), signature))
+}
+
+// UpdateMetadataWithSig submits an owner-signed UpdateMetadata message.
+func (fmc *FileMetadataContract) UpdateMetadataWithSig(ctx context.Context, owner common.Address, fileID string, isDeleted bool, deadline uint64, signature []byte, opts *bind.TransactOpts) (*types.Transaction, error) {
+	opts = fmc.transactOpts(ctx, opts)
+	return fmc.submitted("updateMetadataWithSig")(fmc.contract.UpdateMetadataWithSig(opts, owner, fileID, isDeleted, new(big.Int).SetUint64(deadline), signature))
+}
+
+// submitted returns a function that records a sent transaction of method for metrics.
+func (fmc *FileMetadataContract) submitted(method string) func(*types.Transaction, error) (*types.Transaction, error) {
+	return func(tx *types.Transaction, err error) (*types.Transaction, error) {
+		if err != nil {
+			return nil, err
+		}
+		metrics.TransactionsSubmitted.WithLabelValues(method).Inc()
+		fmc.mu.Lock()
+		fmc.pending[tx.Hash()] = method
+		fmc.mu.Unlock()
+		return tx, nil
+	}
 }
 
 func (fmc *FileMetadataContract) WaitForTransaction(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
@@ -78,9 +158,28 @@ func (fmc *FileMetadataContract) WaitForTransaction(ctx context.Context, txHash
 		return nil, errors.New("backend does not implement bind.DeployBackend")
 	}
 
+	fmc.mu.Lock()
+	method, ok := fmc.pending[txHash]
+	delete(fmc.pending, txHash)
+	fmc.mu.Unlock()
+	if !ok {
+		method = "unknown"
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.ReceiptWait.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	}()
+
 	for {
 		receipt, err := backend.TransactionReceipt(ctx, txHash)
 		if err == nil {
+			if receipt.Status == types.ReceiptStatusSuccessful {
+				metrics.TransactionsConfirmed.WithLabelValues(method).Inc()
+			} else {
+				metrics.TransactionsReverted.WithLabelValues(method).Inc()
+			}
+			metrics.GasUsed.WithLabelValues(method).Add(float64(receipt.GasUsed))
 			return receipt, nil
 		}
 
@@ -134,7 +233,7 @@ func (bc *mockBoundContract) GetMetadata(opts *bind.CallOpts, fileID string) (st
 	}, nil
 }
 
-func (bc *mockBoundContract) StoreMetadata(opts *bind.TransactOpts, fileID string, name string, size uint64, cid string, downloadKeyword string, deleteKeyword string) (*types.Transaction, error) {
+func (bc *mockBoundContract) StoreMetadata(opts *bind.TransactOpts, owner common.Address, fileID string, name string, size uint64, cid string, downloadKeyword string, deleteKeyword string) (*types.Transaction, error) {
 	// モックの実装
 	return types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil), nil
 }
@@ -143,3 +242,23 @@ func (bc *mockBoundContract) UpdateMetadata(opts *bind.TransactOpts, fileID stri
 	// モックの実装
 	return types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil), nil
 }
+
+func (bc *mockBoundContract) GetFileIDsByOwner(opts *bind.CallOpts, owner common.Address) ([]string, error) {
+	// モックの実装
+	return []string{}, nil
+}
+
+func (bc *mockBoundContract) Nonces(opts *bind.CallOpts, owner common.Address) (*big.Int, error) {
+	// モックの実装
+	return big.NewInt(0), nil
+}
+
+func (bc *mockBoundContract) StoreMetadataWithSig(opts *bind.TransactOpts, owner common.Address, fileID string, name string, size uint64, cid string, downloadKeyword string, deleteKeyword string, deadline *big.Int, signature []byte) (*types.Transaction, error) {
+	// モックの実装
+	return types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil), nil
+}
+
+func (bc *mockBoundContract) UpdateMetadataWithSig(opts *bind.TransactOpts, owner common.Address, fileID string, isDeleted bool, deadline *big.Int, signature []byte) (*types.Transaction, error) {
+	// モックの実装
+	return types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil), nil
+}
@@ -0,0 +1,60 @@
+// Package metrics defines the Prometheus metrics exported by blockchain-service on /metrics.
+package metrics
+
+import (
+	"decentralstore/shared/servermetrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "blockchain_service"
+
+var (
+	// Requests records the HTTP and gRPC requests served by blockchain-service.
+	Requests = servermetrics.NewRequests(namespace)
+
+	TransactionsSubmitted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_submitted_total",
+		Help:      "Transactions sent to the chain by contract method.",
+	}, []string{"method"})
+
+	TransactionsConfirmed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_confirmed_total",
+		Help:      "Transactions mined successfully by contract method.",
+	}, []string{"method"})
+
+	TransactionsReverted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_reverted_total",
+		Help:      "Transactions mined with a failed status by contract method.",
+	}, []string{"method"})
+
+	GasUsed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gas_used_total",
+		Help:      "Gas used by mined transactions by contract method.",
+	}, []string{"method"})
+
+	ReceiptWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "receipt_wait_duration_seconds",
+		Help:      "Time spent waiting for a transaction receipt by contract method.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 15, 30, 60, 120, 300},
+	}, []string{"method"})
+
+	RPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Ethereum JSON-RPC call latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	RPCErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Failed Ethereum JSON-RPC calls by method.",
+	}, []string{"method"})
+)
@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"decentralstore/shared/servermetrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	TransactionsSubmitted.WithLabelValues("storeMetadata").Inc()
+
+	rr := httptest.NewRecorder()
+	servermetrics.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.Contains(rr.Body.String(), "blockchain_service_transactions_submitted_total"))
+}
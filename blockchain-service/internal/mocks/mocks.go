@@ -10,6 +10,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -42,6 +43,28 @@ func (m *MockEthClient) Close() {
 	m.Called()
 }
 
+// MockTransactionReader is a mock of the usecase.TransactionReader interface
+type MockTransactionReader struct {
+	mock.Mock
+}
+
+func (m *MockTransactionReader) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	args := m.Called(ctx, hash)
+	tx, _ := args.Get(0).(*types.Transaction)
+	return tx, args.Bool(1), args.Error(2)
+}
+
+func (m *MockTransactionReader) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	args := m.Called(ctx, hash)
+	receipt, _ := args.Get(0).(*types.Receipt)
+	return receipt, args.Error(1)
+}
+
+func (m *MockTransactionReader) BlockNumber(ctx context.Context) (uint64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
 // MockContractBackend is a mock of the bind.ContractBackend interface
 type MockContractBackend struct {
 	mock.Mock
@@ -127,7 +150,38 @@ func (m *MockFileMetadataContract) UpdateMetadata(ctx context.Context, fileID st
 	return args.Get(0).(*types.Transaction), args.Error(1)
 }
 
+func (m *MockFileMetadataContract) ListFileIDsByOwner(ctx context.Context, owner common.Address) ([]string, error) {
+	args := m.Called(ctx, owner)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (m *MockFileMetadataContract) WaitForTransaction(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
 	args := m.Called(ctx, txHash)
 	return args.Get(0).(*types.Receipt), args.Error(1)
 }
+
+func (m *MockFileMetadataContract) Nonce(ctx context.Context, owner common.Address) (uint64, error) {
+	args := m.Called(ctx, owner)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockFileMetadataContract) StoreMetadataWithSig(ctx context.Context, metadata *domain.FileMetadata, deadline uint64, signature []byte, opts *bind.TransactOpts) (*types.Transaction, error) {
+	args := m.Called(ctx, metadata, deadline, signature, opts)
+	return args.Get(0).(*types.Transaction), args.Error(1)
+}
+
+func (m *MockFileMetadataContract) UpdateMetadataWithSig(ctx context.Context, owner common.Address, fileID string, isDeleted bool, deadline uint64, signature []byte, opts *bind.TransactOpts) (*types.Transaction, error) {
+	args := m.Called(ctx, owner, fileID, isDeleted, deadline, signature, opts)
+	return args.Get(0).(*types.Transaction), args.Error(1)
+}
+
+func (m *MockFileMetadataContract) WatchMetadata(ctx context.Context, sink chan<- *domain.MetadataEvent) (event.Subscription, error) {
+	args := m.Called(ctx, sink)
+	return args.Get(0).(event.Subscription), args.Error(1)
+}
+
+func (m *MockFileMetadataContract) FilterMetadata(ctx context.Context, fromBlock uint64) ([]*domain.MetadataEvent, error) {
+	args := m.Called(ctx, fromBlock)
+	events, _ := args.Get(0).([]*domain.MetadataEvent)
+	return events, args.Error(1)
+}
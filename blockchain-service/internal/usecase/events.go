@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"context"
+
+	"decentralstore/blockchain-service/internal/domain"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EventPublisher notifies an owner of metadata lifecycle events, e.g. through webhooks.
+type EventPublisher interface {
+	Publish(ctx context.Context, owner, eventType string, data any)
+}
+
+// publishTransaction sends the anchored event of a submitted transaction and returns a
+// function that sends its confirmed or reverted event once the receipt is known.
+func publishTransaction(ctx context.Context, events EventPublisher, event domain.TransactionEvent, tx *types.Transaction) func(*types.Receipt) {
+	if events == nil {
+		return func(*types.Receipt) {}
+	}
+
+	// Owners are keyed by their checksummed address, as in sessions.
+	owner := common.HexToAddress(event.Owner).Hex()
+	event.Owner = owner
+	event.TransactionHash = tx.Hash().Hex()
+	events.Publish(ctx, owner, domain.EventMetadataAnchored, event)
+
+	return func(receipt *types.Receipt) {
+		if receipt.BlockNumber != nil {
+			event.BlockNumber = receipt.BlockNumber.Uint64()
+		}
+		eventType := domain.EventMetadataConfirmed
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			eventType = domain.EventMetadataReverted
+		}
+		events.Publish(ctx, owner, eventType, event)
+	}
+}
@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/eip712"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RelayerService relays owner-signed metadata requests so that owners need no ETH.
+type RelayerService interface {
+	Domain() eip712.Domain
+	Nonce(ctx context.Context, owner common.Address) (uint64, error)
+	RelayStoreMetadata(ctx context.Context, req *domain.RelayStoreRequest) (common.Hash, error)
+	RelayUpdateMetadata(ctx context.Context, req *domain.RelayUpdateRequest) (common.Hash, error)
+}
+
+type RelayContractInterface interface {
+	GetMetadata(ctx context.Context, fileID string) (*domain.FileMetadata, error)
+	Nonce(ctx context.Context, owner common.Address) (uint64, error)
+	StoreMetadataWithSig(ctx context.Context, metadata *domain.FileMetadata, deadline uint64, signature []byte, opts *bind.TransactOpts) (*types.Transaction, error)
+	UpdateMetadataWithSig(ctx context.Context, owner common.Address, fileID string, isDeleted bool, deadline uint64, signature []byte, opts *bind.TransactOpts) (*types.Transaction, error)
+	WaitForTransaction(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type relayerServiceImpl struct {
+	contract RelayContractInterface
+	domain   eip712.Domain
+	events   EventPublisher
+	now      func() time.Time
+
+	// 同じ署名の並行送信でガスを無駄にしないよう、処理中の (owner, nonce) を記録する
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+}
+
+// NewRelayerService returns the relay usecase. events may be nil.
+func NewRelayerService(contract RelayContractInterface, typedDataDomain eip712.Domain, events EventPublisher) RelayerService {
+	return &relayerServiceImpl{
+		contract: contract,
+		domain:   typedDataDomain,
+		events:   events,
+		now:      time.Now,
+		inFlight: make(map[string]struct{}),
+	}
+}
+
+// Domain returns the EIP-712 domain clients must sign against.
+func (s *relayerServiceImpl) Domain() eip712.Domain {
+	return s.domain
+}
+
+// Nonce returns the nonce the owner must use in its next signed request.
+func (s *relayerServiceImpl) Nonce(ctx context.Context, owner common.Address) (uint64, error) {
+	return s.contract.Nonce(ctx, owner)
+}
+
+// RelayStoreMetadata verifies a signed StoreMetadata message and submits it on the owner's behalf.
+func (s *relayerServiceImpl) RelayStoreMetadata(ctx context.Context, req *domain.RelayStoreRequest) (common.Hash, error) {
+	owner := common.HexToAddress(req.Owner)
+	message := eip712.StoreMetadata{
+		Owner:           owner,
+		FileID:          req.ID,
+		Name:            req.Name,
+		Size:            uint64(req.Size),
+		CID:             req.CID,
+		DownloadKeyword: req.DownloadKeyword,
+		DeleteKeyword:   req.DeleteKeyword,
+		Nonce:           req.Nonce,
+		Deadline:        req.Deadline,
+	}
+
+	signature, release, err := s.verify(ctx, owner, req.Nonce, req.Deadline, message.Hash(), req.Signature)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer release()
+
+	tx, err := s.contract.StoreMetadataWithSig(ctx, req.Metadata(), req.Deadline, signature, nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	mined := publishTransaction(ctx, s.events, domain.TransactionEvent{
+		Operation: domain.OperationStore,
+		FileID:    req.ID,
+		Owner:     req.Owner,
+		Relayed:   true,
+	}, tx)
+	_, err = waitForSuccess(ctx, s.contract, tx, mined)
+	return tx.Hash(), err
+}
+
+// RelayUpdateMetadata verifies a signed UpdateMetadata message from the file owner and submits it.
+func (s *relayerServiceImpl) RelayUpdateMetadata(ctx context.Context, req *domain.RelayUpdateRequest) (common.Hash, error) {
+	owner := common.HexToAddress(req.Owner)
+	message := eip712.UpdateMetadata{
+		Owner:     owner,
+		FileID:    req.FileID,
+		IsDeleted: req.IsDeleted,
+		Nonce:     req.Nonce,
+		Deadline:  req.Deadline,
+	}
+
+	signature, release, err := s.verify(ctx, owner, req.Nonce, req.Deadline, message.Hash(), req.Signature)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer release()
+
+	metadata, err := s.contract.GetMetadata(ctx, req.FileID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if common.HexToAddress(metadata.Owner) != owner {
+		return common.Hash{}, domain.ErrForbidden
+	}
+
+	tx, err := s.contract.UpdateMetadataWithSig(ctx, owner, req.FileID, req.IsDeleted, req.Deadline, signature, nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	mined := publishTransaction(ctx, s.events, domain.TransactionEvent{
+		Operation: domain.OperationUpdate,
+		FileID:    req.FileID,
+		Owner:     req.Owner,
+		IsDeleted: req.IsDeleted,
+		Relayed:   true,
+	}, tx)
+	_, err = waitForSuccess(ctx, s.contract, tx, mined)
+	return tx.Hash(), err
+}
+
+// verify checks the deadline, nonce and signature of a relayed request and reserves its
+// (owner, nonce) pair until release is called.
+func (s *relayerServiceImpl) verify(ctx context.Context, owner common.Address, nonce, deadline uint64, structHash common.Hash, signature string) ([]byte, func(), error) {
+	if uint64(s.now().Unix()) > deadline {
+		return nil, nil, domain.ErrSignatureExpired
+	}
+
+	signer, err := eip712.RecoverSigner(eip712.Digest(s.domain, structHash), signature)
+	if err != nil || signer != owner {
+		return nil, nil, domain.ErrInvalidSignature
+	}
+
+	key := fmt.Sprintf("%s:%d", owner.Hex(), nonce)
+	s.mu.Lock()
+	if _, busy := s.inFlight[key]; busy {
+		s.mu.Unlock()
+		return nil, nil, domain.ErrNonceMismatch
+	}
+	s.inFlight[key] = struct{}{}
+	s.mu.Unlock()
+	release := func() {
+		s.mu.Lock()
+		delete(s.inFlight, key)
+		s.mu.Unlock()
+	}
+
+	expected, err := s.contract.Nonce(ctx, owner)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	if nonce != expected {
+		release()
+		return nil, nil, domain.ErrNonceMismatch
+	}
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		release()
+		return nil, nil, domain.ErrInvalidSignature
+	}
+	return sig, release, nil
+}
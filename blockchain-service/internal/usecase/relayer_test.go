@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/eip712"
+	"decentralstore/blockchain-service/internal/mocks"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var relayDomain = eip712.Domain{
+	Name:              "DecentralStore",
+	Version:           "1",
+	ChainID:           big.NewInt(1337),
+	VerifyingContract: common.HexToAddress("0x1234567890123456789012345678901234567890"),
+}
+
+func signTyped(t *testing.T, key *ecdsa.PrivateKey, structHash common.Hash) string {
+	sig, err := crypto.Sign(eip712.Digest(relayDomain, structHash).Bytes(), key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig)
+}
+
+func newSignedStoreRequest(t *testing.T, key *ecdsa.PrivateKey, nonce, deadline uint64) *domain.RelayStoreRequest {
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	req := &domain.RelayStoreRequest{
+		Owner:    owner.Hex(),
+		ID:       "file-1",
+		Name:     "report.pdf",
+		Size:     1024,
+		CID:      "QmTest",
+		Nonce:    nonce,
+		Deadline: deadline,
+	}
+	req.Signature = signTyped(t, key, eip712.StoreMetadata{
+		Owner:    owner,
+		FileID:   req.ID,
+		Name:     req.Name,
+		Size:     uint64(req.Size),
+		CID:      req.CID,
+		Nonce:    nonce,
+		Deadline: deadline,
+	}.Hash())
+	return req
+}
+
+func TestRelayStoreMetadata(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewRelayerService(mockContract, relayDomain, nil)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	ctx := context.Background()
+	req := newSignedStoreRequest(t, key, 2, uint64(time.Now().Add(time.Hour).Unix()))
+
+	mockTx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
+	mockContract.On("Nonce", ctx, owner).Return(uint64(2), nil)
+	mockContract.On("StoreMetadataWithSig", ctx, mock.MatchedBy(func(m *domain.FileMetadata) bool {
+		return m.ID == "file-1" && common.HexToAddress(m.Owner) == owner
+	}), req.Deadline, mock.Anything, mock.Anything).Return(mockTx, nil)
+	mockContract.On("WaitForTransaction", ctx, mockTx.Hash()).Return(&types.Receipt{Status: types.ReceiptStatusSuccessful}, nil)
+
+	txHash, err := service.RelayStoreMetadata(ctx, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, mockTx.Hash(), txHash)
+	mockContract.AssertExpectations(t)
+}
+
+func TestRelayStoreMetadata_Expired(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewRelayerService(mockContract, relayDomain, nil)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	req := newSignedStoreRequest(t, key, 0, uint64(time.Now().Add(-time.Minute).Unix()))
+
+	_, err = service.RelayStoreMetadata(context.Background(), req)
+
+	assert.ErrorIs(t, err, domain.ErrSignatureExpired)
+	mockContract.AssertExpectations(t)
+}
+
+func TestRelayStoreMetadata_TamperedMessage(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewRelayerService(mockContract, relayDomain, nil)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	req := newSignedStoreRequest(t, key, 0, uint64(time.Now().Add(time.Hour).Unix()))
+	req.CID = "QmOther"
+
+	_, err = service.RelayStoreMetadata(context.Background(), req)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidSignature)
+	mockContract.AssertExpectations(t)
+}
+
+func TestRelayStoreMetadata_ReplayedNonce(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewRelayerService(mockContract, relayDomain, nil)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	ctx := context.Background()
+	req := newSignedStoreRequest(t, key, 0, uint64(time.Now().Add(time.Hour).Unix()))
+
+	mockContract.On("Nonce", ctx, owner).Return(uint64(1), nil)
+
+	_, err = service.RelayStoreMetadata(ctx, req)
+
+	assert.ErrorIs(t, err, domain.ErrNonceMismatch)
+	mockContract.AssertExpectations(t)
+}
+
+func TestRelayUpdateMetadata_NotOwner(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewRelayerService(mockContract, relayDomain, nil)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	ctx := context.Background()
+	deadline := uint64(time.Now().Add(time.Hour).Unix())
+
+	req := &domain.RelayUpdateRequest{Owner: owner.Hex(), FileID: "file-1", IsDeleted: true, Deadline: deadline}
+	req.Signature = signTyped(t, key, eip712.UpdateMetadata{Owner: owner, FileID: "file-1", IsDeleted: true, Deadline: deadline}.Hash())
+
+	mockContract.On("Nonce", ctx, owner).Return(uint64(0), nil)
+	mockContract.On("GetMetadata", ctx, "file-1").Return(&domain.FileMetadata{ID: "file-1", Owner: testOwner.Hex()}, nil)
+
+	_, err = service.RelayUpdateMetadata(ctx, req)
+
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	mockContract.AssertExpectations(t)
+}
@@ -2,53 +2,210 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"decentralstore/blockchain-service/internal/auth"
 	"decentralstore/blockchain-service/internal/domain"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 )
 
 type BlockchainService interface {
 	StoreMetadata(ctx context.Context, metadata *domain.FileMetadata) error
 	GetMetadata(ctx context.Context, fileID string) (*domain.FileMetadata, error)
 	UpdateMetadata(ctx context.Context, fileID string, isDeleted bool) error
+	ListOwnedMetadata(ctx context.Context) ([]*domain.FileMetadata, error)
+	WatchMetadata(ctx context.Context, filter domain.MetadataEventFilter, sink chan<- *domain.MetadataEvent) error
 }
 
 type FileMetadataContractInterface interface {
 	StoreMetadata(ctx context.Context, metadata *domain.FileMetadata, opts *bind.TransactOpts) (*types.Transaction, error)
 	GetMetadata(ctx context.Context, fileID string) (*domain.FileMetadata, error)
 	UpdateMetadata(ctx context.Context, fileID string, isDeleted bool, opts *bind.TransactOpts) (*types.Transaction, error)
+	ListFileIDsByOwner(ctx context.Context, owner common.Address) ([]string, error)
 	WaitForTransaction(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	WatchMetadata(ctx context.Context, sink chan<- *domain.MetadataEvent) (event.Subscription, error)
+	FilterMetadata(ctx context.Context, fromBlock uint64) ([]*domain.MetadataEvent, error)
 }
 
 type blockchainServiceImpl struct {
 	contract FileMetadataContractInterface
+	events   EventPublisher
 }
 
-func NewBlockchainService(contract FileMetadataContractInterface) BlockchainService {
-	return &blockchainServiceImpl{contract: contract}
+// NewBlockchainService returns the metadata usecase. events may be nil.
+func NewBlockchainService(contract FileMetadataContractInterface, events EventPublisher) BlockchainService {
+	return &blockchainServiceImpl{contract: contract, events: events}
 }
 
+// StoreMetadata records metadata owned by the signed-in wallet. A fileID that another wallet
+// already owns is refused.
 func (s *blockchainServiceImpl) StoreMetadata(ctx context.Context, metadata *domain.FileMetadata) error {
+	caller, ok := auth.WalletFromContext(ctx)
+	if !ok {
+		return domain.ErrUnauthenticated
+	}
+	existing, err := s.contract.GetMetadata(ctx, metadata.ID)
+	if err != nil && !errors.Is(err, domain.ErrMetadataNotFound) {
+		return err
+	}
+	if err == nil && common.HexToAddress(existing.Owner) != caller {
+		return domain.ErrForbidden
+	}
+	metadata.Owner = caller.Hex()
+
 	tx, err := s.contract.StoreMetadata(ctx, metadata, nil)
 	if err != nil {
 		return err
 	}
-	_, err = s.contract.WaitForTransaction(ctx, tx.Hash())
-	return err
+	mined := publishTransaction(ctx, s.events, domain.TransactionEvent{
+		Operation: domain.OperationStore,
+		FileID:    metadata.ID,
+		Owner:     metadata.Owner,
+	}, tx)
+	receipt, err := waitForSuccess(ctx, s.contract, tx, mined)
+	if err != nil {
+		return err
+	}
+	metadata.SetBlockchainInfo(receipt.BlockNumber, tx.Hash().Hex())
+	return nil
 }
 
 func (s *blockchainServiceImpl) GetMetadata(ctx context.Context, fileID string) (*domain.FileMetadata, error) {
 	return s.contract.GetMetadata(ctx, fileID)
 }
 
+// UpdateMetadata updates metadata only when the signed-in wallet is its owner.
 func (s *blockchainServiceImpl) UpdateMetadata(ctx context.Context, fileID string, isDeleted bool) error {
+	if err := s.requireOwner(ctx, fileID); err != nil {
+		return err
+	}
+
 	tx, err := s.contract.UpdateMetadata(ctx, fileID, isDeleted, nil)
 	if err != nil {
 		return err
 	}
-	_, err = s.contract.WaitForTransaction(ctx, tx.Hash())
+	caller, _ := auth.WalletFromContext(ctx)
+	mined := publishTransaction(ctx, s.events, domain.TransactionEvent{
+		Operation: domain.OperationUpdate,
+		FileID:    fileID,
+		Owner:     caller.Hex(),
+		IsDeleted: isDeleted,
+	}, tx)
+	_, err = waitForSuccess(ctx, s.contract, tx, mined)
 	return err
 }
+
+// ListOwnedMetadata returns the metadata of every file owned by the signed-in wallet.
+func (s *blockchainServiceImpl) ListOwnedMetadata(ctx context.Context) ([]*domain.FileMetadata, error) {
+	caller, ok := auth.WalletFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	fileIDs, err := s.contract.ListFileIDsByOwner(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*domain.FileMetadata, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		metadata, err := s.contract.GetMetadata(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, metadata)
+	}
+	return files, nil
+}
+
+// WatchMetadata sends the contract's metadata events that match filter to sink until ctx
+// is done or the event subscription fails. If filter.After is set, the events emitted after
+// that position are sent first, so that a watcher that reconnects misses nothing.
+func (s *blockchainServiceImpl) WatchMetadata(ctx context.Context, filter domain.MetadataEventFilter, sink chan<- *domain.MetadataEvent) error {
+	// ライブ購読を先に開始し、過去ログの取得中に発生したイベントを取りこぼさないようにする
+	events := make(chan *domain.MetadataEvent, 64)
+	sub, err := s.contract.WatchMetadata(ctx, events)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	send := func(metadataEvent *domain.MetadataEvent) error {
+		if !filter.Matches(metadataEvent) {
+			return nil
+		}
+		select {
+		case sink <- metadataEvent:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		position := metadataEvent.Position()
+		filter.After = &position
+		return nil
+	}
+
+	if filter.After != nil {
+		missed, err := s.contract.FilterMetadata(ctx, filter.After.Block)
+		if err != nil {
+			return err
+		}
+		for _, metadataEvent := range missed {
+			if err := send(metadataEvent); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case metadataEvent := <-events:
+			if err := send(metadataEvent); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// transactionWaiter is the part of the contracts needed to wait for a submitted transaction.
+type transactionWaiter interface {
+	WaitForTransaction(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// waitForSuccess waits for the receipt of tx and passes it to mined. A reverted transaction
+// fails with domain.ErrTransactionReverted.
+func waitForSuccess(ctx context.Context, contract transactionWaiter, tx *types.Transaction, mined func(*types.Receipt)) (*types.Receipt, error) {
+	receipt, err := contract.WaitForTransaction(ctx, tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	mined(receipt)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTransactionReverted, tx.Hash().Hex())
+	}
+	return receipt, nil
+}
+
+func (s *blockchainServiceImpl) requireOwner(ctx context.Context, fileID string) error {
+	caller, ok := auth.WalletFromContext(ctx)
+	if !ok {
+		return domain.ErrUnauthenticated
+	}
+
+	metadata, err := s.contract.GetMetadata(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if common.HexToAddress(metadata.Owner) != caller {
+		return domain.ErrForbidden
+	}
+	return nil
+}
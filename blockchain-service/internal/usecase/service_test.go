@@ -3,23 +3,28 @@ package usecase
 import (
 	"context"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
+	"decentralstore/blockchain-service/internal/auth"
 	"decentralstore/blockchain-service/internal/domain"
 	"decentralstore/blockchain-service/internal/mocks"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+var testOwner = common.HexToAddress("0x1234567890123456789012345678901234567890")
+
 func TestStoreMetadata(t *testing.T) {
 	mockContract := new(mocks.MockFileMetadataContract)
-	service := NewBlockchainService(mockContract)
+	service := NewBlockchainService(mockContract, nil)
 
-	ctx := context.Background()
+	ctx := auth.WithWallet(context.Background(), testOwner)
 	metadata := &domain.FileMetadata{
 		ID:              "testID",
 		Name:            "testFile",
@@ -34,6 +39,7 @@ func TestStoreMetadata(t *testing.T) {
 	mockTx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
 	mockReceipt := &types.Receipt{Status: 1}
 
+	mockContract.On("GetMetadata", ctx, "testID").Return((*domain.FileMetadata)(nil), domain.ErrMetadataNotFound)
 	mockContract.On("StoreMetadata", ctx, metadata, mock.Anything).Return(mockTx, nil)
 	mockContract.On("WaitForTransaction", ctx, mockTx.Hash()).Return(mockReceipt, nil)
 
@@ -43,9 +49,40 @@ func TestStoreMetadata(t *testing.T) {
 	mockContract.AssertExpectations(t)
 }
 
+func TestStoreMetadata_Reverted(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewBlockchainService(mockContract, nil)
+
+	ctx := auth.WithWallet(context.Background(), testOwner)
+	metadata := &domain.FileMetadata{ID: "testID"}
+	mockTx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
+
+	mockContract.On("GetMetadata", ctx, "testID").Return((*domain.FileMetadata)(nil), domain.ErrMetadataNotFound)
+	mockContract.On("StoreMetadata", ctx, metadata, mock.Anything).Return(mockTx, nil)
+	mockContract.On("WaitForTransaction", ctx, mockTx.Hash()).Return(&types.Receipt{Status: types.ReceiptStatusFailed, BlockNumber: big.NewInt(42)}, nil)
+
+	err := service.StoreMetadata(ctx, metadata)
+
+	assert.ErrorIs(t, err, domain.ErrTransactionReverted)
+	assert.Empty(t, metadata.TransactionHash, "a reverted transaction is not recorded")
+}
+
+func TestStoreMetadata_OwnedByAnotherWallet(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewBlockchainService(mockContract, nil)
+
+	ctx := auth.WithWallet(context.Background(), common.HexToAddress("0x0000000000000000000000000000000000000bad"))
+	mockContract.On("GetMetadata", ctx, "testID").Return(&domain.FileMetadata{ID: "testID", Owner: testOwner.Hex()}, nil)
+
+	err := service.StoreMetadata(ctx, &domain.FileMetadata{ID: "testID"})
+
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	mockContract.AssertNotCalled(t, "StoreMetadata", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestGetMetadata(t *testing.T) {
 	mockContract := new(mocks.MockFileMetadataContract)
-	service := NewBlockchainService(mockContract)
+	service := NewBlockchainService(mockContract, nil)
 
 	ctx := context.Background()
 	fileID := "testID"
@@ -67,15 +104,16 @@ func TestGetMetadata(t *testing.T) {
 
 func TestUpdateMetadata(t *testing.T) {
 	mockContract := new(mocks.MockFileMetadataContract)
-	service := NewBlockchainService(mockContract)
+	service := NewBlockchainService(mockContract, nil)
 
-	ctx := context.Background()
+	ctx := auth.WithWallet(context.Background(), testOwner)
 	fileID := "testID"
 	isDeleted := true
 
 	mockTx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
 	mockReceipt := &types.Receipt{Status: 1}
 
+	mockContract.On("GetMetadata", ctx, fileID).Return(&domain.FileMetadata{ID: fileID, Owner: testOwner.Hex()}, nil)
 	mockContract.On("UpdateMetadata", ctx, fileID, isDeleted, mock.Anything).Return(mockTx, nil)
 	mockContract.On("WaitForTransaction", ctx, mockTx.Hash()).Return(mockReceipt, nil)
 
@@ -84,3 +122,173 @@ func TestUpdateMetadata(t *testing.T) {
 	assert.NoError(t, err)
 	mockContract.AssertExpectations(t)
 }
+
+// recordedEvents is an EventPublisher that keeps the published events.
+type recordedEvents struct {
+	owners []string
+	types  []string
+	data   []domain.TransactionEvent
+}
+
+func (r *recordedEvents) Publish(ctx context.Context, owner, eventType string, data any) {
+	r.owners = append(r.owners, owner)
+	r.types = append(r.types, eventType)
+	r.data = append(r.data, data.(domain.TransactionEvent))
+}
+
+func TestUpdateMetadata_PublishesEvents(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	events := &recordedEvents{}
+	service := NewBlockchainService(mockContract, events)
+
+	ctx := auth.WithWallet(context.Background(), testOwner)
+	mockTx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
+	mockReceipt := &types.Receipt{Status: types.ReceiptStatusFailed, BlockNumber: big.NewInt(42)}
+
+	mockContract.On("GetMetadata", ctx, "testID").Return(&domain.FileMetadata{ID: "testID", Owner: testOwner.Hex()}, nil)
+	mockContract.On("UpdateMetadata", ctx, "testID", true, mock.Anything).Return(mockTx, nil)
+	mockContract.On("WaitForTransaction", ctx, mockTx.Hash()).Return(mockReceipt, nil)
+
+	err := service.UpdateMetadata(ctx, "testID", true)
+
+	assert.ErrorIs(t, err, domain.ErrTransactionReverted)
+	assert.Equal(t, []string{domain.EventMetadataAnchored, domain.EventMetadataReverted}, events.types)
+	assert.Equal(t, []string{testOwner.Hex(), testOwner.Hex()}, events.owners)
+	assert.Equal(t, domain.TransactionEvent{
+		Operation:       domain.OperationUpdate,
+		FileID:          "testID",
+		Owner:           testOwner.Hex(),
+		IsDeleted:       true,
+		TransactionHash: mockTx.Hash().Hex(),
+		BlockNumber:     42,
+	}, events.data[1])
+	assert.Zero(t, events.data[0].BlockNumber, "the anchored event is sent before the block is known")
+}
+
+func TestStoreMetadata_Unauthenticated(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewBlockchainService(mockContract, nil)
+
+	err := service.StoreMetadata(context.Background(), &domain.FileMetadata{ID: "testID"})
+
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	mockContract.AssertNotCalled(t, "StoreMetadata", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestStoreMetadata_OwnerFromSession(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewBlockchainService(mockContract, nil)
+
+	ctx := auth.WithWallet(context.Background(), testOwner)
+	metadata := &domain.FileMetadata{ID: "testID", Owner: "0x0000000000000000000000000000000000000bad"}
+
+	mockTx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
+	mockContract.On("GetMetadata", ctx, "testID").Return(&domain.FileMetadata{ID: "testID", Owner: testOwner.Hex()}, nil)
+	mockContract.On("StoreMetadata", ctx, metadata, mock.Anything).Return(mockTx, nil)
+	mockContract.On("WaitForTransaction", ctx, mockTx.Hash()).Return(&types.Receipt{Status: 1}, nil)
+
+	err := service.StoreMetadata(ctx, metadata)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testOwner.Hex(), metadata.Owner)
+	mockContract.AssertExpectations(t)
+}
+
+func TestUpdateMetadata_NotOwner(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewBlockchainService(mockContract, nil)
+
+	ctx := auth.WithWallet(context.Background(), common.HexToAddress("0x0000000000000000000000000000000000000bad"))
+	mockContract.On("GetMetadata", ctx, "testID").Return(&domain.FileMetadata{ID: "testID", Owner: testOwner.Hex()}, nil)
+
+	err := service.UpdateMetadata(ctx, "testID", true)
+
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	mockContract.AssertNotCalled(t, "UpdateMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListOwnedMetadata(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewBlockchainService(mockContract, nil)
+
+	ctx := auth.WithWallet(context.Background(), testOwner)
+	first := &domain.FileMetadata{ID: "a", Owner: testOwner.Hex()}
+	second := &domain.FileMetadata{ID: "b", Owner: testOwner.Hex()}
+
+	mockContract.On("ListFileIDsByOwner", ctx, testOwner).Return([]string{"a", "b"}, nil)
+	mockContract.On("GetMetadata", ctx, "a").Return(first, nil)
+	mockContract.On("GetMetadata", ctx, "b").Return(second, nil)
+
+	files, err := service.ListOwnedMetadata(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*domain.FileMetadata{first, second}, files)
+	mockContract.AssertExpectations(t)
+}
+
+func TestWatchMetadata_Filters(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewBlockchainService(mockContract, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+	mockContract.On("WatchMetadata", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		events := args.Get(1).(chan<- *domain.MetadataEvent)
+		go func() {
+			events <- &domain.MetadataEvent{Kind: domain.MetadataStored, FileID: "other", Owner: "0x0000000000000000000000000000000000000bad"}
+			events <- &domain.MetadataEvent{Kind: domain.MetadataUpdated, FileID: "testID", Owner: testOwner.Hex(), IsDeleted: true}
+		}()
+	}).Return(sub, nil)
+
+	sink := make(chan *domain.MetadataEvent)
+	done := make(chan error, 1)
+	go func() {
+		done <- service.WatchMetadata(ctx, domain.MetadataEventFilter{Owner: strings.ToLower(testOwner.Hex())}, sink)
+	}()
+
+	received := <-sink
+	assert.Equal(t, "testID", received.FileID)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestWatchMetadata_ResumesAfterPosition(t *testing.T) {
+	mockContract := new(mocks.MockFileMetadataContract)
+	service := NewBlockchainService(mockContract, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+	mockContract.On("WatchMetadata", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		events := args.Get(1).(chan<- *domain.MetadataEvent)
+		go func() {
+			// 過去ログと重複するライブイベントは送られない
+			events <- &domain.MetadataEvent{FileID: "b", BlockNumber: 6}
+			events <- &domain.MetadataEvent{FileID: "c", BlockNumber: 7}
+		}()
+	}).Return(sub, nil)
+	mockContract.On("FilterMetadata", ctx, uint64(5)).Return([]*domain.MetadataEvent{
+		{FileID: "seen", BlockNumber: 5, LogIndex: 0},
+		{FileID: "a", BlockNumber: 5, LogIndex: 1},
+		{FileID: "b", BlockNumber: 6},
+	}, nil)
+
+	sink := make(chan *domain.MetadataEvent)
+	done := make(chan error, 1)
+	go func() {
+		done <- service.WatchMetadata(ctx, domain.MetadataEventFilter{After: &domain.EventPosition{Block: 5}}, sink)
+	}()
+
+	var received []string
+	for range 3 {
+		received = append(received, (<-sink).FileID)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, received)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/eip712"
+	"decentralstore/shared/tracing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// WithTracing wraps service so that every call runs in its own span.
+func WithTracing(service BlockchainService) BlockchainService {
+	return &tracedBlockchainService{next: service}
+}
+
+type tracedBlockchainService struct {
+	next BlockchainService
+}
+
+func (t *tracedBlockchainService) StoreMetadata(ctx context.Context, metadata *domain.FileMetadata) error {
+	ctx, span := tracing.Start(ctx, "BlockchainService.StoreMetadata", attribute.String("file.id", metadata.ID))
+	err := t.next.StoreMetadata(ctx, metadata)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedBlockchainService) GetMetadata(ctx context.Context, fileID string) (*domain.FileMetadata, error) {
+	ctx, span := tracing.Start(ctx, "BlockchainService.GetMetadata", attribute.String("file.id", fileID))
+	metadata, err := t.next.GetMetadata(ctx, fileID)
+	tracing.End(span, err)
+	return metadata, err
+}
+
+func (t *tracedBlockchainService) UpdateMetadata(ctx context.Context, fileID string, isDeleted bool) error {
+	ctx, span := tracing.Start(ctx, "BlockchainService.UpdateMetadata", attribute.String("file.id", fileID))
+	err := t.next.UpdateMetadata(ctx, fileID, isDeleted)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedBlockchainService) ListOwnedMetadata(ctx context.Context) ([]*domain.FileMetadata, error) {
+	ctx, span := tracing.Start(ctx, "BlockchainService.ListOwnedMetadata")
+	files, err := t.next.ListOwnedMetadata(ctx)
+	tracing.End(span, err)
+	return files, err
+}
+
+// WatchMetadata is not traced: the call lasts as long as the watcher stays connected.
+func (t *tracedBlockchainService) WatchMetadata(ctx context.Context, filter domain.MetadataEventFilter, sink chan<- *domain.MetadataEvent) error {
+	return t.next.WatchMetadata(ctx, filter, sink)
+}
+
+// WithRelayerTracing wraps service so that every relayed request runs in its own span.
+func WithRelayerTracing(service RelayerService) RelayerService {
+	return &tracedRelayerService{next: service}
+}
+
+type tracedRelayerService struct {
+	next RelayerService
+}
+
+func (t *tracedRelayerService) Domain() eip712.Domain {
+	return t.next.Domain()
+}
+
+func (t *tracedRelayerService) Nonce(ctx context.Context, owner common.Address) (uint64, error) {
+	ctx, span := tracing.Start(ctx, "RelayerService.Nonce", attribute.String("owner", owner.Hex()))
+	nonce, err := t.next.Nonce(ctx, owner)
+	tracing.End(span, err)
+	return nonce, err
+}
+
+func (t *tracedRelayerService) RelayStoreMetadata(ctx context.Context, req *domain.RelayStoreRequest) (common.Hash, error) {
+	ctx, span := tracing.Start(ctx, "RelayerService.RelayStoreMetadata",
+		attribute.String("owner", req.Owner), attribute.String("file.id", req.ID))
+	hash, err := t.next.RelayStoreMetadata(ctx, req)
+	span.SetAttributes(attribute.String("tx.hash", hash.Hex()))
+	tracing.End(span, err)
+	return hash, err
+}
+
+func (t *tracedRelayerService) RelayUpdateMetadata(ctx context.Context, req *domain.RelayUpdateRequest) (common.Hash, error) {
+	ctx, span := tracing.Start(ctx, "RelayerService.RelayUpdateMetadata",
+		attribute.String("owner", req.Owner), attribute.String("file.id", req.FileID))
+	hash, err := t.next.RelayUpdateMetadata(ctx, req)
+	span.SetAttributes(attribute.String("tx.hash", hash.Hex()))
+	tracing.End(span, err)
+	return hash, err
+}
@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTracing_RecordsSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	mockContract := new(mocks.MockFileMetadataContract)
+	mockContract.On("GetMetadata", mock.Anything, "testID").Return(&domain.FileMetadata{ID: "testID"}, nil)
+	mockContract.On("GetMetadata", mock.Anything, "missing").Return((*domain.FileMetadata)(nil), domain.ErrForbidden)
+	service := WithTracing(NewBlockchainService(mockContract, nil))
+
+	_, err := service.GetMetadata(context.Background(), "testID")
+	require.NoError(t, err)
+	_, err = service.GetMetadata(context.Background(), "missing")
+	require.True(t, errors.Is(err, domain.ErrForbidden))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "BlockchainService.GetMetadata", spans[0].Name)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+}
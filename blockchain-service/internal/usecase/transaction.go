@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"decentralstore/blockchain-service/internal/domain"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type TransactionService interface {
+	TransactionStatus(ctx context.Context, hash common.Hash) (*domain.TransactionStatus, error)
+}
+
+// TransactionReader is the part of the Ethereum client needed to look up transactions.
+type TransactionReader interface {
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+type transactionServiceImpl struct {
+	chain TransactionReader
+}
+
+func NewTransactionService(chain TransactionReader) TransactionService {
+	return &transactionServiceImpl{chain: chain}
+}
+
+// TransactionStatus reports whether the transaction is pending, succeeded or reverted,
+// and how many blocks have been mined on top of it.
+func (s *transactionServiceImpl) TransactionStatus(ctx context.Context, hash common.Hash) (*domain.TransactionStatus, error) {
+	_, isPending, err := s.chain.TransactionByHash(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, domain.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := &domain.TransactionStatus{Hash: hash.Hex(), Status: domain.TxPending}
+	if isPending {
+		return status, nil
+	}
+
+	receipt, err := s.chain.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		// The transaction was just mined and the node has not indexed the receipt yet.
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.chain.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Status = domain.TxSucceeded
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		status.Status = domain.TxReverted
+	}
+	status.BlockNumber = receipt.BlockNumber.Uint64()
+	status.GasUsed = receipt.GasUsed
+	if latest >= status.BlockNumber {
+		status.Confirmations = latest - status.BlockNumber + 1
+	}
+	return status, nil
+}
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"decentralstore/blockchain-service/internal/domain"
+	"decentralstore/blockchain-service/internal/mocks"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTxHash = common.HexToHash("0xabc")
+
+func TestTransactionStatus_Mined(t *testing.T) {
+	chain := new(mocks.MockTransactionReader)
+	ctx := context.Background()
+	chain.On("TransactionByHash", ctx, testTxHash).Return(&types.Transaction{}, false, nil)
+	chain.On("TransactionReceipt", ctx, testTxHash).Return(&types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(100), GasUsed: 21000}, nil)
+	chain.On("BlockNumber", ctx).Return(uint64(104), nil)
+
+	status, err := NewTransactionService(chain).TransactionStatus(ctx, testTxHash)
+	require.NoError(t, err)
+	assert.Equal(t, &domain.TransactionStatus{
+		Hash:          testTxHash.Hex(),
+		Status:        domain.TxSucceeded,
+		BlockNumber:   100,
+		GasUsed:       21000,
+		Confirmations: 5,
+	}, status)
+}
+
+func TestTransactionStatus_Reverted(t *testing.T) {
+	chain := new(mocks.MockTransactionReader)
+	ctx := context.Background()
+	chain.On("TransactionByHash", ctx, testTxHash).Return(&types.Transaction{}, false, nil)
+	chain.On("TransactionReceipt", ctx, testTxHash).Return(&types.Receipt{Status: types.ReceiptStatusFailed, BlockNumber: big.NewInt(100)}, nil)
+	chain.On("BlockNumber", ctx).Return(uint64(100), nil)
+
+	status, err := NewTransactionService(chain).TransactionStatus(ctx, testTxHash)
+	require.NoError(t, err)
+	assert.Equal(t, domain.TxReverted, status.Status)
+	assert.Equal(t, uint64(1), status.Confirmations)
+}
+
+func TestTransactionStatus_Pending(t *testing.T) {
+	chain := new(mocks.MockTransactionReader)
+	ctx := context.Background()
+	chain.On("TransactionByHash", ctx, testTxHash).Return(&types.Transaction{}, true, nil)
+
+	status, err := NewTransactionService(chain).TransactionStatus(ctx, testTxHash)
+	require.NoError(t, err)
+	assert.Equal(t, domain.TxPending, status.Status)
+	chain.AssertNotCalled(t, "TransactionReceipt", ctx, testTxHash)
+}
+
+func TestTransactionStatus_NotFound(t *testing.T) {
+	chain := new(mocks.MockTransactionReader)
+	ctx := context.Background()
+	chain.On("TransactionByHash", ctx, testTxHash).Return(nil, false, ethereum.NotFound)
+
+	_, err := NewTransactionService(chain).TransactionStatus(ctx, testTxHash)
+	assert.ErrorIs(t, err, domain.ErrTransactionNotFound)
+}
@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: rpc/blockchainpb/blockchain.proto
+
+package blockchainpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BlockchainService_Store_FullMethodName         = "/decentralstore.blockchain.v1.BlockchainService/Store"
+	BlockchainService_Get_FullMethodName           = "/decentralstore.blockchain.v1.BlockchainService/Get"
+	BlockchainService_Update_FullMethodName        = "/decentralstore.blockchain.v1.BlockchainService/Update"
+	BlockchainService_WatchMetadata_FullMethodName = "/decentralstore.blockchain.v1.BlockchainService/WatchMetadata"
+)
+
+// BlockchainServiceClient is the client API for BlockchainService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BlockchainService records file metadata on chain. It mirrors the /store,
+// /metadata and /update HTTP routes for internal callers; signed-in wallets pass
+// their session token as "authorization: Bearer <token>" metadata.
+type BlockchainServiceClient interface {
+	// Store records metadata owned by the signed-in wallet and waits for the receipt.
+	Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreResponse, error)
+	// Get returns the metadata of a file.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*FileMetadata, error)
+	// Update sets the deleted flag of a file owned by the signed-in wallet.
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error)
+	// WatchMetadata streams metadata changes as the contract emits them.
+	WatchMetadata(ctx context.Context, in *WatchMetadataRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MetadataEvent], error)
+}
+
+type blockchainServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBlockchainServiceClient(cc grpc.ClientConnInterface) BlockchainServiceClient {
+	return &blockchainServiceClient{cc}
+}
+
+func (c *blockchainServiceClient) Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*StoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StoreResponse)
+	err := c.cc.Invoke(ctx, BlockchainService_Store_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*FileMetadata, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileMetadata)
+	err := c.cc.Invoke(ctx, BlockchainService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainServiceClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateResponse)
+	err := c.cc.Invoke(ctx, BlockchainService_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainServiceClient) WatchMetadata(ctx context.Context, in *WatchMetadataRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MetadataEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BlockchainService_ServiceDesc.Streams[0], BlockchainService_WatchMetadata_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchMetadataRequest, MetadataEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlockchainService_WatchMetadataClient = grpc.ServerStreamingClient[MetadataEvent]
+
+// BlockchainServiceServer is the server API for BlockchainService service.
+// All implementations must embed UnimplementedBlockchainServiceServer
+// for forward compatibility.
+//
+// BlockchainService records file metadata on chain. It mirrors the /store,
+// /metadata and /update HTTP routes for internal callers; signed-in wallets pass
+// their session token as "authorization: Bearer <token>" metadata.
+type BlockchainServiceServer interface {
+	// Store records metadata owned by the signed-in wallet and waits for the receipt.
+	Store(context.Context, *StoreRequest) (*StoreResponse, error)
+	// Get returns the metadata of a file.
+	Get(context.Context, *GetRequest) (*FileMetadata, error)
+	// Update sets the deleted flag of a file owned by the signed-in wallet.
+	Update(context.Context, *UpdateRequest) (*UpdateResponse, error)
+	// WatchMetadata streams metadata changes as the contract emits them.
+	WatchMetadata(*WatchMetadataRequest, grpc.ServerStreamingServer[MetadataEvent]) error
+	mustEmbedUnimplementedBlockchainServiceServer()
+}
+
+// UnimplementedBlockchainServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBlockchainServiceServer struct{}
+
+func (UnimplementedBlockchainServiceServer) Store(context.Context, *StoreRequest) (*StoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Store not implemented")
+}
+func (UnimplementedBlockchainServiceServer) Get(context.Context, *GetRequest) (*FileMetadata, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedBlockchainServiceServer) Update(context.Context, *UpdateRequest) (*UpdateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedBlockchainServiceServer) WatchMetadata(*WatchMetadataRequest, grpc.ServerStreamingServer[MetadataEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMetadata not implemented")
+}
+func (UnimplementedBlockchainServiceServer) mustEmbedUnimplementedBlockchainServiceServer() {}
+func (UnimplementedBlockchainServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeBlockchainServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BlockchainServiceServer will
+// result in compilation errors.
+type UnsafeBlockchainServiceServer interface {
+	mustEmbedUnimplementedBlockchainServiceServer()
+}
+
+func RegisterBlockchainServiceServer(s grpc.ServiceRegistrar, srv BlockchainServiceServer) {
+	// If the following call pancis, it indicates UnimplementedBlockchainServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BlockchainService_ServiceDesc, srv)
+}
+
+func _BlockchainService_Store_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServiceServer).Store(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockchainService_Store_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServiceServer).Store(ctx, req.(*StoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockchainService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockchainService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockchainService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockchainService_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServiceServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockchainService_WatchMetadata_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMetadataRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockchainServiceServer).WatchMetadata(m, &grpc.GenericServerStream[WatchMetadataRequest, MetadataEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlockchainService_WatchMetadataServer = grpc.ServerStreamingServer[MetadataEvent]
+
+// BlockchainService_ServiceDesc is the grpc.ServiceDesc for BlockchainService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BlockchainService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "decentralstore.blockchain.v1.BlockchainService",
+	HandlerType: (*BlockchainServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Store",
+			Handler:    _BlockchainService_Store_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _BlockchainService_Get_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _BlockchainService_Update_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchMetadata",
+			Handler:       _BlockchainService_WatchMetadata_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rpc/blockchainpb/blockchain.proto",
+}
@@ -0,0 +1,4 @@
+// Package blockchainpb is the gRPC API of blockchain-service, generated from blockchain.proto.
+package blockchainpb
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative rpc/blockchainpb/blockchain.proto
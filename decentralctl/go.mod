@@ -0,0 +1,31 @@
+module decentralstore/decentralctl
+
+go 1.23.0
+
+require (
+	decentralstore/blockchain-service v0.0.0
+	decentralstore/file-service v0.0.0
+	github.com/stretchr/testify v1.10.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	decentralstore/shared v0.0.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel v1.34.0 // indirect
+	go.opentelemetry.io/otel/trace v1.34.0 // indirect
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
+	google.golang.org/grpc v1.69.4 // indirect
+	google.golang.org/protobuf v1.36.3 // indirect
+)
+
+replace (
+	decentralstore/blockchain-service => ../blockchain-service
+	decentralstore/file-service => ../file-service
+	decentralstore/shared => ../shared
+	decentralstore/webhook => ../webhook
+)
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"decentralstore/decentralctl/internal/progress"
+	fileclient "decentralstore/file-service/client"
+)
+
+// exported is the result of an export.
+type exported struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	out := fs.String("out", "", "CAR file to write (default: <id>.car for one file, export.car for several)")
+	version := fs.Int("version", 1, "CAR version, 1 or 2")
+	if err := parse(fs, args, -1); err != nil {
+		return err
+	}
+	if fs.NArg()%2 != 0 {
+		fs.Usage()
+		return errUsage
+	}
+	items := make([]fileclient.ExportItem, 0, fs.NArg()/2)
+	for i := 0; i < fs.NArg(); i += 2 {
+		items = append(items, fileclient.ExportItem{ID: fs.Arg(i), Keyword: fs.Arg(i + 1)})
+	}
+	if *out == "" {
+		*out = "export.car"
+		if len(items) == 1 {
+			*out = items[0].ID + ".car"
+		}
+	}
+
+	result, err := e.export(ctx, items, *version, *out)
+	if err != nil {
+		return err
+	}
+	return e.out.Print(result, []string{"PATH", "FILES", "SIZE"},
+		[][]string{{result.Path, strconv.Itoa(result.Files), strconv.FormatInt(result.Size, 10)}})
+}
+
+// export writes the CAR file to <path>.part and renames it to path when complete, so that
+// an interrupted export never leaves a truncated backup behind.
+func (e *env) export(ctx context.Context, items []fileclient.ExportItem, version int, path string) (*exported, error) {
+	body, err := e.files.Export(ctx, version, items...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	partPath := path + ".part"
+	part, err := os.Create(partPath)
+	if err != nil {
+		return nil, err
+	}
+	defer part.Close()
+
+	bar := progress.New(e.stderr, path, -1)
+	n, err := io.Copy(part, bar.Reader(body))
+	bar.Finish()
+	if err != nil {
+		os.Remove(partPath)
+		return nil, fmt.Errorf("export interrupted after %d bytes: %w", n, err)
+	}
+	if err := part.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return nil, err
+	}
+	return &exported{Path: path, Files: len(items), Size: n}, nil
+}
+
+func runImport(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	name := fs.String("name", "", "name of the file when the CAR file was not exported by decentralstore")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	bar := progress.New(e.stderr, fs.Arg(0), info.Size())
+	files, err := e.files.Import(ctx, bar.Reader(f), *name)
+	bar.Finish()
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(files))
+	for i, file := range files {
+		rows[i] = []string{file.Name, file.ID, file.CID, strconv.FormatInt(file.Size, 10), file.DownloadKeyword, file.DeleteKeyword}
+	}
+	return e.out.Print(files, []string{"NAME", "ID", "CID", "SIZE", "DOWNLOAD KEYWORD", "DELETE KEYWORD"}, rows)
+}
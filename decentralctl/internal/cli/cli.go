@@ -0,0 +1,174 @@
+// Package cli implements the decentralctl commands on top of the file-service and
+// blockchain-service clients.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	chainclient "decentralstore/blockchain-service/client"
+	"decentralstore/decentralctl/internal/output"
+	"decentralstore/decentralctl/internal/profile"
+	fileclient "decentralstore/file-service/client"
+)
+
+// errUsage is returned by commands whose arguments are invalid; the usage has already been printed.
+var errUsage = errors.New("usage")
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = []command{
+	{"upload", "[-chain] <path>...", "upload files or directories", runUpload},
+	{"download", "[-out <path>] <id> <keyword>", "download a file, resuming a partial download", runDownload},
+	{"export", "[-version 1|2] [-out <path>] <id> <keyword>...", "back up files and their metadata as a CAR file", runExport},
+	{"import", "[-name <name>] <path>", "restore files from a CAR file", runImport},
+	{"delete", "[-chain] <id> <keyword>", "delete a file", runDelete},
+	{"ls", "[-chain]", "list your files", runList},
+	{"metadata", "<id>", "show the on-chain metadata of a file", runMetadata},
+	{"tx", "<hash>", "show the status of a transaction", runTx},
+	{"verify", "<path> <id>", "check a local file against its on-chain CID", runVerify},
+}
+
+// env is what every command runs with.
+type env struct {
+	profile profile.Profile
+	// cmd is the command being run.
+	cmd    command
+	out    *output.Printer
+	stdout io.Writer
+	stderr io.Writer
+	files  *fileclient.Client
+	chain  *chainclient.Client
+}
+
+// Run executes decentralctl with args, which exclude the program name, and returns the exit code.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("decentralctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", profile.Path(), "config file with the service profiles")
+	profileName := fs.String("profile", "", "profile to use instead of the config file's default")
+	format := fs.String("o", string(output.Table), "output format: table or json")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := lookup(fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(stderr, "decentralctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	e, err := newEnv(*configPath, *profileName, *format, stdout, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "decentralctl: %v\n", err)
+		return 1
+	}
+
+	e.cmd = cmd
+	if err := cmd.run(ctx, e, fs.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "decentralctl %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, "Usage: decentralctl [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+}
+
+func newEnv(configPath, profileName, format string, stdout, stderr io.Writer) (*env, error) {
+	p, err := profile.Load(configPath, profileName)
+	if err != nil {
+		return nil, err
+	}
+	f, err := output.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	// No overall timeout: uploads and downloads may take as long as they need.
+	httpClient := &http.Client{}
+	files, err := fileclient.New(p.FileService, fileclient.WithHTTPClient(httpClient), fileclient.WithAPIKey(p.APIKey))
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: file service: %w", p.Name, err)
+	}
+	chain, err := chainclient.New(p.BlockchainService, chainclient.WithHTTPClient(httpClient), chainclient.WithSessionToken(p.SessionToken))
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: blockchain service: %w", p.Name, err)
+	}
+
+	return &env{
+		profile: p,
+		out:     output.New(stdout, f),
+		stdout:  stdout,
+		stderr:  stderr,
+		files:   files,
+		chain:   chain,
+	}, nil
+}
+
+// flags returns the flag set of the command being run, printing its usage to stderr on errors.
+func (e *env) flags() *flag.FlagSet {
+	c := e.cmd
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: decentralctl %s %s\n", c.name, c.args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args and checks that n positional arguments remain, or at least one if n < 0.
+func parse(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if (n >= 0 && fs.NArg() != n) || (n < 0 && fs.NArg() == 0) {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helloCID is the CIDv0 of "hello world\n".
+const helloCID = "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+
+// fakeServices stands in for file-service and blockchain-service.
+type fakeServices struct {
+	mu       sync.Mutex
+	content  []byte
+	uploads  map[string]string
+	metadata map[string]map[string]any
+	requests []*http.Request
+}
+
+func newFakeServices(t *testing.T, content []byte) (*fakeServices, string) {
+	t.Helper()
+	s := &fakeServices{content: content, uploads: map[string]string{}, metadata: map[string]map[string]any{}}
+
+	files := http.NewServeMux()
+	files.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		s.record(r)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.content))
+	})
+	files.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		require.NoError(t, err)
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		// Part.FileName drops directories; read the name as sent.
+		_, params, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		name := params["filename"]
+		data, _ := io.ReadAll(part)
+		s.mu.Lock()
+		s.uploads[name] = string(data)
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"id": "id-" + name, "name": name, "size": len(data), "cid": helloCID})
+	})
+	files.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		s.record(r)
+		w.Header().Set("Content-Type", "application/vnd.ipld.car")
+		w.Write(s.content)
+	})
+	files.HandleFunc("/import", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.uploads[r.URL.Query().Get("name")] = string(data)
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode([]map[string]any{{"id": "id-1", "name": "restored.txt", "size": len(data), "cid": helloCID}})
+	})
+	fileServer := httptest.NewServer(files)
+	t.Cleanup(fileServer.Close)
+
+	chain := http.NewServeMux()
+	chain.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": r.URL.Query().Get("fileID"), "cid": helloCID})
+	})
+	chain.HandleFunc("/store", func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]any
+		json.NewDecoder(r.Body).Decode(&m)
+		s.mu.Lock()
+		s.metadata[m["id"].(string)] = m
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"transactionHash": "0xabc"})
+	})
+	chainServer := httptest.NewServer(chain)
+	t.Cleanup(chainServer.Close)
+
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("profiles:\n  default:\n    fileService: "+fileServer.URL+
+		"\n    blockchainService: "+chainServer.URL+"\n"), 0o600))
+	return s, config
+}
+
+func (s *fakeServices) record(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+}
+
+func run(t *testing.T, config string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), append([]string{"-config", config}, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestDownload_ResumesPartialFile(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+	services, config := newFakeServices(t, content)
+	out := filepath.Join(t.TempDir(), "file.bin")
+	require.NoError(t, os.WriteFile(out+".part", content[:4000], 0o644))
+
+	code, stdout, stderr := run(t, config, "-o", "json", "download", "-out", out, "file-1", "kw")
+	require.Equal(t, 0, code, stderr)
+
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.NoFileExists(t, out+".part")
+	assert.Equal(t, "bytes=4000-", services.requests[0].Header.Get("Range"))
+
+	var result downloaded
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Equal(t, downloaded{ID: "file-1", Path: out, Size: 10000, Resumed: 4000}, result)
+}
+
+func TestDownload_CompletePartFile(t *testing.T) {
+	content := []byte("hello world\n")
+	_, config := newFakeServices(t, content)
+	out := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(out+".part", content, 0o644))
+
+	code, _, stderr := run(t, config, "download", "-out", out, "file-1", "kw")
+	require.Equal(t, 0, code, stderr)
+
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestDownload_RestartsLongerPartFile(t *testing.T) {
+	content := []byte("hello world\n")
+	services, config := newFakeServices(t, content)
+	out := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(out+".part", []byte("a different, longer file\n"), 0o644))
+
+	code, _, stderr := run(t, config, "download", "-out", out, "file-1", "kw")
+	require.Equal(t, 0, code, stderr)
+
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	require.Len(t, services.requests, 2)
+	assert.Empty(t, services.requests[1].Header.Get("Range"))
+}
+
+func TestVerify(t *testing.T) {
+	_, config := newFakeServices(t, nil)
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.txt")
+	bad := filepath.Join(dir, "bad.txt")
+	require.NoError(t, os.WriteFile(good, []byte("hello world\n"), 0o644))
+	require.NoError(t, os.WriteFile(bad, []byte("hello world!\n"), 0o644))
+
+	code, stdout, stderr := run(t, config, "verify", good, "file-1")
+	assert.Equal(t, 0, code, stderr)
+	assert.Regexp(t, `Verified:\s+true`, stdout)
+
+	code, stdout, stderr = run(t, config, "-o", "json", "verify", bad, "file-1")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, errVerifyFailed.Error())
+	var result verification
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.False(t, result.Verified)
+	assert.Equal(t, helloCID, result.Expected)
+}
+
+func TestUpload_DirectoryOnChain(t *testing.T) {
+	services, config := newFakeServices(t, nil)
+	dir := filepath.Join(t.TempDir(), "photos")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "2024"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.jpg"), []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2024", "b.jpg"), []byte("bb"), 0o644))
+
+	code, stdout, stderr := run(t, config, "upload", "-chain", dir)
+	require.Equal(t, 0, code, stderr)
+
+	assert.Equal(t, map[string]string{"photos/a.jpg": "a", "photos/2024/b.jpg": "bb"}, services.uploads)
+	var ids []string
+	for id := range services.metadata {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"id-photos/2024/b.jpg", "id-photos/a.jpg"}, ids)
+	assert.Contains(t, stdout, "0xabc")
+	assert.True(t, strings.HasPrefix(stdout, "NAME"))
+}
+
+func TestExportImport(t *testing.T) {
+	services, config := newFakeServices(t, []byte("car file"))
+	out := filepath.Join(t.TempDir(), "backup.car")
+
+	code, stdout, stderr := run(t, config, "-o", "json", "export", "-version", "2", "-out", out, "file-1", "kw1", "file-2", "kw2")
+	require.Equal(t, 0, code, stderr)
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "car file", string(got))
+	assert.NoFileExists(t, out+".part")
+	var result exported
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Equal(t, exported{Path: out, Files: 2, Size: 8}, result)
+
+	code, stdout, stderr = run(t, config, "import", "-name", "restored.txt", out)
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "car file", services.uploads["restored.txt"])
+	assert.Contains(t, stdout, "id-1")
+
+	code, _, stderr = run(t, config, "export", "file-1")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: decentralctl export")
+}
+
+func TestRun_Usage(t *testing.T) {
+	_, config := newFakeServices(t, nil)
+
+	code, _, stderr := run(t, config, "frobnicate")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "frobnicate"`)
+
+	code, _, stderr = run(t, config, "download", "file-1")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: decentralctl download")
+
+	code, _, stderr = run(t, config, "-o", "yaml", "ls")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "unknown output format")
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	chainclient "decentralstore/blockchain-service/client"
+	"decentralstore/decentralctl/internal/unixfs"
+)
+
+// errVerifyFailed makes verify exit non-zero after printing its result.
+var errVerifyFailed = errors.New("file does not match its on-chain CID")
+
+func runDelete(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	chain := fs.Bool("chain", false, "also mark the on-chain metadata as deleted")
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	id, keyword := fs.Arg(0), fs.Arg(1)
+
+	if err := e.files.Delete(ctx, id, keyword); err != nil {
+		return err
+	}
+	if *chain {
+		if err := e.chain.UpdateMetadata(ctx, id, true); err != nil {
+			return fmt.Errorf("deleted %s but failed to mark it deleted on chain: %w", id, err)
+		}
+	}
+	result := map[string]any{"id": id, "deleted": true, "chain": *chain}
+	return e.out.Fields(result, []string{"ID", "Deleted", "On chain"}, []string{id, "true", strconv.FormatBool(*chain)})
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	chain := fs.Bool("chain", false, "list the metadata owned by the signed-in wallet instead")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *chain {
+		files, err := e.chain.ListOwnedMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, len(files))
+		for i, f := range files {
+			rows[i] = []string{f.ID, f.Name, strconv.FormatInt(f.Size, 10), f.CID, blockNumber(f), orDash(f.TransactionHash)}
+		}
+		return e.out.Print(files, []string{"ID", "NAME", "SIZE", "CID", "BLOCK", "TX"}, rows)
+	}
+
+	files, err := e.files.ListFiles(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(files))
+	for i, f := range files {
+		rows[i] = []string{f.ID, f.Name, strconv.FormatInt(f.Size, 10), f.CID, f.UploadedAt.Format(time.RFC3339)}
+	}
+	return e.out.Print(files, []string{"ID", "NAME", "SIZE", "CID", "UPLOADED"}, rows)
+}
+
+func runMetadata(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	m, err := e.chain.GetMetadata(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return e.out.Fields(m,
+		[]string{"ID", "Name", "Size", "CID", "Uploaded", "Owner", "Block", "Transaction"},
+		[]string{m.ID, m.Name, strconv.FormatInt(m.Size, 10), m.CID, m.UploadedAt.Format(time.RFC3339),
+			orDash(m.Owner), blockNumber(*m), orDash(m.TransactionHash)})
+}
+
+func runTx(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	s, err := e.chain.TransactionStatus(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	names := []string{"Hash", "Status"}
+	values := []string{s.Hash, s.Status}
+	if s.Status != chainclient.TxPending {
+		names = append(names, "Block", "Gas used", "Confirmations")
+		values = append(values, strconv.FormatUint(s.BlockNumber, 10), strconv.FormatUint(s.GasUsed, 10),
+			strconv.FormatUint(s.Confirmations, 10))
+	}
+	return e.out.Fields(s, names, values)
+}
+
+// verification is the result of checking a local file against the chain.
+type verification struct {
+	Path     string `json:"path"`
+	ID       string `json:"id"`
+	Expected string `json:"expectedCid"`
+	Actual   string `json:"actualCid"`
+	Verified bool   `json:"verified"`
+}
+
+func runVerify(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	path, id := fs.Arg(0), fs.Arg(1)
+
+	m, err := e.chain.GetMetadata(ctx, id)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	actual, err := unixfs.Verify(f, m.CID)
+	if err != nil && !errors.Is(err, unixfs.ErrMismatch) {
+		return err
+	}
+	v := verification{Path: path, ID: id, Expected: m.CID, Actual: actual, Verified: err == nil}
+	if perr := e.out.Fields(v,
+		[]string{"Path", "ID", "Expected CID", "Actual CID", "Verified"},
+		[]string{v.Path, v.ID, v.Expected, v.Actual, strconv.FormatBool(v.Verified)}); perr != nil {
+		return perr
+	}
+	if !v.Verified {
+		return errVerifyFailed
+	}
+	return nil
+}
+
+func blockNumber(m chainclient.FileMetadata) string {
+	if m.BlockNumber == nil {
+		return "-"
+	}
+	return m.BlockNumber.String()
+}
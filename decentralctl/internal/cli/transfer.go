@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	chainclient "decentralstore/blockchain-service/client"
+	"decentralstore/decentralctl/internal/progress"
+	fileclient "decentralstore/file-service/client"
+)
+
+// uploaded is the result of uploading one local file.
+type uploaded struct {
+	Path string `json:"path"`
+	fileclient.File
+	// TransactionHash is set when the metadata was recorded on chain.
+	TransactionHash string `json:"transactionHash,omitempty"`
+}
+
+func runUpload(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	chain := fs.Bool("chain", false, "also record the metadata on chain")
+	if err := parse(fs, args, -1); err != nil {
+		return err
+	}
+
+	var results []uploaded
+	for _, root := range fs.Args() {
+		paths, err := filesIn(root)
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			result, err := e.uploadFile(ctx, p.path, p.name, *chain)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p.path, err)
+			}
+			results = append(results, *result)
+		}
+	}
+
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		rows[i] = []string{r.Name, r.ID, r.CID, strconv.FormatInt(r.Size, 10), r.DownloadKeyword, r.DeleteKeyword, orDash(r.TransactionHash)}
+	}
+	return e.out.Print(results, []string{"NAME", "ID", "CID", "SIZE", "DOWNLOAD KEYWORD", "DELETE KEYWORD", "TX"}, rows)
+}
+
+type localFile struct {
+	path string
+	// name is the name the file is uploaded as: its path relative to the uploaded
+	// directory with forward slashes, or its base name.
+	name string
+}
+
+// filesIn returns root if it is a file, or the regular files below it if it is a directory.
+func filesIn(root string) ([]localFile, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []localFile{{path: root, name: filepath.Base(root)}}, nil
+	}
+
+	base := filepath.Base(filepath.Clean(root))
+	var files []localFile
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, localFile{path: path, name: filepath.ToSlash(filepath.Join(base, rel))})
+		return nil
+	})
+	return files, err
+}
+
+func (e *env) uploadFile(ctx context.Context, path, name string, chain bool) (*uploaded, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	bar := progress.New(e.stderr, name, info.Size())
+	file, err := e.files.Upload(ctx, bar.Reader(f), name)
+	bar.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &uploaded{Path: path, File: *file}
+	if chain {
+		metadata := &chainclient.FileMetadata{
+			ID:              file.ID,
+			Name:            file.Name,
+			Size:            file.Size,
+			CID:             file.CID,
+			UploadedAt:      file.UploadedAt,
+			DownloadKeyword: file.DownloadKeyword,
+			DeleteKeyword:   file.DeleteKeyword,
+		}
+		if err := e.chain.StoreMetadata(ctx, metadata); err != nil {
+			return nil, fmt.Errorf("uploaded as %s but failed to record it on chain: %w", file.ID, err)
+		}
+		result.TransactionHash = metadata.TransactionHash
+	}
+	return result, nil
+}
+
+// downloaded is the result of a download.
+type downloaded struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Resumed is the number of bytes kept from an earlier, interrupted download.
+	Resumed int64 `json:"resumed"`
+}
+
+func runDownload(ctx context.Context, e *env, args []string) error {
+	fs := e.flags()
+	out := fs.String("out", "", "file to write (default: the file ID in the current directory)")
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	id, keyword := fs.Arg(0), fs.Arg(1)
+	if *out == "" {
+		*out = id
+	}
+
+	result, err := e.download(ctx, id, keyword, *out)
+	if err != nil {
+		return err
+	}
+	return e.out.Print(result, []string{"ID", "PATH", "SIZE", "RESUMED"},
+		[][]string{{result.ID, result.Path, strconv.FormatInt(result.Size, 10), strconv.FormatInt(result.Resumed, 10)}})
+}
+
+// download writes the file id to <path>.part and renames it to path when complete.
+// A .part file left by an interrupted download is resumed where it ended.
+func (e *env) download(ctx context.Context, id, keyword, path string) (*downloaded, error) {
+	partPath := path + ".part"
+	part, err := os.OpenFile(partPath, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer part.Close()
+	offset, err := part.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &downloaded{ID: id, Path: path, Size: offset, Resumed: offset}
+	d, err := e.files.DownloadFrom(ctx, id, keyword, offset)
+	var rangeErr *fileclient.RangeError
+	if errors.As(err, &rangeErr) && offset > 0 && rangeErr.Size != offset {
+		// The .part file is longer than the file, so it is not from this file: start over.
+		if err := part.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err := part.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		offset, result.Size, result.Resumed = 0, 0, 0
+		d, err = e.files.DownloadFrom(ctx, id, keyword, offset)
+	}
+	switch {
+	case errors.As(err, &rangeErr) && offset > 0:
+		// The previous attempt got everything but did not get to rename the file.
+	case err != nil:
+		return nil, err
+	default:
+		defer d.Body.Close()
+		if d.Offset != offset {
+			// The service sent the whole file instead of the rest of it.
+			if err := part.Truncate(d.Offset); err != nil {
+				return nil, err
+			}
+			if _, err := part.Seek(d.Offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			result.Resumed = d.Offset
+		}
+
+		bar := progress.New(e.stderr, id, d.Size)
+		bar.Start(d.Offset)
+		n, err := io.Copy(part, bar.Reader(d.Body))
+		bar.Finish()
+		if err != nil {
+			return nil, fmt.Errorf("download interrupted after %d bytes, run the command again to resume: %w", d.Offset+n, err)
+		}
+		result.Size = d.Offset + n
+		if d.Size >= 0 && result.Size != d.Size {
+			return nil, fmt.Errorf("download incomplete: got %d of %d bytes, run the command again to resume", result.Size, d.Size)
+		}
+	}
+
+	if err := part.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,66 @@
+// Package output prints command results as aligned tables for people or as JSON for scripts.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+)
+
+// ParseFormat parses the value of the -o flag.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case Table, JSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q: want table or json", s)
+	}
+}
+
+type Printer struct {
+	w      io.Writer
+	format Format
+}
+
+func New(w io.Writer, format Format) *Printer {
+	return &Printer{w: w, format: format}
+}
+
+// Print writes v as indented JSON, or headers and rows as a table. Rows are
+// usually derived from v, so both describe the same result.
+func (p *Printer) Print(v any, headers []string, rows [][]string) error {
+	if p.format == JSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Fields prints a single record: a JSON object, or one "name: value" line per field.
+func (p *Printer) Fields(v any, names []string, values []string) error {
+	if p.format == JSON {
+		return p.Print(v, nil, nil)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 1, ' ', 0)
+	for i, name := range names {
+		fmt.Fprintf(tw, "%s:\t%s\n", name, values[i])
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,108 @@
+// Package profile loads the service endpoints and credentials decentralctl talks to.
+//
+// The config file holds named profiles and the one to use by default:
+//
+//	profile: local
+//	profiles:
+//	  local:
+//	    fileService: http://localhost:8081
+//	    blockchainService: http://localhost:8082
+//	    apiKey: dsk_...
+//	    sessionToken: ...
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv names the environment variable pointing at the config file.
+const ConfigEnv = "DECENTRALCTL_CONFIG"
+
+// Profile is the set of endpoints and credentials used for one deployment.
+type Profile struct {
+	Name              string `yaml:"-"`
+	FileService       string `yaml:"fileService"`
+	BlockchainService string `yaml:"blockchainService"`
+	APIKey            string `yaml:"apiKey"`
+	SessionToken      string `yaml:"sessionToken"`
+}
+
+type file struct {
+	Profile  string             `yaml:"profile"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Default is used when there is no config file: both services on localhost, without credentials.
+func Default() Profile {
+	return Profile{
+		Name:              "default",
+		FileService:       "http://localhost:8081",
+		BlockchainService: "http://localhost:8082",
+	}
+}
+
+// Path returns the config file to read when none is given explicitly: $DECENTRALCTL_CONFIG,
+// else decentralctl/config.yaml in the user's config directory.
+func Path() string {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "decentralctl", "config.yaml")
+}
+
+// Load reads the profile called name from the config file at path, or the file's default
+// profile if name is empty. A missing file yields Default unless a profile was asked for.
+// Endpoints left out of a profile fall back to those of Default.
+func Load(path, name string) (Profile, error) {
+	data, err := os.ReadFile(path)
+	if path == "" || errors.Is(err, fs.ErrNotExist) {
+		if name != "" && name != Default().Name {
+			return Profile{}, fmt.Errorf("profile %q not found: no config file", name)
+		}
+		return Default(), nil
+	}
+	if err != nil {
+		return Profile{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return Profile{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if name == "" {
+		name = f.Profile
+	}
+	if name == "" {
+		name = Default().Name
+	}
+	p, ok := f.Profiles[name]
+	if !ok {
+		if name == Default().Name {
+			return Default(), nil
+		}
+		return Profile{}, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+	p.Name = name
+	if p.FileService == "" {
+		p.FileService = Default().FileService
+	}
+	if p.BlockchainService == "" {
+		p.BlockchainService = Default().BlockchainService
+	}
+	return p, nil
+}
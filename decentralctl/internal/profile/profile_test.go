@@ -0,0 +1,78 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+profile: staging
+profiles:
+  staging:
+    fileService: https://files.staging.example
+    apiKey: dsk_staging
+  prod:
+    fileService: https://files.example
+    blockchainService: https://chain.example
+    sessionToken: token
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_DefaultProfile(t *testing.T) {
+	p, err := Load(writeConfig(t, testConfig), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "staging", p.Name)
+	assert.Equal(t, "https://files.staging.example", p.FileService)
+	assert.Equal(t, "dsk_staging", p.APIKey)
+	// Endpoints left out fall back to localhost.
+	assert.Equal(t, Default().BlockchainService, p.BlockchainService)
+}
+
+func TestLoad_NamedProfile(t *testing.T) {
+	p, err := Load(writeConfig(t, testConfig), "prod")
+	require.NoError(t, err)
+
+	assert.Equal(t, Profile{
+		Name:              "prod",
+		FileService:       "https://files.example",
+		BlockchainService: "https://chain.example",
+		SessionToken:      "token",
+	}, p)
+}
+
+func TestLoad_UnknownProfile(t *testing.T) {
+	_, err := Load(writeConfig(t, testConfig), "dev")
+	assert.ErrorContains(t, err, `profile "dev" not found`)
+}
+
+func TestLoad_UnknownField(t *testing.T) {
+	_, err := Load(writeConfig(t, "profiles:\n  local:\n    fileservice: http://x\n"), "local")
+	assert.Error(t, err)
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+
+	p, err := Load(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, Default(), p)
+
+	_, err = Load(path, "prod")
+	assert.Error(t, err)
+}
+
+func TestPath_Env(t *testing.T) {
+	t.Setenv(ConfigEnv, "/etc/decentralctl.yaml")
+	assert.Equal(t, "/etc/decentralctl.yaml", Path())
+}
@@ -0,0 +1,132 @@
+// Package progress draws transfer progress bars on a terminal.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	width = 30
+	// redrawInterval limits how often the bar is redrawn on fast transfers.
+	redrawInterval = 100 * time.Millisecond
+)
+
+// IsTerminal reports whether w is a character device, i.e. progress bars make sense on it.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Bar reports the progress of one transfer of total bytes; a negative total draws a
+// byte count without a bar. A nil *Bar discards all updates.
+type Bar struct {
+	mu       sync.Mutex
+	out      io.Writer
+	label    string
+	total    int64
+	current  int64
+	lastDraw time.Time
+}
+
+// New returns a bar drawn on out, or nil if out is not a terminal.
+func New(out io.Writer, label string, total int64) *Bar {
+	if !IsTerminal(out) {
+		return nil
+	}
+	return &Bar{out: out, label: label, total: total}
+}
+
+// Start sets the bytes already transferred, e.g. when resuming a download.
+func (b *Bar) Start(current int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current = current
+	b.draw()
+}
+
+// Add records n more bytes transferred.
+func (b *Bar) Add(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current += n
+	if time.Since(b.lastDraw) >= redrawInterval {
+		b.draw()
+	}
+}
+
+// Finish draws the final state and moves to the next line.
+func (b *Bar) Finish() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.draw()
+	fmt.Fprintln(b.out)
+}
+
+// Reader returns r counting the bytes read from it on b.
+func (b *Bar) Reader(r io.Reader) io.Reader {
+	if b == nil {
+		return r
+	}
+	return &reader{r: r, bar: b}
+}
+
+func (b *Bar) draw() {
+	b.lastDraw = time.Now()
+	if b.total < 0 {
+		fmt.Fprintf(b.out, "\r%s %s", b.label, FormatBytes(b.current))
+		return
+	}
+	filled := width
+	percent := 100
+	if b.total > 0 {
+		filled = int(b.current * width / b.total)
+		percent = int(b.current * 100 / b.total)
+	}
+	filled = min(filled, width)
+	fmt.Fprintf(b.out, "\r%s [%s%s] %3d%% %s/%s", b.label,
+		strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+		percent, FormatBytes(b.current), FormatBytes(b.total))
+}
+
+type reader struct {
+	r   io.Reader
+	bar *Bar
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.bar.Add(int64(n))
+	return n, err
+}
+
+// FormatBytes formats n with a binary unit, e.g. "1.5 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
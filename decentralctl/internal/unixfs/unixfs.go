@@ -0,0 +1,195 @@
+// Package unixfs computes the CID that `ipfs add` assigns to a file with the default
+// importer settings: 256 KiB chunks and a balanced DAG of at most 174 links per node.
+// CIDv0 uses dag-pb leaves; CIDv1 uses raw leaves, as kubo does with --cid-version=1.
+package unixfs
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+const (
+	chunkSize     = 256 * 1024
+	linksPerBlock = 174
+
+	codecRaw    = 0x55
+	codecDagPB  = 0x70
+	sha256Code  = 0x12
+	unixfsFile  = 2
+	base58Chars = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+// node is what a parent needs to know about a child in the DAG.
+type node struct {
+	cid      []byte
+	tsize    uint64 // encoded size of the child and all its descendants
+	filesize uint64 // file bytes below the child
+}
+
+// Sum reads r and returns its CID of the given version (0 or 1).
+func Sum(r io.Reader, version int) (string, error) {
+	if version != 0 && version != 1 {
+		return "", fmt.Errorf("unsupported CID version %d", version)
+	}
+	rawLeaves := version == 1
+
+	var level []node
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || (len(level) == 0 && (err == io.EOF || err == io.ErrUnexpectedEOF)) {
+			level = append(level, leaf(buf[:n], rawLeaves))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for len(level) > 1 {
+		var parents []node
+		for start := 0; start < len(level); start += linksPerBlock {
+			end := min(start+linksPerBlock, len(level))
+			parents = append(parents, parent(level[start:end], version))
+		}
+		level = parents
+	}
+	return encodeCID(level[0].cid), nil
+}
+
+// MatchingVersion returns the CID version of cid as produced by `ipfs add`.
+func MatchingVersion(cid string) (int, error) {
+	switch {
+	case strings.HasPrefix(cid, "Qm") && len(cid) == 46:
+		return 0, nil
+	case strings.HasPrefix(cid, "b"):
+		return 1, nil
+	}
+	return 0, fmt.Errorf("unsupported CID %q", cid)
+}
+
+func leaf(data []byte, raw bool) node {
+	if raw {
+		return node{cid: cidBytes(1, codecRaw, data), tsize: uint64(len(data)), filesize: uint64(len(data))}
+	}
+
+	var unixfs []byte
+	unixfs = appendVarintField(unixfs, 1, unixfsFile)
+	if len(data) > 0 {
+		unixfs = appendBytesField(unixfs, 2, data)
+	}
+	unixfs = appendVarintField(unixfs, 3, uint64(len(data)))
+	block := appendBytesField(nil, 1, unixfs)
+	return node{cid: cidBytes(0, codecDagPB, block), tsize: uint64(len(block)), filesize: uint64(len(data))}
+}
+
+func parent(children []node, version int) node {
+	var filesize, tsize uint64
+	unixfs := appendVarintField(nil, 1, unixfsFile)
+	for _, child := range children {
+		filesize += child.filesize
+	}
+	unixfs = appendVarintField(unixfs, 3, filesize)
+	for _, child := range children {
+		unixfs = appendVarintField(unixfs, 4, child.filesize)
+	}
+
+	// dag-pb encodes the links before the data.
+	var block []byte
+	for _, child := range children {
+		var link []byte
+		link = appendBytesField(link, 1, child.cid)
+		link = appendBytesField(link, 2, nil)
+		link = appendVarintField(link, 3, child.tsize)
+		block = appendBytesField(block, 2, link)
+		tsize += child.tsize
+	}
+	block = appendBytesField(block, 1, unixfs)
+
+	return node{cid: cidBytes(version, codecDagPB, block), tsize: tsize + uint64(len(block)), filesize: filesize}
+}
+
+// cidBytes returns the binary CID of block: a bare sha2-256 multihash for version 0,
+// prefixed with the version and codec for version 1.
+func cidBytes(version int, codec uint64, block []byte) []byte {
+	digest := sha256.Sum256(block)
+	var out []byte
+	if version == 1 {
+		out = appendVarint(out, 1)
+		out = appendVarint(out, codec)
+	}
+	out = append(out, sha256Code, sha256.Size)
+	return append(out, digest[:]...)
+}
+
+func encodeCID(cid []byte) string {
+	if cid[0] == sha256Code {
+		return base58(cid)
+	}
+	return "b" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(cid))
+}
+
+func base58(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Chars[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Chars[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	return appendVarint(appendVarint(b, uint64(field)<<3), v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// ErrMismatch is returned by Verify when the content does not hash to the expected CID.
+var ErrMismatch = errors.New("content does not match CID")
+
+// Verify reads r and checks that it hashes to cid.
+func Verify(r io.Reader, cid string) (string, error) {
+	version, err := MatchingVersion(cid)
+	if err != nil {
+		return "", err
+	}
+	actual, err := Sum(r, version)
+	if err != nil {
+		return "", err
+	}
+	if actual != cid {
+		return actual, ErrMismatch
+	}
+	return actual, nil
+}
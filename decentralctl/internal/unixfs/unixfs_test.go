@@ -0,0 +1,58 @@
+package unixfs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSum_KnownCIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		version int
+		want    string
+	}{
+		{"empty v0", "", 0, "QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH"},
+		{"hello world v0", "hello world\n", 0, "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"},
+		{"empty v1", "", 1, "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sum(strings.NewReader(tt.content), tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Sum() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSum_MultiChunkLayout(t *testing.T) {
+	// 175 chunks need a second level: one full node of 174 leaves and one with a single leaf.
+	// Expected CIDs were produced by the reference importer with default settings.
+	content := bytes.Repeat([]byte("decentralstore "), 175*chunkSize/15+3)
+	for version, want := range []string{
+		"QmWU8A7wt7LrSvHnwcDzkgsxyG2T3DL4QeszF2YS7xevFN",
+		"bafybeie4cznwemzyecgncdi4qe6zytmeorfwts3tatt747gvfr2edxgsfy",
+	} {
+		got, err := Sum(bytes.NewReader(content), version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Sum(v%d) = %s, want %s", version, got, want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	if _, err := Verify(strings.NewReader("hello world\n"), "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"); err != nil {
+		t.Errorf("expected a match, got %v", err)
+	}
+	if _, err := Verify(strings.NewReader("hello world"), "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"); err != ErrMismatch {
+		t.Errorf("expected ErrMismatch, got %v", err)
+	}
+}
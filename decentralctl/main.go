@@ -0,0 +1,24 @@
+// Command decentralctl uploads, downloads and manages files stored with decentralstore.
+//
+//	decentralctl upload -chain ./photos
+//	decentralctl download -out report.pdf <id> <keyword>
+//	decentralctl verify report.pdf <id>
+//
+// Service endpoints and credentials come from a profile; see package profile.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"decentralstore/decentralctl/internal/cli"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := cli.Run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
@@ -0,0 +1,404 @@
+// Package client is a Go client for the file-service HTTP API.
+//
+//	c, err := client.New("http://localhost:8081", client.WithAPIKey(key))
+//	file, err := c.Upload(ctx, f, "report.pdf")
+//	body, err := c.Download(ctx, file.ID, file.DownloadKeyword)
+//
+// Uploads and downloads are streamed. Idempotent calls are retried with backoff;
+// failures are returned as *Error, which errors.Is matches against ErrNotFound etc.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/shared/httpretry"
+)
+
+// File is an uploaded file together with the keywords that authorize downloading and deleting it.
+type File = domain.File
+
+// UsageReport is the storage usage and limits of a tenant.
+type UsageReport = domain.UsageReport
+
+// SignedURL is a download URL that works without the keyword until it expires.
+type SignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	retry      RetryPolicy
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends requests with httpClient instead of http.DefaultClient, e.g. to set
+// timeouts, proxies or tracing. Do not set an overall Timeout if you download large files.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithAPIKey authenticates requests as the tenant owning key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// RetryPolicy controls how idempotent requests are retried after network errors
+// and 429, 502, 503 or 504 responses.
+type RetryPolicy = httpretry.Policy
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given.
+var DefaultRetryPolicy = httpretry.DefaultPolicy
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// New returns a client for the service at baseURL, e.g. "http://localhost:8081".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{baseURL: u, httpClient: http.DefaultClient, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c, nil
+}
+
+// Upload streams r to the service as a file called name. The body is not buffered,
+// so uploads are never retried.
+func (c *Client) Upload(ctx context.Context, r io.Reader, name string) (*File, error) {
+	bodyReader, bodyWriter := io.Pipe()
+	form := multipart.NewWriter(bodyWriter)
+	go func() {
+		part, err := form.CreateFormFile("file", name)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		bodyWriter.CloseWithError(err)
+	}()
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/upload", nil, bodyReader)
+	if err != nil {
+		bodyReader.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := c.send(req)
+	// Stop the writer goroutine if the request failed before the body was consumed.
+	bodyReader.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var file File
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode upload response: %w", err)
+	}
+	return &file, nil
+}
+
+// ContentRef identifies uploaded content by its SHA-256 in hexadecimal or its CID.
+type ContentRef = domain.ContentRef
+
+// CheckContent stores a file called name with content the tenant already uploaded, without
+// sending it again. It fails with ErrNotFound if the tenant has no such content, even when
+// another tenant has. Like uploads, checks are never retried.
+func (c *Client) CheckContent(ctx context.Context, ref ContentRef, name string) (*File, error) {
+	body, err := json.Marshal(map[string]string{"sha256": ref.SHA256, "cid": ref.CID, "name": name})
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/upload/check", nil, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var file File
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode check response: %w", err)
+	}
+	return &file, nil
+}
+
+// UploadDeduplicated hashes r and checks whether the tenant already uploaded its content,
+// and only uploads r if not. r is read twice in that case.
+func (c *Client) UploadDeduplicated(ctx context.Context, r io.ReadSeeker, name string) (*File, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return nil, err
+	}
+	file, err := c.CheckContent(ctx, ContentRef{SHA256: hex.EncodeToString(hash.Sum(nil))}, name)
+	if !errors.Is(err, ErrNotFound) {
+		return file, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return c.Upload(ctx, r, name)
+}
+
+// Download opens the file id. The caller must close the returned body, which streams
+// from the service; only establishing the download is retried.
+func (c *Client) Download(ctx context.Context, id, keyword string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/download", url.Values{"id": {id}, "keyword": {keyword}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// PartialDownload is a download that starts at Offset, e.g. to resume an earlier one.
+type PartialDownload struct {
+	Body io.ReadCloser
+	// Offset is where Body starts within the file. It is 0 when the service sent the
+	// whole file instead of the requested range.
+	Offset int64
+	// Size is the size of the whole file, or -1 if the service did not report it.
+	Size int64
+}
+
+// DownloadFrom opens the file id at offset. Offsets at or past the end of the file
+// fail with a *RangeError, which gives the size of the file.
+func (c *Client) DownloadFrom(ctx context.Context, id, keyword string, offset int64) (*PartialDownload, error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/download", url.Values{"id": {id}, "keyword": {keyword}}, header)
+	if err != nil {
+		return nil, err
+	}
+
+	download := &PartialDownload{Body: resp.Body, Size: resp.ContentLength}
+	if resp.StatusCode == http.StatusPartialContent {
+		// Content-Range: bytes <first>-<last>/<size>
+		var last int64
+		_, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &download.Offset, &last, &download.Size)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("invalid Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+	}
+	return download, nil
+}
+
+// Delete deletes the file id. A retried Delete whose first attempt succeeded
+// without the response arriving reports ErrNotFound.
+func (c *Client) Delete(ctx context.Context, id, keyword string) error {
+	resp, err := c.do(ctx, http.MethodDelete, "/delete", url.Values{"id": {id}, "keyword": {keyword}}, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListFiles returns the files of the tenant the API key belongs to.
+func (c *Client) ListFiles(ctx context.Context) ([]File, error) {
+	var files []File
+	if err := c.getJSON(ctx, "/files", nil, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Usage returns the storage usage and limits of the tenant the API key belongs to.
+func (c *Client) Usage(ctx context.Context) (*UsageReport, error) {
+	var report UsageReport
+	if err := c.getJSON(ctx, "/usage", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// SignDownloadURL returns a URL that downloads the file id without the keyword for ttl.
+// The service caps ttl; zero uses its default. The URL is relative to the base URL.
+func (c *Client) SignDownloadURL(ctx context.Context, id, keyword string, ttl time.Duration) (*SignedURL, error) {
+	form := url.Values{"id": {id}, "keyword": {keyword}}
+	if ttl > 0 {
+		form.Set("ttl", strconv.Itoa(int(ttl.Seconds())))
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/download/sign", nil, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var signed SignedURL
+	if err := json.NewDecoder(resp.Body).Decode(&signed); err != nil {
+		return nil, fmt.Errorf("failed to decode signed URL: %w", err)
+	}
+	return &signed, nil
+}
+
+// ExportItem names a file to export by its ID and download keyword.
+type ExportItem = domain.BundleItem
+
+// Export opens a CAR file of version 1 or 2 holding the blocks and metadata of files,
+// which Import restores. The caller must close the returned body. Exports are not retried.
+func (c *Client) Export(ctx context.Context, version int, files ...ExportItem) (io.ReadCloser, error) {
+	body, err := json.Marshal(map[string]any{"version": version, "files": files})
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/export", nil, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Import streams the CAR file r to the service and returns the files it stored, with
+// new keywords. name names the file of a CAR file with one root and no export metadata.
+// Like uploads, imports are never retried.
+func (c *Client) Import(ctx context.Context, r io.Reader, name string) ([]File, error) {
+	var query url.Values
+	if name != "" {
+		query = url.Values{"name": {name}}
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/import", query, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.ipld.car")
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var files []File
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		return nil, fmt.Errorf("failed to decode import response: %w", err)
+	}
+	return files, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out any) error {
+	resp, err := c.do(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	return req, nil
+}
+
+// send performs req once and turns error responses into *Error.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// do performs a bodiless idempotent request, retrying it according to the retry policy.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := c.newRequest(ctx, method, path, query, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && (!httpretry.RetryableStatus(resp.StatusCode) || attempt >= c.retry.MaxAttempts) {
+			if resp.StatusCode >= 400 {
+				defer resp.Body.Close()
+				return nil, responseError(resp)
+			}
+			return resp, nil
+		}
+		if err != nil && (ctx.Err() != nil || attempt >= c.retry.MaxAttempts) {
+			return nil, err
+		}
+
+		wait := c.retry.Delay(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/client"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetry = client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+func newClient(t *testing.T, handler http.HandlerFunc, opts ...client.Option) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := client.New(server.URL, append([]client.Option{fastRetry}, opts...)...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestUpload_StreamsMultipart(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/upload", r.URL.Path)
+		assert.Equal(t, "key-1", r.Header.Get("X-API-Key"))
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		content, _ := io.ReadAll(file)
+		assert.Equal(t, "hello", string(content))
+		w.Write([]byte(`{"id":"f1","name":"` + header.Filename + `","size":5,"downloadKeyword":"dk"}`))
+	}, client.WithAPIKey("key-1"))
+
+	file, err := c.Upload(context.Background(), strings.NewReader("hello"), "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "f1", file.ID)
+	assert.Equal(t, "hello.txt", file.Name)
+	assert.Equal(t, int64(5), file.Size)
+}
+
+func TestUpload_NotRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := c.Upload(context.Background(), strings.NewReader("hello"), "hello.txt")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestExport(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/export", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"version":2,"files":[{"id":"f1","keyword":"dk"}]}`, string(body))
+		w.Header().Set("Content-Type", "application/vnd.ipld.car")
+		w.Write([]byte("car"))
+	})
+
+	body, err := c.Export(context.Background(), 2, client.ExportItem{ID: "f1", Keyword: "dk"})
+	require.NoError(t, err)
+	defer body.Close()
+	content, _ := io.ReadAll(body)
+	assert.Equal(t, "car", string(content))
+}
+
+func TestImport(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/import", r.URL.Path)
+		assert.Equal(t, "notes.txt", r.URL.Query().Get("name"))
+		assert.Equal(t, "application/vnd.ipld.car", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "car", string(body))
+		w.Write([]byte(`[{"id":"f2","name":"notes.txt","size":5}]`))
+	}, client.WithAPIKey("key-1"))
+
+	files, err := c.Import(context.Background(), strings.NewReader("car"), "notes.txt")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "f2", files[0].ID)
+}
+
+func TestUploadDeduplicated(t *testing.T) {
+	// SHA-256 of "hello"
+	const stored = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	var uploads atomic.Int32
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/upload/check":
+			var ref client.ContentRef
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&ref))
+			if ref.SHA256 != stored {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"code":"not_found","message":"content not found"}}`))
+				return
+			}
+			w.Write([]byte(`{"id":"f1","name":"copy.txt","size":5,"sha256":"` + stored + `"}`))
+		case "/upload":
+			uploads.Add(1)
+			io.Copy(io.Discard, r.Body)
+			w.Write([]byte(`{"id":"f2","name":"new.txt","size":3}`))
+		}
+	})
+
+	file, err := c.UploadDeduplicated(context.Background(), strings.NewReader("hello"), "copy.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "f1", file.ID)
+	assert.Equal(t, int32(0), uploads.Load())
+
+	file, err = c.UploadDeduplicated(context.Background(), strings.NewReader("new"), "new.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "f2", file.ID)
+	assert.Equal(t, int32(1), uploads.Load())
+}
+
+func TestDownload_RetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		assert.Equal(t, "f1", r.URL.Query().Get("id"))
+		assert.Equal(t, "dk", r.URL.Query().Get("keyword"))
+		w.Write([]byte("content"))
+	})
+
+	body, err := c.Download(context.Background(), "f1", "dk")
+	require.NoError(t, err)
+	defer body.Close()
+	content, _ := io.ReadAll(body)
+	assert.Equal(t, "content", string(content))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestDownload_GivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := c.Download(context.Background(), "f1", "dk")
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"code":"invalid_keyword","message":"invalid keyword","requestId":"req-9"}}`))
+	})
+
+	err := c.Delete(context.Background(), "f1", "wrong")
+	assert.ErrorIs(t, err, client.ErrInvalidKeyword)
+	assert.NotErrorIs(t, err, client.ErrNotFound)
+
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, client.Error{StatusCode: 401, Code: client.CodeInvalidKeyword, Message: "invalid keyword", RequestID: "req-9"}, *apiErr)
+}
+
+func TestErrorDetails(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":"invalid_request","message":"bad","details":[{"field":"query.keyword","message":"is required"}]}}`))
+	})
+
+	err := c.Delete(context.Background(), "f1", "")
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, []client.Detail{{Field: "query.keyword", Message: "is required"}}, apiErr.Details)
+}
+
+func TestErrorWithoutEnvelope(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream exploded", http.StatusNotFound)
+	})
+
+	_, err := c.ListFiles(context.Background())
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	assert.ErrorContains(t, err, "upstream exploded")
+}
+
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	transport := &countingTransport{}
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"usage":{"bytes":1,"files":1}}`))
+	}, client.WithHTTPClient(&http.Client{Transport: transport}))
+
+	_, err := c.Usage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), transport.requests.Load())
+}
+
+func TestDownload_StopsRetryingWhenContextIsDone(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Second}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Download(ctx, "f1", "dk")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	_, err := client.New("localhost:8081")
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"decentralstore/shared/httperr"
+)
+
+// Error codes the service returns in Error.Code.
+const (
+	CodeBadRequest       = httperr.CodeBadRequest
+	CodeInvalidRequest   = httperr.CodeInvalidRequest
+	CodeUnauthorized     = httperr.CodeUnauthorized
+	CodeInvalidKeyword   = httperr.CodeInvalidKeyword
+	CodeForbidden        = httperr.CodeForbidden
+	CodeNotFound         = httperr.CodeNotFound
+	CodeMethodNotAllowed = httperr.CodeMethodNotAllowed
+	CodePayloadTooLarge  = httperr.CodePayloadTooLarge
+	CodeQuotaExceeded    = httperr.CodeQuotaExceeded
+	CodeRateLimited      = httperr.CodeRateLimited
+	CodeInternal         = httperr.CodeInternal
+	CodeNotImplemented   = httperr.CodeNotImplemented
+	CodeUnavailable      = httperr.CodeUnavailable
+)
+
+// Sentinel errors for use with errors.Is; they match any *Error with the same Code.
+var (
+	ErrInvalidRequest = &Error{Code: CodeInvalidRequest}
+	ErrUnauthorized   = &Error{Code: CodeUnauthorized}
+	ErrInvalidKeyword = &Error{Code: CodeInvalidKeyword}
+	ErrNotFound       = &Error{Code: CodeNotFound}
+	ErrQuotaExceeded  = &Error{Code: CodeQuotaExceeded}
+	ErrRateLimited    = &Error{Code: CodeRateLimited}
+)
+
+// Error is an error response of the service.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	// RequestID identifies the request in the service logs.
+	RequestID string
+	// Details lists what was wrong with a request rejected with CodeInvalidRequest.
+	Details []Detail
+}
+
+// Detail is one problem with a rejected request, e.g. a missing parameter.
+type Detail = httperr.Detail
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("file-service: %s (%d %s)", e.Message, e.StatusCode, e.Code)
+	if e.RequestID != "" {
+		msg += ", request " + e.RequestID
+	}
+	return msg
+}
+
+// Is reports whether target is an *Error with the same Code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// RangeError is the error of a DownloadFrom whose offset is at or past the end of the file.
+type RangeError struct {
+	Err *Error
+	// Size is the size of the file from the Content-Range of the response, or -1 if the
+	// service did not report it.
+	Size int64
+}
+
+func (e *RangeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RangeError) Unwrap() error {
+	return e.Err
+}
+
+// responseError returns a *RangeError for a 416 response and an *Error for any other.
+func responseError(resp *http.Response) error {
+	err := errorFromResponse(resp)
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		return err
+	}
+	// Content-Range: bytes */<size>
+	rangeErr := &RangeError{Err: err, Size: -1}
+	if _, scanErr := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes */%d", &rangeErr.Size); scanErr != nil {
+		rangeErr.Size = -1
+	}
+	return rangeErr
+}
+
+// errorFromResponse reads the error envelope of resp. Responses that are not
+// enveloped, e.g. from a proxy, keep their body as the message.
+func errorFromResponse(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var envelope httperr.Envelope
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error.Code != "" {
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Code:       envelope.Error.Code,
+			Message:    envelope.Error.Message,
+			RequestID:  envelope.Error.RequestID,
+			Details:    envelope.Error.Details,
+		}
+	}
+
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Code:       httperr.CodeForStatus(resp.StatusCode),
+		Message:    message,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+)
+
+func downloadBundle(t *testing.T, serverURL, apiKey, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", serverURL+"/download/bundle", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to download bundle: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func TestDownloadBundle(t *testing.T) {
+	server, _ := newFakeIPFSServer(t, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+
+	first := decodeUploaded(t, uploadParts(t, server, apiKey, "", "file", map[string][]byte{"a.txt": []byte("first a")}))
+	second := decodeUploaded(t, uploadParts(t, server, apiKey, "", "file", map[string][]byte{"a.txt": []byte("second a")}))
+	dir := decodeUploaded(t, uploadParts(t, server, apiKey, "?name=photos", "files", directoryFiles))
+	other := decodeUploaded(t, uploadParts(t, server, apiKey, "", "file", map[string][]byte{"b.txt": []byte("b")}))
+
+	items, _ := json.Marshal(map[string]any{"format": "tar", "files": []domain.BundleItem{
+		{ID: first.ID, Keyword: first.DownloadKeyword},
+		{ID: second.ID, Keyword: second.DownloadKeyword},
+		{ID: dir.ID, Keyword: dir.DownloadKeyword},
+		{ID: first.ID, Keyword: first.DownloadKeyword},
+		{ID: other.ID, Keyword: "wrong"},
+		{ID: "missing", Keyword: "keyword"},
+	}})
+	resp, data := downloadBundle(t, server.URL, apiKey, string(items))
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-tar" {
+		t.Fatalf("Expected a tar bundle; got %v %s: %s", resp.Status, resp.Header.Get("Content-Type"), data)
+	}
+
+	var names []string
+	contents := map[string]string{}
+	reader := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid tar bundle: %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		names = append(names, header.Name)
+		contents[header.Name] = string(content)
+	}
+	want := []string{"a.txt", second.ID + "/a.txt", "photos/photos/a.txt", "photos/photos/b/c.txt", domain.BundleManifestName}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected entries %v; got %v", want, names)
+	}
+	if contents["a.txt"] != "first a" || contents[second.ID+"/a.txt"] != "second a" {
+		t.Errorf("Unexpected contents: %v", contents)
+	}
+
+	var manifest domain.BundleManifest
+	if err := json.Unmarshal([]byte(contents[domain.BundleManifestName]), &manifest); err != nil {
+		t.Fatalf("Invalid manifest: %v", err)
+	}
+	if len(manifest.Files) != 3 || manifest.Files[2].Path != "photos" || manifest.Files[2].Size != dir.Size {
+		t.Errorf("Unexpected manifest files: %+v", manifest.Files)
+	}
+	if len(manifest.Failed) != 2 || manifest.Failed[0].ID != other.ID || manifest.Failed[1].ID != "missing" {
+		t.Errorf("Unexpected manifest failures: %+v", manifest.Failed)
+	}
+}
+
+func TestDownloadBundle_Zip(t *testing.T) {
+	server, _ := newFakeIPFSServer(t, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+	file := decodeUploaded(t, uploadParts(t, server, apiKey, "", "file", map[string][]byte{"a.txt": []byte("content")}))
+
+	resp, data := downloadBundle(t, server.URL, apiKey, `{"files": [{"id": "`+file.ID+`", "keyword": "`+file.DownloadKeyword+`"}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v: %s", resp.Status, data)
+	}
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != "attachment; filename=bundle.zip" {
+		t.Errorf("Unexpected Content-Disposition %q", disposition)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Invalid zip bundle: %v", err)
+	}
+	if len(zipReader.File) != 2 || zipReader.File[0].Name != "a.txt" || zipReader.File[1].Name != domain.BundleManifestName {
+		t.Errorf("Unexpected zip entries: %v", zipReader.File)
+	}
+
+	for _, body := range []string{`{"files": []}`, `{"format": "rar", "files": [{"id": "x", "keyword": "y"}]}`, `not json`} {
+		if resp, _ := downloadBundle(t, server.URL, apiKey, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status Bad Request for %s; got %v", body, resp.Status)
+		}
+	}
+	resp, err = http.Get(server.URL + "/download/bundle")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status Method Not Allowed; got %v", resp.Status)
+	}
+}
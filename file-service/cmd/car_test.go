@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/car"
+	"decentralstore/file-service/internal/domain"
+
+	"github.com/ipfs/go-cid"
+)
+
+func exportCAR(t *testing.T, serverURL, apiKey string, version int, files ...domain.File) (*http.Response, []byte) {
+	t.Helper()
+	items := make([]domain.BundleItem, len(files))
+	for i, file := range files {
+		items[i] = domain.BundleItem{ID: file.ID, Keyword: file.DownloadKeyword}
+	}
+	body, _ := json.Marshal(map[string]any{"version": version, "files": items})
+	req, _ := http.NewRequest("POST", serverURL+"/export", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func importCAR(t *testing.T, serverURL, apiKey, query string, data []byte) (*http.Response, []domain.File) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", serverURL+"/import"+query, bytes.NewReader(data))
+	req.Header.Set("Content-Type", car.ContentType)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	defer resp.Body.Close()
+	var files []domain.File
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+			t.Fatalf("Failed to decode import response: %v", err)
+		}
+	}
+	return resp, files
+}
+
+func TestExportImportCAR(t *testing.T) {
+	for _, version := range []int{1, 2} {
+		server, _ := newFakeIPFSServer(t, ServerOptions{})
+		acme := createAPIKey(t, server, "acme")
+		globex := createAPIKey(t, server, "globex")
+
+		first := decodeUploaded(t, uploadParts(t, server, acme, "", "file", map[string][]byte{"a.txt": []byte("first")}))
+		second := decodeUploaded(t, uploadParts(t, server, acme, "", "file", map[string][]byte{"b.txt": []byte("second file")}))
+
+		resp, data := exportCAR(t, server.URL, acme, version, first, second)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != car.ContentType {
+			t.Fatalf("v%d: Expected a CAR file; got %v %s: %s", version, resp.Status, resp.Header.Get("Content-Type"), data)
+		}
+		contents, err := car.Verify(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("v%d: Exported CAR file does not verify: %v", version, err)
+		}
+		if contents.Version != version || len(contents.Roots) != 3 || contents.Roots[0].String() != first.CID {
+			t.Errorf("v%d: Unexpected header: version %d, roots %v", version, contents.Version, contents.Roots)
+		}
+
+		resp, imported := importCAR(t, server.URL, globex, "", data)
+		if resp.StatusCode != http.StatusOK || len(imported) != 2 {
+			t.Fatalf("v%d: Expected two imported files; got %v %+v", version, resp.Status, imported)
+		}
+		for i, original := range []domain.File{first, second} {
+			file := imported[i]
+			if file.ID == original.ID || file.DownloadKeyword == original.DownloadKeyword || file.TenantID != "globex" {
+				t.Errorf("v%d: Imported file should get a new ID and keywords: %+v", version, file)
+			}
+			if file.Name != original.Name || file.Size != original.Size || file.CID != original.CID ||
+				!file.UploadedAt.Equal(original.UploadedAt) {
+				t.Errorf("v%d: Imported file %+v does not match %+v", version, file, original)
+			}
+		}
+		if _, content := downloadFrom(t, server, globex, imported[1], nil); string(content) != "second file" {
+			t.Errorf("v%d: Unexpected content of the imported file: %q", version, content)
+		}
+	}
+}
+
+func TestExportCAR_InvalidKeyword(t *testing.T) {
+	server, _ := newFakeIPFSServer(t, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+	file := decodeUploaded(t, uploadParts(t, server, apiKey, "", "file", map[string][]byte{"a.txt": []byte("a")}))
+	file.DownloadKeyword = "wrong"
+
+	if resp, _ := exportCAR(t, server.URL, apiKey, 1, file); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status Unauthorized; got %v", resp.Status)
+	}
+	if resp, _ := exportCAR(t, server.URL, apiKey, 3, file); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for version 3; got %v", resp.Status)
+	}
+}
+
+func TestImportCAR_PlainCAR(t *testing.T) {
+	server, ipfs := newFakeIPFSServer(t, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+
+	content := []byte("from another node")
+	root, _ := car.RawCID(content)
+	data := &bytes.Buffer{}
+	writer, _ := car.NewWriter(data, []cid.Cid{root})
+	writer.Put(root, content)
+
+	resp, imported := importCAR(t, server.URL, apiKey, "?name=notes.txt", data.Bytes())
+	if resp.StatusCode != http.StatusOK || len(imported) != 1 {
+		t.Fatalf("Expected one imported file; got %v %+v", resp.Status, imported)
+	}
+	if file := imported[0]; file.Name != "notes.txt" || file.Size != int64(len(content)) || file.CID != root.String() {
+		t.Errorf("Unexpected imported file: %+v", file)
+	}
+	if !ipfs.Pinned[root.String()] {
+		t.Errorf("Expected %s to be pinned", root)
+	}
+	if usage := getUsage(t, server, apiKey); usage.Usage != (domain.Usage{Bytes: int64(len(content)), Files: 1}) {
+		t.Errorf("Expected the imported file to count at its size; got %+v", usage.Usage)
+	}
+
+	deleteFile(t, server, apiKey, imported[0])
+	if usage := getUsage(t, server, apiKey); usage.Usage != (domain.Usage{}) {
+		t.Errorf("Expected the delete to release the quota; got %+v", usage.Usage)
+	}
+}
+
+func TestImportCAR_PlainCAROverQuota(t *testing.T) {
+	server, ipfs := newFakeIPFSServer(t, ServerOptions{QuotaLimits: domain.Limits{MaxBytes: 1024}})
+	apiKey := createAPIKey(t, server, "acme")
+
+	// The CAR file fits, but the same root twice counts twice
+	content := bytes.Repeat([]byte("x"), 600)
+	root, _ := car.RawCID(content)
+	data := &bytes.Buffer{}
+	writer, _ := car.NewWriter(data, []cid.Cid{root, root})
+	writer.Put(root, content)
+
+	if resp, _ := importCAR(t, server.URL, apiKey, "", data.Bytes()); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status Request Entity Too Large; got %v", resp.Status)
+	}
+	if len(ipfs.Pinned) != 0 {
+		t.Errorf("A CAR file over the quota should not be pinned: %d pins", len(ipfs.Pinned))
+	}
+	if usage := getUsage(t, server, apiKey); usage.Usage != (domain.Usage{}) {
+		t.Errorf("Expected the reservations to be released; got %+v", usage.Usage)
+	}
+}
+
+func TestImportCAR_RejectsInvalidFiles(t *testing.T) {
+	server, ipfs := newFakeIPFSServer(t, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+
+	content := []byte("original")
+	root, _ := car.RawCID(content)
+	missing, _ := car.RawCID([]byte("missing"))
+	build := func(roots []cid.Cid, data []byte) []byte {
+		out := &bytes.Buffer{}
+		writer, _ := car.NewWriter(out, roots)
+		writer.Put(root, data)
+		return out.Bytes()
+	}
+
+	for name, data := range map[string][]byte{
+		"tampered block": build([]cid.Cid{root}, []byte("tampered")),
+		"missing root":   build([]cid.Cid{root, missing}, content),
+		"no roots":       build(nil, content),
+		"not a CAR file": []byte("hello"),
+	} {
+		resp, _ := importCAR(t, server.URL, apiKey, "", data)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: Expected status Bad Request; got %v", name, resp.Status)
+		}
+	}
+	if len(ipfs.Blocks) != 0 || len(ipfs.Pinned) != 0 {
+		t.Errorf("Invalid CAR files should not reach IPFS: %d blocks, %d pins", len(ipfs.Blocks), len(ipfs.Pinned))
+	}
+
+	if resp, _ := importCAR(t, server.URL, "", "", build([]cid.Cid{root}, content)); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status Unauthorized without an API key; got %v", resp.Status)
+	}
+}
+
+func TestImportCAR_QuotaCheckedBeforeImport(t *testing.T) {
+	server, ipfs := newFakeIPFSServer(t, ServerOptions{QuotaLimits: domain.Limits{MaxBytes: 1024}})
+	apiKey := createAPIKey(t, server, "acme")
+
+	content := bytes.Repeat([]byte("x"), 2048)
+	root, _ := car.RawCID(content)
+	data := &bytes.Buffer{}
+	writer, _ := car.NewWriter(data, []cid.Cid{root})
+	writer.Put(root, content)
+
+	if resp, _ := importCAR(t, server.URL, apiKey, "", data.Bytes()); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status Request Entity Too Large; got %v", resp.Status)
+	}
+	if len(ipfs.Blocks) != 0 || len(ipfs.Pinned) != 0 {
+		t.Errorf("A CAR file over the quota should not reach IPFS: %d blocks, %d pins", len(ipfs.Blocks), len(ipfs.Pinned))
+	}
+	if usage := getUsage(t, server, apiKey); usage.Usage != (domain.Usage{}) {
+		t.Errorf("Expected the reservation to be released; got %+v", usage.Usage)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/mocks"
+
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+func checkContent(t *testing.T, server *httptest.Server, apiKey string, ref domain.ContentRef, name string) (*http.Response, domain.File) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"sha256": ref.SHA256, "cid": ref.CID, "name": name})
+	req, _ := http.NewRequest("POST", server.URL+"/upload/check", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to check content: %v", err)
+	}
+	defer resp.Body.Close()
+	var file domain.File
+	json.NewDecoder(resp.Body).Decode(&file)
+	return resp, file
+}
+
+func deleteFile(t *testing.T, server *httptest.Server, apiKey string, file domain.File) {
+	t.Helper()
+	req, _ := http.NewRequest("DELETE", server.URL+"/delete?id="+file.ID+"&keyword="+file.DeleteKeyword, nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the file to be deleted; got %v", resp.Status)
+	}
+}
+
+func TestUpload_Deduplicated(t *testing.T) {
+	server, ipfs := newFakeIPFSServer(t, ServerOptions{})
+	acme := createAPIKey(t, server, "acme")
+	other := createAPIKey(t, server, "other")
+
+	content := "the same dataset"
+	sum := sha256.Sum256([]byte(content))
+	first := decodeUploaded(t, uploadFile(t, server, acme, content))
+	if first.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("Expected the SHA-256 of the content; got %q", first.SHA256)
+	}
+	second := decodeUploaded(t, uploadFile(t, server, acme, content))
+	if second.ID == first.ID || second.CID != first.CID || ipfs.Adds != 1 {
+		t.Fatalf("Expected a new file sharing the content without adding it again; got %+v after %d adds", second, ipfs.Adds)
+	}
+
+	// Content of another tenant is added again, so that its existence does not show.
+	shared := decodeUploaded(t, uploadFile(t, server, other, content))
+	if shared.CID != first.CID || ipfs.Adds != 2 {
+		t.Fatalf("Expected the other tenant's upload to be added; got %+v after %d adds", shared, ipfs.Adds)
+	}
+	if resp, data := downloadFrom(t, server, acme, second, nil); resp.StatusCode != http.StatusOK || string(data) != content {
+		t.Fatalf("Expected the shared content; got %v: %q", resp.Status, data)
+	}
+
+	deleteFile(t, server, acme, first)
+	deleteFile(t, server, acme, second)
+	if !ipfs.Pinned[first.CID] {
+		t.Fatalf("Expected the content to stay pinned for the other tenant")
+	}
+	deleteFile(t, server, other, shared)
+	if ipfs.Pinned[first.CID] {
+		t.Errorf("Expected the content to be unpinned with its last file")
+	}
+}
+
+func TestCheckContent(t *testing.T) {
+	server, ipfs := newFakeIPFSServer(t, ServerOptions{})
+	acme := createAPIKey(t, server, "acme")
+	other := createAPIKey(t, server, "other")
+
+	uploaded := decodeUploaded(t, uploadFile(t, server, acme, "acme's dataset"))
+	byHash := domain.ContentRef{SHA256: uploaded.SHA256}
+
+	resp, claimed := checkContent(t, server, acme, byHash, "copy.txt")
+	if resp.StatusCode != http.StatusOK || claimed.CID != uploaded.CID || claimed.Name != "copy.txt" || claimed.Size != uploaded.Size {
+		t.Fatalf("Expected a new file of the content; got %v: %+v", resp.Status, claimed)
+	}
+	resp, byCID := checkContent(t, server, acme, domain.ContentRef{CID: uploaded.CID}, "copy2.txt")
+	if resp.StatusCode != http.StatusOK || byCID.CID != uploaded.CID {
+		t.Fatalf("Expected the content to be found by CID; got %v: %+v", resp.Status, byCID)
+	}
+	if ipfs.Adds != 1 {
+		t.Errorf("Expected the content to be added once; got %d adds", ipfs.Adds)
+	}
+	if usage := getUsage(t, server, acme); usage.Usage.Files != 3 || usage.Usage.Bytes != 3*uploaded.Size {
+		t.Errorf("Expected every file to count against the quota; got %+v", usage.Usage)
+	}
+
+	// Other tenants cannot learn that the content exists.
+	if resp, _ := checkContent(t, server, other, byHash, "copy.txt"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected another tenant's content not to be found; got %v", resp.Status)
+	}
+	if resp, _ := checkContent(t, server, other, domain.ContentRef{CID: uploaded.CID}, "copy.txt"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected another tenant's content not to be found by CID; got %v", resp.Status)
+	}
+	if resp, _ := checkContent(t, server, acme, domain.ContentRef{SHA256: "not a hash"}, "copy.txt"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an invalid hash to be rejected; got %v", resp.Status)
+	}
+
+	for _, file := range []domain.File{uploaded, claimed, byCID} {
+		deleteFile(t, server, acme, file)
+	}
+	if resp, _ := checkContent(t, server, acme, byHash, "copy.txt"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected deleted content not to be found; got %v", resp.Status)
+	}
+}
+
+func TestUpload_SpoolsOnlyPossibleDuplicates(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	ipfs := mocks.NewFakeIPFS()
+	ipfsShell := ipfs.Shell()
+	var spooled []bool
+	add := ipfsShell.AddFn
+	ipfsShell.AddFn = func(r io.Reader, options ...shell.AddOpts) (string, error) {
+		entries, _ := os.ReadDir(os.TempDir())
+		found := false
+		for _, entry := range entries {
+			found = found || strings.HasPrefix(entry.Name(), "upload-")
+		}
+		spooled = append(spooled, found)
+		return add(r, options...)
+	}
+	server := newTestServerWithShell(t, ipfsShell, ServerOptions{})
+	acme := createAPIKey(t, server, "acme")
+
+	first := decodeUploaded(t, uploadFile(t, server, acme, "first dataset"))
+	second := decodeUploaded(t, uploadFile(t, server, acme, "second dataset"))
+	if len(spooled) != 2 || spooled[0] || !spooled[1] {
+		t.Fatalf("Expected only the upload after stored content to be spooled; got %v", spooled)
+	}
+
+	deleteFile(t, server, acme, first)
+	deleteFile(t, server, acme, second)
+	again := decodeUploaded(t, uploadFile(t, server, acme, "first dataset"))
+	if len(spooled) != 3 || spooled[2] || again.SHA256 != first.SHA256 {
+		t.Errorf("Expected the upload to stream once the content is deleted; got %v and %+v", spooled, again)
+	}
+}
+
+func TestCheckContent_PinFailureReleasesReference(t *testing.T) {
+	ipfs := mocks.NewFakeIPFS()
+	ipfsShell := ipfs.Shell()
+	var failPins atomic.Bool
+	pin := ipfsShell.PinFn
+	ipfsShell.PinFn = func(path string) error {
+		if failPins.Load() {
+			return errors.New("pin failed")
+		}
+		return pin(path)
+	}
+	server := newTestServerWithShell(t, ipfsShell, ServerOptions{})
+	acme := createAPIKey(t, server, "acme")
+
+	uploaded := decodeUploaded(t, uploadFile(t, server, acme, "acme's dataset"))
+	failPins.Store(true)
+	if resp, _ := checkContent(t, server, acme, domain.ContentRef{SHA256: uploaded.SHA256}, "copy.txt"); resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected the claim to fail; got %v", resp.Status)
+	}
+	failPins.Store(false)
+
+	deleteFile(t, server, acme, uploaded)
+	if ipfs.Pinned[uploaded.CID] {
+		t.Errorf("Expected the failed claim not to keep the content pinned")
+	}
+}
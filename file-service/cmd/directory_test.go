@@ -0,0 +1,211 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"testing"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+)
+
+// uploadParts sends a multipart upload with one part per file name of field and returns the response.
+func uploadParts(t *testing.T, server *httptest.Server, apiKey, query, field string, files map[string][]byte) *http.Response {
+	t.Helper()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, name := range names {
+		part, _ := writer.CreateFormFile(field, name)
+		part.Write(files[name])
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/upload"+query, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+	return resp
+}
+
+func decodeUploaded(t *testing.T, resp *http.Response) domain.File {
+	t.Helper()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected status OK; got %v: %s", resp.Status, data)
+	}
+	var uploaded domain.File
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("Failed to decode upload response: %v", err)
+	}
+	return uploaded
+}
+
+// downloadFrom downloads file by its keyword with the API key of its tenant.
+func downloadFrom(t *testing.T, server *httptest.Server, apiKey string, file domain.File, extra url.Values) (*http.Response, []byte) {
+	t.Helper()
+	query := url.Values{"id": {file.ID}, "keyword": {file.DownloadKeyword}}
+	for key, values := range extra {
+		query[key] = values
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/download?"+query.Encode(), nil)
+	if apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+var directoryFiles = map[string][]byte{
+	"photos/a.txt":   []byte("first file"),
+	"photos/b/c.txt": []byte("second, nested file"),
+}
+
+func TestUpload_DirectoryFromFiles(t *testing.T) {
+	server, ipfs := newFakeIPFSServer(t, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+
+	uploaded := decodeUploaded(t, uploadParts(t, server, apiKey, "?name=photos", "files", directoryFiles))
+	if uploaded.Name != "photos" || uploaded.Size != 29 || len(uploaded.Entries) != 2 {
+		t.Fatalf("Unexpected directory record: %+v", uploaded)
+	}
+	if entry := uploaded.Entries[1]; entry.Path != "photos/b/c.txt" || entry.Size != 19 || entry.CID == "" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if !ipfs.Pinned[uploaded.CID] {
+		t.Errorf("Expected the root %s to be pinned", uploaded.CID)
+	}
+	if len(ipfs.MFS) != 0 {
+		t.Errorf("Expected the staging directory to be removed; got %v", ipfs.MFS)
+	}
+
+	resp, data := downloadFrom(t, server, apiKey, uploaded, url.Values{"path": {"photos/b/c.txt"}})
+	if resp.StatusCode != http.StatusOK || string(data) != "second, nested file" {
+		t.Errorf("Expected the nested file; got %v: %q", resp.Status, data)
+	}
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != "attachment; filename=c.txt" {
+		t.Errorf("Unexpected Content-Disposition %q", disposition)
+	}
+	if resp, _ := downloadFrom(t, server, apiKey, uploaded, url.Values{"path": {"photos/missing.txt"}}); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status Not Found for a missing path; got %v", resp.Status)
+	}
+	if resp, _ := downloadFrom(t, server, apiKey, uploaded, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for a directory without path; got %v", resp.Status)
+	}
+
+	resp, data = downloadFrom(t, server, apiKey, uploaded, url.Values{"format": {"tar"}})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-tar" {
+		t.Fatalf("Expected a tar archive; got %v %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	tarFiles := map[string]string{}
+	reader := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid tar archive: %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		tarFiles[header.Name] = string(content)
+	}
+	if tarFiles["photos/a.txt"] != "first file" || tarFiles["photos/b/c.txt"] != "second, nested file" {
+		t.Errorf("Unexpected tar content: %v", tarFiles)
+	}
+
+	resp, data = downloadFrom(t, server, apiKey, uploaded, url.Values{"format": {"zip"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a zip archive; got %v", resp.Status)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Invalid zip archive: %v", err)
+	}
+	if len(zipReader.File) != 2 || zipReader.File[0].Name != "photos/a.txt" {
+		t.Errorf("Unexpected zip entries: %v", zipReader.File)
+	}
+
+	if resp, _ := downloadFrom(t, server, apiKey, uploaded, url.Values{"format": {"rar"}}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for an unknown format; got %v", resp.Status)
+	}
+}
+
+func TestUpload_DirectoryFromArchive(t *testing.T) {
+	server, _ := newFakeIPFSServer(t, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+
+	var tarball bytes.Buffer
+	gz := gzip.NewWriter(&tarball)
+	tarWriter := tar.NewWriter(gz)
+	tarWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "photos/", Mode: 0o755})
+	for _, name := range []string{"photos/a.txt", "photos/b/c.txt"} {
+		tarWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: int64(len(directoryFiles[name])), Mode: 0o644})
+		tarWriter.Write(directoryFiles[name])
+	}
+	tarWriter.Close()
+	gz.Close()
+
+	uploaded := decodeUploaded(t, uploadParts(t, server, apiKey, "", "archive", map[string][]byte{"photos.tar.gz": tarball.Bytes()}))
+	if uploaded.Name != "photos" || len(uploaded.Entries) != 2 {
+		t.Fatalf("Unexpected directory record: %+v", uploaded)
+	}
+
+	var zipped bytes.Buffer
+	zipWriter := zip.NewWriter(&zipped)
+	for _, name := range []string{"photos/a.txt", "photos/b/c.txt"} {
+		file, _ := zipWriter.Create(name)
+		file.Write(directoryFiles[name])
+	}
+	zipWriter.Close()
+
+	fromZip := decodeUploaded(t, uploadParts(t, server, apiKey, "", "archive", map[string][]byte{"photos.zip": zipped.Bytes()}))
+	if fromZip.CID != uploaded.CID {
+		t.Errorf("Expected the same root CID for the same files; got %s and %s", fromZip.CID, uploaded.CID)
+	}
+	resp, data := downloadFrom(t, server, apiKey, fromZip, url.Values{"path": {"photos/a.txt"}})
+	if resp.StatusCode != http.StatusOK || string(data) != "first file" {
+		t.Errorf("Expected the file from the zip archive; got %v: %q", resp.Status, data)
+	}
+
+	resp = uploadParts(t, server, "", "", "archive", map[string][]byte{"photos.zip": zipped.Bytes()})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status Unauthorized without an API key; got %v", resp.Status)
+	}
+	resp = uploadParts(t, server, apiKey, "", "archive", map[string][]byte{"photos.rar": []byte("nope")})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for an unknown archive; got %v", resp.Status)
+	}
+	resp = uploadParts(t, server, apiKey, "", "files", map[string][]byte{"../escape.txt": []byte("x")})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request for a path outside the directory; got %v", resp.Status)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/erasure"
+	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/file-service/internal/ipfspool"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/file-service/internal/usecase"
+)
+
+// newErasureTestServer returns a server that splits uploads into shards stored on a pool
+// of fake IPFS nodes.
+func newErasureTestServer(t *testing.T, params erasure.Params, names ...string) (*httptest.Server, *infrastructure.StorageClient, map[string]*mocks.FakeIPFS) {
+	t.Helper()
+	redisClient := mocks.NewFakeRedisClient()
+	nodes := make(map[string]*mocks.FakeIPFS)
+	var poolNodes []ipfspool.Node
+	for _, name := range names {
+		nodes[name] = mocks.NewFakeIPFS()
+		poolNodes = append(poolNodes, ipfspool.Node{Name: name, Shell: nodes[name].Shell()})
+	}
+	pool := ipfspool.NewPool(poolNodes, redisClient, ipfspool.Options{HealthInterval: time.Hour, RepairInterval: time.Hour})
+	t.Cleanup(func() { pool.Close() })
+	storageClient := &infrastructure.StorageClient{IPFSShell: pool, RedisClient: redisClient, Shards: pool}
+
+	return newTestServerWithStorage(t, storageClient, ServerOptions{Erasure: &params}), storageClient, nodes
+}
+
+func TestErasureCoding(t *testing.T) {
+	server, storageClient, nodes := newErasureTestServer(t, erasure.Params{DataShards: 2, ParityShards: 1, BlockSize: 16},
+		"ipfs-1", "ipfs-2", "ipfs-3", "ipfs-4")
+	apiKey := createAPIKey(t, server, "acme")
+
+	content := strings.Repeat("erasure-coded content ", 10)
+	file := decodeUploaded(t, uploadFile(t, server, apiKey, content))
+	if file.CID != "" || file.Erasure == nil || len(file.Erasure.Shards) != 3 {
+		t.Fatalf("Expected a file of 3 shards; got %+v", file)
+	}
+	used := make(map[string]bool)
+	for _, shard := range file.Erasure.Shards {
+		if used[shard.Node] || !nodes[shard.Node].Pinned[shard.CID] {
+			t.Fatalf("Expected each shard pinned on its own node; got %+v", file.Erasure.Shards)
+		}
+		used[shard.Node] = true
+	}
+
+	// Losing a data shard leaves enough shards to rebuild the content.
+	lost := file.Erasure.Shards[0]
+	delete(nodes[lost.Node].Blocks, lost.CID)
+	delete(nodes[lost.Node].Pinned, lost.CID)
+	if resp, data := downloadFrom(t, server, apiKey, file, nil); resp.StatusCode != http.StatusOK || string(data) != content {
+		t.Fatalf("Expected the rebuilt content; got %v: %q", resp.Status, data)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/download?id="+file.ID+"&keyword="+file.DownloadKeyword, nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	req.Header.Set("Range", "bytes=22-43")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(data) != content[22:44] {
+		t.Errorf("Expected the requested range; got %v: %q", resp.Status, data)
+	}
+
+	repairer := usecase.NewShardRepairer(storageClient, time.Hour)
+	t.Cleanup(func() { repairer.Close(context.Background()) })
+	repaired, err := repairer.Repair(context.Background())
+	if err != nil || repaired != 1 {
+		t.Fatalf("Expected one shard to be repaired; got %d, %v", repaired, err)
+	}
+	files := listFiles(t, server, apiKey)
+	if len(files) != 1 {
+		t.Fatalf("Expected one file; got %+v", files)
+	}
+	shard := files[0].Erasure.Shards[0]
+	if shard.CID != lost.CID || !nodes[shard.Node].Pinned[shard.CID] {
+		t.Fatalf("Expected the shard to be rebuilt; got %+v", shard)
+	}
+
+	// With the rebuilt shard, the content survives the loss of another shard.
+	other := files[0].Erasure.Shards[1]
+	delete(nodes[other.Node].Blocks, other.CID)
+	if resp, data := downloadFrom(t, server, apiKey, files[0], nil); resp.StatusCode != http.StatusOK || string(data) != content {
+		t.Fatalf("Expected the rebuilt content; got %v: %q", resp.Status, data)
+	}
+	delete(nodes[shard.Node].Blocks, shard.CID)
+	if resp, _ := downloadFrom(t, server, apiKey, files[0], nil); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a failure with one shard left; got %v", resp.Status)
+	}
+
+	req, _ = http.NewRequest("DELETE", server.URL+"/delete?id="+file.ID+"&keyword="+file.DeleteKeyword, nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+	resp.Body.Close()
+	for name, node := range nodes {
+		if len(node.Pinned) != 0 {
+			t.Errorf("Expected the shards to be unpinned from %s; got %v", name, node.Pinned)
+		}
+	}
+}
+
+func TestErasureCoding_ExportRejected(t *testing.T) {
+	server, _, _ := newErasureTestServer(t, erasure.Params{DataShards: 1, ParityShards: 1, BlockSize: 1024}, "ipfs-1", "ipfs-2")
+	apiKey := createAPIKey(t, server, "acme")
+	file := decodeUploaded(t, uploadFile(t, server, apiKey, "test content"))
+
+	if resp, data := exportCAR(t, server.URL, apiKey, 1, file); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected erasure-coded files not to be exported; got %v: %s", resp.Status, data)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/file-service/internal/metrics"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/file-service/rpc/filepb"
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/ratelimit"
+
+	shell "github.com/ipfs/go-ipfs-api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPCClient serves the gRPC API over an in-memory listener, backed by an IPFS fake
+// that keeps the uploaded content, and returns a client and an API key of tenant "acme".
+func newTestGRPCClient(t *testing.T) (filepb.FileServiceClient, string) {
+	t.Helper()
+	return newTestGRPCClientWithOptions(t, ServerOptions{})
+}
+
+func newTestGRPCClientWithOptions(t *testing.T, opts ServerOptions) (filepb.FileServiceClient, string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	blocks := map[string][]byte{}
+	ipfs := &mocks.MockIPFSShell{
+		AddFn: func(r io.Reader, options ...shell.AddOpts) (string, error) {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return "", err
+			}
+			sum := sha256.Sum256(data)
+			cid := "Qm" + hex.EncodeToString(sum[:8])
+			mu.Lock()
+			blocks[cid] = data
+			mu.Unlock()
+			return cid, nil
+		},
+		CatFn: func(path string) (io.ReadCloser, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return io.NopCloser(bytes.NewReader(blocks[path])), nil
+		},
+		PinFn: func(path string) error {
+			return nil
+		},
+	}
+	storageClient := &infrastructure.StorageClient{
+		IPFSShell:   infrastructure.InstrumentIPFSShell(ipfs),
+		RedisClient: mocks.NewFakeRedisClient(),
+	}
+	secret, _, err := auth.NewKeyStore(storageClient.RedisClient).Create(context.Background(), "acme", "test")
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	ln := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(storageClient, opts)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return filepb.NewFileServiceClient(conn), secret
+}
+
+func upload(ctx context.Context, client filepb.FileServiceClient, name string, chunks ...string) (*filepb.File, error) {
+	stream, err := client.Upload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&filepb.UploadRequest{Data: &filepb.UploadRequest_Name{Name: name}}); err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		if err := stream.Send(&filepb.UploadRequest{Data: &filepb.UploadRequest_Chunk{Chunk: []byte(chunk)}}); err != nil {
+			return nil, err
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+func download(ctx context.Context, client filepb.FileServiceClient, id, keyword string) (string, error) {
+	stream, err := client.Download(ctx, &filepb.DownloadRequest{Id: id, Keyword: keyword})
+	if err != nil {
+		return "", err
+	}
+	var content strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return content.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		content.Write(resp.GetChunk())
+	}
+}
+
+// assertGRPCError checks the gRPC code and the envelope code carried in the ErrorInfo detail.
+func assertGRPCError(t *testing.T, err error, wantCode codes.Code, wantReason string) {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != wantCode {
+		t.Fatalf("Expected code %v; got %v (%v)", wantCode, st.Code(), err)
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason == wantReason {
+			return
+		}
+	}
+	t.Errorf("Expected reason %q in %v", wantReason, st.Details())
+}
+
+func TestGRPC_UploadAndDownload(t *testing.T) {
+	client, secret := newTestGRPCClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyMetadata, secret)
+
+	file, err := upload(ctx, client, "report.txt", "hello ", "grpc ", "world")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if file.GetName() != "report.txt" || file.GetSize() != 16 || file.GetDownloadKeyword() == "" {
+		t.Errorf("Unexpected file: %v", file)
+	}
+
+	content, err := download(ctx, client, file.GetId(), file.GetDownloadKeyword())
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if content != "hello grpc world" {
+		t.Errorf("Expected the uploaded content; got %q", content)
+	}
+
+	_, err = download(ctx, client, file.GetId(), "wrong")
+	assertGRPCError(t, err, codes.Unauthenticated, httperr.CodeInvalidKeyword)
+
+	_, err = download(context.Background(), client, file.GetId(), file.GetDownloadKeyword())
+	assertGRPCError(t, err, codes.NotFound, httperr.CodeNotFound)
+}
+
+func TestGRPC_Upload_RequiresAPIKey(t *testing.T) {
+	client, _ := newTestGRPCClient(t)
+
+	_, err := upload(context.Background(), client, "report.txt", "hello")
+	assertGRPCError(t, err, codes.Unauthenticated, httperr.CodeUnauthorized)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyMetadata, "dsk_invalid")
+	_, err = upload(ctx, client, "report.txt", "hello")
+	assertGRPCError(t, err, codes.Unauthenticated, httperr.CodeUnauthorized)
+}
+
+func TestGRPC_InvalidRequests(t *testing.T) {
+	client, secret := newTestGRPCClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyMetadata, secret)
+
+	stream, err := client.Upload(ctx)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	stream.Send(&filepb.UploadRequest{Data: &filepb.UploadRequest_Chunk{Chunk: []byte("no name")}})
+	_, err = stream.CloseAndRecv()
+	assertGRPCError(t, err, codes.InvalidArgument, httperr.CodeBadRequest)
+
+	_, err = download(ctx, client, "", "")
+	assertGRPCError(t, err, codes.InvalidArgument, httperr.CodeBadRequest)
+}
+
+func TestGRPC_RateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(),
+		map[string]ratelimit.Limit{ratelimit.ClassDownload: {Rate: 1.0 / 60, Burst: 1}}, rateLimitKey)
+	client, secret := newTestGRPCClientWithOptions(t, ServerOptions{RateLimiter: limiter})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyMetadata, secret)
+
+	file, err := upload(ctx, client, "report.txt", "hello")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if _, err := download(ctx, client, file.GetId(), file.GetDownloadKeyword()); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	stream, err := client.Download(ctx, &filepb.DownloadRequest{Id: file.GetId(), Keyword: file.GetDownloadKeyword()})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	_, err = stream.Recv()
+	assertGRPCError(t, err, codes.ResourceExhausted, httperr.CodeRateLimited)
+	if header, _ := stream.Header(); len(header.Get(ratelimit.RetryAfterMetadata)) == 0 {
+		t.Errorf("Expected a retry-after header; got %v", header)
+	}
+}
+
+func TestGRPC_Instrumented(t *testing.T) {
+	client, secret := newTestGRPCClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyMetadata, secret, "x-request-id", "grpc-req-1")
+
+	counter := metrics.Requests.GRPCRequests.WithLabelValues(filepb.FileService_Upload_FullMethodName, codes.OK.String())
+	before := testutil.ToFloat64(counter)
+	stream, err := client.Upload(ctx)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	stream.Send(&filepb.UploadRequest{Data: &filepb.UploadRequest_Name{Name: "report.txt"}})
+	if _, err := stream.CloseAndRecv(); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if after := testutil.ToFloat64(counter); after != before+1 {
+		t.Errorf("Expected the upload to be counted; got %v after %v", after, before)
+	}
+	if header, _ := stream.Header(); len(header.Get("x-request-id")) == 0 || header.Get("x-request-id")[0] != "grpc-req-1" {
+		t.Errorf("Expected the request ID to be echoed; got %v", header)
+	}
+}
@@ -1,50 +1,433 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"decentralstore/file-service/internal/api"
+	"decentralstore/file-service/internal/apispec"
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/config"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/erasure"
+	"decentralstore/file-service/internal/healthcheck"
 	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/file-service/internal/metrics"
+	"decentralstore/file-service/internal/pinning"
+	"decentralstore/file-service/internal/quota"
+	"decentralstore/file-service/internal/shutdown"
+	"decentralstore/file-service/internal/signedurl"
 	"decentralstore/file-service/internal/usecase"
+	"decentralstore/file-service/rpc/filepb"
+	"decentralstore/shared/health"
+	"decentralstore/shared/logging"
+	"decentralstore/shared/ratelimit"
+	"decentralstore/shared/servermetrics"
+	"decentralstore/shared/tracing"
+	"decentralstore/webhook"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	log.Println("Starting File Service...")
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+
+	levelVar, err := logging.Setup(os.Stdout, cfg.Log.Level)
+	if err != nil {
+		fatal("Failed to configure logging", err)
+	}
+	slog.Info("Starting File Service")
+	slog.Info("Effective configuration", "config", cfg.String())
+
+	// Resources are closed in reverse order once the server has drained, so traces
+	// are flushed last.
+	closers := shutdown.NewRegistry()
+	shutdownTracing, err := tracing.Setup(context.Background(), "file-service")
+	if err != nil {
+		fatal("Failed to configure tracing", err)
+	}
+	closers.Add("tracing", shutdownTracing)
+
+	storageClient, err := infrastructure.NewStorageClient(cfg.IPFSNodes(), cfg.Redis.URL, cfg.IPFSPool())
+	if err != nil {
+		fatal("Failed to create storage client", err)
+	}
+	closers.Add("storage", func(context.Context) error { return storageClient.Close() })
+
+	urlSigner, err := newURLSigner(cfg.DownloadURLs.Keys, time.Now())
+	if err != nil {
+		fatal("Failed to configure signed download URLs", err)
+	}
 
-	ipfsAPI := os.Getenv("IPFS_API_URL")
-	if ipfsAPI == "" {
-		ipfsAPI = "localhost:5001"
+	if cfg.Admin.Token == "" {
+		slog.Warn("admin.token not set, admin API is disabled")
 	}
 
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		redisURL = "localhost:6379"
+	rateLimits, err := cfg.RateLimits()
+	if err != nil {
+		fatal("Failed to configure rate limits", err)
 	}
+	rateLimiter := newRateLimiter(storageClient.RedisClient, rateLimits)
+	quotaTracker := quota.NewTracker(storageClient.RedisClient, cfg.QuotaLimits())
+
+	// Webhooks are stopped before the storage they are logged in.
+	webhooks := webhook.NewDispatcher(webhook.NewRedisStore(storageClient.RedisClient), webhook.Options{})
+	closers.Add("webhooks", webhooks.Close)
 
-	storageClient, err := infrastructure.NewStorageClient(ipfsAPI, redisURL)
+	pinningServices, err := cfg.PinningServices()
 	if err != nil {
-		log.Fatalf("Failed to create storage client: %v", err)
+		fatal("Failed to configure remote pinning", err)
+	}
+	var replicator *pinning.Replicator
+	if len(pinningServices) > 0 {
+		replicator = pinning.NewReplicator(storageClient.RedisClient, pinningServices, pinning.Options{
+			MaxAttempts:  cfg.Pinning.MaxAttempts,
+			PollInterval: cfg.Pinning.PollInterval,
+		})
+		closers.Add("pinning", replicator.Close)
+	}
+
+	// Shards are repaired in the background whenever files are erasure-coded, including
+	// files uploaded before erasure coding was turned off.
+	if storageClient.Shards != nil {
+		repairer := usecase.NewShardRepairer(storageClient, cfg.Erasure.RepairInterval)
+		closers.Add("shard repair", repairer.Close)
 	}
 
-	router := SetupRoutes(storageClient)
+	if cfg.ErasureParams() != nil {
+		slog.Warn("Erasure coding is enabled, uploads are not deduplicated")
+	}
+
+	opts := ServerOptions{
+		URLSigner:         urlSigner,
+		AdminToken:        cfg.Admin.Token,
+		QuotaTracker:      quotaTracker,
+		RateLimiter:       rateLimiter,
+		UploadBandwidth:   cfg.Bandwidth.Upload,
+		DownloadBandwidth: cfg.Bandwidth.Download,
+		ValidateRequests:  cfg.Server.ValidateRequests,
+		Webhooks:          webhooks,
+		Pinning:           replicator,
+		Erasure:           cfg.ErasureParams(),
+	}
+	// The HTTP and gRPC APIs share one usecase.
+	opts.FileUseCase = opts.fileUseCase(storageClient, quotaTracker, webhooks)
+	router := SetupRoutes(storageClient, opts)
+
+	reloadOnSIGHUP(cfg, os.Args[1:], func(cfg *config.Config) {
+		level, _ := logging.ParseLevel(cfg.Log.Level)
+		levelVar.Set(level)
+		quotaTracker.SetDefaults(cfg.QuotaLimits())
+		limits, _ := cfg.RateLimits()
+		rateLimiter.SetLimits(limits)
+	})
+
+	server := &http.Server{
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	ln, err := net.Listen("tcp", cfg.Server.Addr)
+	if err != nil {
+		fatal("Failed to listen", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	grpcDone := make(chan struct{})
+	if cfg.Server.GRPCAddr != "" {
+		grpcLn, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+		if err != nil {
+			fatal("Failed to listen for gRPC", err)
+		}
+		go func() {
+			defer close(grpcDone)
+			slog.Info("gRPC server is listening", "addr", grpcLn.Addr().String())
+			if err := shutdown.ServeGRPC(ctx, NewGRPCServer(storageClient, opts), grpcLn, cfg.Server.ShutdownTimeout); err != nil {
+				slog.Error("gRPC server stopped before in-flight calls finished", "error", err)
+			}
+		}()
+	} else {
+		close(grpcDone)
+	}
+
+	slog.Info("Server is listening", "addr", ln.Addr().String())
+	if err := shutdown.Serve(ctx, server, ln, cfg.Server.ShutdownTimeout); err != nil {
+		slog.Error("Server stopped before in-flight requests finished", "error", err)
+	}
+	<-grpcDone
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := closers.Close(closeCtx); err != nil {
+		slog.Error("Failed to release resources", "error", err)
+	}
+	slog.Info("Server exiting")
+}
+
+// reloadOnSIGHUP reloads the configuration whenever the process receives SIGHUP. Settings
+// marked reloadable are copied into cfg and passed to apply; other changes are only logged
+// because they need a restart. A configuration that fails validation is ignored.
+func reloadOnSIGHUP(cfg *config.Config, args []string, apply func(*config.Config)) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			next, err := config.Load(args)
+			if err != nil {
+				slog.Error("Failed to reload configuration", "error", err)
+				continue
+			}
+			for _, change := range cfg.Diff(next) {
+				if change.Reloadable {
+					slog.Info("Configuration changed", "setting", change.Path)
+				} else {
+					slog.Warn("Configuration change needs a restart", "setting", change.Path)
+				}
+			}
+			cfg.ApplyReloadable(next)
+			apply(cfg)
+		}
+	}()
+}
+
+// fatal logs err and exits; deferred functions do not run.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
 
-	log.Fatal(http.ListenAndServe(":8081", router))
+// ServerOptions holds the settings of the HTTP API besides the storage backends.
+type ServerOptions struct {
+	URLSigner  *signedurl.Signer
+	AdminToken string
+	// QuotaLimits are the default per-tenant limits; zero values mean unlimited.
+	QuotaLimits domain.Limits
+	// QuotaTracker is used instead of a tracker built from QuotaLimits when set,
+	// so the caller can change the defaults at runtime.
+	QuotaTracker *quota.Tracker
+	// RateLimiter limits requests per client; nil disables rate limiting.
+	RateLimiter *ratelimit.Limiter
+	// UploadBandwidth and DownloadBandwidth cap each transfer in bytes per second; 0 means unlimited.
+	UploadBandwidth   int64
+	DownloadBandwidth int64
+	// ValidateRequests rejects requests that do not match the OpenAPI document with a 400.
+	ValidateRequests bool
+	// Webhooks receives the file events of the tenants. When nil, a dispatcher backed by
+	// Redis is started that is never closed.
+	Webhooks *webhook.Dispatcher
+	// Pinning replicates the pins of uploaded files to remote pinning services; nil disables it.
+	Pinning *pinning.Replicator
+	// Erasure splits uploaded files into shards stored on the nodes of StorageClient.Shards;
+	// nil stores them whole.
+	Erasure *erasure.Params
+	// FileUseCase is shared by SetupRoutes and NewGRPCServer when set; otherwise each builds
+	// its own from the options above.
+	FileUseCase usecase.FileUseCase
 }
 
-func SetupRoutes(storageClient *infrastructure.StorageClient) http.Handler {
-	fileHandler := CreateFileHandler(storageClient)
+func SetupRoutes(storageClient *infrastructure.StorageClient, opts ServerOptions) http.Handler {
+	quotaTracker := opts.quotaTracker(storageClient)
+	webhooks := opts.webhooks(storageClient)
+	fileUseCase := opts.fileUseCase(storageClient, quotaTracker, webhooks)
+	fileHandler := api.NewFileHandler(fileUseCase, opts.URLSigner)
+	keyStore := auth.NewKeyStore(storageClient.RedisClient)
+	adminHandler := api.NewAdminHandler(keyStore, quotaTracker, fileUseCase)
+	webhookHandler := api.NewWebhookHandler(webhooks)
+	adminToken := opts.AdminToken
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/upload", fileHandler.UploadFile)
-	mux.HandleFunc("/download", fileHandler.DownloadFile)
-	mux.HandleFunc("/delete", fileHandler.DeleteFile)
+	limiter := opts.RateLimiter
+	mux.Handle("/upload", limiter.Wrap(ratelimit.ClassUpload,
+		ratelimit.ThrottleBody(opts.UploadBandwidth, http.HandlerFunc(fileHandler.UploadFile))))
+	mux.Handle("/upload/check", limiter.Wrap(ratelimit.ClassUpload, http.HandlerFunc(fileHandler.CheckContent)))
+	mux.Handle("/download", limiter.Wrap(ratelimit.ClassDownload,
+		ratelimit.ThrottleResponse(opts.DownloadBandwidth, http.HandlerFunc(fileHandler.DownloadFile))))
+	mux.Handle("/download/sign", limiter.Wrap(ratelimit.ClassDownload, http.HandlerFunc(fileHandler.SignDownloadURL)))
+	mux.Handle("/download/bundle", limiter.Wrap(ratelimit.ClassDownload,
+		ratelimit.ThrottleResponse(opts.DownloadBandwidth, http.HandlerFunc(fileHandler.DownloadBundle))))
+	mux.Handle("/export", limiter.Wrap(ratelimit.ClassDownload,
+		ratelimit.ThrottleResponse(opts.DownloadBandwidth, http.HandlerFunc(fileHandler.ExportCAR))))
+	mux.Handle("/import", limiter.Wrap(ratelimit.ClassUpload,
+		ratelimit.ThrottleBody(opts.UploadBandwidth, http.HandlerFunc(fileHandler.ImportCAR))))
+	mux.Handle("/delete", limiter.Wrap(ratelimit.ClassWrite, http.HandlerFunc(fileHandler.DeleteFile)))
+	mux.Handle("/files", limiter.Wrap(ratelimit.ClassRead, http.HandlerFunc(fileHandler.ListFiles)))
+	mux.Handle("/usage", limiter.Wrap(ratelimit.ClassRead, http.HandlerFunc(fileHandler.Usage)))
+	mux.Handle("/webhooks", limiter.Wrap(ratelimit.ClassWrite, http.HandlerFunc(webhookHandler.Webhooks)))
+	mux.Handle("/webhooks/deliveries", limiter.Wrap(ratelimit.ClassRead, http.HandlerFunc(webhookHandler.Deliveries)))
+	mux.Handle("/webhooks/deliveries/replay", limiter.Wrap(ratelimit.ClassWrite, http.HandlerFunc(webhookHandler.Replay)))
+
+	mux.Handle("/admin/keys", auth.RequireAdmin(adminToken, http.HandlerFunc(adminHandler.Keys)))
+	mux.Handle("/admin/keys/rotate", auth.RequireAdmin(adminToken, http.HandlerFunc(adminHandler.RotateKey)))
+	mux.Handle("/admin/keys/revoke", auth.RequireAdmin(adminToken, http.HandlerFunc(adminHandler.RevokeKey)))
+	mux.Handle("/admin/quota", auth.RequireAdmin(adminToken, http.HandlerFunc(adminHandler.Quota)))
+	mux.Handle("/admin/usage/recompute", auth.RequireAdmin(adminToken, http.HandlerFunc(adminHandler.RecomputeUsage)))
+
+	mux.Handle("/metrics", servermetrics.Handler())
+	spec := apispec.MustLoad()
+	mux.Handle("/openapi.json", spec)
+
+	checker := health.NewChecker(2 * time.Second)
+	checker.Add("ipfs", healthcheck.IPFS(storageClient.IPFSShell))
+	checker.Add("redis", healthcheck.Redis(storageClient.RedisClient))
+	mux.HandleFunc("/healthz", health.Live)
+	mux.HandleFunc("/readyz", checker.Ready)
+
+	var handler http.Handler = mux
+	if opts.ValidateRequests {
+		handler = spec.Middleware(handler)
+	}
+	handler = keyStore.Middleware(handler)
+	handler = metrics.Requests.Instrument(mux, handler)
+	handler = logging.Middleware(mux, handler)
+	return tracing.Middleware(mux, handler)
+}
+
+// NewGRPCServer returns the gRPC API of the service. It shares the usecase, API keys,
+// quotas, rate limits and bandwidth caps with SetupRoutes, and is instrumented the same way.
+func NewGRPCServer(storageClient *infrastructure.StorageClient, opts ServerOptions) *grpc.Server {
+	fileUseCase := opts.fileUseCase(storageClient, opts.quotaTracker(storageClient), opts.webhooks(storageClient))
+	keyStore := auth.NewKeyStore(storageClient.RedisClient)
+	// Uploads and downloads count against the same budgets as their HTTP counterparts.
+	classes := map[string]string{
+		filepb.FileService_Upload_FullMethodName:   ratelimit.ClassUpload,
+		filepb.FileService_Download_FullMethodName: ratelimit.ClassDownload,
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			tracing.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(),
+			metrics.Requests.UnaryServerInterceptor(),
+			keyStore.UnaryServerInterceptor(),
+			opts.RateLimiter.UnaryServerInterceptor(classes, grpcRateLimitKey),
+		),
+		grpc.ChainStreamInterceptor(
+			tracing.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(),
+			metrics.Requests.StreamServerInterceptor(),
+			keyStore.StreamServerInterceptor(),
+			opts.RateLimiter.StreamServerInterceptor(classes, grpcRateLimitKey),
+			ratelimit.ThrottleStreamInterceptor(opts.UploadBandwidth, opts.DownloadBandwidth),
+		),
+	)
+	filepb.RegisterFileServiceServer(server, api.NewFileServer(fileUseCase))
+	return server
+}
+
+// fileUseCase returns FileUseCase, or a new usecase built from the options if it is nil.
+func (opts ServerOptions) fileUseCase(storageClient *infrastructure.StorageClient, quotaTracker *quota.Tracker, webhooks *webhook.Dispatcher) usecase.FileUseCase {
+	if opts.FileUseCase != nil {
+		return opts.FileUseCase
+	}
+	return usecase.WithTracing(usecase.NewFileUseCase(storageClient, quotaTracker, webhooks, opts.pins(), opts.Erasure))
+}
+
+// quotaTracker returns QuotaTracker, or a tracker with the QuotaLimits defaults if it is nil.
+func (opts ServerOptions) quotaTracker(storageClient *infrastructure.StorageClient) *quota.Tracker {
+	if opts.QuotaTracker != nil {
+		return opts.QuotaTracker
+	}
+	return quota.NewTracker(storageClient.RedisClient, opts.QuotaLimits)
+}
 
-	return mux
+// webhooks returns Webhooks, or a new dispatcher backed by Redis if it is nil.
+func (opts ServerOptions) webhooks(storageClient *infrastructure.StorageClient) *webhook.Dispatcher {
+	if opts.Webhooks != nil {
+		return opts.Webhooks
+	}
+	return webhook.NewDispatcher(webhook.NewRedisStore(storageClient.RedisClient), webhook.Options{})
 }
 
-func CreateFileHandler(storageClient *infrastructure.StorageClient) *api.FileHandler {
-	fileUseCase := usecase.NewFileUseCase(storageClient)
-	return api.NewFileHandler(fileUseCase)
+// pins returns Pinning as a usecase.PinReplicator, which is nil rather than a nil pointer
+// when replication is disabled.
+func (opts ServerOptions) pins() usecase.PinReplicator {
+	if opts.Pinning == nil {
+		return nil
+	}
+	return opts.Pinning
+}
+
+func CreateFileHandler(storageClient *infrastructure.StorageClient, opts ServerOptions) *api.FileHandler {
+	quotaTracker := quota.NewTracker(storageClient.RedisClient, opts.QuotaLimits)
+	fileUseCase := usecase.WithTracing(usecase.NewFileUseCase(storageClient, quotaTracker, nil, opts.pins(), opts.Erasure))
+	return api.NewFileHandler(fileUseCase, opts.URLSigner)
+}
+
+// newRateLimiter builds the per-client limiter. Buckets live in Redis so that every
+// replica enforces the same budget.
+func newRateLimiter(redisClient infrastructure.RedisClient, limits map[string]ratelimit.Limit) *ratelimit.Limiter {
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if scripter, ok := redisClient.(redis.Scripter); ok {
+		store = ratelimit.NewRedisStore(scripter)
+	}
+	return ratelimit.NewLimiter(store, limits, rateLimitKey)
+}
+
+// rateLimitKey accounts requests with an API key to its tenant and all others to the client IP.
+func rateLimitKey(r *http.Request) string {
+	if tenantID, ok := auth.TenantFromContext(r.Context()); ok {
+		return "tenant:" + tenantID
+	}
+	return "ip:" + ratelimit.ClientIP(r)
+}
+
+// grpcRateLimitKey is the gRPC counterpart of rateLimitKey.
+func grpcRateLimitKey(ctx context.Context) string {
+	if tenantID, ok := auth.TenantFromContext(ctx); ok {
+		return "tenant:" + tenantID
+	}
+	return "ip:" + ratelimit.PeerIP(ctx)
+}
+
+// newURLSigner builds the signer for stateless download URLs from a "kid:secret,kid:secret@expiry,..."
+// key list. The first key signs new URLs; the remaining keys are accepted until their expiry, and
+// keys that expired before now are dropped. Without configured keys a random per-process key is
+// used, which does not work across replicas. Keys are not rotated at runtime, so there is no grace period.
+func newURLSigner(keySpec string, now time.Time) (*signedurl.Signer, error) {
+	if keySpec == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		slog.Warn("downloadUrls.keys not set, using an ephemeral signing key")
+		keys, err := signedurl.NewKeySet(signedurl.Key{ID: "ephemeral", Secret: secret}, 0)
+		if err != nil {
+			return nil, err
+		}
+		return signedurl.NewSigner(keys), nil
+	}
+
+	parsed, err := signedurl.ParseKeys(keySpec)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := signedurl.NewKeySet(parsed[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range parsed[1:] {
+		if !now.Before(key.Expires) {
+			slog.Warn("Retired download URL key has expired and can be removed", "kid", key.ID, "expired", key.Expires)
+			continue
+		}
+		if err := keys.Retire(key, key.Expires); err != nil {
+			return nil, err
+		}
+	}
+	return signedurl.NewSigner(keys), nil
 }
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"decentralstore/file-service/internal/infrastructure"
 	"decentralstore/file-service/internal/mocks"
+	"decentralstore/webhook"
 )
 
 func TestSetupRoutes(t *testing.T) {
@@ -18,7 +21,7 @@ func TestSetupRoutes(t *testing.T) {
 		RedisClient: mockRedisClient,
 	}
 
-	router := SetupRoutes(storageClient)
+	router := SetupRoutes(storageClient, ServerOptions{Webhooks: newMemoryWebhooks(t)})
 
 	testServer := httptest.NewServer(router)
 	defer testServer.Close()
@@ -43,9 +46,54 @@ func TestCreateFileHandler(t *testing.T) {
 		RedisClient: mockRedisClient,
 	}
 
-	handler := CreateFileHandler(storageClient)
+	handler := CreateFileHandler(storageClient, ServerOptions{Webhooks: newMemoryWebhooks(t)})
 
 	if handler == nil {
 		t.Error("Expected non-nil FileHandler")
 	}
 }
+
+// newMemoryWebhooks keeps the webhook queue away from the Redis mock, which the
+// dispatcher would otherwise scan in the background.
+func newMemoryWebhooks(t *testing.T) *webhook.Dispatcher {
+	webhooks := webhook.NewDispatcher(webhook.NewMemoryStore(), webhook.Options{})
+	t.Cleanup(func() { webhooks.Close(context.Background()) })
+	return webhooks
+}
+
+func TestNewURLSigner(t *testing.T) {
+	now := time.Now()
+	expiry := now.Add(time.Hour).UTC().Format(time.RFC3339)
+	signer, err := newURLSigner("new:0123456789abcdef,old:fedcba9876543210@"+expiry, now)
+	if err != nil {
+		t.Fatalf("Failed to create URL signer: %v", err)
+	}
+
+	query := signer.Sign("123", now.Add(time.Minute))
+	if query.Get("kid") != "new" {
+		t.Errorf("Expected URLs to be signed with the first key; got %q", query.Get("kid"))
+	}
+
+	old, err := newURLSigner("old:fedcba9876543210", now)
+	if err != nil {
+		t.Fatalf("Failed to create URL signer: %v", err)
+	}
+	if _, err := signer.Verify(old.Sign("123", now.Add(time.Minute))); err != nil {
+		t.Errorf("Expected the retired key to be accepted before its expiry; got %v", err)
+	}
+
+	// Restarting after the expiry drops the key instead of extending it.
+	restarted, err := newURLSigner("new:0123456789abcdef,old:fedcba9876543210@"+expiry, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create URL signer: %v", err)
+	}
+	if _, err := restarted.Verify(old.Sign("123", now.Add(3*time.Hour))); err == nil {
+		t.Error("Expected the expired key to be rejected")
+	}
+
+	for _, spec := range []string{"not-a-key", "new:0123456789abcdef,old:fedcba9876543210", "new:0123456789abcdef,old:fedcba9876543210@tomorrow"} {
+		if _, err := newURLSigner(spec, now); err == nil {
+			t.Errorf("Expected an error for the key list %q", spec)
+		}
+	}
+}
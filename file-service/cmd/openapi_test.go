@@ -0,0 +1,271 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/apispec"
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/openapi"
+)
+
+// contractServer serves the real routes and checks every response against the
+// OpenAPI document, recording which operations were exercised.
+type contractServer struct {
+	*httptest.Server
+	doc *openapi.Document
+
+	mu        sync.Mutex
+	exercised map[string]bool
+}
+
+func newContractServer(t *testing.T, opts ServerOptions) *contractServer {
+	t.Helper()
+	routes := newTestRoutes(t, opts)
+	s := &contractServer{doc: apispec.MustLoad(), exercised: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, r)
+
+		if err := s.doc.ValidateResponse(r.Method, r.URL.Path, rr.Code, rr.Header(), rr.Body.Bytes()); err != nil {
+			t.Errorf("Response does not match the OpenAPI document: %v", err)
+		}
+		s.mu.Lock()
+		s.exercised[r.Method+" "+r.URL.Path] = true
+		s.mu.Unlock()
+
+		for name, values := range rr.Header() {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(rr.Code)
+		w.Write(rr.Body.Bytes())
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// call sends a request and returns the response with its body decoded into out, if given.
+func (s *contractServer) call(t *testing.T, method, path string, header http.Header, body string, wantStatus int, out any) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: expected status %d; got %v", method, path, wantStatus, resp.Status)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func TestOpenAPI_Contract(t *testing.T) {
+	signer, err := newURLSigner("", time.Now())
+	if err != nil {
+		t.Fatalf("Failed to create URL signer: %v", err)
+	}
+	s := newContractServer(t, ServerOptions{AdminToken: testAdminToken, URLSigner: signer})
+	admin := http.Header{"Authorization": {"Bearer " + testAdminToken}}
+	jsonBody := http.Header{"Content-Type": {"application/json"}}
+
+	var key struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+	s.call(t, "POST", "/admin/keys", admin, `{"tenantId": "acme", "name": "ci"}`, http.StatusCreated, &key)
+	s.call(t, "GET", "/admin/keys?tenantId=acme", admin, "", http.StatusOK, nil)
+	s.call(t, "GET", "/admin/keys?tenantId=acme", nil, "", http.StatusUnauthorized, nil)
+	tenant := http.Header{auth.APIKeyHeader: {key.Key}}
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+	var subscription struct {
+		ID string `json:"id"`
+	}
+	s.call(t, "POST", "/webhooks", mergeHeaders(tenant, jsonBody), `{"url": "`+receiver.URL+`", "events": ["*"]}`, http.StatusCreated, &subscription)
+	s.call(t, "POST", "/webhooks", mergeHeaders(tenant, jsonBody), `{"url": "ftp://example.com"}`, http.StatusBadRequest, nil)
+	s.call(t, "GET", "/webhooks", tenant, "", http.StatusOK, nil)
+	s.call(t, "GET", "/webhooks", nil, "", http.StatusUnauthorized, nil)
+
+	resp := uploadFile(t, s.Server, key.Key, "test content")
+	var file struct {
+		ID              string `json:"id"`
+		DownloadKeyword string `json:"downloadKeyword"`
+		DeleteKeyword   string `json:"deleteKeyword"`
+	}
+	json.NewDecoder(resp.Body).Decode(&file)
+	resp.Body.Close()
+	uploadFile(t, s.Server, "", "test content").Body.Close()
+
+	sum := sha256.Sum256([]byte("test content"))
+	check := `{"sha256": "` + hex.EncodeToString(sum[:]) + `", "name": "copy.txt"}`
+	s.call(t, "POST", "/upload/check", mergeHeaders(tenant, jsonBody), check, http.StatusOK, nil)
+	s.call(t, "POST", "/upload/check", mergeHeaders(tenant, jsonBody), `{"sha256": "`+strings.Repeat("0", 64)+`", "name": "copy.txt"}`, http.StatusNotFound, nil)
+	s.call(t, "POST", "/upload/check", mergeHeaders(tenant, jsonBody), `{"name": "copy.txt"}`, http.StatusBadRequest, nil)
+	s.call(t, "POST", "/upload/check", jsonBody, check, http.StatusUnauthorized, nil)
+	s.call(t, "GET", "/files", tenant, "", http.StatusOK, nil)
+	s.call(t, "GET", "/files", nil, "", http.StatusUnauthorized, nil)
+	s.call(t, "GET", "/usage", tenant, "", http.StatusOK, nil)
+
+	download := "/download?" + url.Values{"id": {file.ID}, "keyword": {file.DownloadKeyword}}.Encode()
+	s.call(t, "GET", download, tenant, "", http.StatusOK, nil)
+	s.call(t, "GET", download, nil, "", http.StatusNotFound, nil)
+	s.call(t, "GET", download, mergeHeaders(tenant, http.Header{"Range": {"bytes=5-"}}), "", http.StatusPartialContent, nil)
+	s.call(t, "GET", download, mergeHeaders(tenant, http.Header{"Range": {"bytes=12-"}}), "", http.StatusRequestedRangeNotSatisfiable, nil)
+	s.call(t, "GET", download+"&format=tar", tenant, "", http.StatusOK, nil)
+	s.call(t, "GET", download+"&format=zip", tenant, "", http.StatusOK, nil)
+	s.call(t, "GET", download+"&path=a.txt", tenant, "", http.StatusNotFound, nil)
+	bundle := `{"format": "tar", "files": [{"id": "` + file.ID + `", "keyword": "` + file.DownloadKeyword + `"}, {"id": "missing", "keyword": "x"}]}`
+	s.call(t, "POST", "/download/bundle", mergeHeaders(tenant, jsonBody), bundle, http.StatusOK, nil)
+	s.call(t, "POST", "/download/bundle", jsonBody, `{"files": []}`, http.StatusBadRequest, nil)
+	s.call(t, "POST", "/export", mergeHeaders(tenant, jsonBody), `{"files": [{"id": "`+file.ID+`", "keyword": "wrong"}]}`, http.StatusUnauthorized, nil)
+	s.call(t, "POST", "/export", jsonBody, `{"version": 3, "files": []}`, http.StatusBadRequest, nil)
+	carBody := http.Header{"Content-Type": {"application/vnd.ipld.car"}}
+	s.call(t, "POST", "/import", mergeHeaders(tenant, carBody), "not a CAR file", http.StatusBadRequest, nil)
+	s.call(t, "POST", "/import", carBody, "not a CAR file", http.StatusUnauthorized, nil)
+	s.call(t, "GET", "/download?id="+file.ID, nil, "", http.StatusBadRequest, nil)
+	s.call(t, "GET", "/download?id="+file.ID+"&keyword=wrong", tenant, "", http.StatusUnauthorized, nil)
+
+	form := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+	var signed struct {
+		URL string `json:"url"`
+	}
+	s.call(t, "POST", "/download/sign", mergeHeaders(tenant, form), url.Values{"id": {file.ID}, "keyword": {file.DownloadKeyword}, "ttl": {"60"}}.Encode(), http.StatusOK, &signed)
+	s.call(t, "GET", signed.URL, nil, "", http.StatusOK, nil)
+	s.call(t, "GET", signed.URL+"0", nil, "", http.StatusForbidden, nil)
+
+	s.call(t, "GET", "/admin/quota?tenantId=acme", admin, "", http.StatusOK, nil)
+	s.call(t, "PUT", "/admin/quota?tenantId=acme", mergeHeaders(admin, jsonBody), `{"maxBytes": 1000, "maxFiles": 10}`, http.StatusOK, nil)
+	s.call(t, "PUT", "/admin/quota?tenantId=acme", mergeHeaders(admin, jsonBody), `{"maxBytes": -1}`, http.StatusBadRequest, nil)
+	s.call(t, "POST", "/admin/usage/recompute?tenantId=acme", admin, "", http.StatusOK, nil)
+
+	s.call(t, "DELETE", "/delete?id="+file.ID+"&keyword="+file.DeleteKeyword, tenant, "", http.StatusOK, nil)
+	s.call(t, "DELETE", "/delete?id="+file.ID+"&keyword="+file.DeleteKeyword, tenant, "", http.StatusNotFound, nil)
+
+	var deliveries []struct {
+		ID string `json:"id"`
+	}
+	s.call(t, "GET", "/webhooks/deliveries?subscriptionId="+subscription.ID, tenant, "", http.StatusOK, &deliveries)
+	s.call(t, "GET", "/webhooks/deliveries?subscriptionId=missing", tenant, "", http.StatusNotFound, nil)
+	if len(deliveries) == 0 {
+		t.Fatal("Expected webhook deliveries for the file events")
+	}
+	s.call(t, "POST", "/webhooks/deliveries/replay?id="+deliveries[0].ID, tenant, "", http.StatusAccepted, nil)
+	s.call(t, "POST", "/webhooks/deliveries/replay", tenant, "", http.StatusBadRequest, nil)
+	s.call(t, "DELETE", "/webhooks?id="+subscription.ID, tenant, "", http.StatusNoContent, nil)
+	s.call(t, "DELETE", "/webhooks?id="+subscription.ID, tenant, "", http.StatusNotFound, nil)
+
+	s.call(t, "POST", "/admin/keys/rotate?id="+key.ID, admin, "", http.StatusOK, nil)
+	s.call(t, "POST", "/admin/keys/revoke?id="+key.ID, admin, "", http.StatusOK, nil)
+	s.call(t, "POST", "/admin/keys/rotate?id="+key.ID, admin, "", http.StatusConflict, nil)
+	s.call(t, "POST", "/admin/keys/revoke?id=missing", admin, "", http.StatusNotFound, nil)
+
+	for _, path := range []string{"/metrics", "/healthz", "/readyz", "/openapi.json"} {
+		s.call(t, "GET", path, nil, "", http.StatusOK, nil)
+	}
+
+	var missing []string
+	for path, item := range s.doc.Paths {
+		for method := range item.Operations() {
+			if !s.exercised[method+" "+path] {
+				missing = append(missing, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("Operations not exercised by the contract test: %v", missing)
+	}
+}
+
+func mergeHeaders(headers ...http.Header) http.Header {
+	merged := http.Header{}
+	for _, h := range headers {
+		for name, values := range h {
+			merged[name] = values
+		}
+	}
+	return merged
+}
+
+// TestOpenAPI_DocumentsEveryRoute compares the routes registered in SetupRoutes with
+// the paths of the document.
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse main.go: %v", err)
+	}
+	routes := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+			return true
+		}
+		if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			path, _ := strconv.Unquote(lit.Value)
+			routes[path] = true
+		}
+		return true
+	})
+
+	doc := apispec.MustLoad()
+	for path := range routes {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("Route %s is not in the OpenAPI document", path)
+		}
+	}
+	for path := range doc.Paths {
+		if !routes[path] {
+			t.Errorf("Path %s of the OpenAPI document is not a route", path)
+		}
+	}
+}
+
+func TestOpenAPI_ValidateRequests(t *testing.T) {
+	server := newTestServerWithOptions(t, ServerOptions{AdminToken: testAdminToken, ValidateRequests: true})
+
+	req, _ := http.NewRequest("PUT", server.URL+"/admin/quota?tenantId=acme", strings.NewReader(`{"maxFiles": "ten"}`))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope httperr.Envelope
+	json.NewDecoder(resp.Body).Decode(&envelope)
+	if resp.StatusCode != http.StatusBadRequest || envelope.Error.Code != httperr.CodeInvalidRequest {
+		t.Fatalf("Expected an invalid_request error; got %v %+v", resp.Status, envelope.Error)
+	}
+	want := []httperr.Detail{{Field: "body.maxFiles", Message: "must be an integer"}}
+	if len(envelope.Error.Details) != 1 || envelope.Error.Details[0] != want[0] {
+		t.Errorf("Expected details %v; got %v", want, envelope.Error.Details)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/file-service/internal/pinning"
+	"decentralstore/file-service/internal/pinning/pinningtest"
+)
+
+func TestRemotePinning(t *testing.T) {
+	remote := pinningtest.NewServer("psa-token")
+	t.Cleanup(remote.Close)
+	replicator := pinning.NewReplicator(mocks.NewFakeRedisClient(),
+		[]pinning.Service{{Name: "remote", Endpoint: remote.URL, Token: remote.Token}},
+		pinning.Options{PollInterval: 10 * time.Millisecond})
+	t.Cleanup(func() { replicator.Close(context.Background()) })
+
+	server := newTestServerWithOptions(t, ServerOptions{AdminToken: testAdminToken, Pinning: replicator})
+	apiKey := createAPIKey(t, server, "acme")
+	file := decodeUploaded(t, uploadFile(t, server, apiKey, "test content"))
+
+	var pins []domain.RemotePin
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		files := listFiles(t, server, apiKey)
+		if len(files) != 1 {
+			t.Fatalf("Expected one file; got %+v", files)
+		}
+		pins = files[0].RemotePins
+		if len(pins) == 1 && pins[0].Status == domain.PinPinned {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the file to be pinned remotely; got %+v", pins)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pins[0].Service != "remote" || pins[0].RequestID == "" {
+		t.Errorf("Unexpected remote pin: %+v", pins[0])
+	}
+	if remotePins := remote.Pins(); len(remotePins) != 1 || remotePins[0].Pin.CID != file.CID {
+		t.Errorf("Expected the service to pin %s; got %+v", file.CID, remotePins)
+	}
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/delete?id="+file.ID+"&keyword="+file.DeleteKeyword, nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+	resp.Body.Close()
+
+	replicator.Close(context.Background())
+	if remotePins := remote.Pins(); len(remotePins) != 0 {
+		t.Errorf("Expected the remote pin to be removed; got %+v", remotePins)
+	}
+}
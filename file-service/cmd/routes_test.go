@@ -0,0 +1,664 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/client"
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/shared/ratelimit"
+	"decentralstore/webhook"
+
+	"github.com/go-redis/redis/v8"
+	shell "github.com/ipfs/go-ipfs-api"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const testAdminToken = "admin-token"
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return newTestServerWithOptions(t, ServerOptions{AdminToken: testAdminToken})
+}
+
+func newTestServerWithOptions(t *testing.T, opts ServerOptions) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(newTestRoutes(t, opts))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestRoutes returns the routes of the service backed by in-memory IPFS and Redis fakes.
+func newTestRoutes(t *testing.T, opts ServerOptions) http.Handler {
+	t.Helper()
+
+	ipfs := &mocks.MockIPFSShell{
+		AddFn: func(r io.Reader, options ...shell.AddOpts) (string, error) {
+			io.Copy(io.Discard, r)
+			return "QmTest123", nil
+		},
+		CatFn: func(path string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("test content")), nil
+		},
+		PinFn: func(path string) error {
+			return nil
+		},
+		UnpinFn: func(path string) error {
+			return nil
+		},
+		FilesStatFn: func(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error) {
+			return &shell.FilesStatObject{Type: "file", Size: uint64(len("test content"))}, nil
+		},
+		VersionFn: func() (string, string, error) {
+			return "0.27.0", "", nil
+		},
+	}
+	storageClient := &infrastructure.StorageClient{
+		IPFSShell:   infrastructure.InstrumentIPFSShell(ipfs),
+		RedisClient: mocks.NewFakeRedisClient(),
+	}
+	if opts.Webhooks == nil {
+		// Receivers in the tests listen on loopback.
+		opts.Webhooks = webhook.NewDispatcher(webhook.NewRedisStore(storageClient.RedisClient), webhook.Options{AllowPrivateAddresses: true})
+		t.Cleanup(func() { opts.Webhooks.Close(context.Background()) })
+	}
+
+	return SetupRoutes(storageClient, opts)
+}
+
+// newFakeIPFSServer returns a server backed by a content-addressed fake IPFS and an in-memory Redis.
+func newFakeIPFSServer(t *testing.T, opts ServerOptions) (*httptest.Server, *mocks.FakeIPFS) {
+	t.Helper()
+	ipfs := mocks.NewFakeIPFS()
+	return newTestServerWithShell(t, ipfs.Shell(), opts), ipfs
+}
+
+// newTestServerWithShell returns a server backed by ipfsShell and an in-memory Redis.
+func newTestServerWithShell(t *testing.T, ipfsShell infrastructure.IPFSShell, opts ServerOptions) *httptest.Server {
+	t.Helper()
+	return newTestServerWithStorage(t, &infrastructure.StorageClient{
+		IPFSShell:   infrastructure.InstrumentIPFSShell(ipfsShell),
+		RedisClient: mocks.NewFakeRedisClient(),
+	}, opts)
+}
+
+// newTestServerWithStorage returns a server backed by storageClient. The admin token
+// defaults to testAdminToken.
+func newTestServerWithStorage(t *testing.T, storageClient *infrastructure.StorageClient, opts ServerOptions) *httptest.Server {
+	t.Helper()
+	if opts.AdminToken == "" {
+		opts.AdminToken = testAdminToken
+	}
+	server := httptest.NewServer(SetupRoutes(storageClient, opts))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func createAPIKey(t *testing.T, server *httptest.Server, tenantID string) string {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]string{"tenantId": tenantID, "name": "test"})
+	req, _ := http.NewRequest("POST", server.URL+"/admin/keys", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status Created; got %v", resp.Status)
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	return created.Key
+}
+
+func uploadFile(t *testing.T, server *httptest.Server, apiKey, content string) *http.Response {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "test.txt")
+	io.Copy(part, strings.NewReader(content))
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+	return resp
+}
+
+func listFiles(t *testing.T, server *httptest.Server, apiKey string) []domain.File {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", server.URL+"/files", nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var files []domain.File
+	json.NewDecoder(resp.Body).Decode(&files)
+	return files
+}
+
+func TestUpload_RequiresAPIKey(t *testing.T) {
+	server := newTestServer(t)
+
+	resp := uploadFile(t, server, "", "test content")
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status Unauthorized; got %v", resp.Status)
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	server := newTestServer(t)
+	acmeKey := createAPIKey(t, server, "acme")
+	globexKey := createAPIKey(t, server, "globex")
+
+	resp := uploadFile(t, server, acmeKey, "test content")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+	var uploaded domain.File
+	json.NewDecoder(resp.Body).Decode(&uploaded)
+	if uploaded.TenantID != "acme" {
+		t.Errorf("Expected upload to be attributed to acme; got %q", uploaded.TenantID)
+	}
+
+	if files := listFiles(t, server, acmeKey); len(files) != 1 || files[0].ID != uploaded.ID {
+		t.Errorf("Expected acme to list its file; got %+v", files)
+	}
+	if files := listFiles(t, server, globexKey); len(files) != 0 {
+		t.Errorf("Expected globex to see no files; got %+v", files)
+	}
+
+	for name, apiKey := range map[string]string{"globex": globexKey, "anonymous": ""} {
+		if resp, _ := downloadFrom(t, server, apiKey, uploaded, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected %s's download by keyword to get Not Found; got %v", name, resp.Status)
+		}
+	}
+
+	deleteURL := server.URL + "/delete?id=" + uploaded.ID + "&keyword=" + uploaded.DeleteKeyword
+	req, _ := http.NewRequest("DELETE", deleteURL, nil)
+	req.Header.Set(auth.APIKeyHeader, globexKey)
+	deleteResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	deleteResp.Body.Close()
+	if deleteResp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected another tenant's delete to get Not Found; got %v", deleteResp.Status)
+	}
+
+	req, _ = http.NewRequest("DELETE", deleteURL, nil)
+	req.Header.Set(auth.APIKeyHeader, acmeKey)
+	deleteResp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	deleteResp.Body.Close()
+	if deleteResp.StatusCode != http.StatusOK {
+		t.Errorf("Expected owner's delete to succeed; got %v", deleteResp.Status)
+	}
+	if files := listFiles(t, server, acmeKey); len(files) != 0 {
+		t.Errorf("Expected deleted file to disappear from the listing; got %+v", files)
+	}
+}
+
+func TestMetadata_NamespacedByTenant(t *testing.T) {
+	redisClient := mocks.NewFakeRedisClient()
+	server := newTestServerWithStorage(t, &infrastructure.StorageClient{IPFSShell: mocks.NewFakeIPFS().Shell(), RedisClient: redisClient}, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+	ctx := context.Background()
+
+	uploaded := decodeUploaded(t, uploadFile(t, server, apiKey, "test content"))
+	if _, err := redisClient.Get(ctx, "tenant:acme:file:"+uploaded.ID).Result(); err != nil {
+		t.Fatalf("Expected the record in the tenant's namespace: %v", err)
+	}
+	if _, err := redisClient.Get(ctx, "file:"+uploaded.ID).Result(); err == nil {
+		t.Errorf("Expected no record outside the tenant's namespace")
+	}
+
+	// Records stored before they were namespaced are still found, and moved on first read.
+	legacy := domain.File{ID: "legacy", Name: "old.txt", Size: uploaded.Size, CID: uploaded.CID, TenantID: "acme", DownloadKeyword: "dk", DeleteKeyword: "del"}
+	data, _ := json.Marshal(legacy)
+	redisClient.Set(ctx, "file:legacy", string(data), 0)
+	redisClient.SAdd(ctx, "tenant:acme:files", "legacy")
+	if files := listFiles(t, server, apiKey); len(files) != 2 {
+		t.Fatalf("Expected the legacy file to be listed; got %+v", files)
+	}
+	if _, err := redisClient.Get(ctx, "tenant:acme:file:legacy").Result(); err != nil {
+		t.Errorf("Expected the legacy record to be moved: %v", err)
+	}
+	if resp, body := downloadFrom(t, server, apiKey, legacy, nil); resp.StatusCode != http.StatusOK || string(body) != "test content" {
+		t.Errorf("Expected the legacy file to download; got %v: %q", resp.Status, body)
+	}
+
+	deleteFile(t, server, apiKey, legacy)
+	if owner, _ := redisClient.HGet(ctx, "file:owners", "legacy").Result(); owner != "" {
+		t.Errorf("Expected the owner to be removed with the file; got %q", owner)
+	}
+}
+
+func getUsage(t *testing.T, server *httptest.Server, apiKey string) domain.UsageReport {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", server.URL+"/usage", nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var report domain.UsageReport
+	json.NewDecoder(resp.Body).Decode(&report)
+	return report
+}
+
+func TestQuota_ByteLimit(t *testing.T) {
+	server := newTestServerWithOptions(t, ServerOptions{
+		AdminToken:  testAdminToken,
+		QuotaLimits: domain.Limits{MaxBytes: 16},
+	})
+	apiKey := createAPIKey(t, server, "acme")
+
+	resp := uploadFile(t, server, apiKey, "0123456789")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+
+	resp = uploadFile(t, server, apiKey, "0123456789")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status Request Entity Too Large; got %v", resp.Status)
+	}
+
+	report := getUsage(t, server, apiKey)
+	if report.Usage.Bytes != 10 || report.Usage.Files != 1 {
+		t.Errorf("Expected the rejected upload to be released; got %+v", report.Usage)
+	}
+}
+
+func TestQuota_FileLimitAndRelease(t *testing.T) {
+	server := newTestServerWithOptions(t, ServerOptions{
+		AdminToken:  testAdminToken,
+		QuotaLimits: domain.Limits{MaxFiles: 1},
+	})
+	apiKey := createAPIKey(t, server, "acme")
+
+	resp := uploadFile(t, server, apiKey, "test content")
+	var uploaded domain.File
+	json.NewDecoder(resp.Body).Decode(&uploaded)
+	resp.Body.Close()
+
+	resp = uploadFile(t, server, apiKey, "test content")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status Too Many Requests; got %v", resp.Status)
+	}
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/delete?id="+uploaded.ID+"&keyword="+uploaded.DeleteKeyword, nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	deleteResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	deleteResp.Body.Close()
+
+	if report := getUsage(t, server, apiKey); report.Usage != (domain.Usage{}) {
+		t.Errorf("Expected usage to drop to zero after delete; got %+v", report.Usage)
+	}
+
+	resp = uploadFile(t, server, apiKey, "test content")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected upload after delete to succeed; got %v", resp.Status)
+	}
+}
+
+// failingRedis fails SAdd to the keys for which fail returns true.
+type failingRedis struct {
+	*mocks.FakeRedisClient
+	fail func(key string) bool
+}
+
+func (f *failingRedis) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	if f.fail != nil && f.fail(key) {
+		return redis.NewIntResult(0, errors.New("connection reset"))
+	}
+	return f.FakeRedisClient.SAdd(ctx, key, members...)
+}
+
+func TestUpload_IndexFailureReleasesQuotaAndPin(t *testing.T) {
+	redisClient := &failingRedis{FakeRedisClient: mocks.NewFakeRedisClient()}
+	ipfsShell := mocks.NewFakeIPFS().Shell()
+	var unpinned []string
+	unpin := ipfsShell.UnpinFn
+	ipfsShell.UnpinFn = func(path string) error {
+		unpinned = append(unpinned, path)
+		return unpin(path)
+	}
+	server := newTestServerWithStorage(t, &infrastructure.StorageClient{
+		IPFSShell:   infrastructure.InstrumentIPFSShell(ipfsShell),
+		RedisClient: redisClient,
+	}, ServerOptions{})
+	apiKey := createAPIKey(t, server, "acme")
+
+	redisClient.fail = func(key string) bool { return key == "tenant:acme:files" }
+	resp := uploadFile(t, server, apiKey, "test content")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected the upload to fail; got %v", resp.Status)
+	}
+	redisClient.fail = nil
+
+	if report := getUsage(t, server, apiKey); report.Usage != (domain.Usage{}) {
+		t.Errorf("Expected the quota to be released; got %+v", report.Usage)
+	}
+	if len(unpinned) != 1 {
+		t.Errorf("Expected the content to be unpinned; got %v", unpinned)
+	}
+	refs := redisClient.HGetAll(context.Background(), "cid:refs").Val()
+	for cid, n := range refs {
+		if n != "0" {
+			t.Errorf("Expected no references to %s; got %s", cid, n)
+		}
+	}
+	if keys := redisClient.HGetAll(context.Background(), "file:owners").Val(); len(keys) != 0 {
+		t.Errorf("Expected no file owners; got %v", keys)
+	}
+	if files := listFiles(t, server, apiKey); len(files) != 0 {
+		t.Errorf("Expected no files; got %+v", files)
+	}
+}
+
+func TestRateLimit_Upload(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(),
+		map[string]ratelimit.Limit{ratelimit.ClassUpload: {Rate: 1.0 / 60, Burst: 1}}, rateLimitKey)
+	server := newTestServerWithOptions(t, ServerOptions{AdminToken: testAdminToken, RateLimiter: limiter})
+	acmeKey := createAPIKey(t, server, "acme")
+	globexKey := createAPIKey(t, server, "globex")
+
+	resp := uploadFile(t, server, acmeKey, "test content")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+
+	resp = uploadFile(t, server, acmeKey, "test content")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status Too Many Requests; got %v", resp.Status)
+	}
+	if resp.Header.Get("Retry-After") != "60" {
+		t.Errorf("Expected Retry-After 60; got %q", resp.Header.Get("Retry-After"))
+	}
+
+	resp = uploadFile(t, server, globexKey, "test content")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected another tenant to have its own budget; got %v", resp.Status)
+	}
+}
+
+func TestRateLimit_Read(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(),
+		map[string]ratelimit.Limit{ratelimit.ClassRead: {Rate: 1.0 / 60, Burst: 1}}, rateLimitKey)
+	server := newTestServerWithOptions(t, ServerOptions{AdminToken: testAdminToken, RateLimiter: limiter})
+	apiKey := createAPIKey(t, server, "acme")
+
+	listFiles(t, server, apiKey)
+	req, _ := http.NewRequest("GET", server.URL+"/usage", nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected listings and usage to share the read budget; got %v", resp.Status)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	server := newTestServer(t)
+	apiKey := createAPIKey(t, server, "acme")
+
+	resp := uploadFile(t, server, apiKey, "test content")
+	resp.Body.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`file_service_http_requests_total{method="POST",route="/upload",status="200"}`,
+		`file_service_ipfs_operation_duration_seconds_count{operation="add"}`,
+		`file_service_uploaded_bytes_total`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %s", want)
+		}
+	}
+}
+
+func TestTracing_UploadSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := newTestServer(t)
+	apiKey := createAPIKey(t, server, "acme")
+	exporter.Reset()
+
+	resp := uploadFile(t, server, apiKey, "test content")
+	resp.Body.Close()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range exporter.GetSpans().Snapshots() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"POST /upload", "FileUseCase.UploadFile", "ipfs.add"} {
+		if _, ok := spans[name]; !ok {
+			t.Fatalf("Expected span %q; got %v", name, spans)
+		}
+	}
+	if spans["ipfs.add"].Parent().SpanID() != spans["FileUseCase.UploadFile"].SpanContext().SpanID() {
+		t.Errorf("Expected ipfs.add to be a child of the usecase span")
+	}
+	if spans["FileUseCase.UploadFile"].Parent().SpanID() != spans["POST /upload"].SpanContext().SpanID() {
+		t.Errorf("Expected the usecase span to be a child of the request span")
+	}
+}
+
+func TestProbes(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected %s to return OK; got %v", path, resp.Status)
+		}
+	}
+}
+
+func TestClient_RoundTrip(t *testing.T) {
+	server := newTestServer(t)
+	c, err := client.New(server.URL, client.WithAPIKey(createAPIKey(t, server, "acme")))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	uploaded, err := c.Upload(ctx, strings.NewReader("test content"), "hello.txt")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if uploaded.Name != "hello.txt" || uploaded.TenantID != "acme" {
+		t.Errorf("Unexpected upload result %+v", uploaded)
+	}
+
+	body, err := c.Download(ctx, uploaded.ID, uploaded.DownloadKeyword)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	content, _ := io.ReadAll(body)
+	body.Close()
+	if string(content) != "test content" {
+		t.Errorf("Expected downloaded content; got %q", content)
+	}
+
+	if _, err := c.Download(ctx, uploaded.ID, "wrong"); !errors.Is(err, client.ErrInvalidKeyword) {
+		t.Errorf("Expected ErrInvalidKeyword; got %v", err)
+	}
+	if err := c.Delete(ctx, uploaded.ID, uploaded.DeleteKeyword); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := c.Delete(ctx, uploaded.ID, uploaded.DeleteKeyword); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete; got %v", err)
+	}
+}
+
+func TestDownload_Resume(t *testing.T) {
+	server := newTestServer(t)
+	c, err := client.New(server.URL, client.WithAPIKey(createAPIKey(t, server, "acme")))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	uploaded, err := c.Upload(ctx, strings.NewReader("test content"), "hello.txt")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	download, err := c.DownloadFrom(ctx, uploaded.ID, uploaded.DownloadKeyword, 5)
+	if err != nil {
+		t.Fatalf("DownloadFrom failed: %v", err)
+	}
+	rest, _ := io.ReadAll(download.Body)
+	download.Body.Close()
+	if download.Offset != 5 || download.Size != 12 || string(rest) != "content" {
+		t.Errorf("Expected the rest of the file from offset 5; got offset %d, size %d, %q", download.Offset, download.Size, rest)
+	}
+
+	_, err = c.DownloadFrom(ctx, uploaded.ID, uploaded.DownloadKeyword, 12)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("Expected 416 for an offset at the end of the file; got %v", err)
+	}
+	var rangeErr *client.RangeError
+	if !errors.As(err, &rangeErr) || rangeErr.Size != 12 {
+		t.Errorf("Expected the size of the file with the 416; got %v", err)
+	}
+}
+
+func TestWebhooks_FileEvents(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	events := make(chan received, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		events <- received{header: r.Header, body: body}
+	}))
+	t.Cleanup(receiver.Close)
+
+	webhooks := webhook.NewDispatcher(webhook.NewMemoryStore(), webhook.Options{AllowPrivateAddresses: true})
+	t.Cleanup(func() { webhooks.Close(context.Background()) })
+	server := newTestServerWithOptions(t, ServerOptions{AdminToken: testAdminToken, Webhooks: webhooks})
+	apiKey := createAPIKey(t, server, "acme")
+
+	body := `{"url": "` + receiver.URL + `", "events": ["file.uploaded", "file.deleted"]}`
+	req, _ := http.NewRequest("POST", server.URL+"/webhooks", strings.NewReader(body))
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status Created; got %v", resp.Status)
+	}
+	var sub struct {
+		Secret string `json:"secret"`
+	}
+	json.NewDecoder(resp.Body).Decode(&sub)
+
+	resp = uploadFile(t, server, apiKey, "test content")
+	var file domain.File
+	json.NewDecoder(resp.Body).Decode(&file)
+	resp.Body.Close()
+
+	// Downloads are not subscribed to, so the next event is the deletion.
+	resp, _ = http.Get(server.URL + "/download?id=" + file.ID + "&keyword=" + file.DownloadKeyword)
+	resp.Body.Close()
+	req, _ = http.NewRequest("DELETE", server.URL+"/delete?id="+file.ID+"&keyword="+file.DeleteKeyword, nil)
+	req.Header.Set(auth.APIKeyHeader, apiKey)
+	resp, _ = http.DefaultClient.Do(req)
+	resp.Body.Close()
+
+	for _, want := range []string{domain.EventFileUploaded, domain.EventFileDeleted} {
+		var got received
+		select {
+		case got = <-events:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected a %s webhook", want)
+		}
+		if err := webhook.Verify(sub.Secret, got.header.Get(webhook.HeaderSignature), got.body, time.Minute, time.Now()); err != nil {
+			t.Errorf("Invalid signature of the %s webhook: %v", want, err)
+		}
+
+		var event struct {
+			Type string           `json:"type"`
+			Data domain.FileEvent `json:"data"`
+		}
+		json.Unmarshal(got.body, &event)
+		if event.Type != want || event.Data.ID != file.ID || event.Data.TenantID != "acme" {
+			t.Errorf("Expected a %s event of file %s; got %s", want, file.ID, got.body)
+		}
+		if bytes.Contains(got.body, []byte(file.DeleteKeyword)) {
+			t.Errorf("Webhook must not contain keywords; got %s", got.body)
+		}
+	}
+}
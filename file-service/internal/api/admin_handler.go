@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/quota"
+	"decentralstore/file-service/internal/usecase"
+	"decentralstore/shared/httperr"
+)
+
+type AdminHandler struct {
+	keyStore    *auth.KeyStore
+	quota       *quota.Tracker
+	fileUseCase usecase.FileUseCase
+}
+
+func NewAdminHandler(keyStore *auth.KeyStore, quotaTracker *quota.Tracker, fileUseCase usecase.FileUseCase) *AdminHandler {
+	return &AdminHandler{keyStore: keyStore, quota: quotaTracker, fileUseCase: fileUseCase}
+}
+
+type issuedKeyResponse struct {
+	*auth.APIKey
+	Key string `json:"key"`
+}
+
+// Keys creates a key (POST) or lists a tenant's keys (GET ?tenantId=).
+func (h *AdminHandler) Keys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.createKey(w, r)
+	case http.MethodGet:
+		h.listKeys(w, r)
+	default:
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *AdminHandler) createKey(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TenantID string `json:"tenantId"`
+		Name     string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	secret, key, err := h.keyStore.Create(r.Context(), request.TenantID, request.Name)
+	if errors.Is(err, auth.ErrInvalidTenantID) {
+		httperr.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		httperr.Error(w, r, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(issuedKeyResponse{APIKey: key, Key: secret})
+}
+
+func (h *AdminHandler) listKeys(w http.ResponseWriter, r *http.Request) {
+	tenantID := r.URL.Query().Get("tenantId")
+	if tenantID == "" {
+		httperr.Error(w, r, "Missing tenantId parameter", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.keyStore.List(r.Context(), tenantID)
+	if err != nil {
+		httperr.Error(w, r, "Failed to list API keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RotateKey replaces the secret of an API key and returns the new secret.
+func (h *AdminHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		httperr.Error(w, r, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	secret, key, err := h.keyStore.Rotate(r.Context(), id)
+	if err != nil {
+		httperr.Error(w, r, keyErrorMessage(err, "Failed to rotate API key"), keyErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(issuedKeyResponse{APIKey: key, Key: secret})
+}
+
+// RevokeKey permanently disables an API key.
+func (h *AdminHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		httperr.Error(w, r, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.keyStore.Revoke(r.Context(), id)
+	if err != nil {
+		httperr.Error(w, r, keyErrorMessage(err, "Failed to revoke API key"), keyErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(key)
+}
+
+// Quota returns (GET) or overrides (PUT) the limits of a tenant (?tenantId=).
+// A limit of 0 means unlimited.
+func (h *AdminHandler) Quota(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenantId")
+	if tenantID == "" {
+		httperr.Error(w, r, "Missing tenantId parameter", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var limits domain.Limits
+		if err := json.NewDecoder(r.Body).Decode(&limits); err != nil || limits.MaxBytes < 0 || limits.MaxFiles < 0 {
+			httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.quota.SetLimits(r.Context(), tenantID, limits); err != nil {
+			httperr.Error(w, r, "Failed to set quota", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	limits, err := h.quota.Limits(r.Context(), tenantID)
+	if err != nil {
+		httperr.Error(w, r, "Failed to get quota", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+// RecomputeUsage rebuilds a tenant's usage counters (?tenantId=) from its file metadata.
+func (h *AdminHandler) RecomputeUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := r.URL.Query().Get("tenantId")
+	if tenantID == "" {
+		httperr.Error(w, r, "Missing tenantId parameter", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.fileUseCase.RecomputeUsage(r.Context(), tenantID)
+	if err != nil {
+		httperr.Error(w, r, "Failed to recompute usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func keyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, auth.ErrInvalidAPIKey):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func keyErrorMessage(err error, fallback string) string {
+	if keyErrorStatus(err) == http.StatusInternalServerError {
+		return fallback
+	}
+	return err.Error()
+}
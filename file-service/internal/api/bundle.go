@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"decentralstore/file-service/internal/archive"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/shared/httperr"
+)
+
+// maxBundleFiles limits the number of files in one bundle request.
+const maxBundleFiles = 100
+
+// DownloadBundle streams the files of a list of id and keyword pairs as one tar or zip
+// archive. Files that cannot be included are listed in the archive's manifest instead.
+func (h *FileHandler) DownloadBundle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Format string              `json:"format"`
+		Files  []domain.BundleItem `json:"files"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Format == "" {
+		request.Format = archive.FormatZip
+	}
+	if request.Format != archive.FormatTar && request.Format != archive.FormatZip {
+		httperr.Error(w, r, "Invalid format", http.StatusBadRequest)
+		return
+	}
+	if len(request.Files) == 0 || len(request.Files) > maxBundleFiles {
+		httperr.Error(w, r, fmt.Sprintf("Request between 1 and %d files", maxBundleFiles), http.StatusBadRequest)
+		return
+	}
+
+	reader, err := h.fileUseCase.OpenBundle(r.Context(), request.Format, request.Files)
+	if err != nil {
+		writeError(w, r, err, "Failed to download bundle")
+		return
+	}
+	defer reader.Close()
+	writeArchive(w, "bundle."+request.Format, request.Format, reader)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"decentralstore/file-service/internal/car"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/shared/httperr"
+)
+
+// ExportCAR streams the DAGs of a list of id and keyword pairs, with their metadata, as one
+// CARv1 or CARv2 file. Unlike a bundle, the export fails if any file cannot be included.
+func (h *FileHandler) ExportCAR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Version int                 `json:"version"`
+		Files   []domain.BundleItem `json:"files"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Version == 0 {
+		request.Version = 1
+	}
+	if request.Version != 1 && request.Version != 2 {
+		httperr.Error(w, r, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	if len(request.Files) == 0 || len(request.Files) > maxBundleFiles {
+		httperr.Error(w, r, fmt.Sprintf("Request between 1 and %d files", maxBundleFiles), http.StatusBadRequest)
+		return
+	}
+
+	reader, err := h.fileUseCase.ExportCAR(r.Context(), request.Files, request.Version)
+	if err != nil {
+		writeError(w, r, err, "Failed to export files")
+		return
+	}
+	defer reader.Close()
+
+	name := "export.car"
+	if len(request.Files) == 1 {
+		name = request.Files[0].ID + ".car"
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.Header().Set("Content-Type", car.ContentType)
+	if _, err := io.Copy(w, reader); err != nil {
+		// ヘッダー送信後は失敗を伝えられないため、接続を切って不完全なCARファイルを完了扱いにさせない
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// ImportCAR stores the files of a CAR file sent as the request body. Files exported with
+// their metadata keep their names; the roots of other CAR files are stored as single files,
+// named by the query parameter name when there is only one.
+func (h *FileHandler) ImportCAR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	files, err := h.fileUseCase.ImportCAR(r.Context(), r.Body, r.URL.Query().Get("name"))
+	if err != nil {
+		writeError(w, r, err, "Failed to import CAR file")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/shared/httperr"
+)
+
+// CheckContent creates a file from content the tenant already stored, given its SHA-256 or
+// CID, so that clients can skip sending it again. Content the tenant does not have is
+// reported as not found, even when another tenant has it.
+func (h *FileHandler) CheckContent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		domain.ContentRef
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httperr.Error(w, r, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Name == "" {
+		httperr.Error(w, r, "Missing file name", http.StatusBadRequest)
+		return
+	}
+
+	claimed, err := h.fileUseCase.ClaimContent(r.Context(), request.ContentRef, request.Name)
+	if err != nil {
+		writeError(w, r, err, "Failed to check content")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claimed)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+
+	"decentralstore/file-service/internal/archive"
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/shared/httperr"
+)
+
+// uploadDirectory stores the "files" parts of a multipart upload, or the files of an
+// "archive" part, as one directory. The query parameter name names the directory.
+func (h *FileHandler) uploadDirectory(r *http.Request, reader *multipart.Reader, first *multipart.Part) (*domain.File, error) {
+	name := r.URL.Query().Get("name")
+	if first.FormName() == "files" {
+		if name == "" {
+			name = "files"
+		}
+		return h.fileUseCase.UploadDirectory(r.Context(), name, &multipartEntries{reader: reader, next: first})
+	}
+
+	format, err := archive.FormatOf(first.FileName())
+	if err != nil {
+		return nil, &domain.ErrInvalidUpload{Reason: "archives must be .tar, .tar.gz, .tgz or .zip files"}
+	}
+	if name == "" {
+		name = archive.TrimExtension(first.FileName())
+	}
+	if format == archive.FormatTar {
+		return h.fileUseCase.UploadDirectory(r.Context(), name, archive.NewTarReader(first))
+	}
+
+	// zipは末尾に索引があるため、一時ファイルに書き出してから読む
+	if _, ok := auth.TenantFromContext(r.Context()); !ok {
+		return nil, &domain.ErrUnauthenticated{Operation: "upload"}
+	}
+	spool, err := os.CreateTemp("", "upload-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool zip archive: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	size, err := io.Copy(spool, first)
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool zip archive: %w", err)
+	}
+	entries, err := archive.NewZipReader(spool, size)
+	if err != nil {
+		return nil, &domain.ErrInvalidUpload{Reason: "invalid zip archive: " + err.Error()}
+	}
+	return h.fileUseCase.UploadDirectory(r.Context(), name, entries)
+}
+
+// multipartEntries yields the "files" parts of a multipart upload, keeping the
+// directories of their file names.
+type multipartEntries struct {
+	reader *multipart.Reader
+	next   *multipart.Part
+}
+
+func (m *multipartEntries) Next() (string, io.Reader, error) {
+	part := m.next
+	m.next = nil
+	for part == nil {
+		next, err := m.reader.NextPart()
+		if err != nil {
+			return "", nil, err
+		}
+		if next.FormName() == "files" && next.FileName() != "" {
+			part = next
+		} else {
+			next.Close()
+		}
+	}
+	return entryName(part), part, nil
+}
+
+// entryName returns the file name of part as sent, including directories, which
+// multipart.Part.FileName strips.
+func entryName(part *multipart.Part) string {
+	_, params, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	if err != nil || params["filename"] == "" {
+		return part.FileName()
+	}
+	return params["filename"]
+}
+
+// wantsDirectoryDownload reports whether a download asks for one path of a directory or an archive.
+func wantsDirectoryDownload(r *http.Request) bool {
+	query := r.URL.Query()
+	return query.Get("path") != "" || query.Get("format") != ""
+}
+
+// downloadDirectory serves the path query parameter of an authorized download, or the whole
+// file as a tar or zip archive if format is set.
+func (h *FileHandler) downloadDirectory(w http.ResponseWriter, r *http.Request, fileID string) {
+	query := r.URL.Query()
+	entryPath, format := query.Get("path"), query.Get("format")
+	if entryPath != "" && format != "" {
+		httperr.Error(w, r, "Set either path or format", http.StatusBadRequest)
+		return
+	}
+
+	if entryPath != "" {
+		reader, err := h.fileUseCase.OpenEntry(r.Context(), fileID, entryPath)
+		if err != nil {
+			writeError(w, r, err, "Failed to download file")
+			return
+		}
+		defer reader.Close()
+		writeDownload(w, r, path.Base(entryPath), reader)
+		return
+	}
+
+	if format != archive.FormatTar && format != archive.FormatZip {
+		httperr.Error(w, r, "Invalid format", http.StatusBadRequest)
+		return
+	}
+	reader, err := h.fileUseCase.OpenArchive(r.Context(), fileID, format)
+	if err != nil {
+		writeError(w, r, err, "Failed to download file")
+		return
+	}
+	defer reader.Close()
+	writeArchive(w, fileID+"."+format, format, reader)
+}
+
+// writeArchive streams an archive of format to the client as the file name.
+func writeArchive(w http.ResponseWriter, name, format string, reader io.Reader) {
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.Header().Set("Content-Type", archive.ContentType(format))
+	if _, err := io.Copy(w, reader); err != nil {
+		// ヘッダー送信後は失敗を伝えられないため、接続を切って不完全なアーカイブを完了扱いにさせない
+		panic(http.ErrAbortHandler)
+	}
+}
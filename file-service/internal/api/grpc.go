@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/usecase"
+	"decentralstore/file-service/rpc/filepb"
+	"decentralstore/shared/grpcerr"
+	"decentralstore/shared/httperr"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// downloadChunkSize is the size of the chunks sent by FileServer.Download.
+const downloadChunkSize = 64 << 10
+
+var errUnexpectedName = errors.New("only the first upload message may carry the file name")
+
+// FileServer serves the FileService gRPC API over the same usecase as FileHandler.
+type FileServer struct {
+	filepb.UnimplementedFileServiceServer
+	fileUseCase usecase.FileUseCase
+}
+
+func NewFileServer(fileUseCase usecase.FileUseCase) *FileServer {
+	return &FileServer{fileUseCase: fileUseCase}
+}
+
+// Upload streams the chunks of the call into IPFS without buffering the whole file.
+func (s *FileServer) Upload(stream filepb.FileService_UploadServer) error {
+	first, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	name := first.GetName()
+	if name == "" {
+		return grpcerr.Error(http.StatusBadRequest, httperr.CodeBadRequest, "The first message must carry the file name")
+	}
+
+	uploadedFile, err := s.fileUseCase.UploadFile(stream.Context(), &uploadReader{stream: stream}, name)
+	if errors.Is(err, errUnexpectedName) {
+		return grpcerr.Error(http.StatusBadRequest, httperr.CodeBadRequest, errUnexpectedName.Error())
+	}
+	if err != nil {
+		return grpcError(err, "Failed to upload file")
+	}
+	return stream.SendAndClose(fileToProto(uploadedFile))
+}
+
+func (s *FileServer) Download(req *filepb.DownloadRequest, stream filepb.FileService_DownloadServer) error {
+	if req.GetId() == "" || req.GetKeyword() == "" {
+		return grpcerr.Error(http.StatusBadRequest, httperr.CodeBadRequest, "Missing file ID or keyword")
+	}
+
+	reader, err := s.fileUseCase.DownloadFile(stream.Context(), req.GetId(), req.GetKeyword())
+	if err != nil {
+		return grpcError(err, "Failed to download file")
+	}
+	defer reader.Close()
+
+	buf := make([]byte, downloadChunkSize)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&filepb.DownloadResponse{Chunk: buf[:n]}); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return grpcError(err, "Failed to download file")
+		}
+	}
+}
+
+// uploadReader reads the chunks of an Upload call as one stream.
+type uploadReader struct {
+	stream filepb.FileService_UploadServer
+	chunk  []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		msg, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := msg.GetData().(*filepb.UploadRequest_Name); ok {
+			return 0, errUnexpectedName
+		}
+		r.chunk = msg.GetChunk()
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+func fileToProto(file *domain.File) *filepb.File {
+	return &filepb.File{
+		Id:              file.ID,
+		Name:            file.Name,
+		Size:            file.Size,
+		Cid:             file.CID,
+		UploadedAt:      timestamppb.New(file.UploadedAt),
+		DownloadKeyword: file.DownloadKeyword,
+		DeleteKeyword:   file.DeleteKeyword,
+	}
+}
+
+// grpcError is the gRPC counterpart of writeError.
+func grpcError(err error, fallback string) error {
+	return grpcerr.Error(statusFromError(err), errorCode(err), errorMessage(err, fallback))
+}
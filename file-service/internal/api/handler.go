@@ -1,37 +1,57 @@
 package api
 
 import (
-	"io"
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"time"
 
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/signedurl"
 	"decentralstore/file-service/internal/usecase"
+	"decentralstore/shared/httperr"
+)
+
+const (
+	defaultSignedURLTTL = 15 * time.Minute
+	maxSignedURLTTL     = 24 * time.Hour
 )
 
 type FileHandler struct {
 	fileUseCase usecase.FileUseCase
+	urlSigner   *signedurl.Signer
 }
 
-func NewFileHandler(fileUseCase usecase.FileUseCase) *FileHandler {
-	return &FileHandler{fileUseCase: fileUseCase}
+func NewFileHandler(fileUseCase usecase.FileUseCase, urlSigner *signedurl.Signer) *FileHandler {
+	return &FileHandler{fileUseCase: fileUseCase, urlSigner: urlSigner}
 }
 
 func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	file, header, err := r.FormFile("file")
+	// ファイルをメモリに溜めず、クォータを確認しながら読み込む（重複排除のため単一ファイルは一時ファイルに書き出される）
+	reader, part, err := uploadPart(r)
 	if err != nil {
-		http.Error(w, "Failed to get file from form", http.StatusBadRequest)
+		httperr.Error(w, r, "Failed to get file from form", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
+	defer part.Close()
 
-	uploadedFile, err := h.fileUseCase.UploadFile(r.Context(), file, header.Filename)
+	var uploadedFile *domain.File
+	if part.FormName() == "file" {
+		uploadedFile, err = h.fileUseCase.UploadFile(r.Context(), part, part.FileName())
+	} else {
+		uploadedFile, err = h.uploadDirectory(r, reader, part)
+	}
 	if err != nil {
-		http.Error(w, "Failed to upload file", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to upload file")
 		return
 	}
 
@@ -41,33 +61,134 @@ func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 
 func (h *FileHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	fileID := r.URL.Query().Get("id")
-	keyword := r.URL.Query().Get("keyword")
+	query := r.URL.Query()
+	if signedurl.IsSigned(query) {
+		h.downloadSigned(w, r)
+		return
+	}
+
+	fileID := query.Get("id")
+	keyword := query.Get("keyword")
 
 	if fileID == "" || keyword == "" {
-		http.Error(w, "Missing file ID or keyword", http.StatusBadRequest)
+		httperr.Error(w, r, "Missing file ID or keyword", http.StatusBadRequest)
+		return
+	}
+
+	if wantsDirectoryDownload(r) {
+		if err := h.fileUseCase.AuthorizeDownload(r.Context(), fileID, keyword); err != nil {
+			writeError(w, r, err, "Failed to download file")
+			return
+		}
+		h.downloadDirectory(w, r, fileID)
 		return
 	}
 
 	reader, err := h.fileUseCase.DownloadFile(r.Context(), fileID, keyword)
 	if err != nil {
-		http.Error(w, "Failed to download file", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to download file")
 		return
 	}
 	defer reader.Close()
 
-	w.Header().Set("Content-Disposition", "attachment; filename="+fileID)
+	writeDownload(w, r, fileID, reader)
+}
+
+func (h *FileHandler) downloadSigned(w http.ResponseWriter, r *http.Request) {
+	if h.urlSigner == nil {
+		httperr.Error(w, r, "Signed URLs are not enabled", http.StatusForbidden)
+		return
+	}
+
+	fileID, err := h.urlSigner.Verify(r.URL.Query())
+	if err != nil {
+		httperr.Error(w, r, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if wantsDirectoryDownload(r) {
+		h.downloadDirectory(w, r, fileID)
+		return
+	}
+
+	reader, err := h.fileUseCase.OpenFile(r.Context(), fileID)
+	if err != nil {
+		writeError(w, r, err, "Failed to download file")
+		return
+	}
+	defer reader.Close()
+
+	writeDownload(w, r, fileID, reader)
+}
+
+// SignDownloadURL validates the download keyword and returns a time-limited URL
+// that authorizes the download without the keyword.
+func (h *FileHandler) SignDownloadURL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.urlSigner == nil {
+		httperr.Error(w, r, "Signed URLs are not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	fileID := r.FormValue("id")
+	keyword := r.FormValue("keyword")
+
+	if fileID == "" || keyword == "" {
+		httperr.Error(w, r, "Missing file ID or keyword", http.StatusBadRequest)
+		return
+	}
+
+	ttl := defaultSignedURLTTL
+	if v := r.FormValue("ttl"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			httperr.Error(w, r, "Invalid ttl", http.StatusBadRequest)
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+		if ttl > maxSignedURLTTL {
+			ttl = maxSignedURLTTL
+		}
+	}
+
+	if err := h.fileUseCase.AuthorizeDownload(r.Context(), fileID, keyword); err != nil {
+		writeError(w, r, err, "Failed to sign download URL")
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	query := h.urlSigner.Sign(fileID, expiresAt)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":       "/download?" + query.Encode(),
+		"expiresAt": expiresAt.UTC().Truncate(time.Second),
+	})
+}
+
+// writeDownload streams reader to the client as the file name. Seekable content is served with
+// http.ServeContent, which answers Range requests so interrupted downloads can resume.
+func writeDownload(w http.ResponseWriter, r *http.Request, name string, reader io.Reader) {
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
 	w.Header().Set("Content-Type", "application/octet-stream")
+	if content, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, name, time.Time{}, content)
+		return
+	}
 	io.Copy(w, reader)
 }
 
 func (h *FileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -75,16 +196,126 @@ func (h *FileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 	keyword := r.URL.Query().Get("keyword")
 
 	if fileID == "" || keyword == "" {
-		http.Error(w, "Missing file ID or keyword", http.StatusBadRequest)
+		httperr.Error(w, r, "Missing file ID or keyword", http.StatusBadRequest)
 		return
 	}
 
 	err := h.fileUseCase.DeleteFile(r.Context(), fileID, keyword)
 	if err != nil {
-		http.Error(w, "Failed to delete file", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to delete file")
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("File deleted successfully"))
 }
+
+// ListFiles returns the files uploaded by the caller's tenant.
+func (h *FileHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	files, err := h.fileUseCase.ListFiles(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to list files")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files)
+}
+
+// Usage returns the storage usage and limits of the caller's tenant.
+func (h *FileHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := h.fileUseCase.GetUsage(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to get usage")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// uploadPart returns the first upload part of a multipart body without buffering it:
+// "file" for a single file, "files" for the first file of a directory, or "archive".
+func uploadPart(r *http.Request) (*multipart.Reader, *multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch part.FormName() {
+		case "file", "files", "archive":
+			if part.FileName() != "" {
+				return reader, part, nil
+			}
+		}
+		part.Close()
+	}
+}
+
+// statusFromError maps domain errors returned by the usecase to HTTP status codes.
+func statusFromError(err error) int {
+	var invalidKeyword *domain.ErrInvalidKeyword
+	var unauthenticated *domain.ErrUnauthenticated
+	var notFound *domain.ErrNotFound
+	var quotaExceeded *domain.ErrQuotaExceeded
+	var invalidUpload *domain.ErrInvalidUpload
+	var isDirectory *domain.ErrIsDirectory
+	var erasureCoded *domain.ErrErasureCoded
+	switch {
+	case errors.As(err, &invalidUpload), errors.As(err, &isDirectory), errors.As(err, &erasureCoded):
+		return http.StatusBadRequest
+	case errors.As(err, &invalidKeyword), errors.As(err, &unauthenticated):
+		return http.StatusUnauthorized
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &quotaExceeded):
+		if quotaExceeded.Resource == domain.QuotaFiles {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorCode returns the envelope code of a domain error.
+func errorCode(err error) string {
+	var invalidKeyword *domain.ErrInvalidKeyword
+	var quotaExceeded *domain.ErrQuotaExceeded
+	switch {
+	case errors.As(err, &invalidKeyword):
+		return httperr.CodeInvalidKeyword
+	case errors.As(err, &quotaExceeded):
+		return httperr.CodeQuotaExceeded
+	default:
+		return httperr.CodeForStatus(statusFromError(err))
+	}
+}
+
+// writeError replies with the status, code and message of a usecase error.
+func writeError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	httperr.Write(w, r, statusFromError(err), errorCode(err), errorMessage(err, fallback))
+}
+
+// errorMessage exposes domain error messages to the client and hides internal failures behind fallback.
+func errorMessage(err error, fallback string) string {
+	if statusFromError(err) == http.StatusInternalServerError {
+		return fallback
+	}
+	return err.Error()
+}
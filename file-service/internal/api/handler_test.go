@@ -2,24 +2,54 @@ package api_test
 
 import (
 	"bytes"
-	"decentralstore/file-service/internal/api"
-	"decentralstore/file-service/internal/domain"
-	"decentralstore/file-service/internal/mocks"
+	"context"
 	"encoding/json"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/api"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/file-service/internal/signedurl"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+func newTestSigner(t *testing.T) *signedurl.Signer {
+	t.Helper()
+	keys, err := signedurl.NewKeySet(signedurl.Key{ID: "k1", Secret: []byte("0123456789abcdef")}, time.Hour)
+	require.NoError(t, err)
+	return signedurl.NewSigner(keys)
+}
+
+func decodeError(t *testing.T, rr *httptest.ResponseRecorder) (string, string) {
+	t.Helper()
+	var envelope struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &envelope))
+	return envelope.Error.Code, envelope.Error.Message
+}
+
 func TestFileHandler_UploadFile(t *testing.T) {
-	mockUseCase := new(mocks.MockFileUseCase)
-	handler := api.NewFileHandler(mockUseCase)
+	mockUseCase := &mocks.MockFileUseCase{
+		UploadFileFn: func(ctx context.Context, file io.Reader, filename string) (*domain.File, error) {
+			content, _ := io.ReadAll(file)
+			assert.Equal(t, "test content", string(content))
+			return &domain.File{ID: "123", Name: filename, CID: "QmTest123"}, nil
+		},
+	}
+	handler := api.NewFileHandler(mockUseCase, nil)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -27,12 +57,6 @@ func TestFileHandler_UploadFile(t *testing.T) {
 	io.Copy(part, strings.NewReader("test content"))
 	writer.Close()
 
-	mockUseCase.On("UploadFile", mock.Anything, mock.Anything, "test.txt").Return(&domain.File{
-		ID:   "123",
-		Name: "test.txt",
-		CID:  "QmTest123",
-	}, nil)
-
 	req, _ := http.NewRequest("POST", "/upload", body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	rr := httptest.NewRecorder()
@@ -45,14 +69,47 @@ func TestFileHandler_UploadFile(t *testing.T) {
 	assert.Equal(t, "123", response["id"])
 	assert.Equal(t, "test.txt", response["name"])
 	assert.Equal(t, "QmTest123", response["cid"])
-	mockUseCase.AssertExpectations(t)
 }
 
-func TestFileHandler_DownloadFile(t *testing.T) {
-	mockUseCase := new(mocks.MockFileUseCase)
-	handler := api.NewFileHandler(mockUseCase)
+func TestFileHandler_UploadFile_QuotaExceeded(t *testing.T) {
+	for resource, status := range map[string]int{
+		domain.QuotaBytes: http.StatusRequestEntityTooLarge,
+		domain.QuotaFiles: http.StatusTooManyRequests,
+	} {
+		mockUseCase := &mocks.MockFileUseCase{
+			UploadFileFn: func(ctx context.Context, file io.Reader, filename string) (*domain.File, error) {
+				return nil, &domain.ErrQuotaExceeded{Resource: resource, Limit: 10}
+			},
+		}
+		handler := api.NewFileHandler(mockUseCase, nil)
+
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "test.txt")
+		io.Copy(part, strings.NewReader("test content"))
+		writer.Close()
+		req, _ := http.NewRequest("POST", "/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rr := httptest.NewRecorder()
 
-	mockUseCase.On("DownloadFile", mock.Anything, "123", "test-keyword").Return(io.NopCloser(strings.NewReader("test content")), nil)
+		handler.UploadFile(rr, req)
+
+		assert.Equal(t, status, rr.Code, resource)
+		code, message := decodeError(t, rr)
+		assert.Equal(t, "quota_exceeded", code, resource)
+		assert.Contains(t, message, resource+" limit is 10")
+	}
+}
+
+func TestFileHandler_DownloadFile(t *testing.T) {
+	mockUseCase := &mocks.MockFileUseCase{
+		DownloadFileFn: func(ctx context.Context, fileID string, keyword string) (io.ReadCloser, error) {
+			assert.Equal(t, "123", fileID)
+			assert.Equal(t, "test-keyword", keyword)
+			return io.NopCloser(strings.NewReader("test content")), nil
+		},
+	}
+	handler := api.NewFileHandler(mockUseCase, nil)
 
 	req, _ := http.NewRequest("GET", "/download?id=123&keyword=test-keyword", nil)
 	rr := httptest.NewRecorder()
@@ -63,14 +120,110 @@ func TestFileHandler_DownloadFile(t *testing.T) {
 	assert.Equal(t, "test content", rr.Body.String())
 	assert.Equal(t, "attachment; filename=123", rr.Header().Get("Content-Disposition"))
 	assert.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
-	mockUseCase.AssertExpectations(t)
 }
 
-func TestFileHandler_DeleteFile(t *testing.T) {
-	mockUseCase := new(mocks.MockFileUseCase)
-	handler := api.NewFileHandler(mockUseCase)
+func TestFileHandler_DownloadFile_Signed(t *testing.T) {
+	mockUseCase := &mocks.MockFileUseCase{
+		OpenFileFn: func(ctx context.Context, fileID string) (io.ReadCloser, error) {
+			assert.Equal(t, "123", fileID)
+			return io.NopCloser(strings.NewReader("test content")), nil
+		},
+	}
+	signer := newTestSigner(t)
+	handler := api.NewFileHandler(mockUseCase, signer)
 
-	mockUseCase.On("DeleteFile", mock.Anything, "123", "test-keyword").Return(nil)
+	query := signer.Sign("123", time.Now().Add(time.Minute))
+	rr := httptest.NewRecorder()
+	handler.DownloadFile(rr, httptest.NewRequest("GET", "/download?"+query.Encode(), nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "test content", rr.Body.String())
+
+	// Signatures bind the file ID and expire.
+	query.Set(signedurl.ParamFileID, "456")
+	rr = httptest.NewRecorder()
+	handler.DownloadFile(rr, httptest.NewRequest("GET", "/download?"+query.Encode(), nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	expired := signer.Sign("123", time.Now().Add(-time.Minute))
+	rr = httptest.NewRecorder()
+	handler.DownloadFile(rr, httptest.NewRequest("GET", "/download?"+expired.Encode(), nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestFileHandler_DownloadFile_SignedWithoutSigner(t *testing.T) {
+	handler := api.NewFileHandler(&mocks.MockFileUseCase{}, nil)
+
+	query := newTestSigner(t).Sign("123", time.Now().Add(time.Minute))
+	rr := httptest.NewRecorder()
+	handler.DownloadFile(rr, httptest.NewRequest("GET", "/download?"+query.Encode(), nil))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestFileHandler_SignDownloadURL(t *testing.T) {
+	mockUseCase := &mocks.MockFileUseCase{
+		AuthorizeDownloadFn: func(ctx context.Context, fileID string, keyword string) error {
+			if keyword != "test-keyword" {
+				return &domain.ErrInvalidKeyword{Operation: "download"}
+			}
+			return nil
+		},
+	}
+	signer := newTestSigner(t)
+	handler := api.NewFileHandler(mockUseCase, signer)
+
+	sign := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/download/sign", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		handler.SignDownloadURL(rr, req)
+		return rr
+	}
+
+	rr := sign(url.Values{"id": {"123"}, "keyword": {"test-keyword"}, "ttl": {"172800"}})
+	require.Equal(t, http.StatusOK, rr.Code)
+	var signed struct {
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &signed))
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), signed.ExpiresAt, time.Minute, "ttl is capped at one day")
+	signedURL, err := url.Parse(signed.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "/download", signedURL.Path)
+	fileID, err := signer.Verify(signedURL.Query())
+	assert.NoError(t, err)
+	assert.Equal(t, "123", fileID)
+
+	rr = sign(url.Values{"id": {"123"}, "keyword": {"wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	code, _ := decodeError(t, rr)
+	assert.Equal(t, "invalid_keyword", code)
+
+	assert.Equal(t, http.StatusBadRequest, sign(url.Values{"id": {"123"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, sign(url.Values{"id": {"123"}, "keyword": {"test-keyword"}, "ttl": {"-1"}}).Code)
+}
+
+func TestFileHandler_SignDownloadURL_Disabled(t *testing.T) {
+	handler := api.NewFileHandler(&mocks.MockFileUseCase{}, nil)
+
+	req := httptest.NewRequest("POST", "/download/sign", strings.NewReader("id=123&keyword=test-keyword"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.SignDownloadURL(rr, req)
+
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
+
+func TestFileHandler_DeleteFile(t *testing.T) {
+	mockUseCase := &mocks.MockFileUseCase{
+		DeleteFileFn: func(ctx context.Context, fileID string, keyword string) error {
+			assert.Equal(t, "123", fileID)
+			assert.Equal(t, "test-keyword", keyword)
+			return nil
+		},
+	}
+	handler := api.NewFileHandler(mockUseCase, nil)
 
 	req, _ := http.NewRequest("DELETE", "/delete?id=123&keyword=test-keyword", nil)
 	rr := httptest.NewRecorder()
@@ -79,14 +232,15 @@ func TestFileHandler_DeleteFile(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Equal(t, "File deleted successfully", rr.Body.String())
-	mockUseCase.AssertExpectations(t)
 }
 
 func TestFileHandler_DeleteFile_InvalidKeyword(t *testing.T) {
-	mockUseCase := new(mocks.MockFileUseCase)
-	handler := api.NewFileHandler(mockUseCase)
-
-	mockUseCase.On("DeleteFile", mock.Anything, "123", "wrong-keyword").Return(&domain.ErrInvalidKeyword{Operation: "delete"})
+	mockUseCase := &mocks.MockFileUseCase{
+		DeleteFileFn: func(ctx context.Context, fileID string, keyword string) error {
+			return &domain.ErrInvalidKeyword{Operation: "delete"}
+		},
+	}
+	handler := api.NewFileHandler(mockUseCase, nil)
 
 	req, _ := http.NewRequest("DELETE", "/delete?id=123&keyword=wrong-keyword", nil)
 	rr := httptest.NewRecorder()
@@ -95,5 +249,23 @@ func TestFileHandler_DeleteFile_InvalidKeyword(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Invalid keyword for delete operation")
-	mockUseCase.AssertExpectations(t)
+}
+
+func TestFileHandler_DeleteFile_OtherTenant(t *testing.T) {
+	// The usecase reports files of other tenants as missing, so that their IDs do not leak.
+	mockUseCase := &mocks.MockFileUseCase{
+		DeleteFileFn: func(ctx context.Context, fileID string, keyword string) error {
+			return &domain.ErrNotFound{Resource: "file", ID: fileID}
+		},
+	}
+	handler := api.NewFileHandler(mockUseCase, nil)
+
+	req, _ := http.NewRequest("DELETE", "/delete?id=123&keyword=test-keyword", nil)
+	rr := httptest.NewRecorder()
+
+	handler.DeleteFile(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	code, _ := decodeError(t, rr)
+	assert.Equal(t, "not_found", code)
 }
@@ -0,0 +1,26 @@
+// Package apispec embeds the OpenAPI description of the file-service HTTP API.
+package apispec
+
+import (
+	_ "embed"
+
+	"decentralstore/shared/openapi"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Load parses the embedded document.
+func Load() (*openapi.Document, error) {
+	return openapi.Parse(spec)
+}
+
+// MustLoad is like Load but panics on error. The embedded document is checked by
+// the package tests, so an error is a bug in the build.
+func MustLoad() *openapi.Document {
+	doc, err := Load()
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
@@ -0,0 +1,150 @@
+package apispec_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"decentralstore/file-service/internal/apispec"
+	"decentralstore/shared/httperr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	doc, err := apispec.Load()
+	require.NoError(t, err)
+
+	for path, item := range doc.Paths {
+		for method, op := range item.Operations() {
+			assert.NotEmpty(t, op.OperationID, "%s %s", method, path)
+		}
+	}
+}
+
+// serve runs req through the validation middleware and returns the recorder and
+// the body the handler saw, if it was called.
+func serve(t *testing.T, req *http.Request) (*httptest.ResponseRecorder, *string) {
+	t.Helper()
+	var seen *string
+	handler := apispec.MustLoad().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s := string(body)
+		seen = &s
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr, seen
+}
+
+func details(t *testing.T, rr *httptest.ResponseRecorder) []httperr.Detail {
+	t.Helper()
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	var envelope httperr.Envelope
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&envelope))
+	assert.Equal(t, httperr.CodeInvalidRequest, envelope.Error.Code)
+	return envelope.Error.Details
+}
+
+func TestMiddleware_MissingParameters(t *testing.T) {
+	rr, seen := serve(t, httptest.NewRequest(http.MethodDelete, "/delete?id=", nil))
+
+	assert.Nil(t, seen)
+	assert.Equal(t, []httperr.Detail{
+		{Field: "query.id", Message: "is required"},
+		{Field: "query.keyword", Message: "is required"},
+	}, details(t, rr))
+}
+
+func TestMiddleware_InvalidJSONBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/admin/quota?tenantId=acme", strings.NewReader(`{"maxBytes": -1, "maxFiles": "ten"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr, _ := serve(t, req)
+
+	assert.Equal(t, []httperr.Detail{
+		{Field: "body.maxBytes", Message: "must be at least 0"},
+		{Field: "body.maxFiles", Message: "must be an integer"},
+	}, details(t, rr))
+}
+
+func TestMiddleware_RestoresBody(t *testing.T) {
+	// Without a Content-Type the only documented one is assumed.
+	req := httptest.NewRequest(http.MethodPut, "/admin/quota?tenantId=acme", strings.NewReader(`{"maxBytes": 100}`))
+	rr, seen := serve(t, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	require.NotNil(t, seen)
+	assert.Equal(t, `{"maxBytes": 100}`, *seen)
+}
+
+func TestMiddleware_FormBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/download/sign", strings.NewReader("id=f1&keyword=k&ttl=soon"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr, _ := serve(t, req)
+
+	assert.Equal(t, []httperr.Detail{{Field: "body.ttl", Message: "must be an integer"}}, details(t, rr))
+}
+
+func TestMiddleware_ContentType(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("data"))
+	req.Header.Set("Content-Type", "text/plain")
+	rr, _ := serve(t, req)
+
+	assert.Equal(t, []httperr.Detail{{Field: "header.Content-Type", Message: "must be one of multipart/form-data"}}, details(t, rr))
+}
+
+func TestMiddleware_StreamsBinaryBody(t *testing.T) {
+	car := strings.Repeat("x", 2<<20)
+	req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(car))
+	req.Header.Set("Content-Type", "application/vnd.ipld.car")
+	rr, seen := serve(t, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	require.NotNil(t, seen)
+	assert.Len(t, *seen, len(car))
+}
+
+func TestMiddleware_PassesUndocumentedRequests(t *testing.T) {
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/unknown", nil),
+		httptest.NewRequest(http.MethodPost, "/files", nil),
+		httptest.NewRequest(http.MethodGet, "/download?id=f1&keyword=k", nil),
+	} {
+		rr, seen := serve(t, req)
+		assert.Equal(t, http.StatusOK, rr.Code, req.URL)
+		assert.NotNil(t, seen, req.URL)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := apispec.MustLoad()
+	header := http.Header{"Content-Type": {"application/json"}}
+
+	assert.NoError(t, doc.ValidateResponse(http.MethodGet, "/usage", http.StatusOK, header,
+		[]byte(`{"tenantId": "acme", "usage": {"bytes": 1, "files": 1}, "limits": {"maxBytes": 0, "maxFiles": 0}}`)))
+
+	err := doc.ValidateResponse(http.MethodGet, "/usage", http.StatusOK, header, []byte(`{"tenantId": "acme", "usage": {"bytes": 1.5}}`))
+	assert.ErrorContains(t, err, "body.limits is required")
+	assert.ErrorContains(t, err, "body.usage.bytes must be an integer")
+	assert.ErrorContains(t, err, "body.usage.files is required")
+
+	err = doc.ValidateResponse(http.MethodGet, "/usage", http.StatusTeapot, header, nil)
+	assert.ErrorContains(t, err, "status 418 is not documented")
+
+	err = doc.ValidateResponse(http.MethodGet, "/usage", http.StatusOK, http.Header{"Content-Type": {"text/html"}}, nil)
+	assert.ErrorContains(t, err, `content type "text/html"`)
+}
+
+func TestServeHTTP(t *testing.T) {
+	rr := httptest.NewRecorder()
+	apispec.MustLoad().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var doc map[string]any
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+}
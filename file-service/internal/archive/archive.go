@@ -0,0 +1,239 @@
+// Package archive reads the files of tar and zip uploads one at a time and writes
+// directory downloads as tar or zip streams.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+)
+
+// Supported archive formats.
+const (
+	FormatTar = "tar"
+	FormatZip = "zip"
+)
+
+// ErrUnsupportedFormat is returned for archive names or formats other than tar, tar.gz and zip.
+var ErrUnsupportedFormat = errors.New("unsupported archive format")
+
+// FormatOf returns the format of an archive from its file name.
+func FormatOf(name string) (string, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar"), strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return FormatTar, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return FormatZip, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// TrimExtension returns name without its archive extension.
+func TrimExtension(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	if format == FormatZip {
+		return "application/zip"
+	}
+	return "application/x-tar"
+}
+
+// TarReader yields the regular files of a tar stream, which may be gzip-compressed.
+// Directories, links and other special entries are skipped.
+type TarReader struct {
+	src    io.Reader
+	reader *tar.Reader
+}
+
+func NewTarReader(src io.Reader) *TarReader {
+	return &TarReader{src: src}
+}
+
+// Next returns the path and content of the next file, or io.EOF after the last one.
+// The content is only valid until the next call.
+func (t *TarReader) Next() (string, io.Reader, error) {
+	if t.reader == nil {
+		src, err := maybeGunzip(t.src)
+		if err != nil {
+			return "", nil, err
+		}
+		t.reader = tar.NewReader(src)
+	}
+	for {
+		header, err := t.reader.Next()
+		if err != nil {
+			return "", nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			return header.Name, t.reader, nil
+		}
+	}
+}
+
+// maybeGunzip decompresses src if it starts with the gzip magic number.
+func maybeGunzip(src io.Reader) (io.Reader, error) {
+	buffered := &peekReader{src: src}
+	magic, err := buffered.peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
+
+// peekReader lets the first bytes of a stream be inspected before it is read.
+type peekReader struct {
+	src  io.Reader
+	head []byte
+}
+
+func (p *peekReader) peek(n int) ([]byte, error) {
+	p.head = make([]byte, n)
+	read, err := io.ReadFull(p.src, p.head)
+	p.head = p.head[:read]
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	return p.head, err
+}
+
+func (p *peekReader) Read(b []byte) (int, error) {
+	if len(p.head) > 0 {
+		n := copy(b, p.head)
+		p.head = p.head[n:]
+		return n, nil
+	}
+	return p.src.Read(b)
+}
+
+// ZipReader yields the files of a zip archive. Zip keeps its index at the end, so the
+// archive must be fully available, e.g. spooled to a temporary file.
+type ZipReader struct {
+	files   []*zip.File
+	current io.ReadCloser
+}
+
+func NewZipReader(r io.ReaderAt, size int64) (*ZipReader, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipReader{files: reader.File}, nil
+}
+
+// Next returns the path and content of the next file, or io.EOF after the last one.
+// The content is only valid until the next call.
+func (z *ZipReader) Next() (string, io.Reader, error) {
+	if z.current != nil {
+		z.current.Close()
+		z.current = nil
+	}
+	for len(z.files) > 0 {
+		file := z.files[0]
+		z.files = z.files[1:]
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		z.current = content
+		return file.Name, content, nil
+	}
+	return "", nil, io.EOF
+}
+
+// Write writes entries to w as an archive of format. open returns the content of an entry.
+func Write(w io.Writer, format string, modified time.Time, entries []domain.FileEntry, open func(entry domain.FileEntry) (io.ReadCloser, error)) error {
+	writer, err := NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		content, err := open(entry)
+		if err != nil {
+			return err
+		}
+		err = writer.Add(entry.Path, entry.Size, modified, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Writer writes files to a tar or zip stream one at a time, so only the file being
+// copied is held open.
+type Writer struct {
+	tar *tar.Writer
+	zip *zip.Writer
+}
+
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	switch format {
+	case FormatTar:
+		return &Writer{tar: tar.NewWriter(w)}, nil
+	case FormatZip:
+		return &Writer{zip: zip.NewWriter(w)}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// Add writes the file name with size bytes read from content. It fails if content
+// has a different size, since a tar header has already promised it.
+func (a *Writer) Add(name string, size int64, modified time.Time, content io.Reader) error {
+	var w io.Writer
+	if a.tar != nil {
+		header := &tar.Header{Typeflag: tar.TypeReg, Name: name, Size: size, Mode: 0o644, ModTime: modified}
+		if err := a.tar.WriteHeader(header); err != nil {
+			return err
+		}
+		w = a.tar
+	} else {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified}
+		header.SetMode(0o644)
+		file, err := a.zip.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		w = file
+	}
+
+	n, err := io.Copy(w, content)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("entry %s: read %d bytes, expected %d", name, n, size)
+	}
+	return nil
+}
+
+// Close writes the end of the archive. It does not close the underlying writer.
+func (a *Writer) Close() error {
+	if a.tar != nil {
+		return a.tar.Close()
+	}
+	return a.zip.Close()
+}
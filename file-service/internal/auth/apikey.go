@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"decentralstore/file-service/internal/infrastructure"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "dsk_"
+
+var (
+	ErrInvalidAPIKey   = errors.New("invalid or revoked API key")
+	ErrKeyNotFound     = errors.New("API key not found")
+	ErrInvalidTenantID = errors.New("tenant ID must be 1-64 characters of letters, digits, '-' or '_'")
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// APIKey is the stored record of an API key. The secret itself is never stored, only its SHA-256 hash.
+type APIKey struct {
+	ID        string     `json:"id"`
+	TenantID  string     `json:"tenantId"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RotatedAt *time.Time `json:"rotatedAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+type storedKey struct {
+	APIKey
+	Hash string `json:"hash"`
+}
+
+// KeyStore manages hashed API keys in Redis.
+//
+//	apikey:id:<id>       -> JSON record
+//	apikey:hash:<sha256> -> key ID
+//	tenant:<id>:apikeys  -> set of key IDs
+type KeyStore struct {
+	redis infrastructure.RedisClient
+	now   func() time.Time
+}
+
+func NewKeyStore(redisClient infrastructure.RedisClient) *KeyStore {
+	return &KeyStore{redis: redisClient, now: time.Now}
+}
+
+// Create issues a new key for tenantID and returns the plaintext secret, which is shown only once.
+func (s *KeyStore) Create(ctx context.Context, tenantID, name string) (string, *APIKey, error) {
+	if !tenantIDPattern.MatchString(tenantID) {
+		return "", nil, ErrInvalidTenantID
+	}
+
+	id, err := randomString(8)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := &APIKey{
+		ID:        id,
+		TenantID:  tenantID,
+		Name:      name,
+		Hash:      hashSecret(secret),
+		CreatedAt: s.now(),
+	}
+	if err := s.save(ctx, key); err != nil {
+		return "", nil, err
+	}
+	if err := s.redis.Set(ctx, hashKey(key.Hash), key.ID, 0).Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to index API key: %w", err)
+	}
+	if err := s.redis.SAdd(ctx, tenantKeysKey(tenantID), key.ID).Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to index API key: %w", err)
+	}
+
+	return secret, key, nil
+}
+
+// Rotate replaces the secret of key id. The previous secret stops working immediately.
+func (s *KeyStore) Rotate(ctx context.Context, id string) (string, *APIKey, error) {
+	key, err := s.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if key.RevokedAt != nil {
+		return "", nil, ErrInvalidAPIKey
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return "", nil, err
+	}
+
+	oldHash := key.Hash
+	now := s.now()
+	key.Hash = hashSecret(secret)
+	key.RotatedAt = &now
+	if err := s.save(ctx, key); err != nil {
+		return "", nil, err
+	}
+	if err := s.redis.Set(ctx, hashKey(key.Hash), key.ID, 0).Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to index API key: %w", err)
+	}
+	if err := s.redis.Del(ctx, hashKey(oldHash)).Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to remove previous API key: %w", err)
+	}
+
+	return secret, key, nil
+}
+
+// Revoke permanently disables key id while keeping its record for auditing.
+func (s *KeyStore) Revoke(ctx context.Context, id string) (*APIKey, error) {
+	key, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt == nil {
+		now := s.now()
+		key.RevokedAt = &now
+		if err := s.save(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.redis.Del(ctx, hashKey(key.Hash)).Err(); err != nil {
+		return nil, fmt.Errorf("failed to remove API key: %w", err)
+	}
+	return key, nil
+}
+
+// Get returns the record of key id.
+func (s *KeyStore) Get(ctx context.Context, id string) (*APIKey, error) {
+	data, err := s.redis.Get(ctx, idKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	var stored storedKey
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal API key: %w", err)
+	}
+	key := stored.APIKey
+	key.Hash = stored.Hash
+	return &key, nil
+}
+
+// List returns all keys issued to tenantID.
+func (s *KeyStore) List(ctx context.Context, tenantID string) ([]*APIKey, error) {
+	ids, err := s.redis.SMembers(ctx, tenantKeysKey(tenantID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	keys := make([]*APIKey, 0, len(ids))
+	for _, id := range ids {
+		key, err := s.Get(ctx, id)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Authenticate resolves a plaintext secret to its active key.
+func (s *KeyStore) Authenticate(ctx context.Context, secret string) (*APIKey, error) {
+	id, err := s.redis.Get(ctx, hashKey(hashSecret(secret))).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	key, err := s.Get(ctx, id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+func (s *KeyStore) save(ctx context.Context, key *APIKey) error {
+	data, err := json.Marshal(storedKey{APIKey: *key, Hash: key.Hash})
+	if err != nil {
+		return fmt.Errorf("failed to marshal API key: %w", err)
+	}
+	if err := s.redis.Set(ctx, idKey(key.ID), string(data), 0).Err(); err != nil {
+		return fmt.Errorf("failed to store API key: %w", err)
+	}
+	return nil
+}
+
+func idKey(id string) string {
+	return "apikey:id:" + id
+}
+
+func hashKey(hash string) string {
+	return "apikey:hash:" + hash
+}
+
+func tenantKeysKey(tenantID string) string {
+	return "tenant:" + tenantID + ":apikeys"
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,132 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyStore_CreateAndAuthenticate(t *testing.T) {
+	redisClient := mocks.NewFakeRedisClient()
+	store := auth.NewKeyStore(redisClient)
+	ctx := context.Background()
+
+	secret, key, err := store.Create(ctx, "acme", "ci")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "dsk_"))
+
+	authenticated, err := store.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, authenticated.ID)
+	assert.Equal(t, "acme", authenticated.TenantID)
+
+	stored, err := redisClient.Get(ctx, "apikey:id:"+key.ID).Result()
+	require.NoError(t, err)
+	assert.NotContains(t, stored, secret, "the plaintext secret must not be stored")
+}
+
+func TestKeyStore_Create_InvalidTenant(t *testing.T) {
+	store := auth.NewKeyStore(mocks.NewFakeRedisClient())
+
+	_, _, err := store.Create(context.Background(), "bad tenant!", "ci")
+
+	assert.ErrorIs(t, err, auth.ErrInvalidTenantID)
+}
+
+func TestKeyStore_Rotate(t *testing.T) {
+	store := auth.NewKeyStore(mocks.NewFakeRedisClient())
+	ctx := context.Background()
+
+	oldSecret, key, err := store.Create(ctx, "acme", "ci")
+	require.NoError(t, err)
+
+	newSecret, rotated, err := store.Rotate(ctx, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, rotated.ID)
+	assert.NotNil(t, rotated.RotatedAt)
+
+	_, err = store.Authenticate(ctx, oldSecret)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+
+	_, err = store.Authenticate(ctx, newSecret)
+	assert.NoError(t, err)
+}
+
+func TestKeyStore_Revoke(t *testing.T) {
+	store := auth.NewKeyStore(mocks.NewFakeRedisClient())
+	ctx := context.Background()
+
+	secret, key, err := store.Create(ctx, "acme", "ci")
+	require.NoError(t, err)
+
+	revoked, err := store.Revoke(ctx, key.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	_, err = store.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+
+	_, _, err = store.Rotate(ctx, key.ID)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey, "revoked keys cannot be rotated back to life")
+
+	keys, err := store.List(ctx, "acme")
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func TestKeyStore_Middleware(t *testing.T) {
+	store := auth.NewKeyStore(mocks.NewFakeRedisClient())
+	secret, _, err := store.Create(context.Background(), "acme", "ci")
+	require.NoError(t, err)
+
+	var tenantID string
+	var authenticated bool
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID, authenticated = auth.TenantFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/files", nil)
+	req.Header.Set(auth.APIKeyHeader, secret)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.True(t, authenticated)
+	assert.Equal(t, "acme", tenantID)
+
+	authenticated = false
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/download", nil))
+	assert.False(t, authenticated)
+
+	req = httptest.NewRequest("GET", "/files", nil)
+	req.Header.Set(auth.APIKeyHeader, "dsk_unknown")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestRequireAdmin(t *testing.T) {
+	handler := auth.RequireAdmin("s3cret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest("POST", "/admin/keys", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/admin/keys", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	disabled := auth.RequireAdmin("", handler)
+	rr = httptest.NewRecorder()
+	disabled.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"decentralstore/shared/grpcerr"
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/logging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// APIKeyMetadata is the gRPC metadata key that carries a tenant API key.
+const APIKeyMetadata = "x-api-key"
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware for unary calls.
+func (s *KeyStore) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := s.authenticateGRPC(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the gRPC counterpart of Middleware for streaming calls.
+func (s *KeyStore) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := s.authenticateGRPC(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticateGRPC attaches the tenant of the API key in the call metadata to ctx.
+// Like Middleware, calls without a key pass through and invalid keys are rejected.
+func (s *KeyStore) authenticateGRPC(ctx context.Context) (context.Context, error) {
+	secrets := metadata.ValueFromIncomingContext(ctx, APIKeyMetadata)
+	if len(secrets) == 0 || secrets[0] == "" {
+		return ctx, nil
+	}
+
+	key, err := s.Authenticate(ctx, secrets[0])
+	if errors.Is(err, ErrInvalidAPIKey) {
+		return nil, grpcerr.Error(http.StatusUnauthorized, httperr.CodeUnauthorized, err.Error())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to authenticate API key", "error", err)
+		return nil, grpcerr.Error(http.StatusInternalServerError, httperr.CodeInternal, "Failed to authenticate API key")
+	}
+	return WithTenant(ctx, key.TenantID), nil
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
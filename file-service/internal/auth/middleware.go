@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/logging"
+)
+
+// APIKeyHeader is the request header that carries a tenant API key.
+const APIKeyHeader = "X-API-Key"
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the authenticated tenant ID.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the authenticated tenant ID, if any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// Middleware attaches the tenant of a valid API key to the request context.
+// Requests without a key pass through so that signed-URL downloads keep working; the
+// usecase decides which operations require a tenant.
+func (s *KeyStore) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get(APIKeyHeader)
+		if secret == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := s.Authenticate(r.Context(), secret)
+		if errors.Is(err, ErrInvalidAPIKey) {
+			httperr.Error(w, r, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Failed to authenticate API key", "error", err)
+			httperr.Error(w, r, "Failed to authenticate API key", http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), key.TenantID)))
+	})
+}
+
+// RequireAdmin only lets requests through that present token as a bearer token.
+// An empty token disables the wrapped handler entirely.
+func RequireAdmin(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			httperr.Error(w, r, "Admin API is disabled", http.StatusForbidden)
+			return
+		}
+
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			httperr.Error(w, r, "Invalid admin token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
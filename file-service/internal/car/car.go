@@ -0,0 +1,348 @@
+// Package car reads and writes CAR (content-addressable archive) files: CARv1 streams of
+// blocks and the CARv2 wrapper around them. Reading verifies that every block hashes to its
+// CID, so a file that passes can be imported without trusting whoever produced it.
+package car
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ContentType is the media type of CAR files.
+const ContentType = "application/vnd.ipld.car"
+
+const (
+	// maxHeaderSize and maxSectionSize bound what is read into memory at once. Kubo
+	// refuses blocks over 4 MiB, so larger sections cannot be imported anyway.
+	maxHeaderSize  = 1 << 20
+	maxSectionSize = 4<<20 + 128
+
+	// A CARv2 file starts with an 11-byte pragma and a 40-byte header.
+	v2PragmaSize = 11
+	v2HeaderSize = v2PragmaSize + 40
+)
+
+// ErrInvalid is returned, wrapped, for malformed CAR files and blocks that do not match their CID.
+var ErrInvalid = errors.New("invalid CAR file")
+
+// Reader reads the blocks of a CARv1 or CARv2 file and checks each against its CID.
+type Reader struct {
+	// Version is the CAR version of the file, 1 or 2.
+	Version int
+	// Roots are the root CIDs declared in the header.
+	Roots []cid.Cid
+
+	src     *bufio.Reader
+	payload *bufio.Reader
+}
+
+// NewReader reads the header of a CAR file.
+func NewReader(r io.Reader) (*Reader, error) {
+	src := bufio.NewReader(r)
+	version, roots, err := readHeader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &Reader{Version: int(version), Roots: roots, src: src, payload: src}
+	switch version {
+	case 1:
+		return reader, nil
+	case 2:
+		// The CARv1 header read above was the CARv2 pragma; the CARv1 payload follows
+		// at the data offset and is followed by an optional index.
+		var header [40]byte
+		if _, err := io.ReadFull(src, header[:]); err != nil {
+			return nil, invalid("truncated CARv2 header: %v", err)
+		}
+		dataOffset := binary.LittleEndian.Uint64(header[16:24])
+		dataSize := binary.LittleEndian.Uint64(header[24:32])
+		if dataOffset < v2HeaderSize {
+			return nil, invalid("CARv2 data offset %d overlaps the header", dataOffset)
+		}
+		if _, err := io.CopyN(io.Discard, src, int64(dataOffset-v2HeaderSize)); err != nil {
+			return nil, invalid("truncated CARv2 file: %v", err)
+		}
+		reader.payload = bufio.NewReader(io.LimitReader(src, int64(dataSize)))
+		inner, roots, err := readHeader(reader.payload)
+		if err != nil {
+			return nil, err
+		}
+		if inner != 1 {
+			return nil, invalid("CARv2 payload has version %d", inner)
+		}
+		reader.Roots = roots
+		return reader, nil
+	}
+	return nil, invalid("unsupported version %d", version)
+}
+
+// Next returns the next block after checking that it hashes to its CID. It returns io.EOF
+// after the last block, having read the rest of the file, e.g. a CARv2 index.
+func (r *Reader) Next() (cid.Cid, []byte, error) {
+	size, err := binary.ReadUvarint(r.payload)
+	if err == io.EOF {
+		_, err = io.Copy(io.Discard, r.src)
+		if err == nil {
+			err = io.EOF
+		}
+		return cid.Undef, nil, err
+	}
+	if err != nil {
+		return cid.Undef, nil, invalid("truncated section: %v", err)
+	}
+	if size == 0 || size > maxSectionSize {
+		return cid.Undef, nil, invalid("section of %d bytes", size)
+	}
+
+	section := make([]byte, size)
+	if _, err := io.ReadFull(r.payload, section); err != nil {
+		return cid.Undef, nil, invalid("truncated section: %v", err)
+	}
+	n, id, err := cid.CidFromBytes(section)
+	if err != nil {
+		return cid.Undef, nil, invalid("bad CID: %v", err)
+	}
+	data := section[n:]
+
+	sum, err := id.Prefix().Sum(data)
+	if err != nil {
+		return cid.Undef, nil, invalid("cannot hash block %s: %v", id, err)
+	}
+	if !sum.Equals(id) {
+		return cid.Undef, nil, invalid("block %s does not match its hash", id)
+	}
+	return id, data, nil
+}
+
+// Contents summarizes a verified CAR file: which blocks it holds and how they link.
+type Contents struct {
+	Version int
+	Roots   []cid.Cid
+
+	links map[cid.Cid][]cid.Cid
+	// unknown holds blocks of codecs whose links cannot be read.
+	unknown map[cid.Cid]bool
+	// rootData keeps the data of small raw root blocks, such as a metadata record.
+	rootData map[cid.Cid][]byte
+}
+
+// maxKeptRootBlock is the size up to which the data of raw root blocks is kept.
+const maxKeptRootBlock = 1 << 20
+
+// Verify reads a whole CAR file and checks every block against its CID.
+func Verify(r io.Reader) (*Contents, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	contents := &Contents{
+		Version:  reader.Version,
+		Roots:    reader.Roots,
+		links:    make(map[cid.Cid][]cid.Cid),
+		unknown:  make(map[cid.Cid]bool),
+		rootData: make(map[cid.Cid][]byte),
+	}
+	roots := make(map[cid.Cid]bool)
+	for _, root := range reader.Roots {
+		roots[root] = true
+	}
+
+	for {
+		id, data, err := reader.Next()
+		if err == io.EOF {
+			return contents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch id.Type() {
+		case cid.DagProtobuf:
+			links, err := dagPBLinks(data)
+			if err != nil {
+				return nil, invalid("block %s: %v", id, err)
+			}
+			contents.links[id] = links
+		case cid.Raw:
+			contents.links[id] = nil
+			if roots[id] && len(data) <= maxKeptRootBlock {
+				contents.rootData[id] = data
+			}
+		default:
+			contents.links[id] = nil
+			contents.unknown[id] = true
+		}
+	}
+}
+
+// Has reports whether the file holds the block id.
+func (c *Contents) Has(id cid.Cid) bool {
+	_, ok := c.links[id]
+	return ok || id.Prefix().MhType == multihash.IDENTITY
+}
+
+// RootData returns the data of a raw root block of at most 1 MiB.
+func (c *Contents) RootData(id cid.Cid) ([]byte, bool) {
+	data, ok := c.rootData[id]
+	return data, ok
+}
+
+// Complete checks that every block of the DAG under root is in the file. Only dag-pb and
+// raw blocks, the codecs of UnixFS, can be followed.
+func (c *Contents) Complete(root cid.Cid) error {
+	seen := make(map[cid.Cid]bool)
+	pending := []cid.Cid{root}
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[id] || id.Prefix().MhType == multihash.IDENTITY {
+			continue
+		}
+		seen[id] = true
+
+		links, ok := c.links[id]
+		if !ok {
+			return invalid("block %s of %s is missing", id, root)
+		}
+		if c.unknown[id] {
+			return invalid("block %s of %s has an unsupported codec", id, root)
+		}
+		pending = append(pending, links...)
+	}
+	return nil
+}
+
+// RawCID returns the CIDv1 of data stored as a raw block.
+func RawCID(data []byte) (cid.Cid, error) {
+	prefix := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: multihash.SHA2_256, MhLength: -1}
+	return prefix.Sum(data)
+}
+
+// Writer writes a CARv1 stream.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the header of a CARv1 stream with roots.
+func NewWriter(w io.Writer, roots []cid.Cid) (*Writer, error) {
+	header := encodeHeader(roots)
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(header)))); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// Put writes one block.
+func (w *Writer) Put(id cid.Cid, data []byte) error {
+	key := id.Bytes()
+	section := binary.AppendUvarint(nil, uint64(len(key)+len(data)))
+	section = append(section, key...)
+	if _, err := w.w.Write(section); err != nil {
+		return err
+	}
+	_, err := w.w.Write(data)
+	return err
+}
+
+// CopyBlocks appends the blocks of the CARv1 stream r, as written by `ipfs dag export`,
+// without its header. The blocks are copied as they are and not verified.
+func (w *Writer) CopyBlocks(r io.Reader) error {
+	src := bufio.NewReader(r)
+	version, _, err := readHeader(src)
+	if err != nil {
+		return err
+	}
+	if version != 1 {
+		return invalid("expected a CARv1 stream, got version %d", version)
+	}
+	_, err = io.Copy(w.w, src)
+	return err
+}
+
+// WriteV2 writes the CARv1 payload of size bytes as a CARv2 file without an index.
+func WriteV2(w io.Writer, payload io.Reader, size int64) error {
+	// The pragma is the CARv1 header {"version": 2}.
+	pragma := []byte{0x0a, 0xa1, 0x67, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0x02}
+	var header [40]byte
+	binary.LittleEndian.PutUint64(header[16:24], v2HeaderSize)
+	binary.LittleEndian.PutUint64(header[24:32], uint64(size))
+	if _, err := w.Write(append(pragma, header[:]...)); err != nil {
+		return err
+	}
+	n, err := io.Copy(w, payload)
+	if err == nil && n != size {
+		err = fmt.Errorf("CARv2 payload: copied %d bytes, expected %d", n, size)
+	}
+	return err
+}
+
+// dagPBLinks returns the CIDs that a dag-pb node links to.
+func dagPBLinks(data []byte) ([]cid.Cid, error) {
+	var links []cid.Cid
+	for len(data) > 0 {
+		field, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		if field != 2 || wireType != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(field, wireType, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		link, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		hash, err := dagPBLinkHash(link)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, hash)
+	}
+	return links, nil
+}
+
+// dagPBLinkHash returns the Hash field of a PBLink.
+func dagPBLinkHash(link []byte) (cid.Cid, error) {
+	for len(link) > 0 {
+		field, wireType, n := protowire.ConsumeTag(link)
+		if n < 0 {
+			return cid.Undef, protowire.ParseError(n)
+		}
+		link = link[n:]
+		if field == 1 && wireType == protowire.BytesType {
+			hash, n := protowire.ConsumeBytes(link)
+			if n < 0 {
+				return cid.Undef, protowire.ParseError(n)
+			}
+			return cid.Cast(hash)
+		}
+		n = protowire.ConsumeFieldValue(field, wireType, link)
+		if n < 0 {
+			return cid.Undef, protowire.ParseError(n)
+		}
+		link = link[n:]
+	}
+	return cid.Undef, errors.New("link without a hash")
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+}
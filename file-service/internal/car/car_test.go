@@ -0,0 +1,148 @@
+package car_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"decentralstore/file-service/internal/car"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type block struct {
+	id   cid.Cid
+	data []byte
+}
+
+func rawBlock(t *testing.T, data string) block {
+	t.Helper()
+	id, err := car.RawCID([]byte(data))
+	require.NoError(t, err)
+	return block{id: id, data: []byte(data)}
+}
+
+// dagPBBlock returns a dag-pb node linking to children, as UnixFS stores chunked files.
+func dagPBBlock(t *testing.T, children ...block) block {
+	t.Helper()
+	var node []byte
+	for _, child := range children {
+		link := protowire.AppendTag(nil, 1, protowire.BytesType)
+		link = protowire.AppendBytes(link, child.id.Bytes())
+		node = protowire.AppendTag(node, 2, protowire.BytesType)
+		node = protowire.AppendBytes(node, link)
+	}
+	node = protowire.AppendTag(node, 1, protowire.BytesType)
+	node = protowire.AppendBytes(node, []byte{0x08, 0x02})
+
+	prefix := cid.Prefix{Version: 1, Codec: cid.DagProtobuf, MhType: multihash.SHA2_256, MhLength: -1}
+	id, err := prefix.Sum(node)
+	require.NoError(t, err)
+	return block{id: id, data: node}
+}
+
+func writeCAR(t *testing.T, roots []cid.Cid, blocks ...block) []byte {
+	t.Helper()
+	out := &bytes.Buffer{}
+	writer, err := car.NewWriter(out, roots)
+	require.NoError(t, err)
+	for _, b := range blocks {
+		require.NoError(t, writer.Put(b.id, b.data))
+	}
+	return out.Bytes()
+}
+
+func TestReader_RoundTrip(t *testing.T) {
+	first, second := rawBlock(t, "first"), rawBlock(t, "second")
+	data := writeCAR(t, []cid.Cid{first.id}, first, second)
+
+	reader, err := car.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 1, reader.Version)
+	assert.Equal(t, []cid.Cid{first.id}, reader.Roots)
+
+	for _, want := range []block{first, second} {
+		id, content, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, want.id, id)
+		assert.Equal(t, want.data, content)
+	}
+	_, _, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReader_V2(t *testing.T) {
+	file := rawBlock(t, "content")
+	payload := writeCAR(t, []cid.Cid{file.id}, file)
+	out := &bytes.Buffer{}
+	require.NoError(t, car.WriteV2(out, bytes.NewReader(payload), int64(len(payload))))
+
+	contents, err := car.Verify(out)
+	require.NoError(t, err)
+	assert.Equal(t, 2, contents.Version)
+	assert.Equal(t, []cid.Cid{file.id}, contents.Roots)
+	assert.NoError(t, contents.Complete(file.id))
+
+	data, ok := contents.RootData(file.id)
+	assert.True(t, ok)
+	assert.Equal(t, "content", string(data))
+}
+
+func TestVerify_RejectsTamperedBlock(t *testing.T) {
+	file := rawBlock(t, "original")
+	data := writeCAR(t, []cid.Cid{file.id}, block{id: file.id, data: []byte("tampered")})
+
+	_, err := car.Verify(bytes.NewReader(data))
+	assert.ErrorIs(t, err, car.ErrInvalid)
+	assert.ErrorContains(t, err, "does not match its hash")
+}
+
+func TestVerify_RejectsMalformedFiles(t *testing.T) {
+	file := rawBlock(t, "content")
+	data := writeCAR(t, []cid.Cid{file.id}, file)
+
+	for name, malformed := range map[string][]byte{
+		"empty":          nil,
+		"not CBOR":       []byte("\x05hello"),
+		"truncated":      data[:len(data)-1],
+		"huge header":    {0xff, 0xff, 0xff, 0x7f},
+		"empty sections": append(append([]byte{}, data...), 0x00),
+	} {
+		_, err := car.Verify(bytes.NewReader(malformed))
+		assert.ErrorIs(t, err, car.ErrInvalid, name)
+	}
+}
+
+func TestContents_Complete(t *testing.T) {
+	first, second := rawBlock(t, "chunk 1"), rawBlock(t, "chunk 2")
+	root := dagPBBlock(t, first, second)
+
+	contents, err := car.Verify(bytes.NewReader(writeCAR(t, []cid.Cid{root.id}, root, first, second)))
+	require.NoError(t, err)
+	assert.NoError(t, contents.Complete(root.id))
+
+	contents, err = car.Verify(bytes.NewReader(writeCAR(t, []cid.Cid{root.id}, root, first)))
+	require.NoError(t, err)
+	assert.True(t, contents.Has(first.id))
+	assert.False(t, contents.Has(second.id))
+	assert.ErrorContains(t, contents.Complete(root.id), "block "+second.id.String()+" of "+root.id.String()+" is missing")
+}
+
+func TestWriter_CopyBlocks(t *testing.T) {
+	first, second := rawBlock(t, "first"), rawBlock(t, "second")
+	out := &bytes.Buffer{}
+	writer, err := car.NewWriter(out, []cid.Cid{first.id, second.id})
+	require.NoError(t, err)
+	require.NoError(t, writer.CopyBlocks(bytes.NewReader(writeCAR(t, []cid.Cid{first.id}, first))))
+	require.NoError(t, writer.CopyBlocks(bytes.NewReader(writeCAR(t, []cid.Cid{second.id}, second))))
+
+	contents, err := car.Verify(out)
+	require.NoError(t, err)
+	assert.Equal(t, []cid.Cid{first.id, second.id}, contents.Roots)
+	assert.NoError(t, contents.Complete(first.id))
+	assert.NoError(t, contents.Complete(second.id))
+}
@@ -0,0 +1,203 @@
+package car
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+)
+
+// A CARv1 header is the dag-cbor map {"roots": [CID...], "version": 1}. Only the small
+// subset of CBOR that it needs is implemented here.
+
+const (
+	cborUint  = 0
+	cborBytes = 2
+	cborText  = 3
+	cborArray = 4
+	cborMap   = 5
+	cborTag   = 6
+
+	// cborTagCID is the CBOR tag of CIDs in dag-cbor.
+	cborTagCID = 42
+)
+
+// readHeader reads the length-prefixed header of a CARv1 stream. A CARv2 pragma reads as a
+// header with version 2 and no roots.
+func readHeader(r *bufio.Reader) (uint64, []cid.Cid, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, invalid("missing header: %v", err)
+	}
+	if size == 0 || size > maxHeaderSize {
+		return 0, nil, invalid("header of %d bytes", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, invalid("truncated header: %v", err)
+	}
+
+	decoder := &cborDecoder{data: data}
+	fields, err := decoder.mapHead()
+	if err != nil {
+		return 0, nil, invalid("header: %v", err)
+	}
+	var version uint64
+	var roots []cid.Cid
+	for i := uint64(0); i < fields; i++ {
+		key, err := decoder.text()
+		if err != nil {
+			return 0, nil, invalid("header: %v", err)
+		}
+		switch key {
+		case "version":
+			version, err = decoder.head(cborUint)
+		case "roots":
+			roots, err = decoder.cids()
+		default:
+			err = fmt.Errorf("unexpected field %q", key)
+		}
+		if err != nil {
+			return 0, nil, invalid("header: %v", err)
+		}
+	}
+	if version == 0 {
+		return 0, nil, invalid("header without a version")
+	}
+	return version, roots, nil
+}
+
+func encodeHeader(roots []cid.Cid) []byte {
+	header := appendHead(nil, cborMap, 2)
+	header = appendHead(header, cborText, 5)
+	header = append(header, "roots"...)
+	header = appendHead(header, cborArray, uint64(len(roots)))
+	for _, root := range roots {
+		// dag-cbor prefixes the binary CID with a zero byte, the identity multibase.
+		key := root.Bytes()
+		header = appendHead(header, cborTag, cborTagCID)
+		header = appendHead(header, cborBytes, uint64(len(key)+1))
+		header = append(header, 0)
+		header = append(header, key...)
+	}
+	header = appendHead(header, cborText, 7)
+	header = append(header, "version"...)
+	return appendHead(header, cborUint, 1)
+}
+
+// appendHead appends the initial bytes of a CBOR item of major type with argument n.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= 0xff:
+		return append(b, major|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+type cborDecoder struct {
+	data []byte
+}
+
+// head reads the initial bytes of an item that must be of major type and returns its argument.
+func (d *cborDecoder) head(major byte) (uint64, error) {
+	if len(d.data) == 0 {
+		return 0, errors.New("unexpected end")
+	}
+	initial := d.data[0]
+	d.data = d.data[1:]
+	if initial>>5 != major {
+		return 0, fmt.Errorf("expected major type %d, got %d", major, initial>>5)
+	}
+
+	info := initial & 0x1f
+	if info < 24 {
+		return uint64(info), nil
+	}
+	size := 0
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default:
+		return 0, fmt.Errorf("unsupported additional information %d", info)
+	}
+	if len(d.data) < size {
+		return 0, errors.New("unexpected end")
+	}
+	var n uint64
+	for _, b := range d.data[:size] {
+		n = n<<8 | uint64(b)
+	}
+	d.data = d.data[size:]
+	return n, nil
+}
+
+func (d *cborDecoder) mapHead() (uint64, error) {
+	return d.head(cborMap)
+}
+
+func (d *cborDecoder) bytes(major byte) ([]byte, error) {
+	n, err := d.head(major)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(d.data)) < n {
+		return nil, errors.New("unexpected end")
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *cborDecoder) text() (string, error) {
+	b, err := d.bytes(cborText)
+	return string(b), err
+}
+
+func (d *cborDecoder) cids() ([]cid.Cid, error) {
+	n, err := d.head(cborArray)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.data)) {
+		return nil, errors.New("unexpected end")
+	}
+	roots := make([]cid.Cid, 0, n)
+	for i := uint64(0); i < n; i++ {
+		tag, err := d.head(cborTag)
+		if err != nil {
+			return nil, err
+		}
+		if tag != cborTagCID {
+			return nil, fmt.Errorf("unexpected tag %d", tag)
+		}
+		key, err := d.bytes(cborBytes)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) == 0 || key[0] != 0 {
+			return nil, errors.New("CID without the identity multibase prefix")
+		}
+		root, err := cid.Cast(key[1:])
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
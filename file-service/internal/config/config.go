@@ -0,0 +1,299 @@
+// Package config loads the file-service configuration from a YAML file, environment
+// variables and command-line flags, in increasing order of precedence.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/erasure"
+	"decentralstore/file-service/internal/ipfspool"
+	"decentralstore/file-service/internal/pinning"
+	"decentralstore/shared/configloader"
+	"decentralstore/shared/logging"
+	"decentralstore/shared/ratelimit"
+)
+
+type Config struct {
+	Server struct {
+		Addr              string        `yaml:"addr" env:"HTTP_ADDR"`
+		ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+		// ReadTimeout and WriteTimeout bound a whole request and response, so they must
+		// cover the slowest upload and download; 0 disables them.
+		ReadTimeout  time.Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT"`
+		WriteTimeout time.Duration `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT"`
+		IdleTimeout  time.Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT"`
+		// ShutdownTimeout is how long in-flight transfers may run after a shutdown signal.
+		ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT"`
+		// ValidateRequests rejects requests that do not match the OpenAPI document.
+		ValidateRequests bool `yaml:"validateRequests" env:"VALIDATE_REQUESTS"`
+		// GRPCAddr is the listen address of the gRPC API; empty disables it.
+		GRPCAddr string `yaml:"grpcAddr" env:"GRPC_ADDR"`
+	} `yaml:"server"`
+
+	IPFS struct {
+		// APIURL is the address of the IPFS API, or a comma-separated list of the
+		// addresses of a pool of nodes.
+		APIURL string `yaml:"apiUrl" env:"IPFS_API_URL"`
+		// Replication is the number of nodes of a pool that pin each file.
+		Replication    int           `yaml:"replication" env:"IPFS_REPLICATION"`
+		HealthInterval time.Duration `yaml:"healthInterval" env:"IPFS_HEALTH_INTERVAL"`
+		RepairInterval time.Duration `yaml:"repairInterval" env:"IPFS_REPAIR_INTERVAL"`
+		// ReadTimeout is how long a download waits for a node before trying the next one.
+		ReadTimeout time.Duration `yaml:"readTimeout" env:"IPFS_READ_TIMEOUT"`
+		// WriteTimeout is how long an upload waits for a node to take data before it
+		// continues without the node.
+		WriteTimeout time.Duration `yaml:"writeTimeout" env:"IPFS_WRITE_TIMEOUT"`
+	} `yaml:"ipfs"`
+
+	Redis struct {
+		URL string `yaml:"url" env:"REDIS_URL"`
+	} `yaml:"redis"`
+
+	Log struct {
+		Level string `yaml:"level" env:"LOG_LEVEL" reload:"true"`
+	} `yaml:"log"`
+
+	Admin struct {
+		Token string `yaml:"token" env:"ADMIN_TOKEN" secret:"true"`
+	} `yaml:"admin"`
+
+	DownloadURLs struct {
+		// Keys is a "kid:secret,kid:secret@expiry,..." list; the first key signs, the others only
+		// verify until their RFC 3339 expiry.
+		Keys string `yaml:"keys" env:"DOWNLOAD_URL_KEYS" secret:"true"`
+	} `yaml:"downloadUrls"`
+
+	Quota struct {
+		MaxBytes int64 `yaml:"maxBytes" env:"QUOTA_MAX_BYTES" reload:"true"`
+		MaxFiles int64 `yaml:"maxFiles" env:"QUOTA_MAX_FILES" reload:"true"`
+	} `yaml:"quota"`
+
+	RateLimit struct {
+		Upload   string `yaml:"upload" env:"RATE_LIMIT_UPLOAD" reload:"true"`
+		Download string `yaml:"download" env:"RATE_LIMIT_DOWNLOAD" reload:"true"`
+		Write    string `yaml:"write" env:"RATE_LIMIT_WRITE" reload:"true"`
+		Read     string `yaml:"read" env:"RATE_LIMIT_READ" reload:"true"`
+	} `yaml:"rateLimit"`
+
+	Bandwidth struct {
+		Upload   int64 `yaml:"upload" env:"UPLOAD_BANDWIDTH"`
+		Download int64 `yaml:"download" env:"DOWNLOAD_BANDWIDTH"`
+	} `yaml:"bandwidth"`
+
+	Pinning struct {
+		// Services is a "name|endpoint|token,..." list of remote pinning services; empty
+		// disables replication.
+		Services     string        `yaml:"services" env:"PINNING_SERVICES" secret:"true"`
+		MaxAttempts  int           `yaml:"maxAttempts" env:"PINNING_MAX_ATTEMPTS"`
+		PollInterval time.Duration `yaml:"pollInterval" env:"PINNING_POLL_INTERVAL"`
+	} `yaml:"pinning"`
+
+	Erasure struct {
+		// DataShards and ParityShards enable erasure coding when DataShards is positive.
+		// Each file is then split into that many shards, each stored on a different IPFS node.
+		// Shards are rebuilt per file, so erasure-coded uploads are never deduplicated.
+		DataShards     int           `yaml:"dataShards" env:"ERASURE_DATA_SHARDS"`
+		ParityShards   int           `yaml:"parityShards" env:"ERASURE_PARITY_SHARDS"`
+		BlockSize      int           `yaml:"blockSize" env:"ERASURE_BLOCK_SIZE"`
+		RepairInterval time.Duration `yaml:"repairInterval" env:"ERASURE_REPAIR_INTERVAL"`
+	} `yaml:"erasure"`
+}
+
+// Default returns the configuration used when no source sets a value.
+func Default() *Config {
+	cfg := &Config{}
+	cfg.Server.Addr = ":8081"
+	cfg.Server.GRPCAddr = ":9081"
+	cfg.Server.ReadHeaderTimeout = 10 * time.Second
+	cfg.Server.ReadTimeout = time.Hour
+	cfg.Server.WriteTimeout = time.Hour
+	cfg.Server.IdleTimeout = 2 * time.Minute
+	cfg.Server.ShutdownTimeout = 30 * time.Second
+	cfg.IPFS.APIURL = "localhost:5001"
+	cfg.IPFS.Replication = 2
+	cfg.IPFS.HealthInterval = 10 * time.Second
+	cfg.IPFS.RepairInterval = 5 * time.Minute
+	cfg.IPFS.ReadTimeout = 30 * time.Second
+	cfg.IPFS.WriteTimeout = 30 * time.Second
+	cfg.Redis.URL = "localhost:6379"
+	cfg.Log.Level = "info"
+	cfg.RateLimit.Upload = "60/m"
+	cfg.RateLimit.Download = "600/m"
+	cfg.RateLimit.Write = "120/m"
+	cfg.RateLimit.Read = "600/m"
+	cfg.Pinning.MaxAttempts = 8
+	cfg.Pinning.PollInterval = 30 * time.Second
+	cfg.Erasure.ParityShards = 2
+	cfg.Erasure.BlockSize = erasure.DefaultBlockSize
+	cfg.Erasure.RepairInterval = 10 * time.Minute
+	return cfg
+}
+
+// Load builds the configuration from the defaults, the YAML file named by -config or
+// CONFIG_FILE, the environment and args, and validates it.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	if err := configloader.Load(cfg, args, "CONFIG_FILE"); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr must not be empty"))
+	}
+	if c.Server.ReadHeaderTimeout < 0 || c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		errs = append(errs, errors.New("server timeouts must not be negative"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdownTimeout must be positive"))
+	}
+	if len(c.IPFSNodes()) == 0 {
+		errs = append(errs, errors.New("ipfs.apiUrl must not be empty"))
+	}
+	if c.IPFS.Replication <= 0 || c.IPFS.HealthInterval <= 0 || c.IPFS.RepairInterval <= 0 ||
+		c.IPFS.ReadTimeout <= 0 || c.IPFS.WriteTimeout <= 0 {
+		errs = append(errs, errors.New("ipfs.replication and the ipfs intervals and timeouts must be positive"))
+	}
+	if c.Redis.URL == "" {
+		errs = append(errs, errors.New("redis.url must not be empty"))
+	}
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if c.Quota.MaxBytes < 0 || c.Quota.MaxFiles < 0 {
+		errs = append(errs, errors.New("quota limits must not be negative"))
+	}
+	if _, err := c.RateLimits(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Bandwidth.Upload < 0 || c.Bandwidth.Download < 0 {
+		errs = append(errs, errors.New("bandwidth limits must not be negative"))
+	}
+	if _, err := c.PinningServices(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Pinning.MaxAttempts <= 0 || c.Pinning.PollInterval <= 0 {
+		errs = append(errs, errors.New("pinning.maxAttempts and pinning.pollInterval must be positive"))
+	}
+	if params := c.ErasureParams(); params != nil {
+		if err := params.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("erasure: %w", err))
+		}
+		if nodes := len(c.IPFSNodes()); params.Shards() > nodes {
+			errs = append(errs, fmt.Errorf("erasure coding needs an IPFS node per shard, %d shards for %d nodes", params.Shards(), nodes))
+		}
+		if c.Erasure.RepairInterval <= 0 {
+			errs = append(errs, errors.New("erasure.repairInterval must be positive"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IPFSNodes returns the addresses of the IPFS API.
+func (c *Config) IPFSNodes() []string {
+	var addrs []string
+	for _, addr := range strings.Split(c.IPFS.APIURL, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// IPFSPool returns the settings of a pool of IPFS nodes.
+func (c *Config) IPFSPool() ipfspool.Options {
+	return ipfspool.Options{
+		Replication:    c.IPFS.Replication,
+		HealthInterval: c.IPFS.HealthInterval,
+		RepairInterval: c.IPFS.RepairInterval,
+		ReadTimeout:    c.IPFS.ReadTimeout,
+		WriteTimeout:   c.IPFS.WriteTimeout,
+	}
+}
+
+// ErasureParams returns the coding of uploaded files, or nil when erasure coding is disabled.
+func (c *Config) ErasureParams() *erasure.Params {
+	if c.Erasure.DataShards <= 0 {
+		return nil
+	}
+	return &erasure.Params{
+		DataShards:   c.Erasure.DataShards,
+		ParityShards: c.Erasure.ParityShards,
+		BlockSize:    c.Erasure.BlockSize,
+	}
+}
+
+// QuotaLimits returns the default per-tenant limits.
+func (c *Config) QuotaLimits() domain.Limits {
+	return domain.Limits{MaxBytes: c.Quota.MaxBytes, MaxFiles: c.Quota.MaxFiles}
+}
+
+// RateLimits returns the per-class request limits.
+func (c *Config) RateLimits() (map[string]ratelimit.Limit, error) {
+	specs := map[string]string{
+		ratelimit.ClassUpload:   c.RateLimit.Upload,
+		ratelimit.ClassDownload: c.RateLimit.Download,
+		ratelimit.ClassWrite:    c.RateLimit.Write,
+		ratelimit.ClassRead:     c.RateLimit.Read,
+	}
+	limits := make(map[string]ratelimit.Limit, len(specs))
+	for class, spec := range specs {
+		limit, err := ratelimit.ParseLimit(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rateLimit.%s: %w", class, err)
+		}
+		limits[class] = limit
+	}
+	return limits, nil
+}
+
+// PinningServices returns the remote pinning services to replicate pins to.
+func (c *Config) PinningServices() ([]pinning.Service, error) {
+	var services []pinning.Service
+	names := make(map[string]bool)
+	for _, spec := range strings.Split(c.Pinning.Services, ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		parts := strings.SplitN(spec, "|", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, errors.New(`pinning.services: expected "name|endpoint|token"`)
+		}
+		if names[parts[0]] {
+			return nil, fmt.Errorf("pinning.services: duplicate service %q", parts[0])
+		}
+		if u, err := url.Parse(parts[1]); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("pinning.services: invalid endpoint of %q", parts[0])
+		}
+		names[parts[0]] = true
+		services = append(services, pinning.Service{Name: parts[0], Endpoint: parts[1], Token: parts[2]})
+	}
+	return services, nil
+}
+
+// String returns the effective configuration as YAML with secrets redacted.
+func (c *Config) String() string {
+	return configloader.RedactedYAML(c)
+}
+
+// Diff lists the settings that differ in next.
+func (c *Config) Diff(next *Config) []configloader.Change {
+	return configloader.Diff(c, next)
+}
+
+// ApplyReloadable copies the settings that may change at runtime from next.
+func (c *Config) ApplyReloadable(next *Config) {
+	configloader.MergeReloadable(c, next)
+}
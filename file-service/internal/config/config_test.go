@@ -0,0 +1,152 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/config"
+	"decentralstore/shared/configloader"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, ":8081", cfg.Server.Addr)
+	assert.Equal(t, "localhost:5001", cfg.IPFS.APIURL)
+	assert.Equal(t, 30*time.Second, cfg.IPFS.ReadTimeout)
+	assert.Equal(t, "60/m", cfg.RateLimit.Upload)
+	assert.Equal(t, 30*time.Second, cfg.Server.ShutdownTimeout)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  addr: ":9000"
+ipfs:
+  apiUrl: ipfs:5001
+quota:
+  maxBytes: 1000
+  maxFiles: 10
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("IPFS_API_URL", "ipfs-env:5001")
+	t.Setenv("QUOTA_MAX_FILES", "20")
+
+	cfg, err := config.Load([]string{"-quota.maxFiles", "30", "-server.shutdownTimeout=1h"})
+	require.NoError(t, err)
+	assert.Equal(t, ":9000", cfg.Server.Addr, "file overrides defaults")
+	assert.Equal(t, "ipfs-env:5001", cfg.IPFS.APIURL, "env overrides the file")
+	assert.Equal(t, int64(1000), cfg.Quota.MaxBytes)
+	assert.Equal(t, int64(30), cfg.Quota.MaxFiles, "flags override env")
+	assert.Equal(t, time.Hour, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "localhost:6379", cfg.Redis.URL)
+}
+
+func TestLoad_ConfigFlag(t *testing.T) {
+	path := writeConfig(t, "log:\n  level: debug\n")
+	cfg, err := config.Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "debug", cfg.Log.Level)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := config.Load([]string{"-quota.maxBytes", "lots"})
+	assert.Error(t, err)
+
+	_, err = config.Load([]string{"-config", writeConfig(t, "server:\n  port: 80\n")})
+	assert.ErrorContains(t, err, "port", "unknown keys are rejected")
+
+	_, err = config.Load([]string{"-log.level", "chatty", "-rateLimit.write", "often", "-bandwidth.upload", "-1", "-server.shutdownTimeout", "0s"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "log.level")
+	assert.Contains(t, err.Error(), "rateLimit.write")
+	assert.Contains(t, err.Error(), "bandwidth")
+	assert.Contains(t, err.Error(), "server.shutdownTimeout")
+}
+
+func TestConfig_StringRedactsSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Admin.Token = "admin-secret"
+	cfg.DownloadURLs.Keys = "k1:0123456789abcdef"
+
+	out := cfg.String()
+	assert.NotContains(t, out, "admin-secret")
+	assert.NotContains(t, out, "0123456789abcdef")
+	assert.Contains(t, out, "[REDACTED]")
+	assert.Contains(t, out, "localhost:5001")
+	assert.Equal(t, "admin-secret", cfg.Admin.Token, "the config itself is unchanged")
+
+	assert.Equal(t, 0, strings.Count(config.Default().String(), "[REDACTED]"), "unset secrets are shown empty")
+}
+
+func TestConfig_DiffAndApplyReloadable(t *testing.T) {
+	cfg := config.Default()
+	next := config.Default()
+	next.Server.Addr = ":9000"
+	next.Log.Level = "debug"
+	next.RateLimit.Write = "off"
+
+	changes := cfg.Diff(next)
+	assert.ElementsMatch(t, []configloader.Change{
+		{Path: "server.addr", Reloadable: false},
+		{Path: "log.level", Reloadable: true},
+		{Path: "rateLimit.write", Reloadable: true},
+	}, changes)
+
+	cfg.ApplyReloadable(next)
+	assert.Equal(t, ":8081", cfg.Server.Addr)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, "off", cfg.RateLimit.Write)
+}
+
+func TestConfig_PinningServices(t *testing.T) {
+	t.Setenv("PINNING_SERVICES", "pinata|https://api.pinata.cloud/psa|jwt-1, local|http://localhost:8080/|abc|def")
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+	services, err := cfg.PinningServices()
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+	assert.Equal(t, "pinata", services[0].Name)
+	assert.Equal(t, "https://api.pinata.cloud/psa", services[0].Endpoint)
+	assert.Equal(t, "jwt-1", services[0].Token)
+	assert.Equal(t, "abc|def", services[1].Token, "tokens may contain the separator")
+	assert.NotContains(t, cfg.String(), "jwt-1")
+
+	for _, spec := range []string{"pinata|https://api.pinata.cloud/psa", "pinata|ftp://host|t", "a|http://x|t,a|http://y|t", "|http://x|t"} {
+		cfg := config.Default()
+		cfg.Pinning.Services = spec
+		assert.ErrorContains(t, cfg.Validate(), "pinning.services", spec)
+	}
+}
+
+func TestConfig_ErasureParams(t *testing.T) {
+	cfg := config.Default()
+	assert.Nil(t, cfg.ErasureParams(), "disabled by default")
+
+	cfg.IPFS.APIURL = "ipfs-1:5001,ipfs-2:5001,ipfs-3:5001"
+	cfg.Erasure.DataShards = 2
+	cfg.Erasure.ParityShards = 1
+	require.NoError(t, cfg.Validate())
+	params := cfg.ErasureParams()
+	require.NotNil(t, params)
+	assert.Equal(t, 3, params.Shards())
+	assert.Equal(t, 1<<20, params.BlockSize)
+
+	cfg.Erasure.ParityShards = 2
+	assert.ErrorContains(t, cfg.Validate(), "4 shards for 3 nodes")
+	cfg.Erasure.ParityShards = 0
+	assert.ErrorContains(t, cfg.Validate(), "erasure:")
+}
@@ -0,0 +1,31 @@
+package domain
+
+// BundleManifestName はバンドル内のマニフェストのパスです
+const BundleManifestName = "manifest.json"
+
+// BundleItem はバンドルに含めるファイルとそのダウンロードキーワードです
+type BundleItem struct {
+	ID      string `json:"id"`
+	Keyword string `json:"keyword"`
+}
+
+// BundleManifest はバンドルに含めたファイルと、含められなかったファイルの一覧です。
+// バンドルの最後のエントリとして書き込まれます
+type BundleManifest struct {
+	Files  []BundleFile    `json:"files"`
+	Failed []BundleFailure `json:"failed"`
+}
+
+// BundleFile はバンドルに含めたファイルです。ディレクトリの場合、Pathはその下にエントリを含むディレクトリです
+type BundleFile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// BundleFailure は検証や読み出しに失敗したため、バンドルに含めなかったファイルです
+type BundleFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
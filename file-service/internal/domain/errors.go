@@ -0,0 +1,72 @@
+package domain
+
+import "fmt"
+
+// ErrNotFound はリソースが見つからない場合のエラーです
+type ErrNotFound struct {
+	Resource string
+	ID       string
+}
+
+func (e *ErrNotFound) Error() string {
+	return fmt.Sprintf("%s with ID %s not found", e.Resource, e.ID)
+}
+
+// ErrInvalidKeyword は無効なキーワードが使用された場合のエラーです
+type ErrInvalidKeyword struct {
+	Operation string
+}
+
+func (e *ErrInvalidKeyword) Error() string {
+	return fmt.Sprintf("Invalid keyword for %s operation", e.Operation)
+}
+
+// ErrStorageOperation はストレージ操作に失敗した場合のエラーです
+type ErrStorageOperation struct {
+	Operation string
+	Err       error
+}
+
+func (e *ErrStorageOperation) Error() string {
+	return fmt.Sprintf("Storage operation '%s' failed: %v", e.Operation, e.Err)
+}
+
+func (e *ErrStorageOperation) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnauthenticated は操作にAPIキー（テナント）が必要な場合のエラーです
+type ErrUnauthenticated struct {
+	Operation string
+}
+
+func (e *ErrUnauthenticated) Error() string {
+	return fmt.Sprintf("API key required for %s operation", e.Operation)
+}
+
+// ErrInvalidUpload はディレクトリのアップロードやCARファイルのインポートの内容が不正な場合のエラーです（パスの重複や欠けたブロックなど）
+type ErrInvalidUpload struct {
+	Reason string
+}
+
+func (e *ErrInvalidUpload) Error() string {
+	return fmt.Sprintf("Invalid upload: %s", e.Reason)
+}
+
+// ErrIsDirectory はディレクトリ全体を単一ファイルとして取得しようとした場合のエラーです
+type ErrIsDirectory struct {
+	ID string
+}
+
+func (e *ErrIsDirectory) Error() string {
+	return fmt.Sprintf("File with ID %s is a directory; download a path or an archive", e.ID)
+}
+
+// ErrErasureCoded はシャードに分割して保存されたファイルのDAGを必要とする操作（CARファイルのエクスポートなど）のエラーです
+type ErrErasureCoded struct {
+	ID string
+}
+
+func (e *ErrErasureCoded) Error() string {
+	return fmt.Sprintf("File with ID %s is erasure-coded and has no IPFS DAG", e.ID)
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// ファイルのライフサイクルイベントの種類（Webhookで通知されます）
+const (
+	EventFileUploaded   = "file.uploaded"
+	EventFileDownloaded = "file.downloaded"
+	EventFileDeleted    = "file.deleted"
+)
+
+// FileEvent はライフサイクルイベントのデータです。キーワードは含みません
+type FileEvent struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	CID        string    `json:"cid"`
+	UploadedAt time.Time `json:"uploadedAt"`
+	TenantID   string    `json:"tenantId"`
+}
+
+// NewFileEvent はファイルのメタデータからイベントデータを作成します
+func NewFileEvent(file *File) *FileEvent {
+	return &FileEvent{
+		ID:         file.ID,
+		Name:       file.Name,
+		Size:       file.Size,
+		CID:        file.CID,
+		UploadedAt: file.UploadedAt,
+		TenantID:   file.TenantID,
+	}
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// ExportFormat はCARエクスポートに含めるメタデータの形式です
+const ExportFormat = "decentralstore-export/1"
+
+// ExportManifest はCARエクスポートに含めるファイルのメタデータです。CARの最後のルートにrawブロックとして入ります。
+// キーワードとテナントは含めず、インポート時に新しく発行します
+type ExportManifest struct {
+	Format string         `json:"format"`
+	Files  []ExportedFile `json:"files"`
+}
+
+// ExportedFile はエクスポートされたファイルのメタデータです
+type ExportedFile struct {
+	Name       string      `json:"name"`
+	Size       int64       `json:"size"`
+	CID        string      `json:"cid"`
+	UploadedAt time.Time   `json:"uploadedAt"`
+	Entries    []FileEntry `json:"entries,omitempty"`
+}
@@ -1,13 +1,88 @@
 package domain
 
-import "time"
+import (
+	"path"
+	"strings"
+	"time"
+)
 
 type File struct {
-    ID              string    `json:"id"`
-    Name            string    `json:"name"`
-    Size            int64     `json:"size"`
-    CID             string    `json:"cid"`
-    UploadedAt      time.Time `json:"uploadedAt"`
-    DownloadKeyword string    `json:"downloadKeyword"`
-    DeleteKeyword   string    `json:"deleteKeyword"`
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Size            int64     `json:"size"`
+	CID             string    `json:"cid"`
+	UploadedAt      time.Time `json:"uploadedAt"`
+	DownloadKeyword string    `json:"downloadKeyword"`
+	DeleteKeyword   string    `json:"deleteKeyword"`
+	TenantID        string    `json:"tenantId,omitempty"`
+	// Entries はディレクトリとしてアップロードされた場合のファイル一覧です。CIDはディレクトリのルートを指します
+	Entries []FileEntry `json:"entries,omitempty"`
+	// RemotePins はリモートのピンニングサービスへの複製状況です。メタデータとは別に記録され、一覧の取得時に加えられます
+	RemotePins []RemotePin `json:"remotePins,omitempty"`
+	// Erasure はイレイジャーコーディングで保存された場合のシャードです。この場合CIDは空です
+	Erasure *ErasureCoding `json:"erasure,omitempty"`
+	// SHA256 は単一ファイルとしてアップロードされた内容のSHA-256（16進数）です
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// ContentRef はテナントがアップロード済みの内容をSHA-256（16進数）またはCIDで指定します
+type ContentRef struct {
+	SHA256 string `json:"sha256,omitempty"`
+	CID    string `json:"cid,omitempty"`
+}
+
+// ErasureCoding はリード・ソロモン符号でDataShards個のデータシャードとParityShards個のパリティシャードに
+// 分割して保存されたファイルの符号化パラメータです。任意のDataShards個のシャードから内容を復元できます
+type ErasureCoding struct {
+	DataShards   int `json:"dataShards"`
+	ParityShards int `json:"parityShards"`
+	BlockSize    int `json:"blockSize"`
+	// Shards はデータシャード、パリティシャードの順に並びます
+	Shards []Shard `json:"shards"`
+}
+
+// Shard は1つのIPFSノードにのみ保存されたシャードです
+type Shard struct {
+	CID  string `json:"cid"`
+	Node string `json:"node"`
+}
+
+// FileEntry はディレクトリ内の1ファイルです。Pathは"/"区切りの相対パスです
+type FileEntry struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	CID  string `json:"cid"`
+}
+
+// IsDirectory はファイルがディレクトリとしてアップロードされたかを返します
+func (f *File) IsDirectory() bool {
+	return len(f.Entries) > 0
+}
+
+// IsErasureCoded はファイルがシャードに分割して保存されたかを返します
+func (f *File) IsErasureCoded() bool {
+	return f.Erasure != nil
+}
+
+// Entry はディレクトリ内のパスのエントリを返します
+func (f *File) Entry(entryPath string) (*FileEntry, bool) {
+	for i := range f.Entries {
+		if f.Entries[i].Path == entryPath {
+			return &f.Entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// CleanEntryPath はディレクトリ内のパスを正規化します。絶対パスや親ディレクトリを指すパスは拒否します
+func CleanEntryPath(entryPath string) (string, error) {
+	entryPath = strings.ReplaceAll(entryPath, "\\", "/")
+	if entryPath == "" || strings.HasPrefix(entryPath, "/") {
+		return "", &ErrInvalidUpload{Reason: "invalid entry path " + entryPath}
+	}
+	cleaned := path.Clean(entryPath)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", &ErrInvalidUpload{Reason: "invalid entry path " + entryPath}
+	}
+	return cleaned, nil
 }
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// リモートのピンの状態です。Pinning Service APIの状態と同じ値です
+const (
+	PinQueued  = "queued"
+	PinPinning = "pinning"
+	PinPinned  = "pinned"
+	PinFailed  = "failed"
+)
+
+// RemotePin はリモートのピンニングサービス1つへのファイルの複製状況です
+type RemotePin struct {
+	Service string `json:"service"`
+	Status  string `json:"status"`
+	// RequestID はピンニングサービスが発行したピンのリクエストIDです
+	RequestID string `json:"requestId,omitempty"`
+	// Attempts は失敗した試行の回数です
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"lastError,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// NextCheckAt は次に状態を確認する、または再試行する時刻です。完了した場合はnilです
+	NextCheckAt *time.Time `json:"nextCheckAt,omitempty"`
+}
+
+// Done はピンが完了したか、再試行をあきらめたかを返します
+func (p *RemotePin) Done() bool {
+	return p.NextCheckAt == nil
+}
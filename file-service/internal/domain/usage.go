@@ -0,0 +1,37 @@
+package domain
+
+import "fmt"
+
+// Usage はテナントが保存しているデータ量とファイル数です
+type Usage struct {
+	Bytes int64 `json:"bytes"`
+	Files int64 `json:"files"`
+}
+
+// Limits はテナントのクォータです。0は無制限を表します
+type Limits struct {
+	MaxBytes int64 `json:"maxBytes"`
+	MaxFiles int64 `json:"maxFiles"`
+}
+
+// UsageReport は /usage エンドポイントのレスポンスです
+type UsageReport struct {
+	TenantID string `json:"tenantId"`
+	Usage    Usage  `json:"usage"`
+	Limits   Limits `json:"limits"`
+}
+
+const (
+	QuotaBytes = "bytes"
+	QuotaFiles = "files"
+)
+
+// ErrQuotaExceeded はアップロードがテナントのクォータを超える場合のエラーです
+type ErrQuotaExceeded struct {
+	Resource string
+	Limit    int64
+}
+
+func (e *ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("Quota exceeded: %s limit is %d", e.Resource, e.Limit)
+}
@@ -0,0 +1,273 @@
+// Package erasure splits a stream into Reed-Solomon data and parity shards, and rebuilds
+// the stream or lost shards from any DataShards of them.
+//
+// The stream is coded in stripes of DataShards blocks of BlockSize bytes each, and shard i
+// holds block i of every stripe, so that shards can be written and read as streams. The last
+// stripe is padded with zeros; the size of the stream is needed to strip them.
+package erasure
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// DefaultBlockSize is a block size that keeps the memory of a stream small while IPFS
+// reads shards in large chunks.
+const DefaultBlockSize = 1 << 20
+
+// ErrTooFewShards is returned when fewer than DataShards shards can be read.
+var ErrTooFewShards = errors.New("too few shards")
+
+// Params are the coding parameters of a stream.
+type Params struct {
+	DataShards   int
+	ParityShards int
+	BlockSize    int
+}
+
+// Validate checks that the parameters can be coded.
+func (p Params) Validate() error {
+	if p.DataShards < 1 || p.ParityShards < 1 {
+		return fmt.Errorf("need at least one data and one parity shard, got %d and %d", p.DataShards, p.ParityShards)
+	}
+	if p.Shards() > 256 {
+		return fmt.Errorf("at most 256 shards, got %d", p.Shards())
+	}
+	if p.BlockSize < 1 {
+		return fmt.Errorf("invalid block size %d", p.BlockSize)
+	}
+	return nil
+}
+
+// Shards returns the number of data and parity shards.
+func (p Params) Shards() int {
+	return p.DataShards + p.ParityShards
+}
+
+// stripes returns the number of stripes of a stream of size bytes.
+func (p Params) stripes(size int64) int64 {
+	stripe := int64(p.DataShards) * int64(p.BlockSize)
+	return (size + stripe - 1) / stripe
+}
+
+// ShardSize returns the size of each shard of a stream of size bytes.
+func (p Params) ShardSize(size int64) int64 {
+	return p.stripes(size) * int64(p.BlockSize)
+}
+
+// Encode reads r to the end and writes its shards to the writers, one per shard. It returns
+// the number of bytes read. A failing writer fails the whole stream.
+func Encode(r io.Reader, p Params, shards []io.Writer) (int64, error) {
+	enc, buf, blocks, err := coder(p, len(shards))
+	if err != nil {
+		return 0, err
+	}
+	data := buf[:p.DataShards*p.BlockSize]
+
+	var size int64
+	for {
+		n, readErr := io.ReadFull(r, data)
+		size += int64(n)
+		if readErr == io.EOF {
+			return size, nil
+		}
+		if readErr != nil && readErr != io.ErrUnexpectedEOF {
+			return size, readErr
+		}
+		clear(data[n:])
+
+		if err := enc.Encode(blocks); err != nil {
+			return size, err
+		}
+		for i, w := range shards {
+			if _, err := w.Write(blocks[i]); err != nil {
+				return size, fmt.Errorf("shard %d: %w", i, err)
+			}
+		}
+		if readErr == io.ErrUnexpectedEOF {
+			return size, nil
+		}
+	}
+}
+
+// NewReader returns a reader of the size bytes coded in shards, one reader per shard. Missing
+// shards are nil. Only DataShards shards are read, the data shards first; when one fails, it
+// is not read any further and the next shard takes its place from the current stripe on. The
+// reader fails once fewer than DataShards shards are left.
+func NewReader(shards []io.Reader, p Params, size int64) (io.Reader, error) {
+	d, err := newDecoder(shards, p, size)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{decoder: d, remaining: size}, nil
+}
+
+type reader struct {
+	*decoder
+	remaining int64
+	buf       []byte
+}
+
+func (r *reader) Read(b []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.remaining == 0 {
+			return 0, io.EOF
+		}
+		if err := r.next(false); err != nil {
+			return 0, err
+		}
+		r.buf = r.data[:min(int64(len(r.data)), r.remaining)]
+		r.remaining -= int64(len(r.buf))
+	}
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Rebuild regenerates shards from the others. shards holds a reader for each available shard
+// and nil for the others; out holds a writer for each shard to regenerate and nil for the
+// others. size is the size of the coded stream.
+func Rebuild(shards []io.Reader, out []io.Writer, p Params, size int64) error {
+	if len(out) != len(shards) {
+		return fmt.Errorf("%d outputs for %d shards", len(out), len(shards))
+	}
+	d, err := newDecoder(shards, p, size)
+	if err != nil {
+		return err
+	}
+	for range p.stripes(size) {
+		if err := d.next(true); err != nil {
+			return err
+		}
+		for i, w := range out {
+			if w == nil {
+				continue
+			}
+			if _, err := w.Write(d.full[i]); err != nil {
+				return fmt.Errorf("shard %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// decoder reads the shards stripe by stripe and reconstructs the missing blocks.
+type decoder struct {
+	enc    reedsolomon.Encoder
+	p      Params
+	shards []io.Reader
+	// full holds the block buffers of the shards, and data the data blocks among them.
+	// blocks is passed to the encoder, with the blocks of missing shards emptied.
+	full   [][]byte
+	data   []byte
+	blocks [][]byte
+	errs   []error
+	// stripe is the index of the next stripe, and done the number of stripes read of each shard.
+	stripe int64
+	done   []int64
+}
+
+func newDecoder(shards []io.Reader, p Params, size int64) (*decoder, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("negative size %d", size)
+	}
+	enc, buf, full, err := coder(p, len(shards))
+	if err != nil {
+		return nil, err
+	}
+	return &decoder{
+		enc:    enc,
+		p:      p,
+		shards: slices.Clone(shards),
+		full:   full,
+		data:   buf[:p.DataShards*p.BlockSize],
+		blocks: make([][]byte, len(full)),
+		done:   make([]int64, len(full)),
+	}, nil
+}
+
+// next reads the next stripe into full. It reads the first DataShards shards that do not
+// fail, so that no decoding is needed while the data shards can be read, and reconstructs the
+// missing data blocks, or every missing block with all.
+func (d *decoder) next(all bool) error {
+	read := 0
+	for i, r := range d.shards {
+		d.blocks[i] = d.full[i][:0]
+		if r == nil || read == d.p.DataShards {
+			continue
+		}
+		if err := d.readBlock(i); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = errors.New("shard is too short")
+			}
+			d.shards[i] = nil
+			d.errs = append(d.errs, fmt.Errorf("shard %d: %w", i, err))
+			continue
+		}
+		d.blocks[i] = d.full[i]
+		read++
+	}
+	d.stripe++
+	if read < d.p.DataShards {
+		return errors.Join(append([]error{ErrTooFewShards}, d.errs...)...)
+	}
+
+	var err error
+	if all {
+		err = d.enc.Reconstruct(d.blocks)
+	} else {
+		err = d.enc.ReconstructData(d.blocks)
+	}
+	if err != nil {
+		return err
+	}
+	// The encoder may allocate the blocks it reconstructs.
+	for i, block := range d.blocks {
+		if len(block) > 0 && &block[0] != &d.full[i][0] {
+			copy(d.full[i], block)
+		}
+	}
+	return nil
+}
+
+// readBlock reads the block of the current stripe of shard i, skipping the blocks of the stripes
+// for which the shard was not needed.
+func (d *decoder) readBlock(i int) error {
+	block := int64(len(d.full[i]))
+	if skip := (d.stripe - d.done[i]) * block; skip > 0 {
+		if _, err := io.CopyN(io.Discard, d.shards[i], skip); err != nil {
+			return err
+		}
+	}
+	if _, err := io.ReadFull(d.shards[i], d.full[i]); err != nil {
+		return err
+	}
+	d.done[i] = d.stripe + 1
+	return nil
+}
+
+// coder returns an encoder for p, and a buffer of a stripe split into a block per shard.
+// The data blocks come first, so that the data of a stripe is read or written at once.
+func coder(p Params, shards int) (reedsolomon.Encoder, []byte, [][]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	if shards != p.Shards() {
+		return nil, nil, nil, fmt.Errorf("%d shards given for %d data and %d parity shards", shards, p.DataShards, p.ParityShards)
+	}
+	enc, err := reedsolomon.New(p.DataShards, p.ParityShards)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	size := p.BlockSize
+	buf := make([]byte, shards*size)
+	blocks := make([][]byte, shards)
+	for i := range blocks {
+		blocks[i] = buf[i*size : (i+1)*size : (i+1)*size]
+	}
+	return enc, buf, blocks, nil
+}
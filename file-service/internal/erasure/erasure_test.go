@@ -0,0 +1,151 @@
+package erasure_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"decentralstore/file-service/internal/erasure"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var params = erasure.Params{DataShards: 4, ParityShards: 2, BlockSize: 1024}
+
+func encode(t *testing.T, data []byte, p erasure.Params) []*bytes.Buffer {
+	t.Helper()
+	buffers := make([]*bytes.Buffer, p.Shards())
+	writers := make([]io.Writer, p.Shards())
+	for i := range buffers {
+		buffers[i] = &bytes.Buffer{}
+		writers[i] = buffers[i]
+	}
+	size, err := erasure.Encode(bytes.NewReader(data), p, writers)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), size)
+	for _, b := range buffers {
+		require.Equal(t, p.ShardSize(size), int64(b.Len()))
+	}
+	return buffers
+}
+
+// readers returns readers of the shards, nil for the missing ones.
+func readers(shards []*bytes.Buffer, missing ...int) []io.Reader {
+	r := make([]io.Reader, len(shards))
+	for i, shard := range shards {
+		r[i] = bytes.NewReader(shard.Bytes())
+	}
+	for _, i := range missing {
+		r[i] = nil
+	}
+	return r
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	data := make([]byte, n)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	return data
+}
+
+func TestReader_RebuildsFromAnyDataShards(t *testing.T) {
+	for _, size := range []int{0, 1, 1024, 4 * 1024, 10*1024 + 7} {
+		data := randomBytes(t, size)
+		shards := encode(t, data, params)
+
+		for _, missing := range [][]int{nil, {0}, {1, 3}, {4, 5}, {0, 5}} {
+			r, err := erasure.NewReader(readers(shards, missing...), params, int64(size))
+			require.NoError(t, err)
+			got, err := io.ReadAll(r)
+			require.NoError(t, err, "size %d, missing %v", size, missing)
+			assert.Equal(t, data, got, "size %d, missing %v", size, missing)
+		}
+	}
+}
+
+func TestReader_ReadsOnlyDataShards(t *testing.T) {
+	data := randomBytes(t, 20*1024)
+	shards := encode(t, data, params)
+
+	r := readers(shards)
+	r[4] = &failingReader{}
+	r[5] = &failingReader{}
+	reader, err := erasure.NewReader(r, params, int64(len(data)))
+	require.NoError(t, err)
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+}
+
+func TestReader_TooFewShards(t *testing.T) {
+	data := randomBytes(t, 5000)
+	shards := encode(t, data, params)
+
+	r, err := erasure.NewReader(readers(shards, 0, 2, 4), params, int64(len(data)))
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.ErrorIs(t, err, erasure.ErrTooFewShards)
+}
+
+type failingReader struct {
+	r     io.Reader
+	after int
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.after <= 0 {
+		return 0, errors.New("connection reset")
+	}
+	n, err := f.r.Read(p[:min(len(p), f.after)])
+	f.after -= n
+	return n, err
+}
+
+func TestReader_SurvivesFailingShards(t *testing.T) {
+	data := randomBytes(t, 20*1024)
+	shards := encode(t, data, params)
+
+	r := readers(shards, 1)
+	r[3] = &failingReader{r: r[3], after: 2500}
+	reader, err := erasure.NewReader(r, params, int64(len(data)))
+	require.NoError(t, err)
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	r = readers(shards, 1, 2)
+	r[3] = &failingReader{r: r[3], after: 2500}
+	reader, err = erasure.NewReader(r, params, int64(len(data)))
+	require.NoError(t, err)
+	_, err = io.ReadAll(reader)
+	assert.ErrorIs(t, err, erasure.ErrTooFewShards)
+	assert.ErrorContains(t, err, "shard 3: connection reset")
+}
+
+func TestRebuild(t *testing.T) {
+	data := randomBytes(t, 9*1024+100)
+	shards := encode(t, data, params)
+
+	out := make([]io.Writer, params.Shards())
+	rebuilt := map[int]*bytes.Buffer{1: {}, 5: {}}
+	for i, b := range rebuilt {
+		out[i] = b
+	}
+	require.NoError(t, erasure.Rebuild(readers(shards, 1, 5), out, params, int64(len(data))))
+	for i, b := range rebuilt {
+		assert.Equal(t, shards[i].Bytes(), b.Bytes(), "shard %d", i)
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	assert.NoError(t, erasure.Params{DataShards: 10, ParityShards: 4, BlockSize: erasure.DefaultBlockSize}.Validate())
+	assert.Error(t, erasure.Params{DataShards: 4, BlockSize: 1024}.Validate())
+	assert.Error(t, erasure.Params{DataShards: 200, ParityShards: 100, BlockSize: 1024}.Validate())
+	assert.Error(t, erasure.Params{DataShards: 4, ParityShards: 2}.Validate())
+
+	_, err := erasure.Encode(bytes.NewReader(nil), params, make([]io.Writer, 5))
+	assert.Error(t, err)
+}
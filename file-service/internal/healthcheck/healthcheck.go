@@ -0,0 +1,43 @@
+// Package healthcheck provides the readiness checks of file-service's dependencies.
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+
+	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/shared/health"
+)
+
+// IPFS asks the IPFS API for its version.
+func IPFS(ipfs infrastructure.IPFSShell) health.Check {
+	return func(ctx context.Context) (string, error) {
+		// go-ipfs-apiのVersionはコンテキストを受け取らないため、タイムアウト時は結果を待たずに返す
+		type result struct {
+			version string
+			err     error
+		}
+		done := make(chan result, 1)
+		go func() {
+			version, _, err := ipfs.Version()
+			done <- result{version, err}
+		}()
+
+		select {
+		case r := <-done:
+			if r.err != nil {
+				return "", r.err
+			}
+			return "version " + r.version, nil
+		case <-ctx.Done():
+			return "", fmt.Errorf("IPFS did not answer: %w", ctx.Err())
+		}
+	}
+}
+
+// Redis sends PING.
+func Redis(redisClient infrastructure.RedisClient) health.Check {
+	return func(ctx context.Context) (string, error) {
+		return "", redisClient.Ping(ctx).Err()
+	}
+}
@@ -0,0 +1,77 @@
+package healthcheck_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/healthcheck"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/shared/health"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReady_AllChecksPass(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Add("ipfs", healthcheck.IPFS(&mocks.MockIPFSShell{
+		VersionFn: func() (string, string, error) { return "0.27.0", "abc", nil },
+	}))
+	checker.Add("redis", healthcheck.Redis(mocks.NewFakeRedisClient()))
+
+	rr := httptest.NewRecorder()
+	checker.Ready(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report health.Report
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Equal(t, "version 0.27.0", report.Checks["ipfs"].Detail)
+	assert.Equal(t, health.StatusOK, report.Checks["redis"].Status)
+}
+
+func TestReady_FailingDependency(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Add("ipfs", healthcheck.IPFS(&mocks.MockIPFSShell{
+		VersionFn: func() (string, string, error) { return "0.27.0", "abc", nil },
+	}))
+	checker.Add("redis", healthcheck.Redis(&mocks.MockRedisClient{
+		PingFn: func(ctx context.Context) *redis.StatusCmd {
+			return redis.NewStatusResult("", errors.New("connection refused"))
+		},
+	}))
+
+	rr := httptest.NewRecorder()
+	checker.Ready(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var report health.Report
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["redis"].Error)
+	assert.Equal(t, health.StatusOK, report.Checks["ipfs"].Status)
+}
+
+func TestReady_CheckTimesOut(t *testing.T) {
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	checker := health.NewChecker(20 * time.Millisecond)
+	checker.Add("ipfs", healthcheck.IPFS(&mocks.MockIPFSShell{
+		VersionFn: func() (string, string, error) {
+			<-blocked
+			return "", "", nil
+		},
+	}))
+
+	report := checker.Run(context.Background())
+
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Contains(t, report.Checks["ipfs"].Error, "deadline exceeded")
+}
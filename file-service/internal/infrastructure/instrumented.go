@@ -0,0 +1,211 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"decentralstore/file-service/internal/metrics"
+	"decentralstore/shared/tracing"
+
+	"github.com/go-redis/redis/v8"
+	shell "github.com/ipfs/go-ipfs-api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentIPFSShell records latency, errors and transferred bytes of every IPFS call.
+func InstrumentIPFSShell(ipfsShell IPFSShell) IPFSShell {
+	return &instrumentedIPFSShell{next: ipfsShell}
+}
+
+type instrumentedIPFSShell struct {
+	next IPFSShell
+}
+
+func (s *instrumentedIPFSShell) Add(r io.Reader, options ...shell.AddOpts) (string, error) {
+	start := time.Now()
+	cid, err := s.next.Add(&countingReader{Reader: r, counter: metrics.UploadedBytes.Add}, options...)
+	observeIPFS("add", start, err)
+	return cid, err
+}
+
+func (s *instrumentedIPFSShell) Cat(path string) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := s.next.Cat(path)
+	observeIPFS("cat", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &countingReadCloser{countingReader{Reader: rc, counter: metrics.DownloadedBytes.Add}, rc}, nil
+}
+
+func (s *instrumentedIPFSShell) Pin(path string) error {
+	start := time.Now()
+	err := s.next.Pin(path)
+	observeIPFS("pin", start, err)
+	return err
+}
+
+func (s *instrumentedIPFSShell) Unpin(path string) error {
+	start := time.Now()
+	err := s.next.Unpin(path)
+	observeIPFS("unpin", start, err)
+	return err
+}
+
+func (s *instrumentedIPFSShell) Pins() (map[string]shell.PinInfo, error) {
+	start := time.Now()
+	pins, err := s.next.Pins()
+	observeIPFS("pin_ls", start, err)
+	return pins, err
+}
+
+func (s *instrumentedIPFSShell) Version() (string, string, error) {
+	start := time.Now()
+	version, commit, err := s.next.Version()
+	observeIPFS("version", start, err)
+	return version, commit, err
+}
+
+func (s *instrumentedIPFSShell) FilesCp(ctx context.Context, src string, dest string, options ...shell.FilesOpt) error {
+	start := time.Now()
+	err := s.next.FilesCp(ctx, src, dest, options...)
+	observeIPFS("files_cp", start, err)
+	return err
+}
+
+func (s *instrumentedIPFSShell) FilesStat(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error) {
+	start := time.Now()
+	stat, err := s.next.FilesStat(ctx, path, options...)
+	observeIPFS("files_stat", start, err)
+	return stat, err
+}
+
+func (s *instrumentedIPFSShell) FilesRm(ctx context.Context, path string, force bool) error {
+	start := time.Now()
+	err := s.next.FilesRm(ctx, path, force)
+	observeIPFS("files_rm", start, err)
+	return err
+}
+
+func (s *instrumentedIPFSShell) DagExport(cid string) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := s.next.DagExport(cid)
+	observeIPFS("dag_export", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &countingReadCloser{countingReader{Reader: rc, counter: metrics.DownloadedBytes.Add}, rc}, nil
+}
+
+func (s *instrumentedIPFSShell) DagImport(car io.Reader) error {
+	start := time.Now()
+	err := s.next.DagImport(&countingReader{Reader: car, counter: metrics.UploadedBytes.Add})
+	observeIPFS("dag_import", start, err)
+	return err
+}
+
+func observeIPFS(operation string, start time.Time, err error) {
+	metrics.IPFSDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.IPFSErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+type countingReader struct {
+	io.Reader
+	counter func(float64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.counter(float64(n))
+	return n, err
+}
+
+type countingReadCloser struct {
+	countingReader
+	io.Closer
+}
+
+// redisMetricsHook records the latency and errors of every Redis command.
+type redisMetricsHook struct{}
+
+type redisStartKey struct{}
+
+func (redisMetricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (redisMetricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	observeRedis(ctx, cmd)
+	return nil
+}
+
+func (redisMetricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (redisMetricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		observeRedis(ctx, cmd)
+	}
+	return nil
+}
+
+func observeRedis(ctx context.Context, cmd redis.Cmder) {
+	start, ok := ctx.Value(redisStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	metrics.RedisDuration.WithLabelValues(cmd.Name()).Observe(time.Since(start).Seconds())
+	if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+		metrics.RedisErrors.WithLabelValues(cmd.Name()).Inc()
+	}
+}
+
+// redisTracingHook starts a client span for every Redis command.
+type redisTracingHook struct{}
+
+type redisSpanKey struct{}
+
+func (redisTracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, span := tracing.Start(ctx, "redis."+cmd.Name(), attribute.String("db.system", "redis"))
+	return context.WithValue(ctx, redisSpanKey{}, span), nil
+}
+
+func (redisTracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisSpan(ctx, cmd.Err())
+	return nil
+}
+
+func (redisTracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	ctx, span := tracing.Start(ctx, "redis.pipeline",
+		attribute.String("db.system", "redis"), attribute.Int("db.redis.num_cmd", len(cmds)))
+	return context.WithValue(ctx, redisSpanKey{}, span), nil
+}
+
+func (redisTracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	endRedisSpan(ctx, err)
+	return nil
+}
+
+func endRedisSpan(ctx context.Context, err error) {
+	span, ok := ctx.Value(redisSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
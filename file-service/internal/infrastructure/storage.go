@@ -2,37 +2,145 @@ package infrastructure
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net/http"
 	"time"
 
-	shell "github.com/ipfs/go-ipfs-api"
+	"decentralstore/file-service/internal/ipfspool"
+
 	"github.com/go-redis/redis/v8"
+	shell "github.com/ipfs/go-ipfs-api"
+	"github.com/ipfs/go-ipfs-api/options"
 )
 
 type IPFSShell interface {
 	Add(r io.Reader, options ...shell.AddOpts) (string, error)
 	Cat(path string) (io.ReadCloser, error)
+	Pin(path string) error
+	Unpin(path string) error
+	// Pins lists the pinned CIDs and the type of their pin.
+	Pins() (map[string]shell.PinInfo, error)
+	Version() (string, string, error)
+
+	// MFS commands, used to assemble directory uploads.
+	FilesCp(ctx context.Context, src string, dest string, options ...shell.FilesOpt) error
+	FilesStat(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error)
+	FilesRm(ctx context.Context, path string, force bool) error
+
+	// DAG commands, used to export and import CAR files.
+	DagExport(cid string) (io.ReadCloser, error)
+	DagImport(car io.Reader) error
+}
+
+// dagShell adds the DAG commands that go-ipfs-api lacks or exposes differently to shell.Shell.
+type dagShell struct {
+	*shell.Shell
+}
+
+// DagExport streams the DAG under cid as a CARv1 file.
+func (s dagShell) DagExport(cid string) (io.ReadCloser, error) {
+	resp, err := s.Request("dag/export", cid).Send(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		resp.Close()
+		return nil, resp.Error
+	}
+	return resp.Output, nil
+}
+
+// DagImport adds the blocks of a CARv1 or CARv2 file without pinning its roots.
+func (s dagShell) DagImport(car io.Reader) error {
+	_, err := s.DagImportWithOpts(car, options.Dag.PinRoots(false), options.Dag.Silent(true))
+	return err
 }
 
 type RedisClient interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	HIncrBy(ctx context.Context, key, field string, incr int64) *redis.IntCmd
+	HGet(ctx context.Context, key, field string) *redis.StringCmd
+	HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd
+	ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd
+	ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd
+	ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+// ShardStore stores the shards of erasure-coded files on individual IPFS nodes, which
+// ipfspool.Pool implements. Nodes are identified by name.
+type ShardStore interface {
+	// HealthyNodes lists the nodes that can store shards.
+	HealthyNodes() []string
+	// AddTo stores and pins r on one node and returns its CID.
+	AddTo(node string, r io.Reader) (string, error)
+	CatFrom(node string, cid string) (io.ReadCloser, error)
+	NodePins(node string) (map[string]shell.PinInfo, error)
+	// RemoveFrom releases a shard stored with AddTo.
+	RemoveFrom(node string, cid string) error
 }
 
 type StorageClient struct {
 	IPFSShell   IPFSShell
 	RedisClient RedisClient
+	// Shards is nil unless there are several IPFS nodes.
+	Shards ShardStore
+
+	ipfsTransport *http.Transport
+	ipfsPool      *ipfspool.Pool
 }
 
-func NewStorageClient(ipfsAPI, redisURL string) (*StorageClient, error) {
-	ipfsShell := shell.NewShell(ipfsAPI)
+// NewStorageClient connects to the IPFS API at each of ipfsAPIs and to Redis. With more
+// than one IPFS node, the nodes form a pool configured by poolOpts.
+func NewStorageClient(ipfsAPIs []string, redisURL string, poolOpts ipfspool.Options) (*StorageClient, error) {
+	if len(ipfsAPIs) == 0 {
+		return nil, errors.New("no IPFS API address")
+	}
+
+	// Unlike shell.NewShell, keep connections to the IPFS API alive and hold on to the
+	// transport so they can be closed on shutdown.
+	ipfsTransport := &http.Transport{Proxy: http.ProxyFromEnvironment}
 	redisClient := redis.NewClient(&redis.Options{
 		Addr: redisURL,
 	})
+	redisClient.AddHook(redisMetricsHook{})
+	redisClient.AddHook(redisTracingHook{})
+
+	client := &StorageClient{RedisClient: redisClient, ipfsTransport: ipfsTransport}
+	var ipfsShell IPFSShell = dagShell{shell.NewShellWithClient(ipfsAPIs[0], &http.Client{Transport: ipfsTransport})}
+	if len(ipfsAPIs) > 1 {
+		nodes := make([]ipfspool.Node, len(ipfsAPIs))
+		for i, addr := range ipfsAPIs {
+			nodes[i] = ipfspool.Node{Name: addr, Shell: dagShell{shell.NewShellWithClient(addr, &http.Client{Transport: ipfsTransport})}}
+		}
+		client.ipfsPool = ipfspool.NewPool(nodes, redisClient, poolOpts)
+		client.Shards = client.ipfsPool
+		ipfsShell = client.ipfsPool
+	}
+	client.IPFSShell = InstrumentIPFSShell(ipfsShell)
+	return client, nil
+}
 
-	return &StorageClient{
-		IPFSShell:   ipfsShell,
-		RedisClient: redisClient,
-	}, nil
+// Close stops the IPFS pool and releases the connections to IPFS and Redis. Requests still
+// using them fail.
+func (c *StorageClient) Close() error {
+	if c.ipfsPool != nil {
+		c.ipfsPool.Close()
+	}
+	if c.ipfsTransport != nil {
+		c.ipfsTransport.CloseIdleConnections()
+	}
+	if closer, ok := c.RedisClient.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
@@ -1,101 +1,71 @@
 package infrastructure_test
 
 import (
-	"context"
-	"decentralstore/file-service/internal/domain"
-	"decentralstore/file-service/internal/infrastructure"
-	"decentralstore/file-service/internal/mocks"
+	"errors"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/go-redis/redis/v8"
+	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/file-service/internal/ipfspool"
+	"decentralstore/file-service/internal/mocks"
+
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-func TestStorageClient_StoreMetadata(t *testing.T) {
-	mockRedis := new(mocks.MockRedisClient)
-	mockIPFS := new(mocks.MockIPFSShell)
-	storageClient := &infrastructure.StorageClient{
-		IPFSShell:   mockIPFS,
-		RedisClient: mockRedis,
-	}
+func TestNewStorageClient_RequiresIPFSAPI(t *testing.T) {
+	_, err := infrastructure.NewStorageClient(nil, "localhost:6379", ipfspool.Options{})
 
-	ctx := context.Background()
-	file := &domain.File{
-		ID:   "123",
-		Name: "test.txt",
-		CID:  "QmTest123",
-	}
-
-	mockRedis.On("Set", ctx, mock.Anything, mock.Anything, time.Duration(0)).Return(redis.NewStatusResult("OK", nil))
-
-	err := storageClient.StoreMetadata(ctx, file)
-
-	assert.NoError(t, err)
-	mockRedis.AssertExpectations(t)
+	assert.Error(t, err)
 }
 
-func TestStorageClient_GetMetadata(t *testing.T) {
-	mockRedis := new(mocks.MockRedisClient)
-	mockIPFS := new(mocks.MockIPFSShell)
-	storageClient := &infrastructure.StorageClient{
-		IPFSShell:   mockIPFS,
-		RedisClient: mockRedis,
-	}
-
-	ctx := context.Background()
-	fileID := "123"
-	fileJSON := `{"id":"123","name":"test.txt","cid":"QmTest123"}`
+func TestNewStorageClient_SingleNode(t *testing.T) {
+	storageClient, err := infrastructure.NewStorageClient([]string{"localhost:5001"}, "localhost:6379", ipfspool.Options{})
+	require.NoError(t, err)
+	defer storageClient.Close()
 
-	mockRedis.On("Get", ctx, "file:"+fileID).Return(redis.NewStringResult(fileJSON, nil))
-
-	file, err := storageClient.GetMetadata(ctx, fileID)
-
-	assert.NoError(t, err)
-	assert.NotNil(t, file)
-	assert.Equal(t, fileID, file.ID)
-	assert.Equal(t, "test.txt", file.Name)
-	assert.Equal(t, "QmTest123", file.CID)
-	mockRedis.AssertExpectations(t)
+	assert.NotNil(t, storageClient.IPFSShell)
+	assert.NotNil(t, storageClient.RedisClient)
+	assert.Nil(t, storageClient.Shards)
 }
 
-func TestStorageClient_DeleteMetadata(t *testing.T) {
-	mockRedis := new(mocks.MockRedisClient)
-	mockIPFS := new(mocks.MockIPFSShell)
-	storageClient := &infrastructure.StorageClient{
-		IPFSShell:   mockIPFS,
-		RedisClient: mockRedis,
-	}
-
-	ctx := context.Background()
-	fileID := "123"
-
-	mockRedis.On("Del", ctx, "file:"+fileID).Return(redis.NewIntResult(1, nil))
-
-	err := storageClient.DeleteMetadata(ctx, fileID)
+func TestNewStorageClient_PoolsSeveralNodes(t *testing.T) {
+	storageClient, err := infrastructure.NewStorageClient([]string{"localhost:5001", "localhost:5002"}, "localhost:6379",
+		ipfspool.Options{HealthInterval: time.Hour, RepairInterval: time.Hour})
+	require.NoError(t, err)
+	defer storageClient.Close()
 
-	assert.NoError(t, err)
-	mockRedis.AssertExpectations(t)
+	assert.NotNil(t, storageClient.IPFSShell)
+	assert.IsType(t, &ipfspool.Pool{}, storageClient.Shards)
 }
 
-func TestStorageClient_GetMetadata_NotFound(t *testing.T) {
-	mockRedis := new(mocks.MockRedisClient)
-	mockIPFS := new(mocks.MockIPFSShell)
-	storageClient := &infrastructure.StorageClient{
-		IPFSShell:   mockIPFS,
-		RedisClient: mockRedis,
-	}
-
-	ctx := context.Background()
-	fileID := "123"
-
-	mockRedis.On("Get", ctx, "file:"+fileID).Return(redis.NewStringResult("", redis.Nil))
+func TestInstrumentIPFSShell_PassesCallsThrough(t *testing.T) {
+	ipfs := mocks.NewFakeIPFS()
+	ipfsShell := infrastructure.InstrumentIPFSShell(ipfs.Shell())
+
+	cid, err := ipfsShell.Add(strings.NewReader("test content"))
+	require.NoError(t, err)
+	require.NoError(t, ipfsShell.Pin(cid))
+	assert.True(t, ipfs.IsPinned(cid))
+
+	reader, err := ipfsShell.Cat(cid)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "test content", string(data))
+}
 
-	file, err := storageClient.GetMetadata(ctx, fileID)
+func TestInstrumentIPFSShell_ReturnsErrors(t *testing.T) {
+	failure := errors.New("connection refused")
+	ipfsShell := infrastructure.InstrumentIPFSShell(&mocks.MockIPFSShell{
+		CatFn: func(path string) (io.ReadCloser, error) { return nil, failure },
+		PinFn: func(path string) error { return failure },
+	})
 
-	assert.Error(t, err)
-	assert.Nil(t, file)
-	assert.IsType(t, &domain.ErrNotFound{}, err)
-	mockRedis.AssertExpectations(t)
+	_, err := ipfsShell.Cat("missing")
+	assert.ErrorIs(t, err, failure)
+	assert.ErrorIs(t, ipfsShell.Pin("missing"), failure)
 }
@@ -0,0 +1,220 @@
+package ipfspool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"decentralstore/file-service/internal/metrics"
+)
+
+func (p *Pool) run() {
+	defer p.wg.Done()
+	health := time.NewTicker(p.opts.HealthInterval)
+	defer health.Stop()
+	repair := time.NewTicker(p.opts.RepairInterval)
+	defer repair.Stop()
+
+	for {
+		select {
+		case <-health.C:
+			p.CheckHealth()
+		case <-repair.C:
+			p.Repair(context.Background())
+		case <-p.closed:
+			return
+		}
+	}
+}
+
+// CheckHealth asks every node for its version and marks the nodes that do not answer
+// within HealthTimeout as unhealthy.
+func (p *Pool) CheckHealth() {
+	done := make(chan struct{})
+	for _, n := range p.nodes {
+		go func() {
+			p.setHealthy(n, p.ping(n))
+			done <- struct{}{}
+		}()
+	}
+	for range p.nodes {
+		<-done
+	}
+}
+
+func (p *Pool) ping(n *node) error {
+	answered := make(chan error, 1)
+	go func() {
+		_, _, err := n.Shell.Version()
+		answered <- err
+	}()
+	select {
+	case err := <-answered:
+		return err
+	case <-time.After(p.opts.HealthTimeout):
+		return errors.New("health check timed out")
+	}
+}
+
+func (p *Pool) setHealthy(n *node, err error) {
+	healthy := err == nil
+	if n.healthy.Swap(healthy) != healthy {
+		if healthy {
+			slog.Info("IPFS node is healthy again", "node", n.Name)
+		} else {
+			slog.Warn("IPFS node is unhealthy", "node", n.Name, "error", err)
+		}
+	}
+	up := 0.0
+	if healthy {
+		up = 1
+	}
+	metrics.IPFSNodeUp.WithLabelValues(n.Name).Set(up)
+}
+
+// Repair retries the unpins that nodes missed, and copies every pin held by fewer than
+// Replication healthy nodes to more healthy nodes. Pins on unhealthy nodes do not count,
+// so their content is copied elsewhere while they are down. Shards are left alone, and so
+// is content that is unpinned while the repair runs.
+func (p *Pool) Repair(ctx context.Context) {
+	healthy := p.healthyNodes()
+	if len(healthy) == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.unpinned = make(map[string]bool)
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.unpinned = nil
+		p.mu.Unlock()
+	}()
+	shards, err := p.shardCIDs(ctx)
+	if err != nil {
+		slog.Warn("Failed to list shards, skipping repair", "error", err)
+		return
+	}
+
+	locations := make(map[string][]*node)
+	for _, n := range healthy {
+		pins, err := n.Shell.Pins()
+		if err != nil {
+			// Without the pins of every healthy node, content would be copied needlessly.
+			slog.Warn("Failed to list pins, skipping repair", "node", n.Name, "error", err)
+			return
+		}
+		for cid, info := range pins {
+			if info.Type == "recursive" {
+				locations[cid] = append(locations[cid], n)
+			}
+		}
+	}
+
+	for cid := range p.unpinPending(ctx) {
+		delete(locations, cid)
+	}
+	for cid := range shards {
+		delete(locations, cid)
+	}
+
+	under := 0
+	for cid, holders := range locations {
+		select {
+		case <-p.closed:
+			return
+		default:
+		}
+		for _, target := range healthy {
+			if len(holders) >= p.replication || p.wasUnpinned(cid) {
+				break
+			}
+			if slices.Contains(holders, target) {
+				continue
+			}
+			if err := copyPin(holders[0], target, cid); err != nil {
+				slog.Warn("Failed to copy pin", "cid", cid, "from", holders[0].Name, "to", target.Name, "error", err)
+				continue
+			}
+			// Unpin may have missed the copy while it was being pinned.
+			if p.wasUnpinned(cid) {
+				p.unpinCopy(ctx, target, cid)
+				break
+			}
+			holders = append(holders, target)
+		}
+		locations[cid] = holders
+		if len(holders) < p.replication {
+			under++
+		}
+	}
+	metrics.IPFSUnderReplicated.Set(float64(under))
+
+	p.mu.Lock()
+	for cid := range p.unpinned {
+		delete(locations, cid)
+	}
+	p.locations = locations
+	p.mu.Unlock()
+}
+
+// wasUnpinned reports whether cid was unpinned since the running repair started.
+func (p *Pool) wasUnpinned(cid string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.unpinned[cid]
+}
+
+// unpinCopy removes a copy of unpinned content again, or leaves it to the next repair.
+func (p *Pool) unpinCopy(ctx context.Context, n *node, cid string) {
+	err := n.Shell.Unpin(cid)
+	if err == nil || notPinned(err) {
+		return
+	}
+	slog.Warn("IPFS node failed", "node", n.Name, "operation", "unpin", "error", err)
+	if err := p.redis.SAdd(ctx, unpinKey(n.Name), cid).Err(); err != nil {
+		slog.Warn("Failed to record pending unpin", "node", n.Name, "cid", cid, "error", err)
+	}
+}
+
+// unpinPending unpins the CIDs that healthy nodes missed and returns the CIDs that
+// are still to be unpinned somewhere, which must not be copied.
+func (p *Pool) unpinPending(ctx context.Context) map[string]bool {
+	pending := make(map[string]bool)
+	for _, n := range p.nodes {
+		cids, err := p.redis.SMembers(ctx, unpinKey(n.Name)).Result()
+		if err != nil {
+			slog.Warn("Failed to get pending unpins", "node", n.Name, "error", err)
+			continue
+		}
+		for _, cid := range cids {
+			pending[cid] = true
+			if !n.healthy.Load() {
+				continue
+			}
+			if err := n.Shell.Unpin(cid); err != nil && !notPinned(err) {
+				slog.Warn("IPFS node failed", "node", n.Name, "operation", "unpin", "error", err)
+				continue
+			}
+			if err := p.redis.SRem(ctx, unpinKey(n.Name), cid).Err(); err != nil {
+				slog.Warn("Failed to clear pending unpin", "node", n.Name, "cid", cid, "error", err)
+			}
+		}
+	}
+	return pending
+}
+
+// copyPin copies the DAG of cid from one node to another as a CAR stream and pins it there,
+// so that the nodes need not be connected.
+func copyPin(from, to *node, cid string) error {
+	export, err := from.Shell.DagExport(cid)
+	if err != nil {
+		return err
+	}
+	defer export.Close()
+	if err := to.Shell.DagImport(export); err != nil {
+		return err
+	}
+	return to.Shell.Pin(cid)
+}
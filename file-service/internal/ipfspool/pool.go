@@ -0,0 +1,741 @@
+// Package ipfspool spreads the IPFS calls of file-service over several nodes. Pins are
+// written to a number of nodes given by the replication factor, reads go to a healthy node
+// that has the content and fall back to the others, and a background checker copies pins
+// that fall below the replication factor to more nodes.
+package ipfspool
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+// Shell is the IPFS API of one node. It has the methods of infrastructure.IPFSShell,
+// which the pool implements too.
+type Shell interface {
+	Add(r io.Reader, options ...shell.AddOpts) (string, error)
+	Cat(path string) (io.ReadCloser, error)
+	Pin(path string) error
+	Unpin(path string) error
+	Pins() (map[string]shell.PinInfo, error)
+	Version() (string, string, error)
+	FilesCp(ctx context.Context, src string, dest string, options ...shell.FilesOpt) error
+	FilesStat(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error)
+	FilesRm(ctx context.Context, path string, force bool) error
+	DagExport(cid string) (io.ReadCloser, error)
+	DagImport(car io.Reader) error
+}
+
+// RedisClient is the part of infrastructure.RedisClient the pool uses to remember the
+// unpins that a node missed and the shards stored on single nodes.
+type RedisClient interface {
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	HIncrBy(ctx context.Context, key, field string, incr int64) *redis.IntCmd
+	HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd
+}
+
+// Node is an IPFS node of a pool.
+type Node struct {
+	// Name identifies the node in logs, metrics and Redis keys, e.g. its API address.
+	Name  string
+	Shell Shell
+}
+
+// Options configures a Pool. Zero values select the defaults.
+type Options struct {
+	// Replication is the number of nodes that pin each CID (default 2, at most the number of nodes).
+	Replication int
+	// HealthInterval is how often every node is checked (default 10s). A node that does not
+	// answer within HealthTimeout (default 5s) only receives reads of last resort until it
+	// passes a check again.
+	HealthInterval time.Duration
+	HealthTimeout  time.Duration
+	// RepairInterval is how often pins are counted and copied to more nodes (default 5m).
+	RepairInterval time.Duration
+	// ReadTimeout is how long a read waits for the first byte of a node before trying the
+	// next one (default 30s).
+	ReadTimeout time.Duration
+	// WriteTimeout is how long a write waits for a node to take each chunk of the content
+	// (default 30s). A node that misses it is dropped from the write, which continues on the
+	// other nodes; the checker copies the pin to more nodes later.
+	WriteTimeout time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Replication <= 0 {
+		o.Replication = 2
+	}
+	if o.HealthInterval <= 0 {
+		o.HealthInterval = 10 * time.Second
+	}
+	if o.HealthTimeout <= 0 {
+		o.HealthTimeout = 5 * time.Second
+	}
+	if o.RepairInterval <= 0 {
+		o.RepairInterval = 5 * time.Minute
+	}
+	if o.ReadTimeout <= 0 {
+		o.ReadTimeout = 30 * time.Second
+	}
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = 30 * time.Second
+	}
+	return o
+}
+
+type node struct {
+	Node
+	healthy atomic.Bool
+}
+
+// Pool is an IPFS shell backed by several nodes.
+//
+// Writes go to the first Replication healthy nodes in the configured order, so that the
+// calls of one directory upload, which build the directory in MFS, reach the same nodes.
+// A write succeeds when one node succeeds; the checker copies the pin to more nodes later.
+// Unpins that fail, or that a node misses while it is unhealthy, are kept in Redis and
+// retried by the checker, which also never copies those CIDs:
+//
+//	ipfs:unpin:<node> -> set of CIDs still to be unpinned on the node
+type Pool struct {
+	nodes       []*node
+	redis       RedisClient
+	opts        Options
+	replication int
+
+	// locations lists the nodes known to pin a CID. It is only a hint to order reads.
+	// unpinned collects the CIDs unpinned while a repair runs, which it must not copy; it is
+	// nil otherwise.
+	mu        sync.Mutex
+	locations map[string][]*node
+	unpinned  map[string]bool
+
+	closed chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// NewPool checks the health of nodes and keeps checking their health and replication in
+// the background. Call Close to stop it.
+func NewPool(nodes []Node, redisClient RedisClient, opts Options) *Pool {
+	p := &Pool{
+		redis:     redisClient,
+		opts:      opts.withDefaults(),
+		locations: make(map[string][]*node),
+		closed:    make(chan struct{}),
+	}
+	for _, n := range nodes {
+		pn := &node{Node: n}
+		pn.healthy.Store(true)
+		p.nodes = append(p.nodes, pn)
+	}
+	p.replication = min(p.opts.Replication, len(p.nodes))
+
+	p.CheckHealth()
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// Close stops the checker and waits for a running repair to finish its current copy.
+func (p *Pool) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	p.wg.Wait()
+	return nil
+}
+
+// Add stores the content on the write nodes at once. Content added with shell.Pin(false)
+// is not recorded as pinned, since the nodes may collect it before it is pinned.
+func (p *Pool) Add(r io.Reader, options ...shell.AddOpts) (string, error) {
+	nodes := p.writeNodes()
+	cids, err := fanOut("add", nodes, r, p.opts.WriteTimeout, func(n *node, r io.Reader) (string, error) {
+		return n.Shell.Add(r, options...)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var cid string
+	var stored []*node
+	for i, c := range cids {
+		if c == "" {
+			continue
+		}
+		if cid != "" && c != cid {
+			return "", fmt.Errorf("IPFS nodes returned different CIDs %s and %s", cid, c)
+		}
+		cid = c
+		stored = append(stored, nodes[i])
+	}
+	if PinsAdd(options...) {
+		p.keep(cid)
+		p.located(cid, stored)
+	}
+	return cid, nil
+}
+
+// PinsAdd reports whether an add with options pins the content, as it does unless
+// shell.Pin(false) is among them.
+func PinsAdd(options ...shell.AddOpts) bool {
+	// The options only write into a request, whose options are not exported.
+	rb := shell.NewShell("").Request("add")
+	for _, option := range options {
+		option(rb)
+	}
+	pin := reflect.ValueOf(rb).Elem().FieldByName("opts").MapIndex(reflect.ValueOf("pin"))
+	return !pin.IsValid() || pin.String() != "false"
+}
+
+// Cat reads from the nodes that have the content first. When a node fails in the middle,
+// the rest is read from the next node.
+func (p *Pool) Cat(path string) (io.ReadCloser, error) {
+	return p.read("cat", path, true, func(n *node) (io.ReadCloser, error) {
+		return n.Shell.Cat(path)
+	})
+}
+
+// Pin pins path on the write nodes.
+func (p *Pool) Pin(path string) error {
+	cid := rootCID(path)
+	p.keep(cid)
+
+	var pinned []*node
+	var errs []error
+	for _, n := range p.writeNodes() {
+		if err := n.Shell.Pin(path); err != nil {
+			errs = append(errs, nodeError(n, "pin", err))
+			continue
+		}
+		pinned = append(pinned, n)
+	}
+	if len(pinned) == 0 {
+		return errors.Join(errs...)
+	}
+	p.located(cid, pinned)
+	return nil
+}
+
+// Unpin unpins path on every node. Nodes that are unhealthy or fail are unpinned later
+// by the checker, so Unpin only fails if that cannot be recorded.
+func (p *Pool) Unpin(path string) error {
+	cid := rootCID(path)
+	p.mu.Lock()
+	delete(p.locations, cid)
+	if p.unpinned != nil {
+		p.unpinned[cid] = true
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, n := range p.nodes {
+		if n.healthy.Load() {
+			err := n.Shell.Unpin(path)
+			if err == nil || notPinned(err) {
+				continue
+			}
+			slog.Warn("IPFS node failed", "node", n.Name, "operation", "unpin", "error", err)
+		}
+		if err := p.redis.SAdd(context.Background(), unpinKey(n.Name), cid).Err(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record unpin of %s on IPFS node %s: %w", cid, n.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Pins lists the pins of every healthy node.
+func (p *Pool) Pins() (map[string]shell.PinInfo, error) {
+	all := make(map[string]shell.PinInfo)
+	var errs []error
+	listed := false
+	for _, n := range p.healthyNodes() {
+		pins, err := n.Shell.Pins()
+		if err != nil {
+			errs = append(errs, nodeError(n, "pin_ls", err))
+			continue
+		}
+		listed = true
+		for cid, info := range pins {
+			all[cid] = info
+		}
+	}
+	if !listed {
+		return nil, errors.Join(append(errs, errors.New("no healthy IPFS node"))...)
+	}
+	return all, nil
+}
+
+// Version returns the version of the first node that answers.
+func (p *Pool) Version() (string, string, error) {
+	var errs []error
+	for _, n := range p.readNodes("") {
+		version, commit, err := n.Shell.Version()
+		if err == nil {
+			return version, commit, nil
+		}
+		errs = append(errs, nodeError(n, "version", err))
+	}
+	return "", "", errors.Join(errs...)
+}
+
+// FilesCp copies into MFS on the write nodes.
+func (p *Pool) FilesCp(ctx context.Context, src string, dest string, options ...shell.FilesOpt) error {
+	return p.write("files_cp", func(n *node) error {
+		return n.Shell.FilesCp(ctx, src, dest, options...)
+	})
+}
+
+// FilesStat stats an /ipfs/ path on the nodes that have it first, and an MFS path on the
+// write nodes, returning the first answer.
+func (p *Pool) FilesStat(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error) {
+	nodes := p.writeNodes()
+	if rest, ok := strings.CutPrefix(path, "/ipfs/"); ok {
+		nodes = p.readNodes(rootCID(rest))
+	}
+
+	var errs []error
+	for _, n := range nodes {
+		statCtx, cancel := context.WithTimeout(ctx, p.opts.ReadTimeout)
+		stat, err := n.Shell.FilesStat(statCtx, path, options...)
+		cancel()
+		if err == nil {
+			return stat, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errs = append(errs, nodeError(n, "files_stat", err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// FilesRm removes from MFS on the write nodes.
+func (p *Pool) FilesRm(ctx context.Context, path string, force bool) error {
+	return p.write("files_rm", func(n *node) error {
+		return n.Shell.FilesRm(ctx, path, force)
+	})
+}
+
+// DagExport exports from the nodes that have the DAG first. Unlike Cat, it only falls
+// back to the next node before the first byte, because nodes may order blocks differently.
+func (p *Pool) DagExport(cid string) (io.ReadCloser, error) {
+	return p.read("dag_export", cid, false, func(n *node) (io.ReadCloser, error) {
+		return n.Shell.DagExport(cid)
+	})
+}
+
+// DagImport imports the blocks into the write nodes at once.
+func (p *Pool) DagImport(car io.Reader) error {
+	_, err := fanOut("dag_import", p.writeNodes(), car, p.opts.WriteTimeout, func(n *node, r io.Reader) (struct{}, error) {
+		return struct{}{}, n.Shell.DagImport(r)
+	})
+	return err
+}
+
+// writeNodes returns the first Replication healthy nodes. Without enough healthy nodes it
+// fills up with unhealthy ones, in case the health checks are wrong.
+func (p *Pool) writeNodes() []*node {
+	nodes := make([]*node, 0, p.replication)
+	for _, n := range p.healthyNodes() {
+		if len(nodes) < p.replication {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, n := range p.nodes {
+		if len(nodes) < p.replication && !n.healthy.Load() {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// readNodes orders the nodes to read cid from: healthy nodes known to pin it, the other
+// healthy nodes, then the unhealthy ones.
+func (p *Pool) readNodes(cid string) []*node {
+	p.mu.Lock()
+	holders := p.locations[cid]
+	p.mu.Unlock()
+
+	rank := func(n *node) int {
+		switch {
+		case !n.healthy.Load():
+			return 2
+		case slices.Contains(holders, n):
+			return 0
+		default:
+			return 1
+		}
+	}
+	nodes := slices.Clone(p.nodes)
+	slices.SortStableFunc(nodes, func(a, b *node) int { return rank(a) - rank(b) })
+	return nodes
+}
+
+func (p *Pool) healthyNodes() []*node {
+	var nodes []*node
+	for _, n := range p.nodes {
+		if n.healthy.Load() {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// write calls fn on the write nodes and succeeds if one of them does.
+func (p *Pool) write(operation string, fn func(*node) error) error {
+	nodes := p.writeNodes()
+	var errs []error
+	for _, n := range nodes {
+		if err := fn(n); err != nil {
+			errs = append(errs, nodeError(n, operation, err))
+		}
+	}
+	if len(errs) == len(nodes) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		slog.Warn("IPFS node failed", "operation", operation, "error", err)
+	}
+	return nil
+}
+
+// keep cancels the pending unpins of cid, which is pinned again.
+func (p *Pool) keep(cid string) {
+	p.mu.Lock()
+	delete(p.unpinned, cid)
+	p.mu.Unlock()
+	for _, n := range p.nodes {
+		if err := p.redis.SRem(context.Background(), unpinKey(n.Name), cid).Err(); err != nil {
+			slog.Warn("Failed to cancel pending unpin", "node", n.Name, "cid", cid, "error", err)
+		}
+	}
+}
+
+// located records that nodes pin cid and warns when they are too few.
+func (p *Pool) located(cid string, nodes []*node) {
+	p.mu.Lock()
+	holders := p.locations[cid]
+	for _, n := range nodes {
+		if !slices.Contains(holders, n) {
+			holders = append(holders, n)
+		}
+	}
+	p.locations[cid] = holders
+	p.mu.Unlock()
+
+	if len(nodes) < p.replication {
+		slog.Warn("Content is stored on fewer IPFS nodes than the replication factor until the next repair",
+			"cid", cid, "nodes", len(nodes), "replication", p.replication)
+	}
+}
+
+// read opens the first node that sends data within ReadTimeout.
+func (p *Pool) read(operation, path string, resume bool, open func(*node) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	r := &failoverReader{
+		pool:      p,
+		operation: operation,
+		nodes:     p.readNodes(rootCID(path)),
+		open:      open,
+		resume:    resume,
+		err:       errors.New("no IPFS node"),
+	}
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// openWithin opens n and waits up to ReadTimeout for its first byte.
+func (p *Pool) openWithin(n *node, open func(*node) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	type opened struct {
+		rc  io.ReadCloser
+		err error
+	}
+	var mu sync.Mutex
+	var rc io.ReadCloser
+	abandoned := false
+	done := make(chan opened, 1)
+
+	go func() {
+		body, err := open(n)
+		if err != nil {
+			done <- opened{err: err}
+			return
+		}
+		mu.Lock()
+		if abandoned {
+			mu.Unlock()
+			body.Close()
+			return
+		}
+		rc = body
+		mu.Unlock()
+
+		buffered := bufio.NewReader(body)
+		if _, err := buffered.Peek(1); err != nil && err != io.EOF {
+			body.Close()
+			done <- opened{err: err}
+			return
+		}
+		done <- opened{rc: readCloser{buffered, body}}
+	}()
+
+	timer := time.NewTimer(p.opts.ReadTimeout)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		return o.rc, o.err
+	case <-timer.C:
+		// Closing the body unblocks the goroutine.
+		mu.Lock()
+		abandoned = true
+		if rc != nil {
+			rc.Close()
+		}
+		mu.Unlock()
+		return nil, fmt.Errorf("no data within %s", p.opts.ReadTimeout)
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// failoverReader reads from the first node that answers and switches to the next one when
+// a node fails. With resume, it skips the bytes already read on the new node, which relies
+// on every node returning the same bytes; otherwise it only switches before the first byte.
+type failoverReader struct {
+	pool      *Pool
+	operation string
+	nodes     []*node
+	open      func(*node) (io.ReadCloser, error)
+	resume    bool
+
+	current io.ReadCloser
+	read    int64
+	err     error
+}
+
+func (r *failoverReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+		n, err := r.current.Read(p)
+		r.read += int64(n)
+		if err == nil || err == io.EOF || (!r.resume && r.read > 0) {
+			return n, err
+		}
+		r.current.Close()
+		r.current = nil
+		r.err = err
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *failoverReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// next opens the next node, skipping what was read already.
+func (r *failoverReader) next() error {
+	for len(r.nodes) > 0 {
+		n := r.nodes[0]
+		r.nodes = r.nodes[1:]
+
+		rc, err := r.pool.openWithin(n, r.open)
+		if err == nil && r.read > 0 {
+			if _, err = io.CopyN(io.Discard, rc, r.read); err != nil {
+				rc.Close()
+			}
+		}
+		if err != nil {
+			if r.read > 0 || len(r.nodes) > 0 {
+				slog.Warn("IPFS node failed", "node", n.Name, "operation", r.operation, "error", err)
+			}
+			r.err = nodeError(n, r.operation, err)
+			continue
+		}
+		r.current = rc
+		return nil
+	}
+	return r.err
+}
+
+// fanOut streams r to every node at once through write. A node that fails, or that does not
+// take a chunk within timeout, stops receiving data while the others continue, so the slowest
+// live node sets the pace. It returns the results indexed like nodes, zero for the nodes that
+// failed, and fails if r fails or every node does.
+func fanOut[T any](operation string, nodes []*node, r io.Reader, timeout time.Duration, write func(*node, io.Reader) (T, error)) ([]T, error) {
+	results := make([]T, len(nodes))
+	errs := make([]error, len(nodes))
+	pipes := make([]*io.PipeWriter, len(nodes))
+	finished := make([]chan struct{}, len(nodes))
+	for i, n := range nodes {
+		pr, pw := io.Pipe()
+		pipes[i] = pw
+		finished[i] = make(chan struct{})
+		go func() {
+			defer close(finished[i])
+			results[i], errs[i] = write(n, pr)
+			if errs[i] == nil {
+				// Drain what the node did not read, so that the others still get it.
+				io.Copy(io.Discard, pr)
+			}
+			pr.CloseWithError(errNodeDone)
+		}()
+	}
+
+	// A stalled node may never return from write, so its result is not waited for.
+	stalled := make([]error, len(nodes))
+	var readErr error
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			live, dropped := writeChunk(pipes, buf[:n], timeout)
+			for _, i := range dropped {
+				stalled[i] = fmt.Errorf("%w within %s", errNodeStalled, timeout)
+				pipes[i].CloseWithError(stalled[i])
+				pipes[i] = nil
+			}
+			if len(dropped) > 0 {
+				// A dropped node may still be copying the chunk; do not overwrite it.
+				buf = make([]byte, len(buf))
+			}
+			if live == 0 {
+				break
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+	}
+	for _, pw := range pipes {
+		if pw != nil {
+			pw.CloseWithError(readErr)
+		}
+	}
+	returned := make([]T, len(nodes))
+	for i := range nodes {
+		if stalled[i] == nil {
+			<-finished[i]
+			returned[i] = results[i]
+		}
+	}
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	var failed []error
+	for i := range nodes {
+		err := stalled[i]
+		if err == nil {
+			err = errs[i]
+		}
+		if err != nil {
+			failed = append(failed, nodeError(nodes[i], operation, err))
+		}
+	}
+	if len(failed) == len(nodes) {
+		return nil, errors.Join(failed...)
+	}
+	for _, err := range failed {
+		slog.Warn("IPFS node failed", "operation", operation, "error", err)
+	}
+	return returned, nil
+}
+
+// writeChunk writes chunk to every pipe at once. It returns how many pipes took it and the
+// indexes of those that did not within timeout; pipes that fail are set to nil.
+func writeChunk(pipes []*io.PipeWriter, chunk []byte, timeout time.Duration) (int, []int) {
+	type written struct {
+		i   int
+		err error
+	}
+	done := make(chan written, len(pipes))
+	pending := make(map[int]bool)
+	for i, pw := range pipes {
+		if pw == nil {
+			continue
+		}
+		pending[i] = true
+		go func() {
+			_, err := pw.Write(chunk)
+			done <- written{i, err}
+		}()
+	}
+
+	live := 0
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for len(pending) > 0 {
+		select {
+		case w := <-done:
+			delete(pending, w.i)
+			if w.err != nil {
+				pipes[w.i] = nil
+				continue
+			}
+			live++
+		case <-timer.C:
+			var dropped []int
+			for i := range pending {
+				dropped = append(dropped, i)
+			}
+			return live, dropped
+		}
+	}
+	return live, nil
+}
+
+var (
+	errNodeDone    = errors.New("IPFS node stopped reading")
+	errNodeStalled = errors.New("IPFS node took no data")
+)
+
+func nodeError(n *node, operation string, err error) error {
+	return fmt.Errorf("IPFS node %s: %s: %w", n.Name, operation, err)
+}
+
+// notPinned reports whether Unpin failed because the node does not pin the path.
+func notPinned(err error) bool {
+	return strings.Contains(err.Error(), "not pinned")
+}
+
+// rootCID returns the CID a path such as "<cid>/dir/file" starts with.
+func rootCID(path string) string {
+	cid, _, _ := strings.Cut(strings.TrimPrefix(path, "/ipfs/"), "/")
+	return cid
+}
+
+func unpinKey(nodeName string) string {
+	return "ipfs:unpin:" + nodeName
+}
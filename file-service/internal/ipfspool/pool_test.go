@@ -0,0 +1,488 @@
+package ipfspool_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"decentralstore/file-service/internal/ipfspool"
+	"decentralstore/file-service/internal/mocks"
+
+	shell "github.com/ipfs/go-ipfs-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode is an in-memory IPFS node. Every file is a single block whose CID is its hash.
+type fakeNode struct {
+	mu     sync.Mutex
+	blocks map[string][]byte
+	pins   map[string]bool
+	mfs    map[string]string
+	calls  []string
+
+	// down fails every call; hang blocks Cat, Add and DagExport until released; breakAfter
+	// fails Cat after that many bytes.
+	down       bool
+	hang       chan struct{}
+	breakAfter int
+}
+
+func newFakeNode() *fakeNode {
+	return &fakeNode{blocks: make(map[string][]byte), pins: make(map[string]bool), mfs: make(map[string]string)}
+}
+
+var errDown = errors.New("connection refused")
+
+func (f *fakeNode) call(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, name)
+	if f.down {
+		return errDown
+	}
+	return nil
+}
+
+func (f *fakeNode) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *fakeNode) pinned(cid string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pins[cid]
+}
+
+func (f *fakeNode) store(data []byte) string {
+	sum := sha256.Sum256(data)
+	cid := "Qm" + hex.EncodeToString(sum[:8])
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocks[cid] = data
+	return cid
+}
+
+func (f *fakeNode) Add(r io.Reader, options ...shell.AddOpts) (string, error) {
+	if err := f.call("add"); err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	hang := f.hang
+	f.mu.Unlock()
+	if hang != nil {
+		<-hang
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	cid := f.store(data)
+	if ipfspool.PinsAdd(options...) {
+		f.mu.Lock()
+		f.pins[cid] = true
+		f.mu.Unlock()
+	}
+	return cid, nil
+}
+
+func (f *fakeNode) Cat(path string) (io.ReadCloser, error) {
+	if err := f.call("cat"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	data, ok := f.blocks[path]
+	hang, breakAfter := f.hang, f.breakAfter
+	f.mu.Unlock()
+	if !ok {
+		return nil, errors.New("block not found")
+	}
+	if hang != nil {
+		return io.NopCloser(io.MultiReader(blockUntil(hang), bytes.NewReader(data))), nil
+	}
+	if breakAfter > 0 {
+		return io.NopCloser(io.MultiReader(bytes.NewReader(data[:breakAfter]), iotest.ErrReader(errors.New("connection reset")))), nil
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// blockUntil returns a reader that blocks until done is closed and then returns nothing.
+func blockUntil(done chan struct{}) io.Reader {
+	return readerFunc(func(p []byte) (int, error) {
+		<-done
+		return 0, io.EOF
+	})
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+func (f *fakeNode) Pin(path string) error {
+	if err := f.call("pin"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.blocks[path]; !ok {
+		return errors.New("block not found")
+	}
+	f.pins[path] = true
+	return nil
+}
+
+func (f *fakeNode) Unpin(path string) error {
+	if err := f.call("unpin"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.pins[path] {
+		return errors.New("not pinned or pinned indirectly")
+	}
+	delete(f.pins, path)
+	return nil
+}
+
+func (f *fakeNode) Pins() (map[string]shell.PinInfo, error) {
+	if err := f.call("pins"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pins := make(map[string]shell.PinInfo)
+	for cid := range f.pins {
+		pins[cid] = shell.PinInfo{Type: "recursive"}
+	}
+	return pins, nil
+}
+
+func (f *fakeNode) Version() (string, string, error) {
+	if err := f.call("version"); err != nil {
+		return "", "", err
+	}
+	return "0.27.0", "", nil
+}
+
+func (f *fakeNode) FilesCp(ctx context.Context, src string, dest string, options ...shell.FilesOpt) error {
+	if err := f.call("files_cp"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mfs[dest] = strings.TrimPrefix(src, "/ipfs/")
+	return nil
+}
+
+func (f *fakeNode) FilesStat(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error) {
+	if err := f.call("files_stat"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cid, ok := f.mfs[path]
+	if rest, isIPFS := strings.CutPrefix(path, "/ipfs/"); isIPFS {
+		cid, ok = rest, f.blocks[rest] != nil
+	}
+	if !ok {
+		return nil, errors.New("file does not exist")
+	}
+	return &shell.FilesStatObject{Hash: cid, Size: uint64(len(f.blocks[cid]))}, nil
+}
+
+func (f *fakeNode) FilesRm(ctx context.Context, path string, force bool) error {
+	if err := f.call("files_rm"); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.mfs, path)
+	return nil
+}
+
+// DagExport writes the CID and the block on one line each, which DagImport reads back.
+func (f *fakeNode) DagExport(cid string) (io.ReadCloser, error) {
+	if err := f.call("dag_export"); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	hang := f.hang
+	f.mu.Unlock()
+	if hang != nil {
+		<-hang
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.blocks[cid]
+	if !ok {
+		return nil, errors.New("block not found")
+	}
+	return io.NopCloser(strings.NewReader(cid + "\n" + string(data))), nil
+}
+
+func (f *fakeNode) DagImport(car io.Reader) error {
+	if err := f.call("dag_import"); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(car)
+	if err != nil {
+		return err
+	}
+	_, block, _ := bytes.Cut(data, []byte("\n"))
+	f.store(block)
+	return nil
+}
+
+func newPool(t *testing.T, n int, opts ipfspool.Options) (*ipfspool.Pool, []*fakeNode) {
+	fakes := make([]*fakeNode, n)
+	nodes := make([]ipfspool.Node, n)
+	for i := range fakes {
+		fakes[i] = newFakeNode()
+		nodes[i] = ipfspool.Node{Name: "node" + string(rune('0'+i)), Shell: fakes[i]}
+	}
+	// Checks only run when a test calls them.
+	opts.HealthInterval, opts.RepairInterval = time.Hour, time.Hour
+	pool := ipfspool.NewPool(nodes, mocks.NewFakeRedisClient(), opts)
+	t.Cleanup(func() { pool.Close() })
+	return pool, fakes
+}
+
+func readAll(t *testing.T, pool *ipfspool.Pool, cid string) string {
+	t.Helper()
+	rc, err := pool.Cat(cid)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestPool_AddReplicates(t *testing.T) {
+	pool, nodes := newPool(t, 3, ipfspool.Options{Replication: 2})
+
+	cid, err := pool.Add(strings.NewReader("content"))
+	require.NoError(t, err)
+	assert.True(t, nodes[0].pinned(cid))
+	assert.True(t, nodes[1].pinned(cid))
+	assert.False(t, nodes[2].pinned(cid))
+	assert.Equal(t, "content", readAll(t, pool, cid))
+}
+
+func TestPool_AddDropsStalledNode(t *testing.T) {
+	pool, nodes := newPool(t, 2, ipfspool.Options{Replication: 2, WriteTimeout: 50 * time.Millisecond})
+	nodes[0].hang = make(chan struct{})
+	defer close(nodes[0].hang)
+
+	cid, err := pool.Add(strings.NewReader("content"))
+	require.NoError(t, err)
+	assert.True(t, nodes[1].pinned(cid))
+	assert.False(t, nodes[0].pinned(cid))
+}
+
+func TestPool_AddFailsWithReader(t *testing.T) {
+	pool, _ := newPool(t, 2, ipfspool.Options{})
+	readErr := errors.New("quota exceeded")
+
+	_, err := pool.Add(io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(readErr)))
+	assert.ErrorIs(t, err, readErr)
+}
+
+func TestPool_RepairCopiesMissingReplicas(t *testing.T) {
+	pool, nodes := newPool(t, 3, ipfspool.Options{Replication: 2})
+	nodes[0].setDown(true)
+
+	cid, err := pool.Add(strings.NewReader("content"))
+	require.NoError(t, err, "one node is enough for the upload")
+	assert.True(t, nodes[1].pinned(cid))
+	assert.False(t, nodes[2].pinned(cid))
+
+	pool.CheckHealth()
+	pool.Repair(context.Background())
+	assert.True(t, nodes[2].pinned(cid), "the repair copies the pin to the next healthy node")
+	assert.False(t, nodes[0].pinned(cid))
+
+	nodes[0].setDown(false)
+	pool.CheckHealth()
+	pool.Repair(context.Background())
+	assert.False(t, nodes[0].pinned(cid), "replicated content is not copied again")
+}
+
+func TestPool_WritesSkipUnhealthyNodes(t *testing.T) {
+	pool, nodes := newPool(t, 3, ipfspool.Options{Replication: 2})
+	nodes[1].setDown(true)
+	pool.CheckHealth()
+	nodes[1].setDown(false)
+
+	cid, err := pool.Add(strings.NewReader("content"))
+	require.NoError(t, err)
+	assert.True(t, nodes[0].pinned(cid))
+	assert.False(t, nodes[1].pinned(cid))
+	assert.True(t, nodes[2].pinned(cid))
+
+	require.NoError(t, pool.FilesCp(context.Background(), "/ipfs/"+cid, "/staging/a"))
+	stat, err := pool.FilesStat(context.Background(), "/staging/a")
+	require.NoError(t, err)
+	assert.Equal(t, cid, stat.Hash)
+}
+
+func TestPool_CatFallsBack(t *testing.T) {
+	pool, nodes := newPool(t, 2, ipfspool.Options{Replication: 2, ReadTimeout: 50 * time.Millisecond})
+	cid, err := pool.Add(strings.NewReader("0123456789"))
+	require.NoError(t, err)
+
+	nodes[0].setDown(true)
+	assert.Equal(t, "0123456789", readAll(t, pool, cid), "error")
+	nodes[0].setDown(false)
+
+	nodes[0].breakAfter = 4
+	assert.Equal(t, "0123456789", readAll(t, pool, cid), "failure in the middle")
+	nodes[0].breakAfter = 0
+
+	nodes[0].hang = make(chan struct{})
+	defer close(nodes[0].hang)
+	start := time.Now()
+	assert.Equal(t, "0123456789", readAll(t, pool, cid), "timeout")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestPool_CatPrefersNodesWithContent(t *testing.T) {
+	pool, nodes := newPool(t, 3, ipfspool.Options{Replication: 1})
+	nodes[0].setDown(true)
+	pool.CheckHealth()
+	cid, err := pool.Add(strings.NewReader("content"))
+	require.NoError(t, err)
+	require.True(t, nodes[1].pinned(cid))
+	nodes[0].setDown(false)
+	pool.CheckHealth()
+
+	assert.Equal(t, "content", readAll(t, pool, cid))
+	assert.NotContains(t, nodes[0].calls, "cat")
+
+	for _, n := range nodes {
+		n.setDown(true)
+	}
+	_, err = pool.Cat(cid)
+	assert.ErrorIs(t, err, errDown)
+}
+
+func TestPool_UnpinRetriesMissedNodes(t *testing.T) {
+	pool, nodes := newPool(t, 2, ipfspool.Options{Replication: 2})
+	cid, err := pool.Add(strings.NewReader("content"))
+	require.NoError(t, err)
+
+	nodes[1].setDown(true)
+	pool.CheckHealth()
+	require.NoError(t, pool.Unpin(cid))
+	assert.False(t, nodes[0].pinned(cid))
+
+	nodes[1].setDown(false)
+	pool.CheckHealth()
+	pool.Repair(context.Background())
+	assert.False(t, nodes[1].pinned(cid), "the missed unpin is retried")
+	assert.False(t, nodes[0].pinned(cid), "content being unpinned is not copied")
+
+	// Pinning the content again cancels pending unpins.
+	nodes[1].setDown(true)
+	pool.CheckHealth()
+	require.NoError(t, pool.Unpin(cid))
+	nodes[1].setDown(false)
+	pool.CheckHealth()
+	require.NoError(t, pool.Pin(cid))
+	pool.Repair(context.Background())
+	assert.True(t, nodes[0].pinned(cid))
+	assert.True(t, nodes[1].pinned(cid))
+}
+
+func TestPool_UnpinnedAddIsNotKept(t *testing.T) {
+	pool, nodes := newPool(t, 2, ipfspool.Options{Replication: 2})
+	cid, err := pool.Add(strings.NewReader("content"))
+	require.NoError(t, err)
+
+	nodes[1].setDown(true)
+	pool.CheckHealth()
+	require.NoError(t, pool.Unpin(cid))
+	nodes[1].setDown(false)
+	pool.CheckHealth()
+
+	// An unpinned add leaves the pending unpin of the same content in place.
+	added, err := pool.Add(strings.NewReader("content"), shell.Pin(false))
+	require.NoError(t, err)
+	assert.Equal(t, cid, added)
+	pool.Repair(context.Background())
+	assert.False(t, nodes[0].pinned(cid))
+	assert.False(t, nodes[1].pinned(cid), "the missed unpin is retried")
+}
+
+func TestPool_RepairSkipsContentUnpinnedMeanwhile(t *testing.T) {
+	pool, nodes := newPool(t, 2, ipfspool.Options{Replication: 2})
+	nodes[1].setDown(true)
+	cid, err := pool.Add(strings.NewReader("content"))
+	require.NoError(t, err)
+	nodes[1].setDown(false)
+
+	nodes[0].mu.Lock()
+	nodes[0].hang = make(chan struct{})
+	nodes[0].mu.Unlock()
+	repaired := make(chan struct{})
+	go func() {
+		pool.Repair(context.Background())
+		close(repaired)
+	}()
+	require.Eventually(t, func() bool {
+		nodes[0].mu.Lock()
+		defer nodes[0].mu.Unlock()
+		return slices.Contains(nodes[0].calls, "dag_export")
+	}, time.Second, time.Millisecond, "the repair copies the pin")
+
+	require.NoError(t, pool.Unpin(cid))
+	close(nodes[0].hang)
+	<-repaired
+	assert.False(t, nodes[0].pinned(cid))
+	assert.False(t, nodes[1].pinned(cid), "the copy of unpinned content is removed")
+}
+
+func TestPool_ShardsStayOnTheirNode(t *testing.T) {
+	pool, nodes := newPool(t, 3, ipfspool.Options{Replication: 2})
+	assert.Equal(t, []string{"node0", "node1", "node2"}, pool.HealthyNodes())
+
+	cid, err := pool.AddTo("node1", strings.NewReader("shard"))
+	require.NoError(t, err)
+	_, err = pool.AddTo("node1", strings.NewReader("shard"))
+	require.NoError(t, err, "another file stores the same shard")
+	assert.True(t, nodes[1].pinned(cid))
+
+	pool.Repair(context.Background())
+	assert.False(t, nodes[0].pinned(cid), "shards are not replicated")
+	assert.False(t, nodes[2].pinned(cid))
+
+	rc, err := pool.CatFrom("node1", cid)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "shard", string(data))
+	_, err = pool.CatFrom("node0", cid)
+	assert.Error(t, err)
+	_, err = pool.AddTo("node9", strings.NewReader("shard"))
+	assert.ErrorContains(t, err, "unknown IPFS node node9")
+
+	require.NoError(t, pool.RemoveFrom("node1", cid))
+	assert.True(t, nodes[1].pinned(cid), "the other file still stores the shard")
+	nodes[1].setDown(true)
+	pool.CheckHealth()
+	require.NoError(t, pool.RemoveFrom("node1", cid))
+	nodes[1].setDown(false)
+	pool.CheckHealth()
+	pool.Repair(context.Background())
+	assert.False(t, nodes[1].pinned(cid), "the missed unpin is retried")
+}
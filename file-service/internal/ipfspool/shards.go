@@ -0,0 +1,137 @@
+package ipfspool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+// Shards of erasure-coded files are each stored on a single node, since the parity shards
+// provide the redundancy. The checker does not replicate them, and a shard is unpinned from
+// its node once every file that stores the same shard there has removed it:
+//
+//	ipfs:shards -> hash of "<node> <cid>" to the number of files storing the shard there
+const shardsKey = "ipfs:shards"
+
+// HealthyNodes returns the names of the healthy nodes in the configured order.
+func (p *Pool) HealthyNodes() []string {
+	var names []string
+	for _, n := range p.healthyNodes() {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+// AddTo stores r on the named node only and pins it there.
+func (p *Pool) AddTo(nodeName string, r io.Reader) (string, error) {
+	n, err := p.node(nodeName)
+	if err != nil {
+		return "", err
+	}
+	cid, err := n.Shell.Add(r, shell.Pin(false))
+	if err != nil {
+		return "", nodeError(n, "add", err)
+	}
+
+	// Record the shard before pinning it, so that the checker never copies it.
+	ctx := context.Background()
+	if err := p.redis.HIncrBy(ctx, shardsKey, shardField(n.Name, cid), 1).Err(); err != nil {
+		return "", fmt.Errorf("failed to record shard %s on IPFS node %s: %w", cid, n.Name, err)
+	}
+	if err := p.redis.SRem(ctx, unpinKey(n.Name), cid).Err(); err != nil {
+		slog.Warn("Failed to cancel pending unpin", "node", n.Name, "cid", cid, "error", err)
+	}
+	if err := n.Shell.Pin(cid); err != nil {
+		p.RemoveFrom(n.Name, cid)
+		return "", nodeError(n, "pin", err)
+	}
+	return cid, nil
+}
+
+// CatFrom reads cid from the named node only, waiting up to ReadTimeout for the first byte.
+func (p *Pool) CatFrom(nodeName string, cid string) (io.ReadCloser, error) {
+	n, err := p.node(nodeName)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := p.openWithin(n, func(n *node) (io.ReadCloser, error) {
+		return n.Shell.Cat(cid)
+	})
+	if err != nil {
+		return nil, nodeError(n, "cat", err)
+	}
+	return rc, nil
+}
+
+// NodePins lists the pins of the named node.
+func (p *Pool) NodePins(nodeName string) (map[string]shell.PinInfo, error) {
+	n, err := p.node(nodeName)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := n.Shell.Pins()
+	if err != nil {
+		return nil, nodeError(n, "pin_ls", err)
+	}
+	return pins, nil
+}
+
+// RemoveFrom releases a shard that AddTo stored on the named node, and unpins it once no
+// file stores it there. Like Unpin, a node that is unhealthy or fails is unpinned later by
+// the checker. The node need not be part of the pool any more.
+func (p *Pool) RemoveFrom(nodeName string, cid string) error {
+	ctx := context.Background()
+	refs, err := p.redis.HIncrBy(ctx, shardsKey, shardField(nodeName, cid), -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to release shard %s on IPFS node %s: %w", cid, nodeName, err)
+	}
+	if refs > 0 {
+		return nil
+	}
+
+	if n, err := p.node(nodeName); err == nil && n.healthy.Load() {
+		err := n.Shell.Unpin(cid)
+		if err == nil || notPinned(err) {
+			return nil
+		}
+		slog.Warn("IPFS node failed", "node", n.Name, "operation", "unpin", "error", err)
+	}
+	if err := p.redis.SAdd(ctx, unpinKey(nodeName), cid).Err(); err != nil {
+		return fmt.Errorf("failed to record unpin of %s on IPFS node %s: %w", cid, nodeName, err)
+	}
+	return nil
+}
+
+// shardCIDs returns the CIDs stored as shards on some node.
+func (p *Pool) shardCIDs(ctx context.Context) (map[string]bool, error) {
+	fields, err := p.redis.HGetAll(ctx, shardsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	cids := make(map[string]bool)
+	for field, refs := range fields {
+		if n, _ := strconv.Atoi(refs); n > 0 {
+			_, cid, _ := strings.Cut(field, " ")
+			cids[cid] = true
+		}
+	}
+	return cids, nil
+}
+
+func (p *Pool) node(name string) (*node, error) {
+	for _, n := range p.nodes {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown IPFS node %s", name)
+}
+
+func shardField(nodeName, cid string) string {
+	return nodeName + " " + cid
+}
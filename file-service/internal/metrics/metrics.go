@@ -0,0 +1,66 @@
+// Package metrics defines the Prometheus metrics exported by file-service on /metrics.
+package metrics
+
+import (
+	"decentralstore/shared/servermetrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "file_service"
+
+var (
+	// Requests records the HTTP and gRPC requests served by file-service.
+	Requests = servermetrics.NewRequests(namespace)
+
+	UploadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploaded_bytes_total",
+		Help:      "Bytes streamed into IPFS.",
+	})
+
+	DownloadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloaded_bytes_total",
+		Help:      "Bytes streamed out of IPFS.",
+	})
+
+	IPFSDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ipfs_operation_duration_seconds",
+		Help:      "IPFS API latency by operation. For cat this is the time until the content starts streaming.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+
+	IPFSErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ipfs_errors_total",
+		Help:      "Failed IPFS API calls by operation.",
+	}, []string{"operation"})
+
+	IPFSNodeUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ipfs_node_up",
+		Help:      "Whether each IPFS node of the pool passed its last health check.",
+	}, []string{"node"})
+
+	IPFSUnderReplicated = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ipfs_under_replicated_pins",
+		Help:      "Pins below the replication factor after the last repair.",
+	})
+
+	RedisDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redis_operation_duration_seconds",
+		Help:      "Redis command latency by command.",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"command"})
+
+	RedisErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_errors_total",
+		Help:      "Failed Redis commands by command. Missing keys are not counted.",
+	}, []string{"command"})
+)
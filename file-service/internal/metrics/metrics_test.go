@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"decentralstore/shared/servermetrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	UploadedBytes.Add(1)
+
+	rr := httptest.NewRecorder()
+	servermetrics.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.Contains(rr.Body.String(), "file_service_uploaded_bytes_total"))
+}
@@ -0,0 +1,215 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"decentralstore/file-service/internal/car"
+
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+// FakeIPFS はテスト用のインメモリIPFS実装です。ファイルは内容から求めたCIDの1つのブロックとして保存し、
+// ディレクトリを組み立てられるだけのMFSを持ちます。CARファイルはrawブロックだけを読み書きします
+type FakeIPFS struct {
+	mu     sync.Mutex
+	Blocks map[string][]byte
+	// MFS はMFS上のファイルのパスからCIDへの対応、Dirs はディレクトリのCIDから中のファイルへの対応です
+	MFS    map[string]string
+	Dirs   map[string]map[string]string
+	Pinned map[string]bool
+	// Adds はAddが呼ばれた回数です
+	Adds int
+}
+
+func NewFakeIPFS() *FakeIPFS {
+	return &FakeIPFS{
+		Blocks: make(map[string][]byte),
+		MFS:    make(map[string]string),
+		Dirs:   make(map[string]map[string]string),
+		Pinned: make(map[string]bool),
+	}
+}
+
+// IsPinned はcidがピンされているかを返します
+func (f *FakeIPFS) IsPinned(cid string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Pinned[cid]
+}
+
+// Shell はFakeIPFSを操作するMockIPFSShellを返します。テストは個々の関数を差し替えられます
+func (f *FakeIPFS) Shell() *MockIPFSShell {
+	return &MockIPFSShell{
+		AddFn:       f.add,
+		CatFn:       f.cat,
+		PinFn:       f.pin,
+		UnpinFn:     f.unpin,
+		PinsFn:      f.pins,
+		VersionFn:   func() (string, string, error) { return "0.27.0", "", nil },
+		FilesCpFn:   f.filesCp,
+		FilesStatFn: f.filesStat,
+		FilesRmFn:   f.filesRm,
+		DagExportFn: f.dagExport,
+		DagImportFn: f.dagImport,
+	}
+}
+
+func (f *FakeIPFS) add(r io.Reader, options ...shell.AddOpts) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	id, err := car.RawCID(data)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Adds++
+	f.Blocks[id.String()] = data
+	return id.String(), nil
+}
+
+func (f *FakeIPFS) cat(path string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.Blocks[f.resolve(path)]
+	if !ok {
+		return nil, errors.New("no link named " + path)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// resolve は"<cid>/<path>"形式のパスをディレクトリ内のファイルのCIDに変換します
+func (f *FakeIPFS) resolve(path string) string {
+	root, rest, _ := strings.Cut(strings.TrimPrefix(path, "/ipfs/"), "/")
+	if rest != "" {
+		return f.Dirs[root][rest]
+	}
+	return root
+}
+
+func (f *FakeIPFS) pin(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Pinned[path] = true
+	return nil
+}
+
+func (f *FakeIPFS) unpin(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.Pinned, path)
+	return nil
+}
+
+func (f *FakeIPFS) pins() (map[string]shell.PinInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pins := make(map[string]shell.PinInfo)
+	for cid := range f.Pinned {
+		pins[cid] = shell.PinInfo{Type: "recursive"}
+	}
+	return pins, nil
+}
+
+func (f *FakeIPFS) filesCp(ctx context.Context, src string, dest string, options ...shell.FilesOpt) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.MFS[dest] = strings.TrimPrefix(src, "/ipfs/")
+	return nil
+}
+
+// filesStat は/ipfs/のパスならブロックかディレクトリを、MFSのパスならその下のファイルからディレクトリを作って返します
+func (f *FakeIPFS) filesStat(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if rest, ok := strings.CutPrefix(path, "/ipfs/"); ok {
+		if _, isDir := f.Dirs[rest]; isDir {
+			return &shell.FilesStatObject{Hash: rest, Type: "directory"}, nil
+		}
+		cid := f.resolve(rest)
+		data, ok := f.Blocks[cid]
+		if !ok {
+			return nil, errors.New("file does not exist")
+		}
+		return &shell.FilesStatObject{Hash: cid, Type: "file", Size: uint64(len(data))}, nil
+	}
+
+	files := make(map[string]string)
+	var names []string
+	for name, cid := range f.MFS {
+		if rel, ok := strings.CutPrefix(name, path+"/"); ok {
+			files[rel] = cid
+			names = append(names, rel+"="+cid)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("file does not exist")
+	}
+	sort.Strings(names)
+	root, err := car.RawCID([]byte(strings.Join(names, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	f.Dirs[root.String()] = files
+	return &shell.FilesStatObject{Hash: root.String(), Type: "directory"}, nil
+}
+
+func (f *FakeIPFS) filesRm(ctx context.Context, path string, force bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for name := range f.MFS {
+		if strings.HasPrefix(name, path+"/") {
+			delete(f.MFS, name)
+		}
+	}
+	return nil
+}
+
+func (f *FakeIPFS) dagExport(cid string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	data, ok := f.Blocks[cid]
+	f.mu.Unlock()
+	if !ok {
+		return nil, errors.New("block not found")
+	}
+	id, err := car.RawCID(data)
+	if err != nil {
+		return nil, err
+	}
+	export := &bytes.Buffer{}
+	writer, err := car.NewWriter(export, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Put(id, data); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(export), nil
+}
+
+func (f *FakeIPFS) dagImport(r io.Reader) error {
+	reader, err := car.NewReader(r)
+	if err != nil {
+		return err
+	}
+	for {
+		id, data, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.Blocks[id.String()] = data
+		f.mu.Unlock()
+	}
+}
@@ -6,8 +6,9 @@ import (
 	"time"
 
 	"decentralstore/file-service/internal/domain"
-	shell "github.com/ipfs/go-ipfs-api"
+	"decentralstore/file-service/internal/usecase"
 	"github.com/go-redis/redis/v8"
+	shell "github.com/ipfs/go-ipfs-api"
 )
 
 // MockFileUseCase はFileUseCaseのモック実装です
@@ -15,6 +16,19 @@ type MockFileUseCase struct {
 	UploadFileFn   func(ctx context.Context, file io.Reader, filename string) (*domain.File, error)
 	DownloadFileFn func(ctx context.Context, fileID string, keyword string) (io.ReadCloser, error)
 	DeleteFileFn   func(ctx context.Context, fileID string, keyword string) error
+
+	AuthorizeDownloadFn func(ctx context.Context, fileID string, keyword string) error
+	OpenFileFn          func(ctx context.Context, fileID string) (io.ReadCloser, error)
+	ListFilesFn         func(ctx context.Context) ([]*domain.File, error)
+	GetUsageFn          func(ctx context.Context) (*domain.UsageReport, error)
+	RecomputeUsageFn    func(ctx context.Context, tenantID string) (*domain.UsageReport, error)
+	UploadDirectoryFn   func(ctx context.Context, name string, entries usecase.DirectoryReader) (*domain.File, error)
+	OpenEntryFn         func(ctx context.Context, fileID string, entryPath string) (io.ReadCloser, error)
+	OpenArchiveFn       func(ctx context.Context, fileID string, format string) (io.ReadCloser, error)
+	OpenBundleFn        func(ctx context.Context, format string, items []domain.BundleItem) (io.ReadCloser, error)
+	ExportCARFn         func(ctx context.Context, items []domain.BundleItem, version int) (io.ReadCloser, error)
+	ImportCARFn         func(ctx context.Context, car io.Reader, name string) ([]*domain.File, error)
+	ClaimContentFn      func(ctx context.Context, ref domain.ContentRef, filename string) (*domain.File, error)
 }
 
 func (m *MockFileUseCase) UploadFile(ctx context.Context, file io.Reader, filename string) (*domain.File, error) {
@@ -29,10 +43,69 @@ func (m *MockFileUseCase) DeleteFile(ctx context.Context, fileID string, keyword
 	return m.DeleteFileFn(ctx, fileID, keyword)
 }
 
+func (m *MockFileUseCase) AuthorizeDownload(ctx context.Context, fileID string, keyword string) error {
+	return m.AuthorizeDownloadFn(ctx, fileID, keyword)
+}
+
+func (m *MockFileUseCase) OpenFile(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	return m.OpenFileFn(ctx, fileID)
+}
+
+func (m *MockFileUseCase) ListFiles(ctx context.Context) ([]*domain.File, error) {
+	return m.ListFilesFn(ctx)
+}
+
+func (m *MockFileUseCase) GetUsage(ctx context.Context) (*domain.UsageReport, error) {
+	return m.GetUsageFn(ctx)
+}
+
+func (m *MockFileUseCase) RecomputeUsage(ctx context.Context, tenantID string) (*domain.UsageReport, error) {
+	return m.RecomputeUsageFn(ctx, tenantID)
+}
+
+func (m *MockFileUseCase) UploadDirectory(ctx context.Context, name string, entries usecase.DirectoryReader) (*domain.File, error) {
+	return m.UploadDirectoryFn(ctx, name, entries)
+}
+
+func (m *MockFileUseCase) OpenEntry(ctx context.Context, fileID string, entryPath string) (io.ReadCloser, error) {
+	return m.OpenEntryFn(ctx, fileID, entryPath)
+}
+
+func (m *MockFileUseCase) OpenArchive(ctx context.Context, fileID string, format string) (io.ReadCloser, error) {
+	return m.OpenArchiveFn(ctx, fileID, format)
+}
+
+func (m *MockFileUseCase) OpenBundle(ctx context.Context, format string, items []domain.BundleItem) (io.ReadCloser, error) {
+	return m.OpenBundleFn(ctx, format, items)
+}
+
+func (m *MockFileUseCase) ExportCAR(ctx context.Context, items []domain.BundleItem, version int) (io.ReadCloser, error) {
+	return m.ExportCARFn(ctx, items, version)
+}
+
+func (m *MockFileUseCase) ImportCAR(ctx context.Context, car io.Reader, name string) ([]*domain.File, error) {
+	return m.ImportCARFn(ctx, car, name)
+}
+
+func (m *MockFileUseCase) ClaimContent(ctx context.Context, ref domain.ContentRef, filename string) (*domain.File, error) {
+	return m.ClaimContentFn(ctx, ref, filename)
+}
+
 // MockIPFSShell はshell.Shellのモック実装です
 type MockIPFSShell struct {
 	AddFn func(r io.Reader, options ...shell.AddOpts) (string, error)
 	CatFn func(path string) (io.ReadCloser, error)
+
+	PinFn     func(path string) error
+	UnpinFn   func(path string) error
+	PinsFn    func() (map[string]shell.PinInfo, error)
+	VersionFn func() (string, string, error)
+
+	FilesCpFn   func(ctx context.Context, src string, dest string, options ...shell.FilesOpt) error
+	FilesStatFn func(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error)
+	FilesRmFn   func(ctx context.Context, path string, force bool) error
+	DagExportFn func(cid string) (io.ReadCloser, error)
+	DagImportFn func(car io.Reader) error
 }
 
 func (m *MockIPFSShell) Add(r io.Reader, options ...shell.AddOpts) (string, error) {
@@ -43,17 +116,72 @@ func (m *MockIPFSShell) Cat(path string) (io.ReadCloser, error) {
 	return m.CatFn(path)
 }
 
+func (m *MockIPFSShell) Pin(path string) error {
+	return m.PinFn(path)
+}
+
+func (m *MockIPFSShell) Unpin(path string) error {
+	return m.UnpinFn(path)
+}
+
+func (m *MockIPFSShell) Pins() (map[string]shell.PinInfo, error) {
+	return m.PinsFn()
+}
+
+func (m *MockIPFSShell) Version() (string, string, error) {
+	return m.VersionFn()
+}
+
+func (m *MockIPFSShell) FilesCp(ctx context.Context, src string, dest string, options ...shell.FilesOpt) error {
+	return m.FilesCpFn(ctx, src, dest, options...)
+}
+
+func (m *MockIPFSShell) FilesStat(ctx context.Context, path string, options ...shell.FilesOpt) (*shell.FilesStatObject, error) {
+	return m.FilesStatFn(ctx, path, options...)
+}
+
+func (m *MockIPFSShell) FilesRm(ctx context.Context, path string, force bool) error {
+	return m.FilesRmFn(ctx, path, force)
+}
+
+func (m *MockIPFSShell) DagExport(cid string) (io.ReadCloser, error) {
+	return m.DagExportFn(cid)
+}
+
+func (m *MockIPFSShell) DagImport(car io.Reader) error {
+	return m.DagImportFn(car)
+}
+
 // MockRedisClient はredis.Clientのモック実装です
 type MockRedisClient struct {
-	SetFn func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
-	GetFn func(ctx context.Context, key string) *redis.StringCmd
-	DelFn func(ctx context.Context, keys ...string) *redis.IntCmd
+	SetFn   func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetNXFn func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	GetFn   func(ctx context.Context, key string) *redis.StringCmd
+	DelFn   func(ctx context.Context, keys ...string) *redis.IntCmd
+
+	SAddFn     func(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRemFn     func(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembersFn func(ctx context.Context, key string) *redis.StringSliceCmd
+	HIncrByFn  func(ctx context.Context, key, field string, incr int64) *redis.IntCmd
+	HGetFn     func(ctx context.Context, key, field string) *redis.StringCmd
+	HGetAllFn  func(ctx context.Context, key string) *redis.StringStringMapCmd
+	HSetFn     func(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HDelFn     func(ctx context.Context, key string, fields ...string) *redis.IntCmd
+	PingFn     func(ctx context.Context) *redis.StatusCmd
+
+	ZAddFn          func(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd
+	ZRangeByScoreFn func(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd
+	ZRemFn          func(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
 }
 
 func (m *MockRedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
 	return m.SetFn(ctx, key, value, expiration)
 }
 
+func (m *MockRedisClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	return m.SetNXFn(ctx, key, value, expiration)
+}
+
 func (m *MockRedisClient) Get(ctx context.Context, key string) *redis.StringCmd {
 	return m.GetFn(ctx, key)
 }
@@ -61,3 +189,51 @@ func (m *MockRedisClient) Get(ctx context.Context, key string) *redis.StringCmd
 func (m *MockRedisClient) Del(ctx context.Context, keys ...string) *redis.IntCmd {
 	return m.DelFn(ctx, keys...)
 }
+
+func (m *MockRedisClient) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	return m.SAddFn(ctx, key, members...)
+}
+
+func (m *MockRedisClient) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	return m.SRemFn(ctx, key, members...)
+}
+
+func (m *MockRedisClient) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	return m.SMembersFn(ctx, key)
+}
+
+func (m *MockRedisClient) HIncrBy(ctx context.Context, key, field string, incr int64) *redis.IntCmd {
+	return m.HIncrByFn(ctx, key, field, incr)
+}
+
+func (m *MockRedisClient) HGet(ctx context.Context, key, field string) *redis.StringCmd {
+	return m.HGetFn(ctx, key, field)
+}
+
+func (m *MockRedisClient) HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd {
+	return m.HGetAllFn(ctx, key)
+}
+
+func (m *MockRedisClient) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	return m.HSetFn(ctx, key, values...)
+}
+
+func (m *MockRedisClient) HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd {
+	return m.HDelFn(ctx, key, fields...)
+}
+
+func (m *MockRedisClient) Ping(ctx context.Context) *redis.StatusCmd {
+	return m.PingFn(ctx)
+}
+
+func (m *MockRedisClient) ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+	return m.ZAddFn(ctx, key, members...)
+}
+
+func (m *MockRedisClient) ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd {
+	return m.ZRangeByScoreFn(ctx, key, opt)
+}
+
+func (m *MockRedisClient) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	return m.ZRemFn(ctx, key, members...)
+}
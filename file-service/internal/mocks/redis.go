@@ -0,0 +1,267 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// FakeRedisClient はテスト用のインメモリRedis実装です
+type FakeRedisClient struct {
+	mu      sync.Mutex
+	strings map[string]string
+	sets    map[string]map[string]struct{}
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]float64
+}
+
+func NewFakeRedisClient() *FakeRedisClient {
+	return &FakeRedisClient{
+		strings: make(map[string]string),
+		sets:    make(map[string]map[string]struct{}),
+		hashes:  make(map[string]map[string]string),
+		zsets:   make(map[string]map[string]float64),
+	}
+}
+
+func (f *FakeRedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.strings[key] = fmt.Sprint(value)
+	return redis.NewStatusResult("OK", nil)
+}
+
+// SetNX は有効期限を無視します
+func (f *FakeRedisClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.strings[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	f.strings[key] = fmt.Sprint(value)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (f *FakeRedisClient) Get(ctx context.Context, key string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.strings[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (f *FakeRedisClient) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := f.strings[key]; ok {
+			delete(f.strings, key)
+			deleted++
+		}
+		if _, ok := f.sets[key]; ok {
+			delete(f.sets, key)
+			deleted++
+		}
+		if _, ok := f.hashes[key]; ok {
+			delete(f.hashes, key)
+			deleted++
+		}
+		if _, ok := f.zsets[key]; ok {
+			delete(f.zsets, key)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func (f *FakeRedisClient) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	set, ok := f.sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		f.sets[key] = set
+	}
+	var added int64
+	for _, m := range members {
+		member := fmt.Sprint(m)
+		if _, exists := set[member]; !exists {
+			set[member] = struct{}{}
+			added++
+		}
+	}
+	return redis.NewIntResult(added, nil)
+}
+
+func (f *FakeRedisClient) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var removed int64
+	for _, m := range members {
+		member := fmt.Sprint(m)
+		if _, exists := f.sets[key][member]; exists {
+			delete(f.sets[key], member)
+			removed++
+		}
+	}
+	return redis.NewIntResult(removed, nil)
+}
+
+func (f *FakeRedisClient) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	members := make([]string, 0, len(f.sets[key]))
+	for member := range f.sets[key] {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return redis.NewStringSliceResult(members, nil)
+}
+
+func (f *FakeRedisClient) HIncrBy(ctx context.Context, key, field string, incr int64) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hash := f.hash(key)
+	current, _ := strconv.ParseInt(hash[field], 10, 64)
+	current += incr
+	hash[field] = strconv.FormatInt(current, 10)
+	return redis.NewIntResult(current, nil)
+}
+
+func (f *FakeRedisClient) HGet(ctx context.Context, key, field string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.hashes[key][field]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (f *FakeRedisClient) HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	values := make(map[string]string, len(f.hashes[key]))
+	for field, value := range f.hashes[key] {
+		values[field] = value
+	}
+	return redis.NewStringStringMapResult(values, nil)
+}
+
+func (f *FakeRedisClient) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hash := f.hash(key)
+	var added int64
+	for i := 0; i+1 < len(values); i += 2 {
+		field := fmt.Sprint(values[i])
+		if _, exists := hash[field]; !exists {
+			added++
+		}
+		hash[field] = fmt.Sprint(values[i+1])
+	}
+	return redis.NewIntResult(added, nil)
+}
+
+func (f *FakeRedisClient) HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var deleted int64
+	for _, field := range fields {
+		if _, ok := f.hashes[key][field]; ok {
+			delete(f.hashes[key], field)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func (f *FakeRedisClient) ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zset, ok := f.zsets[key]
+	if !ok {
+		zset = make(map[string]float64)
+		f.zsets[key] = zset
+	}
+	var added int64
+	for _, z := range members {
+		member := fmt.Sprint(z.Member)
+		if _, exists := zset[member]; !exists {
+			added++
+		}
+		zset[member] = z.Score
+	}
+	return redis.NewIntResult(added, nil)
+}
+
+// ZRangeByScore は"-inf"、"+inf"と数値の範囲に対応します（"("による開区間には対応しません）
+func (f *FakeRedisClient) ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lo, err := strconv.ParseFloat(strings.TrimPrefix(opt.Min, "+"), 64)
+	if err != nil {
+		return redis.NewStringSliceResult(nil, err)
+	}
+	hi, err := strconv.ParseFloat(strings.TrimPrefix(opt.Max, "+"), 64)
+	if err != nil {
+		return redis.NewStringSliceResult(nil, err)
+	}
+
+	zset := f.zsets[key]
+	var members []string
+	for member, score := range zset {
+		if score >= lo && score <= hi {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if zset[members[i]] != zset[members[j]] {
+			return zset[members[i]] < zset[members[j]]
+		}
+		return members[i] < members[j]
+	})
+	if opt.Offset > 0 {
+		members = members[min(opt.Offset, int64(len(members))):]
+	}
+	if opt.Count > 0 && opt.Count < int64(len(members)) {
+		members = members[:opt.Count]
+	}
+	return redis.NewStringSliceResult(members, nil)
+}
+
+func (f *FakeRedisClient) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var removed int64
+	for _, m := range members {
+		member := fmt.Sprint(m)
+		if _, exists := f.zsets[key][member]; exists {
+			delete(f.zsets[key], member)
+			removed++
+		}
+	}
+	return redis.NewIntResult(removed, nil)
+}
+
+func (f *FakeRedisClient) Ping(ctx context.Context) *redis.StatusCmd {
+	return redis.NewStatusResult("PONG", nil)
+}
+
+func (f *FakeRedisClient) hash(key string) map[string]string {
+	hash, ok := f.hashes[key]
+	if !ok {
+		hash = make(map[string]string)
+		f.hashes[key] = hash
+	}
+	return hash
+}
@@ -0,0 +1,136 @@
+// Package pinning replicates pins to remote pinning services that implement the IPFS
+// Pinning Service API (https://ipfs.github.io/pinning-services-api-spec/).
+package pinning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Pin is a request to pin a CID.
+type Pin struct {
+	CID     string            `json:"cid"`
+	Name    string            `json:"name,omitempty"`
+	Origins []string          `json:"origins,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// PinStatus is the state of a pin request on a pinning service. Status is one of the
+// domain.Pin* values.
+type PinStatus struct {
+	RequestID string            `json:"requestid"`
+	Status    string            `json:"status"`
+	Created   time.Time         `json:"created"`
+	Pin       Pin               `json:"pin"`
+	Delegates []string          `json:"delegates"`
+	Info      map[string]string `json:"info,omitempty"`
+}
+
+// ErrNotFound is returned, wrapped in *APIError, when a pin request does not exist.
+var ErrNotFound = errors.New("pin request not found")
+
+// APIError is an error response of a pinning service.
+type APIError struct {
+	StatusCode int
+	Reason     string
+	Details    string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("pinning service returned %d", e.StatusCode)
+	if e.Reason != "" {
+		msg += " " + e.Reason
+	}
+	if e.Details != "" {
+		msg += ": " + e.Details
+	}
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Client calls the Pinning Service API of one service.
+type Client struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the service at endpoint, e.g. "https://api.example.com/psa",
+// authenticated with the bearer token.
+func NewClient(endpoint, token string, httpClient *http.Client) *Client {
+	return &Client{endpoint: strings.TrimSuffix(endpoint, "/"), token: token, httpClient: httpClient}
+}
+
+// Add asks the service to pin pin.CID.
+func (c *Client) Add(ctx context.Context, pin Pin) (*PinStatus, error) {
+	body, err := json.Marshal(pin)
+	if err != nil {
+		return nil, err
+	}
+	var status PinStatus
+	if err := c.do(ctx, http.MethodPost, "/pins", bytes.NewReader(body), &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Get returns the current state of a pin request.
+func (c *Client) Get(ctx context.Context, requestID string) (*PinStatus, error) {
+	var status PinStatus
+	if err := c.do(ctx, http.MethodGet, "/pins/"+url.PathEscape(requestID), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Remove cancels a pin request and unpins its CID from the service.
+func (c *Client) Remove(ctx context.Context, requestID string) error {
+	return c.do(ctx, http.MethodDelete, "/pins/"+url.PathEscape(requestID), nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var envelope struct {
+			Error struct {
+				Reason  string `json:"reason"`
+				Details string `json:"details"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&envelope)
+		return &APIError{StatusCode: resp.StatusCode, Reason: envelope.Error.Reason, Details: envelope.Error.Details}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid pinning service response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Package pinningtest provides an in-memory pinning service implementing the Pinning
+// Service API, for tests of code that replicates pins.
+package pinningtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/pinning"
+)
+
+// Server is a pinning service. A pin request is queued when it is created and moves to
+// pinning and then pinned with every status check, or to failed if its CID was passed
+// to FailCID.
+type Server struct {
+	*httptest.Server
+	// Token is the bearer token the server accepts.
+	Token string
+
+	mu       sync.Mutex
+	pins     map[string]*pinning.PinStatus
+	nextID   int
+	failures int
+	failCIDs map[string]bool
+	requests int
+}
+
+// NewServer starts a pinning service accepting token. The caller must call Close.
+func NewServer(token string) *Server {
+	s := &Server{Token: token, pins: make(map[string]*pinning.PinStatus), failCIDs: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// FailRequests makes the next n requests fail with 500 Internal Server Error.
+func (s *Server) FailRequests(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// FailCID makes the pin requests for cid fail instead of being pinned.
+func (s *Server) FailCID(cid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failCIDs[cid] = true
+}
+
+// Pins returns the pin requests the server holds, ordered by creation.
+func (s *Server) Pins() []pinning.PinStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pins := make([]pinning.PinStatus, 0, len(s.pins))
+	for _, pin := range s.pins {
+		pins = append(pins, *pin)
+	}
+	sort.Slice(pins, func(i, j int) bool { return requestNumber(pins[i].RequestID) < requestNumber(pins[j].RequestID) })
+	return pins
+}
+
+// Requests returns the number of requests the server received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	if r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid access token")
+		return
+	}
+	if s.failures > 0 {
+		s.failures--
+		writeError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "injected failure")
+		return
+	}
+
+	switch requestID, hasID := strings.CutPrefix(r.URL.Path, "/pins/"); {
+	case r.URL.Path == "/pins" && r.Method == http.MethodGet:
+		s.list(w, r)
+	case r.URL.Path == "/pins" && r.Method == http.MethodPost:
+		s.add(w, r)
+	case hasID && r.Method == http.MethodGet:
+		s.get(w, requestID)
+	case hasID && r.Method == http.MethodDelete:
+		s.remove(w, requestID)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "no such endpoint")
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	cids := r.URL.Query().Get("cid")
+	statuses := r.URL.Query().Get("status")
+	results := []pinning.PinStatus{}
+	for _, pin := range s.pins {
+		if cids != "" && !contains(cids, pin.Pin.CID) {
+			continue
+		}
+		if statuses != "" && !contains(statuses, pin.Status) {
+			continue
+		}
+		results = append(results, *pin)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(results), "results": results})
+}
+
+func (s *Server) add(w http.ResponseWriter, r *http.Request) {
+	var pin pinning.Pin
+	if err := json.NewDecoder(r.Body).Decode(&pin); err != nil || pin.CID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid pin")
+		return
+	}
+	s.nextID++
+	status := &pinning.PinStatus{
+		RequestID: "req-" + strconv.Itoa(s.nextID),
+		Status:    domain.PinQueued,
+		Created:   time.Now().UTC(),
+		Pin:       pin,
+		Delegates: []string{"/dns4/pinning.example/tcp/4001/p2p/QmPinningService"},
+	}
+	s.pins[status.RequestID] = status
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func (s *Server) get(w http.ResponseWriter, requestID string) {
+	pin, ok := s.pins[requestID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "pin request not found")
+		return
+	}
+	switch {
+	case pin.Status == domain.PinQueued && s.failCIDs[pin.Pin.CID]:
+		pin.Status = domain.PinFailed
+	case pin.Status == domain.PinQueued:
+		pin.Status = domain.PinPinning
+	case pin.Status == domain.PinPinning:
+		pin.Status = domain.PinPinned
+	}
+	writeJSON(w, http.StatusOK, pin)
+}
+
+func (s *Server) remove(w http.ResponseWriter, requestID string) {
+	if _, ok := s.pins[requestID]; !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "pin request not found")
+		return
+	}
+	delete(s.pins, requestID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func contains(list, value string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func requestNumber(requestID string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(requestID, "req-"))
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, reason, details string) {
+	writeJSON(w, status, map[string]any{"error": map[string]string{"reason": reason, "details": details}})
+}
@@ -0,0 +1,422 @@
+package pinning
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/infrastructure"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Service is a remote pinning service.
+type Service struct {
+	// Name identifies the service in the pin status of files.
+	Name     string
+	Endpoint string
+	Token    string
+}
+
+// Options configures a Replicator. Zero values select the defaults.
+type Options struct {
+	// Client calls the services; the default times out after 30 seconds.
+	Client *http.Client
+	// MaxAttempts is the number of failed attempts after which a pin is given up (default 8).
+	MaxAttempts int
+	// BaseDelay and MaxDelay bound the exponential backoff between attempts
+	// (default 30s and 6h).
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// PollInterval is how often the queue is scanned and how long to wait before
+	// checking a queued or pinning request again (default 30s).
+	PollInterval time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = 30 * time.Second
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = 6 * time.Hour
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 30 * time.Second
+	}
+	return o
+}
+
+const (
+	// batchSize is the number of files checked per scan of the queue.
+	batchSize = 100
+	// claimLease is how long a file claimed by one replica is hidden from the others.
+	// If the replica stops while checking it, the file is checked again afterwards.
+	claimLease = 5 * time.Minute
+)
+
+type service struct {
+	Service
+	client *Client
+}
+
+// Replicator copies pins to remote pinning services in the background and records
+// the status of each pin. The schedule lives in Redis, so pins that are pending when
+// the process stops are picked up by the next one, or by another replica.
+//
+//	pinning:queue      -> sorted set of file IDs, scored by their next check in Unix milliseconds
+//	pinning:file:<id>  -> hash of "cid", "name" and "service:<name>" -> JSON domain.RemotePin
+type Replicator struct {
+	redis    infrastructure.RedisClient
+	services []service
+	opts     Options
+	wake     chan struct{}
+
+	// ctx is canceled by Close to abort in-flight requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+	closed chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// NewReplicator starts the worker that replicates pins to services. Call Close to stop it.
+func NewReplicator(redisClient infrastructure.RedisClient, services []Service, opts Options) *Replicator {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Replicator{
+		redis:  redisClient,
+		opts:   opts.withDefaults(),
+		wake:   make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+		closed: make(chan struct{}),
+	}
+	for _, s := range services {
+		r.services = append(r.services, service{Service: s, client: NewClient(s.Endpoint, s.Token, r.opts.Client)})
+	}
+	r.wg.Add(1)
+	go r.work()
+	return r
+}
+
+// Replicate queues file to be pinned on every service.
+func (r *Replicator) Replicate(ctx context.Context, file *domain.File) error {
+	now := time.Now().UTC()
+	values := []any{"cid", file.CID, "name", file.Name}
+	for _, s := range r.services {
+		data, err := json.Marshal(domain.RemotePin{Service: s.Name, Status: domain.PinQueued, UpdatedAt: now, NextCheckAt: &now})
+		if err != nil {
+			return err
+		}
+		values = append(values, serviceField(s.Name), string(data))
+	}
+	if err := r.redis.HSet(ctx, fileKey(file.ID), values...).Err(); err != nil {
+		return fmt.Errorf("failed to save remote pins: %w", err)
+	}
+	if err := r.schedule(ctx, file.ID, now); err != nil {
+		return err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Statuses returns the remote pins of a file, ordered by service name.
+func (r *Replicator) Statuses(ctx context.Context, fileID string) ([]domain.RemotePin, error) {
+	values, err := r.redis.HGetAll(ctx, fileKey(fileID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote pins: %w", err)
+	}
+	pins := remotePins(values)
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Service < pins[j].Service })
+	return pins, nil
+}
+
+// Forget stops replicating a deleted file and removes its pins from the services in
+// the background. Failures to remove them are logged.
+func (r *Replicator) Forget(ctx context.Context, fileID string) error {
+	values, err := r.redis.HGetAll(ctx, fileKey(fileID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get remote pins: %w", err)
+	}
+	if err := r.redis.ZRem(ctx, queueKey, fileID).Err(); err != nil {
+		return fmt.Errorf("failed to unschedule remote pins: %w", err)
+	}
+	if err := r.redis.Del(ctx, fileKey(fileID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete remote pins: %w", err)
+	}
+
+	for _, pin := range remotePins(values) {
+		s, ok := r.service(pin.Service)
+		if !ok || pin.RequestID == "" {
+			continue
+		}
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			if err := s.client.Remove(r.ctx, pin.RequestID); err != nil && !errors.Is(err, ErrNotFound) {
+				slog.Warn("Failed to remove remote pin", "service", s.Name, "file", fileID, "request", pin.RequestID, "error", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Close stops the worker and waits for in-flight requests, aborting them when ctx ends.
+func (r *Replicator) Close(ctx context.Context) error {
+	r.once.Do(func() { close(r.closed) })
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *Replicator) work() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		r.scan()
+		select {
+		case <-ticker.C:
+		case <-r.wake:
+		case <-r.closed:
+			return
+		}
+	}
+}
+
+// scan checks the files whose next check is due.
+func (r *Replicator) scan() {
+	ctx := r.ctx
+	due := &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(time.Now().UnixMilli(), 10), Count: batchSize}
+	fileIDs, err := r.redis.ZRangeByScore(ctx, queueKey, due).Result()
+	if err != nil {
+		slog.Warn("Failed to scan remote pin queue", "error", err)
+		return
+	}
+
+	for _, fileID := range fileIDs {
+		select {
+		case <-r.closed:
+			return
+		default:
+		}
+
+		// Claim the file so that other replicas skip it. A replica that lists it at the
+		// same time may still check it too, so a check must be safe to repeat.
+		claimed, err := r.redis.ZRem(ctx, queueKey, fileID).Result()
+		if err != nil || claimed == 0 {
+			continue
+		}
+		if err := r.schedule(ctx, fileID, time.Now().Add(claimLease)); err != nil {
+			slog.Warn("Failed to claim remote pins", "file", fileID, "error", err)
+			continue
+		}
+
+		next, err := r.check(ctx, fileID)
+		if err != nil {
+			slog.Warn("Failed to check remote pins", "file", fileID, "error", err)
+			next = time.Now().Add(r.opts.BaseDelay)
+		}
+		if next.IsZero() {
+			err = r.redis.ZRem(ctx, queueKey, fileID).Err()
+		} else {
+			err = r.schedule(ctx, fileID, next)
+		}
+		if err != nil {
+			slog.Warn("Failed to schedule remote pins", "file", fileID, "error", err)
+		}
+	}
+}
+
+// check advances every due pin of a file and returns when it must be checked next,
+// or the zero time if every pin is done.
+func (r *Replicator) check(ctx context.Context, fileID string) (time.Time, error) {
+	values, err := r.redis.HGetAll(ctx, fileKey(fileID)).Result()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(values) == 0 {
+		// The file was deleted.
+		return time.Time{}, nil
+	}
+
+	var next time.Time
+	for _, s := range r.services {
+		var pin domain.RemotePin
+		if data, ok := values[serviceField(s.Name)]; ok {
+			if err := json.Unmarshal([]byte(data), &pin); err != nil {
+				return time.Time{}, err
+			}
+		} else {
+			// The service was added after the file was uploaded.
+			now := time.Now().UTC()
+			pin = domain.RemotePin{Service: s.Name, Status: domain.PinQueued, NextCheckAt: &now}
+		}
+		if pin.Done() {
+			continue
+		}
+
+		if !pin.NextCheckAt.After(time.Now()) {
+			r.advance(ctx, s, fileID, values["cid"], values["name"], &pin)
+			data, err := json.Marshal(pin)
+			if err != nil {
+				return time.Time{}, err
+			}
+			if err := r.redis.HSet(ctx, fileKey(fileID), serviceField(s.Name), string(data)).Err(); err != nil {
+				return time.Time{}, err
+			}
+		}
+		if pin.NextCheckAt != nil && (next.IsZero() || pin.NextCheckAt.Before(next)) {
+			next = *pin.NextCheckAt
+		}
+	}
+	return next, nil
+}
+
+// advance asks the service to pin cid, or for the state of the pin request, and updates pin.
+func (r *Replicator) advance(ctx context.Context, s service, fileID, cid, name string, pin *domain.RemotePin) {
+	var status *PinStatus
+	var err error
+	if pin.RequestID == "" {
+		status, err = s.client.Add(ctx, Pin{CID: cid, Name: name, Meta: map[string]string{"fileId": fileID}})
+	} else {
+		status, err = s.client.Get(ctx, pin.RequestID)
+		if errors.Is(err, ErrNotFound) {
+			// The service forgot the request; ask again.
+			pin.RequestID = ""
+		}
+	}
+
+	now := time.Now().UTC()
+	pin.UpdatedAt = now
+	switch {
+	case err != nil:
+		r.fail(pin, err.Error(), now)
+	case status.Status == domain.PinFailed:
+		// A failed request is not retried by the service, so remove it and make a new one.
+		if err := s.client.Remove(ctx, status.RequestID); err != nil && !errors.Is(err, ErrNotFound) {
+			slog.Warn("Failed to remove failed remote pin", "service", s.Name, "file", fileID, "request", status.RequestID, "error", err)
+		}
+		pin.RequestID = ""
+		r.fail(pin, "the pinning service failed to pin "+cid, now)
+	case status.Status == domain.PinPinned:
+		pin.RequestID = status.RequestID
+		pin.Status = domain.PinPinned
+		pin.LastError = ""
+		pin.NextCheckAt = nil
+	default:
+		pin.RequestID = status.RequestID
+		pin.Status = status.Status
+		pin.LastError = ""
+		next := now.Add(r.opts.PollInterval)
+		pin.NextCheckAt = &next
+	}
+}
+
+// fail records a failed attempt and schedules the next one, or gives up after MaxAttempts.
+func (r *Replicator) fail(pin *domain.RemotePin, message string, now time.Time) {
+	pin.Attempts++
+	pin.LastError = message
+	if pin.Attempts >= r.opts.MaxAttempts {
+		pin.Status = domain.PinFailed
+		pin.NextCheckAt = nil
+		return
+	}
+	if pin.RequestID == "" {
+		pin.Status = domain.PinQueued
+	}
+	next := now.Add(r.backoff(pin.Attempts))
+	pin.NextCheckAt = &next
+}
+
+// backoff returns the delay after the given number of attempts: BaseDelay doubled per
+// attempt, capped at MaxDelay, with up to 20% random jitter.
+func (r *Replicator) backoff(attempts int) time.Duration {
+	delay := r.opts.MaxDelay
+	if shift := attempts - 1; shift < 32 {
+		if scaled := r.opts.BaseDelay << shift; scaled > 0 && scaled < delay {
+			delay = scaled
+		}
+	}
+	if jitter := int64(delay / 5); jitter > 0 {
+		n, err := rand.Int(rand.Reader, big.NewInt(jitter))
+		if err == nil {
+			delay += time.Duration(n.Int64())
+		}
+	}
+	return delay
+}
+
+func (r *Replicator) schedule(ctx context.Context, fileID string, at time.Time) error {
+	if err := r.redis.ZAdd(ctx, queueKey, &redis.Z{Score: float64(at.UnixMilli()), Member: fileID}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule remote pins: %w", err)
+	}
+	return nil
+}
+
+func (r *Replicator) service(name string) (service, bool) {
+	for _, s := range r.services {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return service{}, false
+}
+
+// remotePins decodes the pins in the hash of a file, skipping malformed ones.
+func remotePins(values map[string]string) []domain.RemotePin {
+	var pins []domain.RemotePin
+	for field, data := range values {
+		if !strings.HasPrefix(field, "service:") {
+			continue
+		}
+		var pin domain.RemotePin
+		if err := json.Unmarshal([]byte(data), &pin); err == nil {
+			pins = append(pins, pin)
+		}
+	}
+	return pins
+}
+
+const queueKey = "pinning:queue"
+
+func fileKey(fileID string) string {
+	return "pinning:file:" + fileID
+}
+
+func serviceField(name string) string {
+	return "service:" + name
+}
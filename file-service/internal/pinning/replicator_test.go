@@ -0,0 +1,132 @@
+package pinning_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/file-service/internal/pinning"
+	"decentralstore/file-service/internal/pinning/pinningtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newService(t *testing.T, name string) (*pinningtest.Server, pinning.Service) {
+	server := pinningtest.NewServer("token-" + name)
+	t.Cleanup(server.Close)
+	return server, pinning.Service{Name: name, Endpoint: server.URL, Token: server.Token}
+}
+
+func newReplicator(t *testing.T, services ...pinning.Service) *pinning.Replicator {
+	r := pinning.NewReplicator(mocks.NewFakeRedisClient(), services, pinning.Options{
+		MaxAttempts:  3,
+		BaseDelay:    10 * time.Millisecond,
+		MaxDelay:     50 * time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+	})
+	t.Cleanup(func() { r.Close(context.Background()) })
+	return r
+}
+
+// waitPins polls the pins of a file until every one is done.
+func waitPins(t *testing.T, r *pinning.Replicator, fileID string) []domain.RemotePin {
+	t.Helper()
+	var pins []domain.RemotePin
+	require.Eventually(t, func() bool {
+		var err error
+		pins, err = r.Statuses(context.Background(), fileID)
+		require.NoError(t, err)
+		for _, pin := range pins {
+			if !pin.Done() {
+				return false
+			}
+		}
+		return len(pins) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	return pins
+}
+
+func TestReplicator_PinsOnEveryService(t *testing.T) {
+	first, firstService := newService(t, "first")
+	second, secondService := newService(t, "second")
+	r := newReplicator(t, secondService, firstService)
+	ctx := context.Background()
+
+	file := &domain.File{ID: "file-1", Name: "a.txt", CID: "QmFile"}
+	require.NoError(t, r.Replicate(ctx, file))
+
+	pins, err := r.Statuses(ctx, file.ID)
+	require.NoError(t, err)
+	require.Len(t, pins, 2)
+	assert.Equal(t, "first", pins[0].Service)
+
+	pins = waitPins(t, r, file.ID)
+	for _, pin := range pins {
+		assert.Equal(t, domain.PinPinned, pin.Status, pin.Service)
+		assert.NotEmpty(t, pin.RequestID)
+		assert.Zero(t, pin.Attempts)
+	}
+	for _, server := range []*pinningtest.Server{first, second} {
+		remote := server.Pins()
+		require.Len(t, remote, 1)
+		assert.Equal(t, "QmFile", remote[0].Pin.CID)
+		assert.Equal(t, "a.txt", remote[0].Pin.Name)
+		assert.Equal(t, "file-1", remote[0].Pin.Meta["fileId"])
+	}
+}
+
+func TestReplicator_RetriesFailedRequests(t *testing.T) {
+	server, service := newService(t, "remote")
+	server.FailRequests(2)
+	r := newReplicator(t, service)
+
+	require.NoError(t, r.Replicate(context.Background(), &domain.File{ID: "file-1", CID: "QmFile"}))
+
+	pins := waitPins(t, r, "file-1")
+	assert.Equal(t, domain.PinPinned, pins[0].Status)
+	assert.Equal(t, 2, pins[0].Attempts)
+	assert.Empty(t, pins[0].LastError)
+	assert.Len(t, server.Pins(), 1)
+}
+
+func TestReplicator_GivesUp(t *testing.T) {
+	failing, failingService := newService(t, "failing")
+	failing.FailCID("QmFile")
+	_, unauthorized := newService(t, "unauthorized")
+	unauthorized.Token = "wrong"
+	r := newReplicator(t, failingService, unauthorized)
+
+	require.NoError(t, r.Replicate(context.Background(), &domain.File{ID: "file-1", CID: "QmFile"}))
+
+	pins := waitPins(t, r, "file-1")
+	for _, pin := range pins {
+		assert.Equal(t, domain.PinFailed, pin.Status, pin.Service)
+		assert.Equal(t, 3, pin.Attempts, pin.Service)
+		assert.Empty(t, pin.RequestID, pin.Service)
+	}
+	assert.Contains(t, pins[0].LastError, "failed to pin QmFile")
+	assert.Contains(t, pins[1].LastError, "401")
+	// Failed requests are removed before asking again.
+	assert.Empty(t, failing.Pins())
+}
+
+func TestReplicator_Forget(t *testing.T) {
+	server, service := newService(t, "remote")
+	r := newReplicator(t, service)
+	ctx := context.Background()
+
+	require.NoError(t, r.Replicate(ctx, &domain.File{ID: "file-1", CID: "QmFile"}))
+	waitPins(t, r, "file-1")
+	require.Len(t, server.Pins(), 1)
+
+	require.NoError(t, r.Forget(ctx, "file-1"))
+	pins, err := r.Statuses(ctx, "file-1")
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+
+	require.NoError(t, r.Close(ctx))
+	assert.Empty(t, server.Pins())
+}
@@ -0,0 +1,257 @@
+// Package quota tracks per-tenant storage usage in Redis and enforces limits while uploads stream.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/infrastructure"
+)
+
+// flushThreshold is how many streamed bytes are accumulated before they are reserved in Redis.
+const flushThreshold = 256 * 1024
+
+// releaseTimeout bounds giving back usage once the request that reserved it is gone.
+const releaseTimeout = 5 * time.Second
+
+// Tracker keeps usage counters in the hash tenant:<id>:usage and optional per-tenant
+// limit overrides in tenant:<id>:quota. Counters are only changed with HINCRBY so
+// concurrent uploads on any replica see a consistent total.
+type Tracker struct {
+	redis infrastructure.RedisClient
+
+	mu       sync.RWMutex
+	defaults domain.Limits
+}
+
+func NewTracker(redisClient infrastructure.RedisClient, defaults domain.Limits) *Tracker {
+	return &Tracker{redis: redisClient, defaults: defaults}
+}
+
+// SetDefaults replaces the limits of tenants without an override.
+func (t *Tracker) SetDefaults(defaults domain.Limits) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.defaults = defaults
+}
+
+// Limits returns the limits of tenantID: the per-tenant override where set, the defaults otherwise.
+func (t *Tracker) Limits(ctx context.Context, tenantID string) (domain.Limits, error) {
+	values, err := t.redis.HGetAll(ctx, limitsKey(tenantID)).Result()
+	if err != nil {
+		return domain.Limits{}, fmt.Errorf("failed to get quota: %w", err)
+	}
+
+	t.mu.RLock()
+	limits := t.defaults
+	t.mu.RUnlock()
+	if v, ok := values["maxBytes"]; ok {
+		limits.MaxBytes, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := values["maxFiles"]; ok {
+		limits.MaxFiles, _ = strconv.ParseInt(v, 10, 64)
+	}
+	return limits, nil
+}
+
+// SetLimits overrides the default limits for tenantID.
+func (t *Tracker) SetLimits(ctx context.Context, tenantID string, limits domain.Limits) error {
+	err := t.redis.HSet(ctx, limitsKey(tenantID), "maxBytes", limits.MaxBytes, "maxFiles", limits.MaxFiles).Err()
+	if err != nil {
+		return fmt.Errorf("failed to set quota: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the current counters of tenantID.
+func (t *Tracker) Usage(ctx context.Context, tenantID string) (domain.Usage, error) {
+	values, err := t.redis.HGetAll(ctx, usageKey(tenantID)).Result()
+	if err != nil {
+		return domain.Usage{}, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	var usage domain.Usage
+	usage.Bytes, _ = strconv.ParseInt(values["bytes"], 10, 64)
+	usage.Files, _ = strconv.ParseInt(values["files"], 10, 64)
+	return usage, nil
+}
+
+// Reserve claims one file slot for an upload by tenantID. It fails early when the
+// file limit is reached or no byte allowance is left.
+func (t *Tracker) Reserve(ctx context.Context, tenantID string) (*Reservation, error) {
+	limits, err := t.Limits(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limits.MaxBytes > 0 {
+		usage, err := t.Usage(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if usage.Bytes >= limits.MaxBytes {
+			return nil, &domain.ErrQuotaExceeded{Resource: domain.QuotaBytes, Limit: limits.MaxBytes}
+		}
+	}
+
+	files, err := t.redis.HIncrBy(ctx, usageKey(tenantID), "files", 1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve file quota: %w", err)
+	}
+	if limits.MaxFiles > 0 && files > limits.MaxFiles {
+		releaseCtx, cancel := releaseContext(ctx)
+		defer cancel()
+		if err := t.redis.HIncrBy(releaseCtx, usageKey(tenantID), "files", -1).Err(); err != nil {
+			return nil, fmt.Errorf("failed to release file quota: %w", err)
+		}
+		return nil, &domain.ErrQuotaExceeded{Resource: domain.QuotaFiles, Limit: limits.MaxFiles}
+	}
+
+	return &Reservation{ctx: ctx, tracker: t, tenantID: tenantID, limits: limits}, nil
+}
+
+// Release gives back the usage of a deleted file. It is not aborted when ctx is canceled,
+// e.g. because the client disconnected, since the counters would leak otherwise.
+func (t *Tracker) Release(ctx context.Context, tenantID string, size int64) error {
+	ctx, cancel := releaseContext(ctx)
+	defer cancel()
+	if err := t.redis.HIncrBy(ctx, usageKey(tenantID), "files", -1).Err(); err != nil {
+		return fmt.Errorf("failed to release file quota: %w", err)
+	}
+	if err := t.redis.HIncrBy(ctx, usageKey(tenantID), "bytes", -size).Err(); err != nil {
+		return fmt.Errorf("failed to release byte quota: %w", err)
+	}
+	return nil
+}
+
+// Recompute resets the counters of tenantID from its file records, e.g. after a crash
+// left a reservation behind. Uploads that finish while it runs may be counted twice or not
+// at all, so it should be run when the tenant is idle.
+func (t *Tracker) Recompute(ctx context.Context, tenantID string, files []*domain.File) (domain.Usage, error) {
+	var usage domain.Usage
+	for _, file := range files {
+		usage.Bytes += file.Size
+		usage.Files++
+	}
+
+	err := t.redis.HSet(ctx, usageKey(tenantID), "bytes", usage.Bytes, "files", usage.Files).Err()
+	if err != nil {
+		return domain.Usage{}, fmt.Errorf("failed to store usage: %w", err)
+	}
+	return usage, nil
+}
+
+// Reservation accounts the bytes of one in-progress upload.
+type Reservation struct {
+	ctx      context.Context
+	tracker  *Tracker
+	tenantID string
+	limits   domain.Limits
+
+	read     int64
+	reserved int64
+	err      error
+}
+
+// Wrap returns a reader that reserves bytes as they are read from src and fails
+// with ErrQuotaExceeded as soon as the tenant's byte limit would be exceeded.
+func (r *Reservation) Wrap(src io.Reader) io.Reader {
+	return &countingReader{src: src, reservation: r}
+}
+
+// Add reserves n bytes that are not read through Wrap, e.g. the size of an imported file.
+// A negative n is refused, since it would let the upload slip past the byte limit.
+func (r *Reservation) Add(n int64) error {
+	if r.err != nil {
+		return r.err
+	}
+	if n < 0 {
+		return fmt.Errorf("quota: cannot reserve %d bytes", n)
+	}
+	r.read += n
+	return r.flush()
+}
+
+// Bytes returns the number of bytes read so far.
+func (r *Reservation) Bytes() int64 {
+	return r.read
+}
+
+// Err returns the quota error that aborted the upload, if any.
+func (r *Reservation) Err() error {
+	return r.err
+}
+
+// Commit reserves any bytes that have not been flushed yet.
+func (r *Reservation) Commit() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.flush()
+}
+
+// Cancel returns everything reserved by a failed upload, also after the upload's context
+// was canceled.
+func (r *Reservation) Cancel() error {
+	return r.tracker.Release(r.ctx, r.tenantID, r.reserved)
+}
+
+func (r *Reservation) flush() error {
+	pending := r.read - r.reserved
+	if pending == 0 {
+		return nil
+	}
+
+	total, err := r.tracker.redis.HIncrBy(r.ctx, usageKey(r.tenantID), "bytes", pending).Result()
+	if err != nil {
+		return fmt.Errorf("failed to reserve byte quota: %w", err)
+	}
+	r.reserved += pending
+
+	if r.limits.MaxBytes > 0 && total > r.limits.MaxBytes {
+		r.err = &domain.ErrQuotaExceeded{Resource: domain.QuotaBytes, Limit: r.limits.MaxBytes}
+		return r.err
+	}
+	return nil
+}
+
+type countingReader struct {
+	src         io.Reader
+	reservation *Reservation
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	r := c.reservation
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := c.src.Read(p)
+	r.read += int64(n)
+
+	if r.read-r.reserved >= flushThreshold || (err == io.EOF && r.read > r.reserved) {
+		if flushErr := r.flush(); flushErr != nil {
+			return n, flushErr
+		}
+	}
+	return n, err
+}
+
+// releaseContext keeps the values of ctx but not its cancellation, and bounds the release
+// by releaseTimeout instead.
+func releaseContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+}
+
+func usageKey(tenantID string) string {
+	return "tenant:" + tenantID + ":usage"
+}
+
+func limitsKey(tenantID string) string {
+	return "tenant:" + tenantID + ":quota"
+}
@@ -0,0 +1,178 @@
+package quota_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/file-service/internal/quota"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker_ReserveAndCommit(t *testing.T) {
+	tracker := quota.NewTracker(mocks.NewFakeRedisClient(), domain.Limits{})
+	ctx := context.Background()
+
+	reservation, err := tracker.Reserve(ctx, "acme")
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, reservation.Wrap(strings.NewReader("hello")))
+	require.NoError(t, err)
+	require.NoError(t, reservation.Commit())
+
+	usage, err := tracker.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Usage{Bytes: 5, Files: 1}, usage)
+	assert.Equal(t, int64(5), reservation.Bytes())
+}
+
+func TestTracker_ByteLimitStopsStream(t *testing.T) {
+	tracker := quota.NewTracker(mocks.NewFakeRedisClient(), domain.Limits{MaxBytes: 1024})
+	ctx := context.Background()
+
+	reservation, err := tracker.Reserve(ctx, "acme")
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, reservation.Wrap(strings.NewReader(strings.Repeat("x", 512*1024))))
+
+	var quotaErr *domain.ErrQuotaExceeded
+	require.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, domain.QuotaBytes, quotaErr.Resource)
+
+	require.NoError(t, reservation.Cancel())
+	usage, err := tracker.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Usage{}, usage)
+}
+
+func TestReservation_AddEnforcesByteLimit(t *testing.T) {
+	tracker := quota.NewTracker(mocks.NewFakeRedisClient(), domain.Limits{MaxBytes: 1024})
+	ctx := context.Background()
+
+	reservation, err := tracker.Reserve(ctx, "acme")
+	require.NoError(t, err)
+	require.NoError(t, reservation.Add(1000))
+	assert.Equal(t, int64(1000), reservation.Bytes())
+
+	var quotaErr *domain.ErrQuotaExceeded
+	require.True(t, errors.As(reservation.Add(100), &quotaErr))
+	require.NoError(t, reservation.Cancel())
+	usage, err := tracker.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Usage{}, usage)
+}
+
+func TestReservation_AddRefusesNegativeSizes(t *testing.T) {
+	tracker := quota.NewTracker(mocks.NewFakeRedisClient(), domain.Limits{MaxBytes: 1024})
+	ctx := context.Background()
+
+	reservation, err := tracker.Reserve(ctx, "acme")
+	require.NoError(t, err)
+	require.Error(t, reservation.Add(-1))
+	require.NoError(t, reservation.Add(1024))
+	require.NoError(t, reservation.Cancel())
+	usage, err := tracker.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Usage{}, usage)
+}
+
+// cancelAwareRedis fails commands on a canceled context, like go-redis does.
+type cancelAwareRedis struct {
+	*mocks.FakeRedisClient
+}
+
+func (r cancelAwareRedis) HIncrBy(ctx context.Context, key, field string, incr int64) *redis.IntCmd {
+	if err := ctx.Err(); err != nil {
+		cmd := redis.NewIntCmd(ctx)
+		cmd.SetErr(err)
+		return cmd
+	}
+	return r.FakeRedisClient.HIncrBy(ctx, key, field, incr)
+}
+
+func TestReservation_CancelAfterRequestIsCanceled(t *testing.T) {
+	tracker := quota.NewTracker(cancelAwareRedis{mocks.NewFakeRedisClient()}, domain.Limits{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	reservation, err := tracker.Reserve(ctx, "acme")
+	require.NoError(t, err)
+	require.NoError(t, reservation.Add(100))
+	cancel()
+
+	require.NoError(t, reservation.Cancel())
+	usage, err := tracker.Usage(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Usage{}, usage)
+}
+
+func TestTracker_ConcurrentReservationsRespectFileLimit(t *testing.T) {
+	tracker := quota.NewTracker(mocks.NewFakeRedisClient(), domain.Limits{MaxFiles: 5})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	granted := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tracker.Reserve(ctx, "acme"); err == nil {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, granted)
+	usage, err := tracker.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), usage.Files)
+}
+
+func TestTracker_SetLimitsOverridesDefaults(t *testing.T) {
+	tracker := quota.NewTracker(mocks.NewFakeRedisClient(), domain.Limits{MaxBytes: 100, MaxFiles: 10})
+	ctx := context.Background()
+
+	require.NoError(t, tracker.SetLimits(ctx, "acme", domain.Limits{MaxBytes: 0, MaxFiles: 3}))
+
+	limits, err := tracker.Limits(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Limits{MaxBytes: 0, MaxFiles: 3}, limits)
+
+	limits, err = tracker.Limits(ctx, "globex")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Limits{MaxBytes: 100, MaxFiles: 10}, limits)
+
+	tracker.SetDefaults(domain.Limits{MaxFiles: 20})
+	limits, err = tracker.Limits(ctx, "globex")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Limits{MaxFiles: 20}, limits)
+
+	limits, err = tracker.Limits(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, domain.Limits{MaxBytes: 0, MaxFiles: 3}, limits, "overrides survive a change of defaults")
+}
+
+func TestTracker_Recompute(t *testing.T) {
+	tracker := quota.NewTracker(mocks.NewFakeRedisClient(), domain.Limits{})
+	ctx := context.Background()
+
+	_, err := tracker.Reserve(ctx, "acme")
+	require.NoError(t, err)
+
+	usage, err := tracker.Recompute(ctx, "acme", []*domain.File{{Size: 3}, {Size: 4}})
+	require.NoError(t, err)
+	assert.Equal(t, domain.Usage{Bytes: 7, Files: 2}, usage)
+
+	stored, err := tracker.Usage(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, usage, stored)
+}
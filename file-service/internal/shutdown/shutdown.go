@@ -0,0 +1,113 @@
+// Package shutdown stops the service in order: the HTTP and gRPC servers drain in-flight requests,
+// then background workers and connections registered with a Registry are closed.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Func releases one resource. It should return once ctx is done even if it could not finish.
+type Func func(ctx context.Context) error
+
+// Registry collects the resources to release on shutdown.
+type Registry struct {
+	mu    sync.Mutex
+	names []string
+	funcs []Func
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Add registers fn under name. Resources are released in reverse order of registration,
+// so a worker registered after the connections it uses is stopped before they are closed.
+func (r *Registry) Add(name string, fn Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, name)
+	r.funcs = append(r.funcs, fn)
+}
+
+// Close releases every registered resource, even if some of them fail, and returns all errors.
+func (r *Registry) Close(ctx context.Context) error {
+	r.mu.Lock()
+	names, funcs := r.names, r.funcs
+	r.names, r.funcs = nil, nil
+	r.mu.Unlock()
+
+	var errs []error
+	for i := len(funcs) - 1; i >= 0; i-- {
+		if err := funcs[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", names[i], err))
+			continue
+		}
+		slog.Debug("Closed", "resource", names[i])
+	}
+	return errors.Join(errs...)
+}
+
+// Serve serves on ln until ctx is done. It then stops accepting connections and waits up to
+// timeout for in-flight requests such as streaming uploads and downloads to finish; requests
+// still running after that are cut off and context.DeadlineExceeded is returned.
+func Serve(ctx context.Context, server *http.Server, ln net.Listener, timeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Draining in-flight requests", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return err
+	}
+	if err := <-serveErr; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ServeGRPC is the gRPC counterpart of Serve: once ctx is done it stops accepting calls and
+// waits up to timeout for running calls and streams before cancelling them.
+func ServeGRPC(ctx context.Context, server *grpc.Server, ln net.Listener, timeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-time.After(timeout):
+		server.Stop()
+		return context.DeadlineExceeded
+	}
+}
@@ -0,0 +1,135 @@
+package shutdown_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/shutdown"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestRegistry_ClosesInReverseOrder(t *testing.T) {
+	registry := shutdown.NewRegistry()
+	var closed []string
+	for _, name := range []string{"redis", "ipfs", "worker"} {
+		registry.Add(name, func(ctx context.Context) error {
+			closed = append(closed, name)
+			if name == "ipfs" {
+				return errors.New("boom")
+			}
+			return nil
+		})
+	}
+
+	err := registry.Close(context.Background())
+	assert.ErrorContains(t, err, "ipfs: boom")
+	assert.Equal(t, []string{"worker", "ipfs", "redis"}, closed, "a failure does not stop the others")
+
+	closed = nil
+	require.NoError(t, registry.Close(context.Background()))
+	assert.Empty(t, closed, "resources are closed once")
+}
+
+// startServer serves handler until the returned cancel func is called.
+func startServer(t *testing.T, handler http.Handler, timeout time.Duration) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- shutdown.Serve(ctx, &http.Server{Handler: handler}, ln, timeout)
+	}()
+	return "http://" + ln.Addr().String(), cancel, done
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	url, stop, done := startServer(t, handler, 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	upload := make(chan result, 1)
+	go func() {
+		resp, err := http.Post(url, "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			upload <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		upload <- result{body: string(body), err: err}
+	}()
+	<-started
+
+	stop()
+	require.Eventually(t, func() bool {
+		_, err := http.Get(url)
+		return err != nil
+	}, time.Second, 10*time.Millisecond, "new connections are refused while draining")
+
+	close(release)
+	got := <-upload
+	require.NoError(t, got.err)
+	assert.Equal(t, "payload", got.body)
+	assert.NoError(t, <-done)
+}
+
+func TestServe_CutsOffRequestsAfterTimeout(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	url, stop, done := startServer(t, handler, 50*time.Millisecond)
+
+	requestErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		requestErr <- err
+	}()
+	<-started
+
+	stop()
+	assert.ErrorIs(t, <-done, context.DeadlineExceeded)
+	assert.Error(t, <-requestErr)
+}
+
+func TestServeGRPC_StopsWhenDone(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- shutdown.ServeGRPC(ctx, grpc.NewServer(), ln, time.Second)
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeGRPC did not return")
+	}
+}
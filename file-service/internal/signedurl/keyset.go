@@ -0,0 +1,156 @@
+package signedurl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key is an HMAC secret identified by a short key ID that is embedded in signed URLs.
+type Key struct {
+	ID     string
+	Secret []byte
+	// Expires is when a retired key stops being accepted. It is zero for the signing key.
+	Expires time.Time
+}
+
+type retiredKey struct {
+	key        Key
+	validUntil time.Time
+}
+
+// KeySet holds the key used to sign new URLs and the previously active keys
+// that are still accepted for verification until their grace period ends.
+type KeySet struct {
+	mu      sync.RWMutex
+	active  Key
+	retired []retiredKey
+	grace   time.Duration
+}
+
+// NewKeySet creates a KeySet that signs with active and keeps rotated-out keys valid for grace.
+func NewKeySet(active Key, grace time.Duration) (*KeySet, error) {
+	if err := validateKey(active); err != nil {
+		return nil, err
+	}
+	return &KeySet{active: active, grace: grace}, nil
+}
+
+// Rotate makes next the signing key. The previous key stays valid for verification
+// until now plus the grace period so that URLs already handed out keep working.
+func (ks *KeySet) Rotate(next Key, now time.Time) error {
+	if err := validateKey(next); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if next.ID == ks.active.ID {
+		return fmt.Errorf("key %q is already active", next.ID)
+	}
+	ks.retired = append(ks.retired, retiredKey{key: ks.active, validUntil: now.Add(ks.grace)})
+	ks.active = next
+	ks.pruneLocked(now)
+	return nil
+}
+
+// Retire registers a key that is no longer used for signing but is accepted until validUntil.
+func (ks *KeySet) Retire(key Key, validUntil time.Time) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if key.ID == ks.active.ID {
+		return fmt.Errorf("key %q is the active key", key.ID)
+	}
+	ks.retired = append(ks.retired, retiredKey{key: key, validUntil: validUntil})
+	return nil
+}
+
+// Active returns the key used to sign new URLs.
+func (ks *KeySet) Active() Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active
+}
+
+func (ks *KeySet) lookup(id string, now time.Time) (Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if id == ks.active.ID {
+		return ks.active, true
+	}
+	for _, rk := range ks.retired {
+		if rk.key.ID == id && now.Before(rk.validUntil) {
+			return rk.key, true
+		}
+	}
+	return Key{}, false
+}
+
+func (ks *KeySet) pruneLocked(now time.Time) {
+	kept := ks.retired[:0]
+	for _, rk := range ks.retired {
+		if now.Before(rk.validUntil) {
+			kept = append(kept, rk)
+		}
+	}
+	ks.retired = kept
+}
+
+// ParseKeys parses a key list of the form "kid1:secret1,kid2:secret2@2024-01-31T00:00:00Z".
+// The first key is the signing key. The others are retired keys, each with the RFC 3339 time
+// after which it is no longer accepted; an absolute time keeps restarts from extending it.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid key entry %q: expected kid:secret", entry)
+		}
+		key := Key{ID: id, Secret: []byte(secret)}
+		if len(keys) > 0 {
+			at := strings.LastIndex(secret, "@")
+			if at < 0 {
+				return nil, fmt.Errorf("retired key %q needs an expiry: expected kid:secret@<RFC 3339 time>", id)
+			}
+			expires, err := time.Parse(time.RFC3339, secret[at+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid expiry of retired key %q: %w", id, err)
+			}
+			key.Secret, key.Expires = []byte(secret[:at]), expires
+		}
+		if err := validateKey(key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	return keys, nil
+}
+
+func validateKey(key Key) error {
+	if key.ID == "" {
+		return errors.New("key ID must not be empty")
+	}
+	if strings.ContainsAny(key.ID, ":,") {
+		return fmt.Errorf("key ID %q must not contain ':' or ','", key.ID)
+	}
+	if len(key.Secret) < 16 {
+		return fmt.Errorf("secret for key %q must be at least 16 bytes", key.ID)
+	}
+	return nil
+}
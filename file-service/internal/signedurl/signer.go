@@ -0,0 +1,96 @@
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Query parameter names carried by a signed download URL.
+const (
+	ParamFileID    = "id"
+	ParamExpires   = "expires"
+	ParamKeyID     = "kid"
+	ParamSignature = "sig"
+)
+
+var (
+	ErrMissingSignature = errors.New("signed URL parameters are missing")
+	ErrExpired          = errors.New("signed URL has expired")
+	ErrUnknownKey       = errors.New("signed URL uses an unknown or retired key")
+	ErrInvalidSignature = errors.New("signed URL signature is invalid")
+)
+
+// Signer mints and verifies download URLs whose authorization is carried entirely
+// in the query string, so verifying them needs no metadata lookup.
+type Signer struct {
+	keys *KeySet
+	now  func() time.Time
+}
+
+func NewSigner(keys *KeySet) *Signer {
+	return &Signer{keys: keys, now: time.Now}
+}
+
+// Sign returns the query parameters authorizing a download of fileID until expires.
+func (s *Signer) Sign(fileID string, expires time.Time) url.Values {
+	key := s.keys.Active()
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	q := url.Values{}
+	q.Set(ParamFileID, fileID)
+	q.Set(ParamExpires, exp)
+	q.Set(ParamKeyID, key.ID)
+	q.Set(ParamSignature, sign(key, fileID, exp))
+	return q
+}
+
+// Verify checks the signature, key and expiry in q and returns the authorized file ID.
+func (s *Signer) Verify(q url.Values) (string, error) {
+	fileID := q.Get(ParamFileID)
+	exp := q.Get(ParamExpires)
+	kid := q.Get(ParamKeyID)
+	sig := q.Get(ParamSignature)
+	if fileID == "" || exp == "" || kid == "" || sig == "" {
+		return "", ErrMissingSignature
+	}
+
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	now := s.now()
+	key, ok := s.keys.lookup(kid, now)
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(sign(key, fileID, exp))) {
+		return "", ErrInvalidSignature
+	}
+	if !now.Before(time.Unix(expUnix, 0)) {
+		return "", ErrExpired
+	}
+
+	return fileID, nil
+}
+
+// IsSigned reports whether q carries a download signature.
+func IsSigned(q url.Values) bool {
+	return q.Get(ParamSignature) != ""
+}
+
+func sign(key Key, fileID, expires string) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(key.ID))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(fileID))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
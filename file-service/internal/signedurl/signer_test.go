@@ -0,0 +1,102 @@
+package signedurl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigner(t *testing.T, now time.Time) *Signer {
+	keys, err := NewKeySet(Key{ID: "k1", Secret: []byte("0123456789abcdef")}, time.Hour)
+	require.NoError(t, err)
+
+	signer := NewSigner(keys)
+	signer.now = func() time.Time { return now }
+	return signer
+}
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := newTestSigner(t, now)
+
+	q := signer.Sign("123", now.Add(time.Minute))
+
+	fileID, err := signer.Verify(q)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "123", fileID)
+	assert.Equal(t, "k1", q.Get(ParamKeyID))
+}
+
+func TestSigner_Verify_Expired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := newTestSigner(t, now)
+
+	q := signer.Sign("123", now.Add(-time.Second))
+
+	_, err := signer.Verify(q)
+
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestSigner_Verify_TamperedFileID(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := newTestSigner(t, now)
+
+	q := signer.Sign("123", now.Add(time.Minute))
+	q.Set(ParamFileID, "456")
+
+	_, err := signer.Verify(q)
+
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestSigner_Verify_MissingParams(t *testing.T) {
+	signer := newTestSigner(t, time.Now())
+
+	_, err := signer.Verify(nil)
+
+	assert.ErrorIs(t, err, ErrMissingSignature)
+}
+
+func TestSigner_Verify_RotatedKeyGracePeriod(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := newTestSigner(t, now)
+
+	q := signer.Sign("123", now.Add(2*time.Hour))
+	require.NoError(t, signer.keys.Rotate(Key{ID: "k2", Secret: []byte("fedcba9876543210")}, now))
+
+	_, err := signer.Verify(q)
+	assert.NoError(t, err, "old key is accepted during the grace period")
+
+	signer.now = func() time.Time { return now.Add(time.Hour + time.Second) }
+	_, err = signer.Verify(q)
+	assert.ErrorIs(t, err, ErrUnknownKey, "old key is rejected after the grace period")
+
+	fresh := signer.Sign("123", now.Add(2*time.Hour))
+	assert.Equal(t, "k2", fresh.Get(ParamKeyID))
+	_, err = signer.Verify(fresh)
+	assert.NoError(t, err)
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("new:0123456789abcdef, old:fedcba9876543210@2024-01-31T00:00:00Z")
+
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "new", keys[0].ID)
+	assert.True(t, keys[0].Expires.IsZero())
+	assert.Equal(t, []byte("fedcba9876543210"), keys[1].Secret)
+	assert.Equal(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), keys[1].Expires)
+
+	_, err = ParseKeys("new:0123456789abcdef,old:fedcba9876543210")
+	assert.Error(t, err, "retired keys need an expiry")
+
+	_, err = ParseKeys("broken")
+	assert.Error(t, err)
+
+	_, err = ParseKeys("short:abc")
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"decentralstore/file-service/internal/archive"
+	"decentralstore/file-service/internal/domain"
+)
+
+// OpenBundle はitemsのキーワードを検証し、有効なファイルを1つのtarまたはzipのストリームにまとめます。
+// ファイルは1つずつIPFSから読み出すため、メモリ使用量はファイルの大きさによりません。
+// 検証や読み出しに失敗したファイルはバンドルを中断せず、最後のマニフェストに記録します
+func (s *FileUseCaseImpl) OpenBundle(ctx context.Context, format string, items []domain.BundleItem) (io.ReadCloser, error) {
+	if format != archive.FormatTar && format != archive.FormatZip {
+		return nil, archive.ErrUnsupportedFormat
+	}
+
+	manifest := &domain.BundleManifest{Files: []domain.BundleFile{}, Failed: []domain.BundleFailure{}}
+	var files []*domain.File
+	included := make(map[string]bool)
+	for _, item := range items {
+		// 同じファイルは1度だけ含める
+		if included[item.ID] {
+			continue
+		}
+
+		metadata, err := s.getOwnMetadata(ctx, item.ID)
+		if err == nil && !validateKeyword(item.Keyword, metadata.DownloadKeyword) {
+			err = &domain.ErrInvalidKeyword{Operation: "download"}
+		}
+		if err != nil {
+			manifest.Failed = append(manifest.Failed, bundleFailure(item.ID, err))
+			continue
+		}
+		included[item.ID] = true
+		files = append(files, metadata)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(s.writeBundle(ctx, writer, format, files, manifest))
+	}()
+	return reader, nil
+}
+
+func (s *FileUseCaseImpl) writeBundle(ctx context.Context, w io.Writer, format string, files []*domain.File, manifest *domain.BundleManifest) error {
+	bundle, err := archive.NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	paths := map[string]bool{domain.BundleManifestName: true}
+	for _, metadata := range files {
+		bundlePath := claimBundlePath(paths, metadata)
+		skipped, err := s.addToBundle(ctx, bundle, bundlePath, metadata)
+		if err != nil {
+			return err
+		}
+		if skipped != nil {
+			manifest.Failed = append(manifest.Failed, bundleFailure(metadata.ID, skipped))
+			continue
+		}
+		manifest.Files = append(manifest.Files, domain.BundleFile{ID: metadata.ID, Name: metadata.Name, Path: bundlePath, Size: metadata.Size})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := bundle.Add(domain.BundleManifestName, int64(len(data)), time.Now(), bytes.NewReader(data)); err != nil {
+		return err
+	}
+	return bundle.Close()
+}
+
+// addToBundle はファイルをbundlePathに書き込みます。読み出しを始められなかったファイルはskippedとして返します。
+// errはアーカイブの途中で失敗したことを示し、ストリームは続けられません
+func (s *FileUseCaseImpl) addToBundle(ctx context.Context, bundle *archive.Writer, bundlePath string, metadata *domain.File) (skipped error, err error) {
+	if !metadata.IsDirectory() {
+		content, err := s.catFile(ctx, metadata)
+		if err != nil {
+			return err, nil
+		}
+		defer content.Close()
+		return nil, bundle.Add(bundlePath, metadata.Size, metadata.UploadedAt, content)
+	}
+
+	// ディレクトリはエントリごとに読み出す。途中のエントリを読み出せない場合、それまでのエントリは残る
+	for _, entry := range metadata.Entries {
+		content, err := s.catEntry(ctx, metadata, entry)
+		if err != nil {
+			return err, nil
+		}
+		err = bundle.Add(bundlePath+"/"+entry.Path, entry.Size, metadata.UploadedAt, content)
+		content.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.publish(ctx, domain.EventFileDownloaded, metadata)
+	return nil, nil
+}
+
+// claimBundlePath はバンドル内で重複しないファイルのパスを返します。同じ名前が既にある場合はIDのディレクトリの下に置きます
+func claimBundlePath(paths map[string]bool, metadata *domain.File) string {
+	name := path.Base(metadata.Name)
+	if name == "." || name == "/" || name == ".." {
+		name = metadata.ID
+	}
+	if paths[name] {
+		name = metadata.ID + "/" + name
+	}
+	paths[name] = true
+	return name
+}
+
+// bundleFailure はマニフェストに記録する失敗です。内部エラーの詳細は記録しません
+func bundleFailure(fileID string, err error) domain.BundleFailure {
+	var notFound *domain.ErrNotFound
+	var invalidKeyword *domain.ErrInvalidKeyword
+	if errors.As(err, &notFound) || errors.As(err, &invalidKeyword) {
+		return domain.BundleFailure{ID: fileID, Error: err.Error()}
+	}
+	return domain.BundleFailure{ID: fileID, Error: "Failed to read file"}
+}
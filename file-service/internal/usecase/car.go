@@ -0,0 +1,322 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/car"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/quota"
+	"decentralstore/shared/tracing"
+
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ExportCAR はitemsのファイルのDAGと、メタデータを記録したブロックを1つのCARファイルとして返します。
+// ルートは各ファイルのCIDとメタデータのブロックです。version 2ではCARv2でラップします
+func (s *FileUseCaseImpl) ExportCAR(ctx context.Context, items []domain.BundleItem, version int) (io.ReadCloser, error) {
+	// バックアップが欠けないよう、1つでも検証に失敗したらエクスポートしない
+	manifest := domain.ExportManifest{Format: domain.ExportFormat}
+	var files []*domain.File
+	var roots []cid.Cid
+	for _, item := range items {
+		metadata, err := s.getOwnMetadata(ctx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !validateKeyword(item.Keyword, metadata.DownloadKeyword) {
+			return nil, &domain.ErrInvalidKeyword{Operation: "download"}
+		}
+		if metadata.IsErasureCoded() {
+			return nil, &domain.ErrErasureCoded{ID: metadata.ID}
+		}
+		root, err := cid.Decode(metadata.CID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CID %s of file %s: %w", metadata.CID, metadata.ID, err)
+		}
+
+		files = append(files, metadata)
+		roots = append(roots, root)
+		manifest.Files = append(manifest.Files, domain.ExportedFile{
+			Name:       metadata.Name,
+			Size:       metadata.Size,
+			CID:        metadata.CID,
+			UploadedAt: metadata.UploadedAt,
+			Entries:    metadata.Entries,
+		})
+	}
+
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifestID, err := car.RawCID(manifestData)
+	if err != nil {
+		return nil, err
+	}
+	roots = append(roots, manifestID)
+
+	reader, writer := io.Pipe()
+	go func() {
+		write := func(w io.Writer) error {
+			return s.writeCAR(ctx, w, roots, files, manifestID, manifestData)
+		}
+		if version == 2 {
+			writer.CloseWithError(writeCARv2(writer, write))
+			return
+		}
+		writer.CloseWithError(write(writer))
+	}()
+	for _, metadata := range files {
+		s.publish(ctx, domain.EventFileDownloaded, metadata)
+	}
+	return reader, nil
+}
+
+// writeCAR はIPFSからエクスポートした各ファイルのブロックを、最後にメタデータのブロックを書き込みます
+func (s *FileUseCaseImpl) writeCAR(ctx context.Context, w io.Writer, roots []cid.Cid, files []*domain.File, manifestID cid.Cid, manifestData []byte) error {
+	writer, err := car.NewWriter(w, roots)
+	if err != nil {
+		return err
+	}
+	for _, metadata := range files {
+		_, span := tracing.Start(ctx, "ipfs.dag_export", attribute.String("ipfs.cid", metadata.CID))
+		export, err := s.StorageClient.IPFSShell.DagExport(metadata.CID)
+		if err == nil {
+			err = writer.CopyBlocks(export)
+			export.Close()
+		}
+		tracing.End(span, err)
+		if err != nil {
+			return fmt.Errorf("failed to export %s from IPFS: %w", metadata.CID, err)
+		}
+	}
+	return writer.Put(manifestID, manifestData)
+}
+
+// writeCARv2 はCARv2のヘッダーにデータの長さが必要なため、CARv1を一時ファイルに書き出してからラップします
+func writeCARv2(w io.Writer, write func(io.Writer) error) error {
+	spool, err := os.CreateTemp("", "export-*.car")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	if err := write(spool); err != nil {
+		return err
+	}
+	size, err := spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return car.WriteV2(w, spool, size)
+}
+
+// ImportCAR はCARファイルの全ブロックがCIDのハッシュと一致し、各ルートのDAGが揃っていることを確かめてから
+// IPFSに取り込み、ファイルとして記録します。ExportCARのメタデータがあればその名前とエントリを使い、
+// なければ各ルートを単一ファイルとして記録します（nameは単一ルートの場合の名前です）
+func (s *FileUseCaseImpl) ImportCAR(ctx context.Context, r io.Reader, name string) ([]*domain.File, error) {
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, &domain.ErrUnauthenticated{Operation: "import"}
+	}
+
+	// 検証が終わるまでIPFSに渡さないよう、一時ファイルに書き出しながら検証する。
+	// クォータを超えるCARファイルは書き出している間に拒否する
+	spool, err := os.CreateTemp("", "import-*.car")
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool CAR file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	spooling, err := s.Quota.Reserve(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := car.Verify(io.TeeReader(spooling.Wrap(r), spool))
+	cancelReservation(ctx, spooling)
+	if quotaErr := spooling.Err(); quotaErr != nil {
+		return nil, quotaErr
+	}
+	if err != nil {
+		return nil, &domain.ErrInvalidUpload{Reason: err.Error()}
+	}
+	imported, err := importedFiles(contents, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// ルートはピン留めせずに取り込む。プレーンなCARファイルのサイズは取り込んだDAGからしかわからないため、
+	// サイズを確認してからクォータを確保する。確保できなければブロックはGCで消える
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to spool CAR file: %w", err)
+	}
+	_, span := tracing.Start(ctx, "ipfs.dag_import", attribute.Int("car.roots", len(contents.Roots)))
+	err = s.StorageClient.IPFSShell.DagImport(spool)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import CAR file to IPFS: %w", err)
+	}
+	for _, file := range imported {
+		if err := s.checkImported(ctx, file); err != nil {
+			return nil, err
+		}
+	}
+
+	var reservations []*quota.Reservation
+	cancel := func() {
+		for _, reservation := range reservations {
+			cancelReservation(ctx, reservation)
+		}
+	}
+	for _, file := range imported {
+		reservation, err := s.Quota.Reserve(ctx, tenantID)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+		if err := reservation.Add(file.Size); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	for i, file := range imported {
+		if err := s.claimContent(ctx, file.CID); err != nil {
+			rollbackCtx, cancelRollback := rollbackContext(ctx)
+			defer cancelRollback()
+			for _, claimed := range imported[:i] {
+				s.releaseClaim(rollbackCtx, claimed.CID)
+			}
+			cancel()
+			return nil, err
+		}
+	}
+
+	for i, file := range imported {
+		file.ID = generateUniqueID()
+		file.DownloadKeyword = generateKeyword()
+		file.DeleteKeyword = generateKeyword()
+		file.TenantID = tenantID
+		if err := s.index(ctx, file); err != nil {
+			// indexは失敗したファイルを解放する。残りのファイルのクォータと参照もここで解放する
+			rollbackCtx, cancelRollback := rollbackContext(ctx)
+			defer cancelRollback()
+			for j, rest := range imported[i+1:] {
+				cancelReservation(ctx, reservations[i+1+j])
+				s.releaseClaim(rollbackCtx, rest.CID)
+			}
+			return nil, err
+		}
+		s.publish(ctx, domain.EventFileUploaded, file)
+	}
+	return imported, nil
+}
+
+// importedFiles はCARファイルに含まれるファイルを返します。各ファイルのDAGがすべて含まれている必要があります
+func importedFiles(contents *car.Contents, name string) ([]*domain.File, error) {
+	var files []*domain.File
+	manifest, manifestID := findManifest(contents)
+	if manifest != nil {
+		for _, exported := range manifest.Files {
+			files = append(files, &domain.File{
+				Name:       exported.Name,
+				Size:       exported.Size,
+				CID:        exported.CID,
+				UploadedAt: exported.UploadedAt,
+				Entries:    exported.Entries,
+			})
+		}
+	} else {
+		for _, root := range contents.Roots {
+			fileName := root.String()
+			if name != "" && len(contents.Roots) == 1 {
+				fileName = name
+			}
+			// サイズはIPFSに取り込んだ後に確認する
+			files = append(files, &domain.File{Name: fileName, Size: -1, CID: root.String(), UploadedAt: time.Now()})
+		}
+	}
+	if len(files) == 0 {
+		return nil, &domain.ErrInvalidUpload{Reason: "the CAR file has no roots"}
+	}
+
+	for _, file := range files {
+		root, err := cid.Decode(file.CID)
+		if err != nil || root == manifestID {
+			return nil, &domain.ErrInvalidUpload{Reason: "invalid root " + file.CID}
+		}
+		if err := contents.Complete(root); err != nil {
+			return nil, &domain.ErrInvalidUpload{Reason: err.Error()}
+		}
+	}
+	return files, nil
+}
+
+// findManifest はExportCARが書き込んだメタデータのブロックを探します
+func findManifest(contents *car.Contents) (*domain.ExportManifest, cid.Cid) {
+	for _, root := range contents.Roots {
+		data, ok := contents.RootData(root)
+		if !ok {
+			continue
+		}
+		var manifest domain.ExportManifest
+		if json.Unmarshal(data, &manifest) == nil && manifest.Format == domain.ExportFormat {
+			return &manifest, root
+		}
+	}
+	return nil, cid.Undef
+}
+
+// checkImported は取り込んだDAGのサイズとエントリがメタデータと一致することを確かめます。
+// メタデータのないファイルはサイズをIPFSから求め、ディレクトリは受け付けません
+func (s *FileUseCaseImpl) checkImported(ctx context.Context, file *domain.File) error {
+	stat, err := s.StorageClient.IPFSShell.FilesStat(ctx, "/ipfs/"+file.CID)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", file.CID, err)
+	}
+	mismatch := &domain.ErrInvalidUpload{Reason: "the metadata of " + file.CID + " does not match its DAG"}
+
+	if !file.IsDirectory() {
+		if stat.Type != "file" {
+			return &domain.ErrInvalidUpload{Reason: file.CID + " is not a file; import directories with their metadata"}
+		}
+		if file.Size == -1 {
+			file.Size = int64(stat.Size)
+		} else if int64(stat.Size) != file.Size {
+			return mismatch
+		}
+		return nil
+	}
+
+	if stat.Type != "directory" {
+		return mismatch
+	}
+	var total int64
+	for _, entry := range file.Entries {
+		if _, err := domain.CleanEntryPath(entry.Path); err != nil {
+			return err
+		}
+		entryStat, err := s.StorageClient.IPFSShell.FilesStat(ctx, "/ipfs/"+file.CID+"/"+entry.Path)
+		if err != nil || entryStat.Hash != entry.CID || int64(entryStat.Size) != entry.Size {
+			return mismatch
+		}
+		total += entry.Size
+	}
+	if total != file.Size {
+		return mismatch
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package usecase_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"decentralstore/file-service/internal/car"
+	"decentralstore/file-service/internal/domain"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plainCAR returns a CARv1 file of one raw block, which is its root.
+func plainCAR(t *testing.T, content string) (*bytes.Buffer, cid.Cid) {
+	t.Helper()
+	root, err := car.RawCID([]byte(content))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	writer, err := car.NewWriter(buf, []cid.Cid{root})
+	require.NoError(t, err)
+	require.NoError(t, writer.Put(root, []byte(content)))
+	return buf, root
+}
+
+func TestImportCAR_PlainCAR(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+	data, root := plainCAR(t, "imported content")
+
+	imported, err := f.files.ImportCAR(ctx, data, "imported.txt")
+	require.NoError(t, err)
+
+	require.Len(t, imported, 1)
+	assert.Equal(t, "imported.txt", imported[0].Name)
+	assert.Equal(t, root.String(), imported[0].CID)
+	assert.Equal(t, int64(len("imported content")), imported[0].Size)
+	assert.True(t, f.ipfs.IsPinned(imported[0].CID))
+	assert.Equal(t, domain.Usage{Bytes: imported[0].Size, Files: 1}, f.usage(t, "acme"))
+
+	require.NoError(t, f.files.DeleteFile(ctx, imported[0].ID, imported[0].DeleteKeyword))
+	assert.False(t, f.ipfs.IsPinned(imported[0].CID))
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+}
+
+func TestImportCAR_OverQuotaPinsNothing(t *testing.T) {
+	// The CAR file fits in the quota, but a root listed twice is counted as two files of its size.
+	content := bytes.Repeat([]byte("x"), 200)
+	root, err := car.RawCID(content)
+	require.NoError(t, err)
+	data := &bytes.Buffer{}
+	writer, err := car.NewWriter(data, []cid.Cid{root, root})
+	require.NoError(t, err)
+	require.NoError(t, writer.Put(root, content))
+	f := newTestFixture(t, domain.Limits{MaxBytes: 350})
+	require.Less(t, data.Len(), 350)
+
+	_, err = f.files.ImportCAR(tenantContext("acme"), data, "")
+
+	var quotaErr *domain.ErrQuotaExceeded
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Empty(t, f.ipfs.Pinned)
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+}
+
+func TestExportCAR_RoundTrip(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	source := tenantContext("acme")
+	data, _ := plainCAR(t, "exported content")
+	imported, err := f.files.ImportCAR(source, data, "exported.txt")
+	require.NoError(t, err)
+
+	reader, err := f.files.ExportCAR(source, []domain.BundleItem{{ID: imported[0].ID, Keyword: imported[0].DownloadKeyword}}, 1)
+	require.NoError(t, err)
+	exported, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	reader.Close()
+
+	// The metadata block carries the name into another tenant.
+	restored, err := f.files.ImportCAR(tenantContext("globex"), bytes.NewReader(exported), "")
+	require.NoError(t, err)
+	require.Len(t, restored, 1)
+	assert.Equal(t, "exported.txt", restored[0].Name)
+	assert.Equal(t, imported[0].CID, restored[0].CID)
+	assert.Equal(t, domain.Usage{Bytes: restored[0].Size, Files: 1}, f.usage(t, "globex"))
+
+	_, err = f.files.ExportCAR(tenantContext("globex"), []domain.BundleItem{{ID: imported[0].ID, Keyword: imported[0].DownloadKeyword}}, 1)
+	assert.IsType(t, &domain.ErrNotFound{}, err)
+}
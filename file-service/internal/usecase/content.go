@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ipfsContent はIPFS上のファイルをio.ReadSeekerとして扱い、Rangeリクエストや
+// ダウンロードの再開に応えられるようにします。
+// IPFSクライアントのCatもシャードからの復元も読み出し位置を指定できないため、シーク後は先頭から開き直して読み飛ばします。
+type ipfsContent struct {
+	open func() (io.ReadCloser, error)
+	size int64
+
+	// offset は次に読む位置、reader はoffsetの位置にあるストリーム（nilなら次のReadで開き直す）
+	offset int64
+	reader io.ReadCloser
+}
+
+// newIPFSContent はopenで開いたreaderからファイルを読み出し、シーク後はopenで開き直します
+func newIPFSContent(open func() (io.ReadCloser, error), size int64, reader io.ReadCloser) *ipfsContent {
+	return &ipfsContent{open: open, size: size, reader: reader}
+}
+
+func (c *ipfsContent) Read(p []byte) (int, error) {
+	if c.reader == nil {
+		if err := c.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := c.reader.Read(p)
+	c.offset += int64(n)
+	return n, err
+}
+
+func (c *ipfsContent) reopen() error {
+	reader, err := c.open()
+	if err != nil {
+		return fmt.Errorf("failed to reopen file from IPFS: %w", err)
+	}
+	if _, err := io.CopyN(io.Discard, reader, c.offset); err != nil && err != io.EOF {
+		reader.Close()
+		return fmt.Errorf("failed to seek in IPFS file: %w", err)
+	}
+	c.reader = reader
+	return nil
+}
+
+func (c *ipfsContent) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.offset
+	case io.SeekEnd:
+		offset += c.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if offset != c.offset && c.reader != nil {
+		c.reader.Close()
+		c.reader = nil
+	}
+	c.offset = offset
+	return offset, nil
+}
+
+func (c *ipfsContent) Close() error {
+	if c.reader == nil {
+		return nil
+	}
+	return c.reader.Close()
+}
@@ -0,0 +1,287 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/quota"
+	"decentralstore/shared/logging"
+	"decentralstore/shared/tracing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 重複排除はテナントごとに行い、他のテナントが同じ内容をアップロードしたかは分からないようにします。
+// テナントが単一ファイルとして保存した内容は次のハッシュに記録されます:
+//
+//	tenant:<id>:content -> "cid:<cid>" はそのCIDを持つテナントのファイル数、"sha256:<hex>" はそのハッシュの内容のCID、
+//	                       "hashes" は"sha256:"のフィールドの数
+func tenantContentKey(tenantID string) string {
+	return "tenant:" + tenantID + ":content"
+}
+
+const contentHashesField = "hashes"
+
+func contentRefsField(cid string) string {
+	return "cid:" + cid
+}
+
+func contentHashField(digest string) string {
+	return "sha256:" + digest
+}
+
+// ClaimContent はテナントがアップロード済みの内容をSHA-256またはCIDで指定し、その内容を指す新しいファイルを作成します。
+// 内容を送らずに済むよう、クライアントはアップロードの前に呼び出します。テナントが持っていない内容は、
+// 他のテナントが持っていてもErrNotFoundになります
+func (s *FileUseCaseImpl) ClaimContent(ctx context.Context, ref domain.ContentRef, filename string) (*domain.File, error) {
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, &domain.ErrUnauthenticated{Operation: "upload"}
+	}
+	contentID, digest, err := s.findContent(ctx, tenantID, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// サイズはIPFSから求める。ピンを共有するため、内容が残っていることも確かめる
+	if err := s.claimContent(ctx, contentID); err != nil {
+		return nil, err
+	}
+	var reservation *quota.Reservation
+	rollback := func() {
+		rollbackCtx, cancel := rollbackContext(ctx)
+		defer cancel()
+		if reservation != nil {
+			cancelReservation(ctx, reservation)
+		}
+		s.releaseClaim(rollbackCtx, contentID)
+	}
+	stat, err := s.StorageClient.IPFSShell.FilesStat(ctx, "/ipfs/"+contentID)
+	if err != nil {
+		rollback()
+		return nil, fmt.Errorf("failed to stat %s: %w", contentID, err)
+	}
+
+	reservation, err = s.Quota.Reserve(ctx, tenantID)
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+	if err := reservation.Add(int64(stat.Size)); err != nil {
+		rollback()
+		return nil, err
+	}
+
+	claimed := &domain.File{
+		ID:              generateUniqueID(),
+		Name:            filename,
+		Size:            int64(stat.Size),
+		CID:             contentID,
+		UploadedAt:      time.Now(),
+		DownloadKeyword: generateKeyword(),
+		DeleteKeyword:   generateKeyword(),
+		TenantID:        tenantID,
+		SHA256:          digest,
+	}
+	if err := s.index(ctx, claimed); err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, domain.EventFileUploaded, claimed)
+	return claimed, nil
+}
+
+// findContent はrefが指す、テナントのファイルが参照している内容のCIDとSHA-256を返します
+func (s *FileUseCaseImpl) findContent(ctx context.Context, tenantID string, ref domain.ContentRef) (string, string, error) {
+	digest := strings.ToLower(ref.SHA256)
+	if digest != "" {
+		if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+			return "", "", &domain.ErrInvalidUpload{Reason: "sha256 must be 64 hexadecimal digits"}
+		}
+	}
+	contentID := ref.CID
+	if contentID != "" {
+		parsed, err := cid.Decode(contentID)
+		if err != nil {
+			return "", "", &domain.ErrInvalidUpload{Reason: "invalid CID " + contentID}
+		}
+		contentID = parsed.String()
+	}
+	if digest == "" && contentID == "" {
+		return "", "", &domain.ErrInvalidUpload{Reason: "sha256 or cid is required"}
+	}
+	notFound := &domain.ErrNotFound{Resource: "content", ID: ref.SHA256 + ref.CID}
+
+	if digest != "" {
+		found, err := s.tenantContent(ctx, tenantID, digest)
+		if err != nil {
+			return "", "", err
+		}
+		if found == "" || (contentID != "" && found != contentID) {
+			return "", "", notFound
+		}
+		return found, digest, nil
+	}
+
+	referenced, err := s.contentReferenced(ctx, tenantID, contentID)
+	if err != nil {
+		return "", "", err
+	}
+	if !referenced {
+		return "", "", notFound
+	}
+	return contentID, "", nil
+}
+
+// tenantContent はテナントのファイルが参照している、SHA-256がdigestの内容のCIDを返します。なければ空です
+func (s *FileUseCaseImpl) tenantContent(ctx context.Context, tenantID, digest string) (string, error) {
+	contentID, err := s.StorageClient.RedisClient.HGet(ctx, tenantContentKey(tenantID), contentHashField(digest)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up content: %w", err)
+	}
+	referenced, err := s.contentReferenced(ctx, tenantID, contentID)
+	if err != nil || !referenced {
+		return "", err
+	}
+	return contentID, nil
+}
+
+// contentReferenced はテナントのファイルがcidを参照しているかを返します
+func (s *FileUseCaseImpl) contentReferenced(ctx context.Context, tenantID, contentID string) (bool, error) {
+	refs, err := s.StorageClient.RedisClient.HGet(ctx, tenantContentKey(tenantID), contentRefsField(contentID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up content: %w", err)
+	}
+	n, _ := strconv.ParseInt(refs, 10, 64)
+	return n > 0, nil
+}
+
+// indexContent はテナントの単一ファイルが参照する内容を記録します
+func (s *FileUseCaseImpl) indexContent(ctx context.Context, file *domain.File) error {
+	key := tenantContentKey(file.TenantID)
+	err := s.StorageClient.RedisClient.HIncrBy(ctx, key, contentRefsField(file.CID), 1).Err()
+	if err != nil {
+		return fmt.Errorf("failed to index content for tenant: %w", err)
+	}
+	if file.SHA256 != "" {
+		added, err := s.StorageClient.RedisClient.HSet(ctx, key, contentHashField(file.SHA256), file.CID).Result()
+		if err == nil && added > 0 {
+			err = s.StorageClient.RedisClient.HIncrBy(ctx, key, contentHashesField, 1).Err()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to index content for tenant: %w", err)
+		}
+	}
+	return nil
+}
+
+// releaseContent はテナントの単一ファイルが削除されたとき、その内容の参照を1つ解放し、参照がなくなれば
+// ハッシュの記録を消します。記録される前にアップロードされたファイルでは参照数が負にならないようにします
+func (s *FileUseCaseImpl) releaseContent(ctx context.Context, file *domain.File) {
+	redisClient := s.StorageClient.RedisClient
+	key := tenantContentKey(file.TenantID)
+	refs, err := redisClient.HIncrBy(ctx, key, contentRefsField(file.CID), -1).Result()
+	if err == nil && refs < 0 {
+		err = redisClient.HSet(ctx, key, contentRefsField(file.CID), 0).Err()
+	}
+	if err == nil && refs <= 0 && file.SHA256 != "" {
+		var removed int64
+		removed, err = redisClient.HDel(ctx, key, contentHashField(file.SHA256)).Result()
+		if err == nil && removed > 0 {
+			err = redisClient.HIncrBy(ctx, key, contentHashesField, -1).Err()
+		}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to release content reference", "file", file.ID, "cid", file.CID, "error", err)
+	}
+}
+
+// addContent はfileのSHA-256を計算しながらIPFSに追加し、そのCIDを参照として数えます。
+// テナントが同じ内容をアップロード済みなら、IPFSに追加せずにそのCIDを返します。内容が一致しうるのは
+// テナントがハッシュを記録した内容を持つ場合だけなので、そのときだけ一時ファイルに書き出してから比べます。
+// 他のテナントだけが持つ内容は、存在が応答時間から分からないよう常にIPFSに追加します（IPFSの中ではブロックが共有されます）
+func (s *FileUseCaseImpl) addContent(ctx context.Context, tenantID string, file io.Reader) (string, string, error) {
+	hash := sha256.New()
+	if !s.hasHashedContent(ctx, tenantID) {
+		contentID, err := s.addNewContent(ctx, io.TeeReader(file, hash))
+		return contentID, hex.EncodeToString(hash.Sum(nil)), err
+	}
+
+	spool, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to spool upload: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	if _, err := io.Copy(io.MultiWriter(spool, hash), file); err != nil {
+		return "", "", err
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	existing, err := s.tenantContent(ctx, tenantID, digest)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to look up duplicate content", "error", err)
+	}
+	if existing != "" {
+		// 削除と重なってピンが外れていても内容を失わないよう、参照を数えてからピンし直す
+		err := s.claimContent(ctx, existing)
+		if err == nil {
+			return existing, digest, nil
+		}
+		logging.FromContext(ctx).Warn("Failed to claim duplicate content", "cid", existing, "error", err)
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", "", fmt.Errorf("failed to spool upload: %w", err)
+	}
+	contentID, err := s.addNewContent(ctx, spool)
+	return contentID, digest, err
+}
+
+// hasHashedContent はテナントがハッシュを記録した内容を持つかを返します。分からなければfalseです
+func (s *FileUseCaseImpl) hasHashedContent(ctx context.Context, tenantID string) bool {
+	hashes, err := s.StorageClient.RedisClient.HGet(ctx, tenantContentKey(tenantID), contentHashesField).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx).Warn("Failed to look up content of tenant", "error", err)
+	}
+	return hashes > 0
+}
+
+// addNewContent はrをIPFSに追加し、そのCIDを参照として数えます。追加の時点でピンされますが、
+// 同じ内容の最後のファイルの削除と重なっても内容を失わないよう、参照を数えてからピンし直します
+func (s *FileUseCaseImpl) addNewContent(ctx context.Context, r io.Reader) (string, error) {
+	_, span := tracing.Start(ctx, "ipfs.add")
+	contentID, err := s.StorageClient.IPFSShell.Add(r)
+	span.SetAttributes(attribute.String("ipfs.cid", contentID))
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.claimContent(ctx, contentID); err != nil {
+		rollbackCtx, cancel := rollbackContext(ctx)
+		defer cancel()
+		s.unpinIfUnreferenced(rollbackCtx, contentID)
+		return "", err
+	}
+	return contentID, nil
+}
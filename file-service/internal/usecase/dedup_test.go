@@ -0,0 +1,113 @@
+package usecase_test
+
+import (
+	"strings"
+	"testing"
+
+	"decentralstore/file-service/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadFile_SharesTheContentOfTheTenant(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+
+	first, err := f.files.UploadFile(ctx, strings.NewReader("shared content"), "a.txt")
+	require.NoError(t, err)
+	second, err := f.files.UploadFile(ctx, strings.NewReader("shared content"), "b.txt")
+	require.NoError(t, err)
+
+	assert.Equal(t, first.CID, second.CID)
+	assert.Equal(t, first.SHA256, second.SHA256)
+	assert.Equal(t, 1, f.ipfs.Adds)
+	assert.Equal(t, domain.Usage{Bytes: 2 * first.Size, Files: 2}, f.usage(t, "acme"))
+
+	// The pin is shared, so it stays until the last file is deleted.
+	require.NoError(t, f.files.DeleteFile(ctx, first.ID, first.DeleteKeyword))
+	assert.True(t, f.ipfs.IsPinned(second.CID))
+	reader, err := f.files.DownloadFile(ctx, second.ID, second.DownloadKeyword)
+	require.NoError(t, err)
+	assert.Equal(t, "shared content", readAll(t, reader))
+
+	require.NoError(t, f.files.DeleteFile(ctx, second.ID, second.DeleteKeyword))
+	assert.False(t, f.ipfs.IsPinned(second.CID))
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+}
+
+func TestUploadFile_AddsContentOfOtherTenants(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+
+	first, err := f.files.UploadFile(tenantContext("acme"), strings.NewReader("shared content"), "a.txt")
+	require.NoError(t, err)
+	second, err := f.files.UploadFile(tenantContext("globex"), strings.NewReader("shared content"), "b.txt")
+	require.NoError(t, err)
+
+	// IPFS shares the blocks, but the upload is not skipped.
+	assert.Equal(t, first.CID, second.CID)
+	assert.Equal(t, 2, f.ipfs.Adds)
+
+	require.NoError(t, f.files.DeleteFile(tenantContext("acme"), first.ID, first.DeleteKeyword))
+	assert.True(t, f.ipfs.IsPinned(second.CID))
+}
+
+func TestClaimContent(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+	uploadedFile, err := f.files.UploadFile(ctx, strings.NewReader("shared content"), "a.txt")
+	require.NoError(t, err)
+
+	bySHA256, err := f.files.ClaimContent(ctx, domain.ContentRef{SHA256: strings.ToUpper(uploadedFile.SHA256)}, "b.txt")
+	require.NoError(t, err)
+	byCID, err := f.files.ClaimContent(ctx, domain.ContentRef{CID: uploadedFile.CID}, "c.txt")
+	require.NoError(t, err)
+
+	for _, claimed := range []*domain.File{bySHA256, byCID} {
+		assert.Equal(t, uploadedFile.CID, claimed.CID)
+		assert.Equal(t, uploadedFile.Size, claimed.Size)
+		assert.NotEqual(t, uploadedFile.ID, claimed.ID)
+	}
+	assert.Equal(t, 1, f.ipfs.Adds)
+	assert.Equal(t, domain.Usage{Bytes: 3 * uploadedFile.Size, Files: 3}, f.usage(t, "acme"))
+
+	for _, file := range []*domain.File{uploadedFile, bySHA256} {
+		require.NoError(t, f.files.DeleteFile(ctx, file.ID, file.DeleteKeyword))
+		assert.True(t, f.ipfs.IsPinned(uploadedFile.CID))
+	}
+	require.NoError(t, f.files.DeleteFile(ctx, byCID.ID, byCID.DeleteKeyword))
+	assert.False(t, f.ipfs.IsPinned(uploadedFile.CID))
+
+	// Once the tenant has no file with the content, it cannot be claimed.
+	_, err = f.files.ClaimContent(ctx, domain.ContentRef{SHA256: uploadedFile.SHA256}, "d.txt")
+	assert.IsType(t, &domain.ErrNotFound{}, err)
+}
+
+func TestClaimContent_OtherTenantsContentIsNotFound(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	uploadedFile, err := f.files.UploadFile(tenantContext("acme"), strings.NewReader("shared content"), "a.txt")
+	require.NoError(t, err)
+
+	for _, ref := range []domain.ContentRef{{SHA256: uploadedFile.SHA256}, {CID: uploadedFile.CID}} {
+		_, err := f.files.ClaimContent(tenantContext("globex"), ref, "b.txt")
+		assert.IsType(t, &domain.ErrNotFound{}, err)
+	}
+	assert.Equal(t, domain.Usage{}, f.usage(t, "globex"))
+}
+
+func TestClaimContent_OverQuota(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{MaxFiles: 1})
+	ctx := tenantContext("acme")
+	uploadedFile, err := f.files.UploadFile(ctx, strings.NewReader("shared content"), "a.txt")
+	require.NoError(t, err)
+
+	_, err = f.files.ClaimContent(ctx, domain.ContentRef{CID: uploadedFile.CID}, "b.txt")
+
+	var quotaErr *domain.ErrQuotaExceeded
+	assert.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, domain.Usage{Bytes: uploadedFile.Size, Files: 1}, f.usage(t, "acme"))
+
+	// The claim was released, so deleting the only file unpins the content.
+	require.NoError(t, f.files.DeleteFile(ctx, uploadedFile.ID, uploadedFile.DeleteKeyword))
+	assert.False(t, f.ipfs.IsPinned(uploadedFile.CID))
+}
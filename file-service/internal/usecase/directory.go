@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"decentralstore/file-service/internal/archive"
+	"decentralstore/file-service/internal/auth"
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/quota"
+	"decentralstore/shared/logging"
+	"decentralstore/shared/tracing"
+
+	shell "github.com/ipfs/go-ipfs-api"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// stagingDir はディレクトリを組み立てるMFS上の作業領域です
+const stagingDir = "/decentralstore/uploads/"
+
+// DirectoryReader はディレクトリのアップロードに含まれるファイルを1つずつ返します
+// （multipartの各パートやアーカイブのエントリ）。終端ではio.EOFを返します
+type DirectoryReader interface {
+	Next() (path string, content io.Reader, err error)
+}
+
+// UploadDirectory はentriesをUnixFSディレクトリとしてIPFSに保存し、ルートCIDで1つのファイルとして記録します。
+// クォータ上はディレクトリ全体で1ファイルと数えます
+func (s *FileUseCaseImpl) UploadDirectory(ctx context.Context, name string, entries DirectoryReader) (*domain.File, error) {
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, &domain.ErrUnauthenticated{Operation: "upload"}
+	}
+
+	reservation, err := s.Quota.Reserve(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := generateUniqueID()
+	root, fileEntries, err := s.addDirectory(ctx, stagingDir+id, reservation, entries)
+	if err == nil {
+		err = reservation.Commit()
+	}
+	if err != nil {
+		rollbackCtx, cancel := rollbackContext(ctx)
+		defer cancel()
+		if root != "" {
+			s.releaseClaim(rollbackCtx, root)
+		}
+		cancelReservation(ctx, reservation)
+		if quotaErr := reservation.Err(); quotaErr != nil {
+			return nil, quotaErr
+		}
+		var invalid *domain.ErrInvalidUpload
+		if errors.As(err, &invalid) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to upload directory to IPFS: %w", err)
+	}
+
+	uploadedFile := &domain.File{
+		ID:              id,
+		Name:            name,
+		Size:            reservation.Bytes(),
+		CID:             root,
+		UploadedAt:      time.Now(),
+		DownloadKeyword: generateKeyword(),
+		DeleteKeyword:   generateKeyword(),
+		TenantID:        tenantID,
+		Entries:         fileEntries,
+	}
+	if err := s.index(ctx, uploadedFile); err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, domain.EventFileUploaded, uploadedFile)
+	return uploadedFile, nil
+}
+
+// addDirectory はエントリをピンせずに追加してMFSのstagingに集め、ルートの参照を数えてピンします。
+// 失敗した場合はstagingを削除するので、追加済みのブロックはGCで回収されます
+func (s *FileUseCaseImpl) addDirectory(ctx context.Context, staging string, reservation *quota.Reservation, entries DirectoryReader) (string, []domain.FileEntry, error) {
+	ctx, span := tracing.Start(ctx, "ipfs.add_directory")
+	root, fileEntries, err := s.stageDirectory(ctx, staging, reservation, entries)
+	if err == nil {
+		err = s.claimContent(ctx, root)
+	}
+	span.SetAttributes(attribute.String("ipfs.cid", root), attribute.Int("ipfs.entries", len(fileEntries)))
+	tracing.End(span, err)
+
+	if rmErr := s.StorageClient.IPFSShell.FilesRm(context.WithoutCancel(ctx), staging, true); rmErr != nil && len(fileEntries) > 0 {
+		logging.FromContext(ctx).Warn("Failed to remove staging directory", "path", staging, "error", rmErr)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return root, fileEntries, nil
+}
+
+func (s *FileUseCaseImpl) stageDirectory(ctx context.Context, staging string, reservation *quota.Reservation, entries DirectoryReader) (string, []domain.FileEntry, error) {
+	var fileEntries []domain.FileEntry
+	paths := make(map[string]bool)
+	for {
+		entryPath, content, err := entries.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fileEntries, &domain.ErrInvalidUpload{Reason: "failed to read entry: " + err.Error()}
+		}
+		entryPath, err = domain.CleanEntryPath(entryPath)
+		if err != nil {
+			return "", fileEntries, err
+		}
+		if err := claimPath(paths, entryPath); err != nil {
+			return "", fileEntries, err
+		}
+
+		counted := &countingReader{src: reservation.Wrap(content)}
+		cid, err := s.StorageClient.IPFSShell.Add(counted, shell.Pin(false))
+		if err != nil {
+			return "", fileEntries, err
+		}
+		err = s.StorageClient.IPFSShell.FilesCp(ctx, "/ipfs/"+cid, staging+"/"+entryPath, shell.FilesCp.Parents(true))
+		if err != nil {
+			return "", fileEntries, err
+		}
+		fileEntries = append(fileEntries, domain.FileEntry{Path: entryPath, Size: counted.n, CID: cid})
+	}
+	if len(fileEntries) == 0 {
+		return "", nil, &domain.ErrInvalidUpload{Reason: "the directory has no files"}
+	}
+
+	stat, err := s.StorageClient.IPFSShell.FilesStat(ctx, staging)
+	if err != nil {
+		return "", fileEntries, err
+	}
+	return stat.Hash, fileEntries, nil
+}
+
+// claimPath はパスを登録します。同じパスや、ファイルとディレクトリの両方として使われるパスは拒否します
+func claimPath(paths map[string]bool, entryPath string) error {
+	conflict := &domain.ErrInvalidUpload{Reason: "duplicate or conflicting entry path " + entryPath}
+	if _, ok := paths[entryPath]; ok {
+		return conflict
+	}
+	parts := strings.Split(entryPath, "/")
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		if paths[dir] {
+			return conflict
+		}
+		paths[dir] = false
+	}
+	paths[entryPath] = true
+	return nil
+}
+
+// OpenEntry は認可済みのリクエストのためにディレクトリ内の1ファイルを取得します
+func (s *FileUseCaseImpl) OpenEntry(ctx context.Context, fileID string, entryPath string) (io.ReadCloser, error) {
+	metadata, err := s.getMetadata(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := metadata.Entry(entryPath)
+	if !ok {
+		return nil, &domain.ErrNotFound{Resource: "path", ID: entryPath}
+	}
+
+	reader, err := s.catEntry(ctx, metadata, *entry)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, domain.EventFileDownloaded, metadata)
+	return reader, nil
+}
+
+// OpenArchive は認可済みのリクエストのためにディレクトリ全体をtarまたはzipのストリームとして返します。
+// 単一ファイルは1エントリのアーカイブになります
+func (s *FileUseCaseImpl) OpenArchive(ctx context.Context, fileID string, format string) (io.ReadCloser, error) {
+	metadata, err := s.getMetadata(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	entries := metadata.Entries
+	if !metadata.IsDirectory() {
+		entries = []domain.FileEntry{{Path: metadata.Name, Size: metadata.Size, CID: metadata.CID}}
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		err := archive.Write(writer, format, metadata.UploadedAt, entries, func(entry domain.FileEntry) (io.ReadCloser, error) {
+			if !metadata.IsDirectory() {
+				return s.openContent(metadata)
+			}
+			return s.catEntry(ctx, metadata, entry)
+		})
+		writer.CloseWithError(err)
+	}()
+	s.publish(ctx, domain.EventFileDownloaded, metadata)
+	return reader, nil
+}
+
+// catEntry はルートCIDからのパスでエントリを取得します
+func (s *FileUseCaseImpl) catEntry(ctx context.Context, metadata *domain.File, entry domain.FileEntry) (io.ReadCloser, error) {
+	entryPath := metadata.CID + "/" + entry.Path
+	_, span := tracing.Start(ctx, "ipfs.cat", attribute.String("ipfs.cid", metadata.CID), attribute.String("ipfs.path", entry.Path))
+	open := func() (io.ReadCloser, error) { return s.StorageClient.IPFSShell.Cat(entryPath) }
+	reader, err := open()
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file from IPFS: %w", err)
+	}
+	return newIPFSContent(open, entry.Size, reader), nil
+}
+
+// countingReader は読み出したバイト数を数えます
+type countingReader struct {
+	src io.Reader
+	n   int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.src.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,79 @@
+package usecase_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"decentralstore/file-service/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// directoryEntries is a DirectoryReader over paths and their contents.
+type directoryEntries [][2]string
+
+func (e *directoryEntries) Next() (string, io.Reader, error) {
+	if len(*e) == 0 {
+		return "", nil, io.EOF
+	}
+	entry := (*e)[0]
+	*e = (*e)[1:]
+	return entry[0], strings.NewReader(entry[1]), nil
+}
+
+func TestUploadDirectory(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+
+	entries := &directoryEntries{{"index.html", "<h1>hello</h1>"}, {"css/site.css", "body {}"}}
+	dir, err := f.files.UploadDirectory(ctx, "site", entries)
+	require.NoError(t, err)
+
+	assert.True(t, dir.IsDirectory())
+	assert.Equal(t, int64(len("<h1>hello</h1>")+len("body {}")), dir.Size)
+	require.Len(t, dir.Entries, 2)
+	assert.Equal(t, "css/site.css", dir.Entries[1].Path)
+	assert.True(t, f.ipfs.IsPinned(dir.CID))
+	assert.Empty(t, f.ipfs.MFS, "staging directory")
+	assert.Equal(t, domain.Usage{Bytes: dir.Size, Files: 1}, f.usage(t, "acme"))
+
+	reader, err := f.files.OpenEntry(ctx, dir.ID, "css/site.css")
+	require.NoError(t, err)
+	assert.Equal(t, "body {}", readAll(t, reader))
+	_, err = f.files.DownloadFile(ctx, dir.ID, dir.DownloadKeyword)
+	assert.IsType(t, &domain.ErrIsDirectory{}, err)
+
+	require.NoError(t, f.files.DeleteFile(ctx, dir.ID, dir.DeleteKeyword))
+	assert.False(t, f.ipfs.IsPinned(dir.CID))
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+}
+
+func TestUploadDirectory_RejectsConflictingPaths(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+
+	entries := &directoryEntries{{"docs", "a file"}, {"docs/readme.md", "a file in a directory of the same name"}}
+	_, err := f.files.UploadDirectory(ctx, "site", entries)
+
+	assert.IsType(t, &domain.ErrInvalidUpload{}, err)
+	assert.Empty(t, f.ipfs.Pinned)
+	assert.Empty(t, f.ipfs.MFS, "staging directory")
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+}
+
+func TestUploadDirectory_OverQuota(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{MaxBytes: 10})
+	ctx := tenantContext("acme")
+
+	entries := &directoryEntries{{"a.txt", "12345"}, {"b.txt", "67890"}, {"c.txt", "too much"}}
+	_, err := f.files.UploadDirectory(ctx, "site", entries)
+
+	var quotaErr *domain.ErrQuotaExceeded
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, domain.QuotaBytes, quotaErr.Resource)
+	assert.Empty(t, f.ipfs.Pinned)
+	assert.Empty(t, f.ipfs.MFS, "staging directory")
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+}
@@ -0,0 +1,439 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/erasure"
+	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/shared/logging"
+)
+
+// erasureFilesKey はシャードに分割して保存されたファイルのIDの集合で、シャードの修復に使います
+const erasureFilesKey = "erasure:files"
+
+// addShards はfileをリード・ソロモン符号でシャードに分割し、それぞれを別の健全なIPFSノードに保存します。
+// 全てのシャードを保存できなければ、保存できたシャードを解放して失敗します
+func (s *FileUseCaseImpl) addShards(file io.Reader) (*domain.ErasureCoding, error) {
+	store := s.StorageClient.Shards
+	if store == nil {
+		return nil, errors.New("erasure coding needs several IPFS nodes")
+	}
+	params := *s.Erasure
+	nodes := store.HealthyNodes()
+	if len(nodes) < params.Shards() {
+		return nil, fmt.Errorf("%d shards need as many healthy IPFS nodes, %d are healthy", params.Shards(), len(nodes))
+	}
+	// ノードが多い場合に負荷が偏らないよう、ランダムに選ぶ
+	rand.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+
+	coding := &domain.ErasureCoding{
+		DataShards:   params.DataShards,
+		ParityShards: params.ParityShards,
+		BlockSize:    params.BlockSize,
+		Shards:       make([]domain.Shard, params.Shards()),
+	}
+	writers := make([]io.Writer, params.Shards())
+	pipes := make([]*io.PipeWriter, params.Shards())
+	errs := make([]error, params.Shards())
+	var wg sync.WaitGroup
+	for i := range coding.Shards {
+		node := nodes[i]
+		reader, writer := io.Pipe()
+		writers[i], pipes[i] = writer, writer
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cid, err := store.AddTo(node, reader)
+			errs[i] = err
+			if err == nil {
+				coding.Shards[i] = domain.Shard{CID: cid, Node: node}
+			}
+			// 失敗したノードへの書き込みはエラーになり、アップロード全体が失敗する
+			reader.CloseWithError(err)
+		}()
+	}
+
+	_, err := erasure.Encode(file, params, writers)
+	for _, writer := range pipes {
+		writer.CloseWithError(err)
+	}
+	wg.Wait()
+	if err == nil {
+		err = errors.Join(errs...)
+	}
+	if err != nil {
+		s.releaseShards(context.Background(), coding)
+		return nil, err
+	}
+	return coding, nil
+}
+
+// releaseShards は保存されたシャードを解放します。解放に失敗したシャードはノードに残ります
+func (s *FileUseCaseImpl) releaseShards(ctx context.Context, coding *domain.ErasureCoding) {
+	for _, shard := range coding.Shards {
+		if shard.CID == "" {
+			continue
+		}
+		if err := s.StorageClient.Shards.RemoveFrom(shard.Node, shard.CID); err != nil {
+			logging.FromContext(ctx).Warn("Failed to release shard", "cid", shard.CID, "node", shard.Node, "error", err)
+		}
+	}
+}
+
+// openShards はシャードからファイルを復元するストリームを返します。データシャードから順に、
+// 復元に必要な数のシャードだけを読み出し、失敗したシャードは次のシャードで補います
+func (s *FileUseCaseImpl) openShards(metadata *domain.File) (io.ReadCloser, error) {
+	store := s.StorageClient.Shards
+	if store == nil {
+		return nil, fmt.Errorf("file %s is erasure-coded, which needs several IPFS nodes", metadata.ID)
+	}
+	coding := metadata.Erasure
+
+	// 健全なノードのシャードだけで足りれば、応答しないノードを待たない
+	healthy := store.HealthyNodes()
+	onHealthy := 0
+	for _, shard := range coding.Shards {
+		if slices.Contains(healthy, shard.Node) {
+			onHealthy++
+		}
+	}
+	readers := make([]io.Reader, len(coding.Shards))
+	content := &shardsContent{}
+	for i, shard := range coding.Shards {
+		if onHealthy >= coding.DataShards && !slices.Contains(healthy, shard.Node) {
+			continue
+		}
+		r := &shardReader{open: func() (io.ReadCloser, error) { return store.CatFrom(shard.Node, shard.CID) }}
+		readers[i] = r
+		content.shards = append(content.shards, r)
+	}
+
+	reader, err := erasure.NewReader(readers, codingParams(coding), metadata.Size)
+	if err != nil {
+		return nil, err
+	}
+	// 最初のストライプを読み出し、シャードが足りない場合はレスポンスを始める前に失敗する
+	buffered := bufio.NewReader(reader)
+	if _, err := buffered.Peek(1); err != nil && err != io.EOF {
+		content.Close()
+		return nil, err
+	}
+	content.Reader = buffered
+	return content, nil
+}
+
+func codingParams(coding *domain.ErasureCoding) erasure.Params {
+	return erasure.Params{DataShards: coding.DataShards, ParityShards: coding.ParityShards, BlockSize: coding.BlockSize}
+}
+
+// shardsContent はシャードから復元した内容で、閉じると開いたシャードを全て閉じます
+type shardsContent struct {
+	io.Reader
+	shards []*shardReader
+}
+
+func (c *shardsContent) Close() error {
+	var errs []error
+	for _, shard := range c.shards {
+		errs = append(errs, shard.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// shardReader は最初に読み出すときにシャードを開きます。復元に使わないシャードは開きません
+type shardReader struct {
+	open   func() (io.ReadCloser, error)
+	reader io.ReadCloser
+}
+
+func (r *shardReader) Read(p []byte) (int, error) {
+	if r.reader == nil {
+		reader, err := r.open()
+		if err != nil {
+			return 0, err
+		}
+		r.reader = reader
+	}
+	return r.reader.Read(p)
+}
+
+func (r *shardReader) Close() error {
+	if r.reader == nil {
+		return nil
+	}
+	return r.reader.Close()
+}
+
+// ShardRepairer は失われたシャードを定期的に残りのシャードから再生成します。
+// 健全でないノードのシャードと、ノードからピンが失われたシャードを失われたとみなし、
+// そのファイルの他のシャードを持たない健全なノードに再生成して、メタデータを更新します。
+// 再生成できたシャードの元のノードからはピンを外します
+type ShardRepairer struct {
+	files    *FileUseCaseImpl
+	interval time.Duration
+
+	closed chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// NewShardRepairer はintervalごとにシャードを修復します。Closeで停止します
+func NewShardRepairer(storageClient *infrastructure.StorageClient, interval time.Duration) *ShardRepairer {
+	r := &ShardRepairer{
+		files:    &FileUseCaseImpl{StorageClient: storageClient},
+		interval: interval,
+		closed:   make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+func (r *ShardRepairer) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := r.Repair(context.Background()); err != nil {
+				slog.Warn("Failed to repair shards", "error", err)
+			}
+		case <-r.closed:
+			return
+		}
+	}
+}
+
+// Close は修復を停止し、実行中のファイルの修復が終わるのを待ちます
+func (r *ShardRepairer) Close(ctx context.Context) error {
+	r.once.Do(func() { close(r.closed) })
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Repair は全てのシャードを確認して失われたシャードを再生成し、再生成したシャードの数を返します。
+// 復元できないファイルや再生成に失敗したシャードは記録して次のファイルに進みます
+func (r *ShardRepairer) Repair(ctx context.Context) (int, error) {
+	store := r.files.StorageClient.Shards
+	if store == nil {
+		return 0, nil
+	}
+	fileIDs, err := r.files.StorageClient.RedisClient.SMembers(ctx, erasureFilesKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list erasure-coded files: %w", err)
+	}
+
+	// 健全なノードのピンを一度だけ取得する。取得できないノードのシャードは失われたとみなさない
+	healthy := store.HealthyNodes()
+	pins := make(map[string]map[string]bool)
+	for _, node := range healthy {
+		nodePins, err := store.NodePins(node)
+		if err != nil {
+			slog.Warn("Failed to list pins, skipping the shards of the node", "node", node, "error", err)
+			continue
+		}
+		pins[node] = make(map[string]bool, len(nodePins))
+		for cid, info := range nodePins {
+			if info.Type == "recursive" {
+				pins[node][cid] = true
+			}
+		}
+	}
+	lost := func(shard domain.Shard) bool {
+		if !slices.Contains(healthy, shard.Node) {
+			return true
+		}
+		nodePins, listed := pins[shard.Node]
+		return listed && !nodePins[shard.CID]
+	}
+
+	repaired := 0
+	for _, fileID := range fileIDs {
+		select {
+		case <-r.closed:
+			return repaired, nil
+		default:
+		}
+		n, err := r.repairFile(ctx, fileID, healthy, lost)
+		repaired += n
+		if err != nil {
+			slog.Warn("Failed to repair shards", "file", fileID, "error", err)
+		}
+	}
+	return repaired, nil
+}
+
+// repairFile はファイルの失われたシャードを再生成します
+func (r *ShardRepairer) repairFile(ctx context.Context, fileID string, healthy []string, lost func(domain.Shard) bool) (int, error) {
+	metadata, err := r.files.getMetadata(ctx, fileID)
+	var notFound *domain.ErrNotFound
+	if errors.As(err, &notFound) {
+		return 0, r.files.StorageClient.RedisClient.SRem(ctx, erasureFilesKey, fileID).Err()
+	}
+	if err != nil {
+		return 0, err
+	}
+	coding := metadata.Erasure
+	if coding == nil {
+		return 0, nil
+	}
+
+	var missing []int
+	used := make(map[string]bool)
+	for i, shard := range coding.Shards {
+		if lost(shard) {
+			missing = append(missing, i)
+		} else {
+			used[shard.Node] = true
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	if len(coding.Shards)-len(missing) < coding.DataShards {
+		return 0, fmt.Errorf("%d of %d shards are lost, the file cannot be rebuilt", len(missing), len(coding.Shards))
+	}
+
+	// 1つのノードが複数のシャードを持つと、そのノードを失ったときに復元できなくなる
+	var targets []string
+	for _, node := range healthy {
+		if !used[node] {
+			targets = append(targets, node)
+		}
+	}
+	if len(targets) == 0 {
+		return 0, errors.New("no healthy IPFS node without a shard of the file")
+	}
+	missing = missing[:min(len(missing), len(targets))]
+
+	rebuilt, rebuildErr := r.rebuild(metadata, missing, targets)
+	if len(rebuilt) == 0 {
+		return 0, rebuildErr
+	}
+	if err := r.replaceShards(ctx, fileID, rebuilt); err != nil {
+		for _, shard := range rebuilt {
+			r.files.StorageClient.Shards.RemoveFrom(shard.Node, shard.CID)
+		}
+		if errors.As(err, &notFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	slog.Info("Repaired shards", "file", fileID, "shards", len(rebuilt))
+	return len(rebuilt), rebuildErr
+}
+
+// replaceShards はメタデータのシャードを再生成したシャードに置き換え、元のシャードを解放します。
+// メタデータは修復中に削除されていないか確認するため読み直します
+func (r *ShardRepairer) replaceShards(ctx context.Context, fileID string, rebuilt map[int]domain.Shard) error {
+	metadata, err := r.files.getMetadata(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if metadata.Erasure == nil {
+		return &domain.ErrNotFound{Resource: "file", ID: fileID}
+	}
+	old := slices.Clone(metadata.Erasure.Shards)
+	for i, shard := range rebuilt {
+		metadata.Erasure.Shards[i] = shard
+	}
+	if err := r.files.storeMetadata(ctx, metadata); err != nil {
+		return err
+	}
+	for i := range rebuilt {
+		if err := r.files.StorageClient.Shards.RemoveFrom(old[i].Node, old[i].CID); err != nil {
+			slog.Warn("Failed to release shard", "cid", old[i].CID, "node", old[i].Node, "error", err)
+		}
+	}
+	return nil
+}
+
+// rebuild は残りのシャードからmissingのシャードを再生成し、targetsのノードに1つずつ保存します。
+// 保存できたシャードをインデックスごとに返し、保存できなかったシャードのエラーを返します
+func (r *ShardRepairer) rebuild(metadata *domain.File, missing []int, targets []string) (map[int]domain.Shard, error) {
+	store := r.files.StorageClient.Shards
+	coding := metadata.Erasure
+
+	readers := make([]io.Reader, len(coding.Shards))
+	var opened []*shardReader
+	for i, shard := range coding.Shards {
+		if slices.Contains(missing, i) {
+			continue
+		}
+		reader := &shardReader{open: func() (io.ReadCloser, error) { return store.CatFrom(shard.Node, shard.CID) }}
+		readers[i] = reader
+		opened = append(opened, reader)
+	}
+	defer func() {
+		for _, reader := range opened {
+			reader.Close()
+		}
+	}()
+
+	writers := make([]io.Writer, len(coding.Shards))
+	pipes := make([]*io.PipeWriter, 0, len(missing))
+	var mu sync.Mutex
+	rebuilt := make(map[int]domain.Shard)
+	var errs []error
+	var wg sync.WaitGroup
+	for j, i := range missing {
+		node := targets[j]
+		reader, writer := io.Pipe()
+		writers[i] = writer
+		pipes = append(pipes, writer)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cid, err := store.AddTo(node, reader)
+			reader.CloseWithError(err)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+				return
+			}
+			if cid != coding.Shards[i].CID {
+				// 同じ内容からは同じCIDになるはずで、異なればシャードを使わない
+				store.RemoveFrom(node, cid)
+				errs = append(errs, fmt.Errorf("shard %d was rebuilt as %s instead of %s", i, cid, coding.Shards[i].CID))
+				return
+			}
+			rebuilt[i] = domain.Shard{CID: cid, Node: node}
+		}()
+	}
+
+	err := erasure.Rebuild(readers, writers, codingParams(coding), metadata.Size)
+	for _, writer := range pipes {
+		writer.CloseWithError(err)
+	}
+	wg.Wait()
+	if err != nil {
+		for _, shard := range rebuilt {
+			store.RemoveFrom(shard.Node, shard.CID)
+		}
+		return nil, err
+	}
+	return rebuilt, errors.Join(errs...)
+}
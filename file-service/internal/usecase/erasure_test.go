@@ -0,0 +1,93 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/erasure"
+	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/file-service/internal/ipfspool"
+	"decentralstore/file-service/internal/mocks"
+	"decentralstore/file-service/internal/quota"
+	"decentralstore/file-service/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newErasureFixture builds a FileUseCase that splits uploads into shards over a pool of fake IPFS nodes.
+func newErasureFixture(t *testing.T, params erasure.Params, names ...string) (usecase.FileUseCase, *infrastructure.StorageClient, map[string]*mocks.FakeIPFS) {
+	t.Helper()
+	redisClient := mocks.NewFakeRedisClient()
+	nodes := make(map[string]*mocks.FakeIPFS)
+	var poolNodes []ipfspool.Node
+	for _, name := range names {
+		nodes[name] = mocks.NewFakeIPFS()
+		poolNodes = append(poolNodes, ipfspool.Node{Name: name, Shell: nodes[name].Shell()})
+	}
+	pool := ipfspool.NewPool(poolNodes, redisClient, ipfspool.Options{HealthInterval: time.Hour, RepairInterval: time.Hour})
+	t.Cleanup(func() { pool.Close() })
+	storageClient := &infrastructure.StorageClient{IPFSShell: pool, RedisClient: redisClient, Shards: pool}
+	files := usecase.NewFileUseCase(storageClient, quota.NewTracker(redisClient, domain.Limits{}), nil, nil, &params)
+	return files, storageClient, nodes
+}
+
+func TestUploadFile_ErasureCodedSurvivesLostShard(t *testing.T) {
+	files, storageClient, nodes := newErasureFixture(t, erasure.Params{DataShards: 2, ParityShards: 1, BlockSize: 16},
+		"ipfs-1", "ipfs-2", "ipfs-3")
+	ctx := tenantContext("acme")
+	content := strings.Repeat("erasure-coded content ", 10)
+
+	uploadedFile, err := files.UploadFile(ctx, strings.NewReader(content), "shards.txt")
+	require.NoError(t, err)
+	require.True(t, uploadedFile.IsErasureCoded())
+	require.Len(t, uploadedFile.Erasure.Shards, 3)
+
+	lost := uploadedFile.Erasure.Shards[0]
+	delete(nodes[lost.Node].Blocks, lost.CID)
+	delete(nodes[lost.Node].Pinned, lost.CID)
+	reader, err := files.DownloadFile(ctx, uploadedFile.ID, uploadedFile.DownloadKeyword)
+	require.NoError(t, err)
+	assert.Equal(t, content, readAll(t, reader))
+
+	repairer := usecase.NewShardRepairer(storageClient, time.Hour)
+	t.Cleanup(func() { repairer.Close(context.Background()) })
+	repaired, err := repairer.Repair(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+
+	// With the shard rebuilt, the file survives the loss of another one.
+	listed, err := files.ListFiles(ctx)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	rebuilt := listed[0].Erasure.Shards[0]
+	assert.True(t, nodes[rebuilt.Node].IsPinned(rebuilt.CID))
+	other := listed[0].Erasure.Shards[1]
+	delete(nodes[other.Node].Blocks, other.CID)
+	reader, err = files.DownloadFile(ctx, uploadedFile.ID, uploadedFile.DownloadKeyword)
+	require.NoError(t, err)
+	assert.Equal(t, content, readAll(t, reader))
+
+	require.NoError(t, files.DeleteFile(ctx, uploadedFile.ID, uploadedFile.DeleteKeyword))
+	for name, node := range nodes {
+		assert.Empty(t, node.Pinned, "pins of %s", name)
+	}
+}
+
+func TestUploadFile_ErasureCodingNeedsEnoughNodes(t *testing.T) {
+	files, _, nodes := newErasureFixture(t, erasure.Params{DataShards: 2, ParityShards: 1, BlockSize: 16}, "ipfs-1", "ipfs-2")
+	ctx := tenantContext("acme")
+
+	_, err := files.UploadFile(ctx, strings.NewReader("erasure-coded content"), "shards.txt")
+
+	require.Error(t, err)
+	for name, node := range nodes {
+		assert.Empty(t, node.Pinned, "pins of %s", name)
+	}
+	listed, err := files.ListFiles(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+}
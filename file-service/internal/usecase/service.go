@@ -2,107 +2,611 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"time"
 
+	"decentralstore/file-service/internal/auth"
 	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/erasure"
 	"decentralstore/file-service/internal/infrastructure"
+	"decentralstore/file-service/internal/quota"
+	"decentralstore/shared/logging"
+	"decentralstore/shared/tracing"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type FileUseCase interface {
 	UploadFile(ctx context.Context, file io.Reader, filename string) (*domain.File, error)
 	DownloadFile(ctx context.Context, fileID string, keyword string) (io.ReadCloser, error)
 	DeleteFile(ctx context.Context, fileID string, keyword string) error
+	AuthorizeDownload(ctx context.Context, fileID string, keyword string) error
+	OpenFile(ctx context.Context, fileID string) (io.ReadCloser, error)
+	ListFiles(ctx context.Context) ([]*domain.File, error)
+	GetUsage(ctx context.Context) (*domain.UsageReport, error)
+	RecomputeUsage(ctx context.Context, tenantID string) (*domain.UsageReport, error)
+	UploadDirectory(ctx context.Context, name string, entries DirectoryReader) (*domain.File, error)
+	OpenEntry(ctx context.Context, fileID string, entryPath string) (io.ReadCloser, error)
+	OpenArchive(ctx context.Context, fileID string, format string) (io.ReadCloser, error)
+	OpenBundle(ctx context.Context, format string, items []domain.BundleItem) (io.ReadCloser, error)
+	ExportCAR(ctx context.Context, items []domain.BundleItem, version int) (io.ReadCloser, error)
+	ImportCAR(ctx context.Context, car io.Reader, name string) ([]*domain.File, error)
+	ClaimContent(ctx context.Context, ref domain.ContentRef, filename string) (*domain.File, error)
+}
+
+// EventPublisher はファイルのライフサイクルイベントをテナントに通知します（Webhookなど）
+type EventPublisher interface {
+	Publish(ctx context.Context, owner, eventType string, data any)
+}
+
+// PinReplicator はファイルのピンをリモートのピンニングサービスに複製し、その状態を記録します
+type PinReplicator interface {
+	Replicate(ctx context.Context, file *domain.File) error
+	Statuses(ctx context.Context, fileID string) ([]domain.RemotePin, error)
+	Forget(ctx context.Context, fileID string) error
 }
 
 type FileUseCaseImpl struct {
 	StorageClient *infrastructure.StorageClient
+	Quota         *quota.Tracker
+	// Events はnilの場合イベントを通知しません
+	Events EventPublisher
+	// Pins はnilの場合リモートにピンを複製しません
+	Pins PinReplicator
+	// Erasure はnil以外の場合、アップロードされたファイルをシャードに分割してStorageClient.Shardsに保存します
+	Erasure *erasure.Params
 }
 
-func NewFileUseCase(storageClient *infrastructure.StorageClient) FileUseCase {
-	return &FileUseCaseImpl{StorageClient: storageClient}
+func NewFileUseCase(storageClient *infrastructure.StorageClient, quotaTracker *quota.Tracker, events EventPublisher, pins PinReplicator, coding *erasure.Params) FileUseCase {
+	return &FileUseCaseImpl{StorageClient: storageClient, Quota: quotaTracker, Events: events, Pins: pins, Erasure: coding}
 }
 
 func (s *FileUseCaseImpl) UploadFile(ctx context.Context, file io.Reader, filename string) (*domain.File, error) {
-	// IPFSにファイルをアップロード
-	cid, err := s.StorageClient.IPFSShell.Add(file)
+	// アップロードにはテナントの認証が必要
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, &domain.ErrUnauthenticated{Operation: "upload"}
+	}
+
+	// クォータを確保し、ストリーミング中にサイズを計測する
+	reservation, err := s.Quota.Reserve(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// IPFSにファイルをアップロード。テナントが同じ内容をアップロード済みならそのCIDを使う。
+	// イレイジャーコーディングが有効ならシャードに分割して複数のノードに保存する。シャードは
+	// ファイルごとに修復されるため共有できず、重複は排除しない
+	var cid, digest string
+	var coding *domain.ErasureCoding
+	if s.Erasure != nil {
+		_, span := tracing.Start(ctx, "ipfs.add_shards")
+		coding, err = s.addShards(reservation.Wrap(file))
+		tracing.End(span, err)
+	} else {
+		cid, digest, err = s.addContent(ctx, tenantID, reservation.Wrap(file))
+	}
+	if err == nil {
+		err = reservation.Commit()
+	}
 	if err != nil {
+		rollbackCtx, cancel := rollbackContext(ctx)
+		defer cancel()
+		if cid != "" {
+			s.releaseClaim(rollbackCtx, cid)
+		}
+		cancelReservation(ctx, reservation)
+		if coding != nil {
+			s.releaseShards(rollbackCtx, coding)
+		}
+		if quotaErr := reservation.Err(); quotaErr != nil {
+			return nil, quotaErr
+		}
 		return nil, fmt.Errorf("failed to upload file to IPFS: %w", err)
 	}
 
 	// メタデータを作成
-	id := generateUniqueID()
-	downloadKeyword := generateKeyword()
-	deleteKeyword := generateKeyword()
 	uploadedFile := &domain.File{
-		ID:              id,
+		ID:              generateUniqueID(),
 		Name:            filename,
+		Size:            reservation.Bytes(),
 		CID:             cid,
 		UploadedAt:      time.Now(),
-		DownloadKeyword: downloadKeyword,
-		DeleteKeyword:   deleteKeyword,
+		DownloadKeyword: generateKeyword(),
+		DeleteKeyword:   generateKeyword(),
+		TenantID:        tenantID,
+		Erasure:         coding,
+		SHA256:          digest,
 	}
 
-	// Redisにメタデータを保存
-	err = s.storeMetadata(ctx, uploadedFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to store metadata: %w", err)
+	if err := s.index(ctx, uploadedFile); err != nil {
+		return nil, err
 	}
 
+	s.publish(ctx, domain.EventFileUploaded, uploadedFile)
 	return uploadedFile, nil
 }
 
+// index はアップロードされたファイルのメタデータを保存し、テナントの一覧に加えます。シャードに分割されて
+// いないファイルの内容は、呼び出し元がclaimContentで参照として数えておきます。
+// 失敗したときは書き込んだ記録を取り消し、ファイルのクォータと内容の参照を解放します
+func (s *FileUseCaseImpl) index(ctx context.Context, file *domain.File) error {
+	var undo []func(context.Context)
+	if err := s.writeIndex(ctx, file, &undo); err != nil {
+		rollbackCtx, cancel := rollbackContext(ctx)
+		defer cancel()
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i](rollbackCtx)
+		}
+		if file.TenantID != "" {
+			if releaseErr := s.Quota.Release(rollbackCtx, file.TenantID, file.Size); releaseErr != nil {
+				logging.FromContext(ctx).Warn("Failed to release quota of unindexed file", "file", file.ID, "error", releaseErr)
+			}
+		}
+		return err
+	}
+
+	// リモートへの複製はバックグラウンドで再試行されるため、失敗してもアップロードは成功させる
+	if s.Pins != nil && !file.IsErasureCoded() {
+		if err := s.Pins.Replicate(ctx, file); err != nil {
+			logging.FromContext(ctx).Warn("Failed to queue remote pins", "file", file.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// writeIndex はindexの記録を書き込み、取り消す処理をundoに積みます。途中で失敗しても
+// ファイルが一覧に現れず、内容の参照を正しく解放できるようにします
+func (s *FileUseCaseImpl) writeIndex(ctx context.Context, file *domain.File, undo *[]func(context.Context)) error {
+	redisClient := s.StorageClient.RedisClient
+
+	// シャードはノードごとに参照数が数えられ、修復の対象になる。リモートのピンニングサービスには複製しない
+	if file.IsErasureCoded() {
+		*undo = append(*undo, func(ctx context.Context) { s.releaseShards(ctx, file.Erasure) })
+	} else {
+		*undo = append(*undo, func(ctx context.Context) { s.releaseClaim(ctx, file.CID) })
+		if file.TenantID != "" && !file.IsDirectory() {
+			if err := s.indexContent(ctx, file); err != nil {
+				return err
+			}
+			*undo = append(*undo, func(ctx context.Context) { s.releaseContent(ctx, file) })
+		}
+	}
+
+	// Redisにメタデータを保存。所有者だけが書き込まれても取り消せるよう、先に取り消しを積む
+	*undo = append(*undo, func(ctx context.Context) { s.forgetMetadata(ctx, file) })
+	err := s.storeMetadata(ctx, file)
+	if err != nil {
+		return fmt.Errorf("failed to store metadata: %w", err)
+	}
+
+	// テナントのファイル一覧に追加
+	err = redisClient.SAdd(ctx, tenantFilesKey(file.TenantID), file.ID).Err()
+	if err != nil {
+		return fmt.Errorf("failed to index file for tenant: %w", err)
+	}
+	*undo = append(*undo, func(ctx context.Context) {
+		if err := redisClient.SRem(ctx, tenantFilesKey(file.TenantID), file.ID).Err(); err != nil {
+			logging.FromContext(ctx).Warn("Failed to remove unindexed file from tenant index", "file", file.ID, "error", err)
+		}
+	})
+
+	if file.IsErasureCoded() {
+		err = redisClient.SAdd(ctx, erasureFilesKey, file.ID).Err()
+		if err != nil {
+			return fmt.Errorf("failed to index erasure-coded file: %w", err)
+		}
+	}
+	return nil
+}
+
+// forgetMetadata は記録しかけたメタデータを削除します
+// rollbackTimeout は失敗したアップロードを取り消すのにかける時間の上限です
+const rollbackTimeout = 5 * time.Second
+
+// rollbackContext は失敗したアップロードを取り消すためのコンテキストを返します。クライアントが切断しても
+// 取り消しが中断されて参照やクォータが残らないよう、ctxのキャンセルは引き継ぎません
+func rollbackContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+}
+
+// cancelReservation は失敗したアップロードが確保したクォータを解放します
+func cancelReservation(ctx context.Context, reservation *quota.Reservation) {
+	if err := reservation.Cancel(); err != nil {
+		logging.FromContext(ctx).Warn("Failed to release quota of failed upload", "error", err)
+	}
+}
+
+func (s *FileUseCaseImpl) forgetMetadata(ctx context.Context, file *domain.File) {
+	if err := s.deleteMetadata(ctx, file); err != nil {
+		logging.FromContext(ctx).Warn("Failed to delete metadata of unindexed file", "file", file.ID, "error", err)
+	}
+}
+
 func (s *FileUseCaseImpl) DownloadFile(ctx context.Context, fileID string, keyword string) (io.ReadCloser, error) {
 	// Redisからメタデータを取得
-	metadata, err := s.getMetadata(ctx, fileID)
+	metadata, err := s.getOwnMetadata(ctx, fileID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get metadata: %w", err)
+		return nil, err
 	}
 
 	// キーワードを検証
 	if !validateKeyword(keyword, metadata.DownloadKeyword) {
-		return nil, fmt.Errorf("invalid download keyword")
+		return nil, &domain.ErrInvalidKeyword{Operation: "download"}
+	}
+
+	return s.catFile(ctx, metadata)
+}
+
+// AuthorizeDownload はダウンロードキーワードのみを検証します（署名付きURLの発行用）
+func (s *FileUseCaseImpl) AuthorizeDownload(ctx context.Context, fileID string, keyword string) error {
+	metadata, err := s.getOwnMetadata(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	if !validateKeyword(keyword, metadata.DownloadKeyword) {
+		return &domain.ErrInvalidKeyword{Operation: "download"}
+	}
+
+	return nil
+}
+
+// OpenFile は認可済みのリクエスト（署名付きURLなど）のためにキーワード検証なしでファイルを取得します
+func (s *FileUseCaseImpl) OpenFile(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	metadata, err := s.getMetadata(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.catFile(ctx, metadata)
+}
+
+func (s *FileUseCaseImpl) catFile(ctx context.Context, metadata *domain.File) (io.ReadCloser, error) {
+	// ディレクトリはパスを指定するかアーカイブとして取得する
+	if metadata.IsDirectory() {
+		return nil, &domain.ErrIsDirectory{ID: metadata.ID}
 	}
 
 	// IPFSからファイルを取得
-	reader, err := s.StorageClient.IPFSShell.Cat(metadata.CID)
+	_, span := tracing.Start(ctx, "ipfs.cat", attribute.String("ipfs.cid", metadata.CID))
+	reader, err := s.openContent(metadata)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file from IPFS: %w", err)
 	}
 
-	return reader, nil
+	s.publish(ctx, domain.EventFileDownloaded, metadata)
+
+	// シーク可能にしてRangeリクエストに対応する
+	open := func() (io.ReadCloser, error) { return s.openContent(metadata) }
+	return newIPFSContent(open, metadata.Size, reader), nil
+}
+
+// openContent はファイルの内容を先頭から開きます。シャードに分割されたファイルはシャードから復元します
+func (s *FileUseCaseImpl) openContent(metadata *domain.File) (io.ReadCloser, error) {
+	if metadata.IsErasureCoded() {
+		return s.openShards(metadata)
+	}
+	return s.StorageClient.IPFSShell.Cat(metadata.CID)
 }
 
 func (s *FileUseCaseImpl) DeleteFile(ctx context.Context, fileID string, keyword string) error {
 	// Redisからメタデータを取得
 	metadata, err := s.getMetadata(ctx, fileID)
 	if err != nil {
-		return fmt.Errorf("failed to get metadata: %w", err)
+		return err
+	}
+
+	// テナントに属するファイルは、そのテナントからのみ削除できる
+	if metadata.TenantID != "" {
+		tenantID, _ := auth.TenantFromContext(ctx)
+		if tenantID != metadata.TenantID {
+			return &domain.ErrNotFound{Resource: "file", ID: fileID}
+		}
 	}
 
 	// キーワードを検証
 	if !validateKeyword(keyword, metadata.DeleteKeyword) {
-		return fmt.Errorf("invalid delete keyword")
+		return &domain.ErrInvalidKeyword{Operation: "delete"}
 	}
 
 	// Redisからメタデータを削除
-	err = s.deleteMetadata(ctx, fileID)
+	err = s.deleteMetadata(ctx, metadata)
 	if err != nil {
 		return fmt.Errorf("failed to delete metadata: %w", err)
 	}
 
+	if metadata.TenantID != "" {
+		err = s.StorageClient.RedisClient.SRem(ctx, tenantFilesKey(metadata.TenantID), fileID).Err()
+		if err != nil {
+			return fmt.Errorf("failed to remove file from tenant index: %w", err)
+		}
+
+		err = s.Quota.Release(ctx, metadata.TenantID, metadata.Size)
+		if err != nil {
+			return err
+		}
+	}
+
+	// シャードはノードごとに、それ以外のファイルはCIDごとに参照数が数えられている
+	if metadata.IsErasureCoded() {
+		err = s.StorageClient.RedisClient.SRem(ctx, erasureFilesKey, fileID).Err()
+		if err != nil {
+			return fmt.Errorf("failed to remove file from erasure index: %w", err)
+		}
+		s.releaseShards(ctx, metadata.Erasure)
+	} else {
+		if metadata.TenantID != "" && !metadata.IsDirectory() {
+			s.releaseContent(ctx, metadata)
+		}
+		if err := s.unpinUnreferenced(ctx, metadata.CID); err != nil {
+			return err
+		}
+	}
+	if s.Pins != nil {
+		if err := s.Pins.Forget(ctx, fileID); err != nil {
+			logging.FromContext(ctx).Warn("Failed to remove remote pins", "file", fileID, "error", err)
+		}
+	}
+
+	s.publish(ctx, domain.EventFileDeleted, metadata)
+	return nil
+}
+
+// 同じ内容のファイルはピンを共有するため、CIDの参照数を数えます。参照数を確かめてからピンを外すまでの間に
+// 同じ内容が参照されないよう、参照数とピンはCIDのロックを取って変更します:
+//
+//	cid:refs        -> CIDからそれを参照するファイル数へのハッシュ
+//	cid:lock:<cid>  -> ロックを取ったリクエストのトークン。contentLockTTLで期限切れになる
+const (
+	cidRefsKey = "cid:refs"
+	// contentLockTTL はロックを取ったレプリカが止まった場合に、ロックが残る最長の時間です
+	contentLockTTL = 30 * time.Second
+	// contentLockRetry はロックが取られているときに再び試すまでの間隔です
+	contentLockRetry = 20 * time.Millisecond
+)
+
+func contentLockKey(cid string) string {
+	return "cid:lock:" + cid
+}
+
+// lockContent はCIDのロックを取り、解放する関数を返します。ロックが取られていれば、解放されるかctxが終わるまで待ちます
+func (s *FileUseCaseImpl) lockContent(ctx context.Context, cid string) (func(), error) {
+	redisClient := s.StorageClient.RedisClient
+	key := contentLockKey(cid)
+	token := generateUniqueID()
+	for {
+		locked, err := redisClient.SetNX(ctx, key, token, contentLockTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock content: %w", err)
+		}
+		if locked {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(contentLockRetry):
+		}
+	}
+
+	return func() {
+		// 期限が切れて他のリクエストが取り直したロックは外さない
+		ctx := context.WithoutCancel(ctx)
+		if held, err := redisClient.Get(ctx, key).Result(); err == nil && held == token {
+			redisClient.Del(ctx, key)
+		}
+	}, nil
+}
+
+// claimContent はCIDの参照を1つ数えてから内容をピンします。同じ内容の他のファイルが削除されても、
+// 数えた参照があるためピンは外されません。ピンできなければ数えた参照を戻します
+func (s *FileUseCaseImpl) claimContent(ctx context.Context, cid string) error {
+	unlock, err := s.lockContent(ctx, cid)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = s.StorageClient.RedisClient.HIncrBy(ctx, cidRefsKey, cid, 1).Err()
+	if err != nil {
+		return fmt.Errorf("failed to count CID reference: %w", err)
+	}
+	_, span := tracing.Start(ctx, "ipfs.pin", attribute.String("ipfs.cid", cid))
+	err = s.StorageClient.IPFSShell.Pin(cid)
+	tracing.End(span, err)
+	if err != nil {
+		if releaseErr := s.releaseLocked(ctx, cid); releaseErr != nil {
+			logging.FromContext(ctx).Warn("Failed to roll back CID reference", "cid", cid, "error", releaseErr)
+		}
+		return fmt.Errorf("failed to pin %s: %w", cid, err)
+	}
+	return nil
+}
+
+// releaseClaim はclaimContentで数えた参照を、ファイルを記録できなかったときに解放します
+func (s *FileUseCaseImpl) releaseClaim(ctx context.Context, cid string) {
+	if err := s.unpinUnreferenced(ctx, cid); err != nil {
+		logging.FromContext(ctx).Warn("Failed to release CID reference", "cid", cid, "error", err)
+	}
+}
+
+// unpinUnreferenced はCIDの参照を1つ解放し、他のファイルから参照されていなければIPFSのピンを外します
+func (s *FileUseCaseImpl) unpinUnreferenced(ctx context.Context, cid string) error {
+	unlock, err := s.lockContent(ctx, cid)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.releaseLocked(ctx, cid)
+}
+
+// releaseLocked はロックを取った状態で参照を1つ解放し、参照がなくなればピンを外します
+func (s *FileUseCaseImpl) releaseLocked(ctx context.Context, cid string) error {
+	refs, err := s.StorageClient.RedisClient.HIncrBy(ctx, cidRefsKey, cid, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to release CID reference: %w", err)
+	}
+	if refs <= 0 {
+		s.unpin(ctx, cid)
+	}
 	return nil
 }
 
+// unpinIfUnreferenced は参照を数えられなかった内容のピンを、他のファイルから参照されていなければ外します
+func (s *FileUseCaseImpl) unpinIfUnreferenced(ctx context.Context, cid string) {
+	unlock, err := s.lockContent(ctx, cid)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to lock content, leaving it pinned", "cid", cid, "error", err)
+		return
+	}
+	defer unlock()
+
+	refs, err := s.StorageClient.RedisClient.HGet(ctx, cidRefsKey, cid).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx).Warn("Failed to look up CID references, leaving content pinned", "cid", cid, "error", err)
+		return
+	}
+	if refs <= 0 {
+		s.unpin(ctx, cid)
+	}
+}
+
+func (s *FileUseCaseImpl) unpin(ctx context.Context, cid string) {
+	_, span := tracing.Start(ctx, "ipfs.unpin", attribute.String("ipfs.cid", cid))
+	err := s.StorageClient.IPFSShell.Unpin(cid)
+	tracing.End(span, err)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to unpin content", "cid", cid, "error", err)
+	}
+}
+
+// publish はファイルを所有するテナントにイベントを通知します。テナントのないファイルは通知しません
+func (s *FileUseCaseImpl) publish(ctx context.Context, eventType string, file *domain.File) {
+	if s.Events == nil || file.TenantID == "" {
+		return
+	}
+	s.Events.Publish(ctx, file.TenantID, eventType, domain.NewFileEvent(file))
+}
+
+// ListFiles は認証済みテナントのファイル一覧を返します
+func (s *FileUseCaseImpl) ListFiles(ctx context.Context) ([]*domain.File, error) {
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, &domain.ErrUnauthenticated{Operation: "list"}
+	}
+
+	files, err := s.listTenantFiles(ctx, tenantID)
+	if err != nil || s.Pins == nil {
+		return files, err
+	}
+
+	// リモートのピンの状態はメタデータとは別に記録されている
+	for _, file := range files {
+		file.RemotePins, err = s.Pins.Statuses(ctx, file.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// GetUsage は認証済みテナントの使用量とクォータを返します
+func (s *FileUseCaseImpl) GetUsage(ctx context.Context) (*domain.UsageReport, error) {
+	tenantID, ok := auth.TenantFromContext(ctx)
+	if !ok {
+		return nil, &domain.ErrUnauthenticated{Operation: "usage"}
+	}
+
+	usage, err := s.Quota.Usage(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := s.Quota.Limits(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UsageReport{TenantID: tenantID, Usage: usage, Limits: limits}, nil
+}
+
+// RecomputeUsage はメタデータストアからテナントの使用量を再計算します
+func (s *FileUseCaseImpl) RecomputeUsage(ctx context.Context, tenantID string) (*domain.UsageReport, error) {
+	files, err := s.listTenantFiles(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.Quota.Recompute(ctx, tenantID, files)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := s.Quota.Limits(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UsageReport{TenantID: tenantID, Usage: usage, Limits: limits}, nil
+}
+
+func (s *FileUseCaseImpl) listTenantFiles(ctx context.Context, tenantID string) ([]*domain.File, error) {
+	fileIDs, err := s.StorageClient.RedisClient.SMembers(ctx, tenantFilesKey(tenantID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant files: %w", err)
+	}
+
+	files := make([]*domain.File, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		metadata, err := s.getTenantMetadata(ctx, tenantID, fileID)
+		var notFound *domain.ErrNotFound
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, metadata)
+	}
+
+	return files, nil
+}
+
+// メタデータはテナントごとの名前空間に保存します。キーワードだけで認可されるダウンロードなど、
+// テナントの分からない参照のためにファイルIDから所有テナントを引けるようにします:
+//
+//	tenant:<id>:file:<fileID> -> メタデータ（JSON）
+//	file:owners               -> ファイルIDから所有テナントへのハッシュ
+//	file:<fileID>             -> テナントのないファイルと、名前空間に移す前のメタデータ
+const fileOwnersKey = "file:owners"
+
+func metadataKey(tenantID, fileID string) string {
+	if tenantID == "" {
+		return "file:" + fileID
+	}
+	return "tenant:" + tenantID + ":file:" + fileID
+}
+
 func (s *FileUseCaseImpl) storeMetadata(ctx context.Context, file *domain.File) error {
 	jsonData, err := json.Marshal(file)
 	if err != nil {
 		return fmt.Errorf("failed to marshal file metadata: %w", err)
 	}
 
-	err = s.StorageClient.RedisClient.Set(ctx, "file:"+file.ID, string(jsonData), 0).Err()
+	if file.TenantID != "" {
+		err = s.StorageClient.RedisClient.HSet(ctx, fileOwnersKey, file.ID, file.TenantID).Err()
+		if err != nil {
+			return fmt.Errorf("failed to record file owner in Redis: %w", err)
+		}
+	}
+	err = s.StorageClient.RedisClient.Set(ctx, metadataKey(file.TenantID, file.ID), string(jsonData), 0).Err()
 	if err != nil {
 		return fmt.Errorf("failed to store metadata in Redis: %w", err)
 	}
@@ -110,8 +614,62 @@ func (s *FileUseCaseImpl) storeMetadata(ctx context.Context, file *domain.File)
 	return nil
 }
 
+// getMetadata はファイルIDからメタデータを取得します。所有テナントが記録されていなければ
+// 名前空間の外のキーを読み、テナントのファイルであれば名前空間に移します
 func (s *FileUseCaseImpl) getMetadata(ctx context.Context, fileID string) (*domain.File, error) {
-	jsonData, err := s.StorageClient.RedisClient.Get(ctx, "file:"+fileID).Result()
+	tenantID, err := s.StorageClient.RedisClient.HGet(ctx, fileOwnersKey, fileID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get file owner from Redis: %w", err)
+	}
+	if tenantID != "" {
+		return s.readMetadata(ctx, tenantID, fileID)
+	}
+
+	file, err := s.readMetadata(ctx, "", fileID)
+	if err != nil || file.TenantID == "" {
+		return file, err
+	}
+	if err := s.storeMetadata(ctx, file); err != nil {
+		logging.FromContext(ctx).Warn("Failed to move metadata to its tenant", "file", fileID, "error", err)
+	} else if err := s.StorageClient.RedisClient.Del(ctx, metadataKey("", fileID)).Err(); err != nil {
+		logging.FromContext(ctx).Warn("Failed to remove moved metadata", "file", fileID, "error", err)
+	}
+	return file, nil
+}
+
+// getOwnMetadata はキーワードで操作するファイルのメタデータを、認証されたテナントの名前空間から取得します。
+// テナントのファイルはそのテナントのAPIキーでしか見つからないため、キーワードを推測されても他のテナントには
+// 届きません。APIキーのないリクエストではテナントのないファイルだけが見つかります
+func (s *FileUseCaseImpl) getOwnMetadata(ctx context.Context, fileID string) (*domain.File, error) {
+	if tenantID, ok := auth.TenantFromContext(ctx); ok {
+		return s.getTenantMetadata(ctx, tenantID, fileID)
+	}
+	file, err := s.getMetadata(ctx, fileID)
+	if err == nil && file.TenantID != "" {
+		return nil, &domain.ErrNotFound{Resource: "file", ID: fileID}
+	}
+	return file, err
+}
+
+// getTenantMetadata はテナントの名前空間からメタデータを取得します。他のテナントのファイルは見つかりません
+func (s *FileUseCaseImpl) getTenantMetadata(ctx context.Context, tenantID, fileID string) (*domain.File, error) {
+	file, err := s.readMetadata(ctx, tenantID, fileID)
+	var notFound *domain.ErrNotFound
+	if errors.As(err, &notFound) {
+		// 名前空間に移す前のメタデータ
+		file, err = s.getMetadata(ctx, fileID)
+		if err == nil && file.TenantID != tenantID {
+			return nil, &domain.ErrNotFound{Resource: "file", ID: fileID}
+		}
+	}
+	return file, err
+}
+
+func (s *FileUseCaseImpl) readMetadata(ctx context.Context, tenantID, fileID string) (*domain.File, error) {
+	jsonData, err := s.StorageClient.RedisClient.Get(ctx, metadataKey(tenantID, fileID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, &domain.ErrNotFound{Resource: "file", ID: fileID}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get metadata from Redis: %w", err)
 	}
@@ -125,22 +683,40 @@ func (s *FileUseCaseImpl) getMetadata(ctx context.Context, fileID string) (*doma
 	return &file, nil
 }
 
-func (s *FileUseCaseImpl) deleteMetadata(ctx context.Context, fileID string) error {
-	err := s.StorageClient.RedisClient.Del(ctx, "file:"+fileID).Err()
+func (s *FileUseCaseImpl) deleteMetadata(ctx context.Context, file *domain.File) error {
+	err := s.StorageClient.RedisClient.Del(ctx, metadataKey(file.TenantID, file.ID)).Err()
 	if err != nil {
 		return fmt.Errorf("failed to delete metadata from Redis: %w", err)
 	}
+	if file.TenantID != "" {
+		err = s.StorageClient.RedisClient.HDel(ctx, fileOwnersKey, file.ID).Err()
+		if err != nil {
+			return fmt.Errorf("failed to delete file owner from Redis: %w", err)
+		}
+	}
 
 	return nil
 }
 
+func tenantFilesKey(tenantID string) string {
+	return "tenant:" + tenantID + ":files"
+}
+
+// generateUniqueID はファイルIDやロックのトークンに使う、推測できない128ビットのIDを返します
 func generateUniqueID() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
+	return hex.EncodeToString(randomBytes(16))
 }
 
+// generateKeyword はダウンロードや削除に使う256ビットのキーワードを返します
 func generateKeyword() string {
-	// 実際の実装では、より安全なランダム文字列生成メソッドを使用してください
-	return fmt.Sprintf("key-%d", time.Now().UnixNano())
+	return "key-" + base64.RawURLEncoding.EncodeToString(randomBytes(32))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	// crypto/randのReadはエラーを返さない
+	rand.Read(b)
+	return b
 }
 
 func validateKeyword(provided, stored string) bool {
@@ -2,101 +2,183 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"decentralstore/file-service/internal/auth"
 	"decentralstore/file-service/internal/domain"
+	"decentralstore/file-service/internal/infrastructure"
 	"decentralstore/file-service/internal/mocks"
+	"decentralstore/file-service/internal/quota"
 	"decentralstore/file-service/internal/usecase"
-	"strings"
-	"testing"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-func TestFileUseCaseImpl_UploadFile(t *testing.T) {
-	mockStorage := new(mocks.MockStorageClient)
-	fileUseCase := usecase.NewFileUseCase(mockStorage)
+type testFixture struct {
+	files usecase.FileUseCase
+	ipfs  *mocks.FakeIPFS
+	redis infrastructure.RedisClient
+	quota *quota.Tracker
+}
 
-	ctx := context.Background()
-	fileContent := "test file content"
-	fileName := "test.txt"
-	// cid := "QmTest123"
+// newTestFixture builds a FileUseCase on the in-memory IPFS and Redis fakes.
+func newTestFixture(t *testing.T, limits domain.Limits) *testFixture {
+	t.Helper()
+	return newTestFixtureWithRedis(t, mocks.NewFakeRedisClient(), limits)
+}
 
-	mockStorage.On("StoreMetadata", mock.Anything, mock.AnythingOfType("*domain.File")).Return(nil)
+func newTestFixtureWithRedis(t *testing.T, redisClient infrastructure.RedisClient, limits domain.Limits) *testFixture {
+	t.Helper()
+	ipfs := mocks.NewFakeIPFS()
+	storageClient := &infrastructure.StorageClient{IPFSShell: ipfs.Shell(), RedisClient: redisClient}
+	tracker := quota.NewTracker(redisClient, limits)
+	return &testFixture{
+		files: usecase.NewFileUseCase(storageClient, tracker, nil, nil, nil),
+		ipfs:  ipfs,
+		redis: redisClient,
+		quota: tracker,
+	}
+}
 
-	uploadedFile, err := fileUseCase.UploadFile(ctx, strings.NewReader(fileContent), fileName)
+func (f *testFixture) usage(t *testing.T, tenantID string) domain.Usage {
+	t.Helper()
+	usage, err := f.quota.Usage(context.Background(), tenantID)
+	require.NoError(t, err)
+	return usage
+}
 
-	assert.NoError(t, err)
-	assert.NotNil(t, uploadedFile)
-	assert.Equal(t, fileName, uploadedFile.Name)
-	assert.NotEmpty(t, uploadedFile.CID)
-	mockStorage.AssertExpectations(t)
+func tenantContext(tenantID string) context.Context {
+	return auth.WithTenant(context.Background(), tenantID)
 }
 
-func TestFileUseCaseImpl_DownloadFile(t *testing.T) {
-	mockStorage := new(mocks.MockStorageClient)
-	fileUseCase := usecase.NewFileUseCase(mockStorage)
-
-	ctx := context.Background()
-	fileID := "123"
-	keyword := "test-keyword"
-	cid := "QmTest123"
-
-	mockFile := &domain.File{
-		ID:              fileID,
-		Name:            "test.txt",
-		CID:             cid,
-		DownloadKeyword: keyword,
-	}
+func readAll(t *testing.T, r io.ReadCloser) string {
+	t.Helper()
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
 
-	mockStorage.On("GetMetadata", ctx, fileID).Return(mockFile, nil)
+func TestFileUseCaseImpl_UploadFile(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+
+	uploadedFile, err := f.files.UploadFile(ctx, strings.NewReader("test file content"), "test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "test.txt", uploadedFile.Name)
+	assert.Equal(t, "acme", uploadedFile.TenantID)
+	assert.Equal(t, int64(len("test file content")), uploadedFile.Size)
+	assert.NotEmpty(t, uploadedFile.SHA256)
+	assert.True(t, f.ipfs.IsPinned(uploadedFile.CID))
+	assert.Equal(t, domain.Usage{Bytes: uploadedFile.Size, Files: 1}, f.usage(t, "acme"))
+
+	files, err := f.files.ListFiles(ctx)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, uploadedFile.ID, files[0].ID)
+}
 
-	reader, err := fileUseCase.DownloadFile(ctx, fileID, keyword)
+func TestFileUseCaseImpl_UploadFile_Unauthenticated(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
 
-	assert.NoError(t, err)
-	assert.NotNil(t, reader)
-	mockStorage.AssertExpectations(t)
-}
+	_, err := f.files.UploadFile(context.Background(), strings.NewReader("test file content"), "test.txt")
 
-func TestFileUseCaseImpl_DeleteFile(t *testing.T) {
-	mockStorage := new(mocks.MockStorageClient)
-	fileUseCase := usecase.NewFileUseCase(mockStorage)
+	var unauthenticated *domain.ErrUnauthenticated
+	assert.True(t, errors.As(err, &unauthenticated))
+	assert.Zero(t, f.ipfs.Adds)
+}
 
-	ctx := context.Background()
-	fileID := "123"
-	keyword := "test-keyword"
+func TestFileUseCaseImpl_UploadFile_OverQuota(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{MaxBytes: 4})
 
-	mockFile := &domain.File{
-		ID:            fileID,
-		DeleteKeyword: keyword,
-	}
+	_, err := f.files.UploadFile(tenantContext("acme"), strings.NewReader("test file content"), "test.txt")
 
-	mockStorage.On("GetMetadata", ctx, fileID).Return(mockFile, nil)
-	mockStorage.On("DeleteMetadata", ctx, fileID).Return(nil)
+	var quotaErr *domain.ErrQuotaExceeded
+	require.True(t, errors.As(err, &quotaErr))
+	assert.Empty(t, f.ipfs.Pinned)
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+}
 
-	err := fileUseCase.DeleteFile(ctx, fileID, keyword)
+// failingSAddRedis fails to add members to sets, so that indexing an upload fails after its metadata is stored.
+type failingSAddRedis struct {
+	*mocks.FakeRedisClient
+}
 
-	assert.NoError(t, err)
-	mockStorage.AssertExpectations(t)
+func (r failingSAddRedis) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	return redis.NewIntResult(0, errors.New("connection reset"))
 }
 
-func TestFileUseCaseImpl_DeleteFile_InvalidKeyword(t *testing.T) {
-	mockStorage := new(mocks.MockStorageClient)
-	fileUseCase := usecase.NewFileUseCase(mockStorage)
+func TestFileUseCaseImpl_UploadFile_RollsBackWhenIndexFails(t *testing.T) {
+	redisClient := failingSAddRedis{mocks.NewFakeRedisClient()}
+	f := newTestFixtureWithRedis(t, redisClient, domain.Limits{})
+	ctx := tenantContext("acme")
 
-	ctx := context.Background()
-	fileID := "123"
-	keyword := "wrong-keyword"
+	_, err := f.files.UploadFile(ctx, strings.NewReader("test file content"), "test.txt")
+	require.Error(t, err)
 
-	mockFile := &domain.File{
-		ID:            fileID,
-		DeleteKeyword: "correct-keyword",
+	assert.Empty(t, f.ipfs.Pinned)
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+	refs, err := redisClient.HGetAll(ctx, "cid:refs").Result()
+	require.NoError(t, err)
+	for cid, n := range refs {
+		assert.Equal(t, "0", n, "references of %s", cid)
 	}
+	content, err := redisClient.HGetAll(ctx, "tenant:acme:content").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "0", content["hashes"])
+}
+
+func TestFileUseCaseImpl_DownloadFile(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+	uploadedFile, err := f.files.UploadFile(ctx, strings.NewReader("test file content"), "test.txt")
+	require.NoError(t, err)
+
+	reader, err := f.files.DownloadFile(ctx, uploadedFile.ID, uploadedFile.DownloadKeyword)
+	require.NoError(t, err)
+	assert.Equal(t, "test file content", readAll(t, reader))
 
-	mockStorage.On("GetMetadata", ctx, fileID).Return(mockFile, nil)
+	_, err = f.files.DownloadFile(ctx, uploadedFile.ID, "wrong-keyword")
+	assert.IsType(t, &domain.ErrInvalidKeyword{}, err)
+
+	// Other tenants cannot tell the file exists, even with its keyword.
+	_, err = f.files.DownloadFile(tenantContext("globex"), uploadedFile.ID, uploadedFile.DownloadKeyword)
+	assert.IsType(t, &domain.ErrNotFound{}, err)
+}
 
-	err := fileUseCase.DeleteFile(ctx, fileID, keyword)
+func TestFileUseCaseImpl_DeleteFile(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+	uploadedFile, err := f.files.UploadFile(ctx, strings.NewReader("test file content"), "test.txt")
+	require.NoError(t, err)
+
+	err = f.files.DeleteFile(ctx, uploadedFile.ID, uploadedFile.DeleteKeyword)
+
+	require.NoError(t, err)
+	assert.False(t, f.ipfs.IsPinned(uploadedFile.CID))
+	assert.Equal(t, domain.Usage{}, f.usage(t, "acme"))
+	_, err = f.files.DownloadFile(ctx, uploadedFile.ID, uploadedFile.DownloadKeyword)
+	assert.IsType(t, &domain.ErrNotFound{}, err)
+}
+
+func TestFileUseCaseImpl_DeleteFile_InvalidKeyword(t *testing.T) {
+	f := newTestFixture(t, domain.Limits{})
+	ctx := tenantContext("acme")
+	uploadedFile, err := f.files.UploadFile(ctx, strings.NewReader("test file content"), "test.txt")
+	require.NoError(t, err)
+
+	err = f.files.DeleteFile(ctx, uploadedFile.ID, "wrong-keyword")
 
 	assert.Error(t, err)
 	assert.IsType(t, &domain.ErrInvalidKeyword{}, err)
-	mockStorage.AssertExpectations(t)
+	assert.True(t, f.ipfs.IsPinned(uploadedFile.CID))
+
+	err = f.files.DeleteFile(tenantContext("globex"), uploadedFile.ID, uploadedFile.DeleteKeyword)
+	assert.IsType(t, &domain.ErrNotFound{}, err)
+	assert.True(t, f.ipfs.IsPinned(uploadedFile.CID))
 }
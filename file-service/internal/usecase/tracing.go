@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"io"
+
+	"decentralstore/file-service/internal/domain"
+	"decentralstore/shared/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// WithTracing は各ユースケース呼び出しをスパンで囲むデコレーターを返します
+func WithTracing(next FileUseCase) FileUseCase {
+	return &tracedFileUseCase{next: next}
+}
+
+type tracedFileUseCase struct {
+	next FileUseCase
+}
+
+func (t *tracedFileUseCase) UploadFile(ctx context.Context, file io.Reader, filename string) (*domain.File, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.UploadFile")
+	uploaded, err := t.next.UploadFile(ctx, file, filename)
+	if uploaded != nil {
+		span.SetAttributes(attribute.String("file.id", uploaded.ID), attribute.Int64("file.size", uploaded.Size))
+	}
+	tracing.End(span, err)
+	return uploaded, err
+}
+
+func (t *tracedFileUseCase) DownloadFile(ctx context.Context, fileID string, keyword string) (io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.DownloadFile", attribute.String("file.id", fileID))
+	reader, err := t.next.DownloadFile(ctx, fileID, keyword)
+	tracing.End(span, err)
+	return reader, err
+}
+
+func (t *tracedFileUseCase) DeleteFile(ctx context.Context, fileID string, keyword string) error {
+	ctx, span := tracing.Start(ctx, "FileUseCase.DeleteFile", attribute.String("file.id", fileID))
+	err := t.next.DeleteFile(ctx, fileID, keyword)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedFileUseCase) AuthorizeDownload(ctx context.Context, fileID string, keyword string) error {
+	ctx, span := tracing.Start(ctx, "FileUseCase.AuthorizeDownload", attribute.String("file.id", fileID))
+	err := t.next.AuthorizeDownload(ctx, fileID, keyword)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedFileUseCase) OpenFile(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.OpenFile", attribute.String("file.id", fileID))
+	reader, err := t.next.OpenFile(ctx, fileID)
+	tracing.End(span, err)
+	return reader, err
+}
+
+func (t *tracedFileUseCase) ListFiles(ctx context.Context) ([]*domain.File, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.ListFiles")
+	files, err := t.next.ListFiles(ctx)
+	tracing.End(span, err)
+	return files, err
+}
+
+func (t *tracedFileUseCase) GetUsage(ctx context.Context) (*domain.UsageReport, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.GetUsage")
+	report, err := t.next.GetUsage(ctx)
+	tracing.End(span, err)
+	return report, err
+}
+
+func (t *tracedFileUseCase) RecomputeUsage(ctx context.Context, tenantID string) (*domain.UsageReport, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.RecomputeUsage", attribute.String("tenant.id", tenantID))
+	report, err := t.next.RecomputeUsage(ctx, tenantID)
+	tracing.End(span, err)
+	return report, err
+}
+
+func (t *tracedFileUseCase) UploadDirectory(ctx context.Context, name string, entries DirectoryReader) (*domain.File, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.UploadDirectory")
+	uploaded, err := t.next.UploadDirectory(ctx, name, entries)
+	if uploaded != nil {
+		span.SetAttributes(attribute.String("file.id", uploaded.ID), attribute.Int64("file.size", uploaded.Size),
+			attribute.Int("file.entries", len(uploaded.Entries)))
+	}
+	tracing.End(span, err)
+	return uploaded, err
+}
+
+func (t *tracedFileUseCase) OpenEntry(ctx context.Context, fileID string, entryPath string) (io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.OpenEntry", attribute.String("file.id", fileID))
+	reader, err := t.next.OpenEntry(ctx, fileID, entryPath)
+	tracing.End(span, err)
+	return reader, err
+}
+
+func (t *tracedFileUseCase) OpenArchive(ctx context.Context, fileID string, format string) (io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.OpenArchive", attribute.String("file.id", fileID))
+	reader, err := t.next.OpenArchive(ctx, fileID, format)
+	tracing.End(span, err)
+	return reader, err
+}
+
+func (t *tracedFileUseCase) OpenBundle(ctx context.Context, format string, items []domain.BundleItem) (io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.OpenBundle", attribute.Int("bundle.items", len(items)))
+	reader, err := t.next.OpenBundle(ctx, format, items)
+	tracing.End(span, err)
+	return reader, err
+}
+
+func (t *tracedFileUseCase) ExportCAR(ctx context.Context, items []domain.BundleItem, version int) (io.ReadCloser, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.ExportCAR", attribute.Int("car.files", len(items)), attribute.Int("car.version", version))
+	reader, err := t.next.ExportCAR(ctx, items, version)
+	tracing.End(span, err)
+	return reader, err
+}
+
+func (t *tracedFileUseCase) ImportCAR(ctx context.Context, car io.Reader, name string) ([]*domain.File, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.ImportCAR")
+	files, err := t.next.ImportCAR(ctx, car, name)
+	span.SetAttributes(attribute.Int("car.files", len(files)))
+	tracing.End(span, err)
+	return files, err
+}
+
+func (t *tracedFileUseCase) ClaimContent(ctx context.Context, ref domain.ContentRef, filename string) (*domain.File, error) {
+	ctx, span := tracing.Start(ctx, "FileUseCase.ClaimContent")
+	claimed, err := t.next.ClaimContent(ctx, ref, filename)
+	if claimed != nil {
+		span.SetAttributes(attribute.String("file.id", claimed.ID), attribute.Int64("file.size", claimed.Size))
+	}
+	tracing.End(span, err)
+	return claimed, err
+}
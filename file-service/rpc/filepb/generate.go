@@ -0,0 +1,4 @@
+// Package filepb is the gRPC API of file-service, generated from file.proto.
+package filepb
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative rpc/filepb/file.proto
@@ -0,0 +1,198 @@
+// Package configloader fills a service's config struct from a YAML file, the environment
+// and command-line flags, and compares and redacts such structs.
+//
+// Struct tags understood on leaf fields:
+//
+//	yaml:"name"     key in the config file; nested keys joined with "." form the flag name
+//	env:"NAME"      environment variable overriding the file
+//	secret:"true"   value is replaced by [REDACTED] when the config is printed
+//	reload:"true"   value may change on SIGHUP without a restart
+package configloader
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Change is a leaf setting that differs between two configs.
+type Change struct {
+	Path       string
+	Reloadable bool
+}
+
+type field struct {
+	path   string
+	tag    reflect.StructTag
+	value  reflect.Value
+	secret bool
+}
+
+// fields returns the leaf fields of the struct pointed to by ptr in declaration order.
+func fields(ptr any) []field {
+	var out []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(v.Field(i), path)
+				continue
+			}
+			out = append(out, field{path: path, tag: sf.Tag, value: v.Field(i), secret: sf.Tag.Get("secret") == "true"})
+		}
+	}
+	walk(reflect.ValueOf(ptr).Elem(), "")
+	return out
+}
+
+// Load fills cfg, which holds the defaults, from the YAML file, the environment and the
+// command-line flags, each overriding the previous source. The file is named by the
+// -config flag or, failing that, by configEnv.
+func Load(cfg any, args []string, configEnv string) error {
+	leaves := fields(cfg)
+
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configPath := fs.String("config", os.Getenv(configEnv), "path to a YAML config file")
+	flagValues := make(map[string]string)
+	for _, leaf := range leaves {
+		path := leaf.path
+		fs.Func(path, "", func(s string) error {
+			flagValues[path] = s
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to parse config file %s: %w", *configPath, err)
+		}
+	}
+
+	for _, leaf := range leaves {
+		name := leaf.tag.Get("env")
+		if name == "" {
+			continue
+		}
+		if s, ok := os.LookupEnv(name); ok && s != "" {
+			if err := setValue(leaf.value, s); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	for _, leaf := range leaves {
+		if s, ok := flagValues[leaf.path]; ok {
+			if err := setValue(leaf.value, s); err != nil {
+				return fmt.Errorf("-%s: %w", leaf.path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// RedactedYAML renders cfg as YAML with non-empty secrets masked.
+func RedactedYAML[T any](cfg *T) string {
+	masked := *cfg
+	for _, leaf := range fields(&masked) {
+		if leaf.secret && !leaf.value.IsZero() {
+			leaf.value.SetString(redacted)
+		}
+	}
+	out, err := yaml.Marshal(&masked)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+// Diff lists the settings that differ between old and next.
+func Diff[T any](old, next *T) []Change {
+	oldFields, nextFields := fields(old), fields(next)
+	var changes []Change
+	for i := range oldFields {
+		if !reflect.DeepEqual(oldFields[i].value.Interface(), nextFields[i].value.Interface()) {
+			changes = append(changes, Change{
+				Path:       oldFields[i].path,
+				Reloadable: oldFields[i].tag.Get("reload") == "true",
+			})
+		}
+	}
+	return changes
+}
+
+// MergeReloadable copies the reloadable settings of next into cfg.
+func MergeReloadable[T any](cfg, next *T) {
+	cfgFields, nextFields := fields(cfg), fields(next)
+	for i := range cfgFields {
+		if cfgFields[i].tag.Get("reload") == "true" {
+			cfgFields[i].value.Set(nextFields[i].value)
+		}
+	}
+}
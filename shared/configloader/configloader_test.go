@@ -0,0 +1,71 @@
+package configloader_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"decentralstore/shared/configloader"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Server struct {
+		Addr    string        `yaml:"addr" env:"TEST_ADDR"`
+		Timeout time.Duration `yaml:"timeout" env:"TEST_TIMEOUT"`
+	} `yaml:"server"`
+	Token   string `yaml:"token" env:"TEST_TOKEN" secret:"true"`
+	Verbose bool   `yaml:"verbose" reload:"true"`
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  addr: \":9000\"\n  timeout: 1s\n"), 0o600))
+	t.Setenv("TEST_CONFIG", path)
+	t.Setenv("TEST_TIMEOUT", "2s")
+
+	var cfg testConfig
+	cfg.Token = "default"
+	require.NoError(t, configloader.Load(&cfg, []string{"-verbose", "true"}, "TEST_CONFIG"))
+	assert.Equal(t, ":9000", cfg.Server.Addr)
+	assert.Equal(t, 2*time.Second, cfg.Server.Timeout, "env overrides the file")
+	assert.True(t, cfg.Verbose, "flags override the file")
+	assert.Equal(t, "default", cfg.Token, "unset sources keep the default")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	var cfg testConfig
+	assert.Error(t, configloader.Load(&cfg, []string{"-server.timeout", "soon"}, "TEST_CONFIG"))
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("unknown: 1\n"), 0o600))
+	assert.ErrorContains(t, configloader.Load(&cfg, []string{"-config", path}, "TEST_CONFIG"), "failed to parse config file")
+}
+
+func TestRedactedYAML(t *testing.T) {
+	var cfg testConfig
+	cfg.Token = "s3cret"
+
+	out := configloader.RedactedYAML(&cfg)
+	assert.NotContains(t, out, "s3cret")
+	assert.Contains(t, out, "token: '[REDACTED]'")
+	assert.Equal(t, "s3cret", cfg.Token, "the config itself is not changed")
+}
+
+func TestDiffAndMergeReloadable(t *testing.T) {
+	var cfg, next testConfig
+	next.Server.Addr = ":9000"
+	next.Verbose = true
+
+	assert.ElementsMatch(t, []configloader.Change{
+		{Path: "server.addr", Reloadable: false},
+		{Path: "verbose", Reloadable: true},
+	}, configloader.Diff(&cfg, &next))
+
+	configloader.MergeReloadable(&cfg, &next)
+	assert.True(t, cfg.Verbose)
+	assert.Empty(t, cfg.Server.Addr)
+}
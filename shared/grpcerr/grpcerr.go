@@ -0,0 +1,50 @@
+// Package grpcerr maps API errors to gRPC statuses, so that gRPC callers see the same
+// error codes as HTTP callers of the httperr envelope.
+package grpcerr
+
+import (
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain is the domain of the ErrorInfo detail attached by Error.
+const Domain = "decentralstore"
+
+// Error is the gRPC counterpart of httperr.Write: it returns a status whose code
+// corresponds to httpStatus and whose ErrorInfo detail carries code as its reason.
+func Error(httpStatus int, code, message string) error {
+	st := status.New(Code(httpStatus), message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: code, Domain: Domain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// Code returns the gRPC code of an HTTP status.
+func Code(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	if httpStatus >= 400 && httpStatus < 500 {
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
@@ -0,0 +1,34 @@
+package grpcerr_test
+
+import (
+	"net/http"
+	"testing"
+
+	"decentralstore/shared/grpcerr"
+	"decentralstore/shared/httperr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestError(t *testing.T) {
+	err := grpcerr.Error(http.StatusConflict, httperr.CodeConflict, "already exists")
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.Aborted, st.Code())
+	assert.Equal(t, "already exists", st.Message())
+	require.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.Equal(t, httperr.CodeConflict, info.Reason)
+	assert.Equal(t, grpcerr.Domain, info.Domain)
+}
+
+func TestCode(t *testing.T) {
+	assert.Equal(t, codes.Unauthenticated, grpcerr.Code(http.StatusUnauthorized))
+	assert.Equal(t, codes.ResourceExhausted, grpcerr.Code(http.StatusRequestEntityTooLarge))
+	assert.Equal(t, codes.InvalidArgument, grpcerr.Code(http.StatusUnprocessableEntity))
+	assert.Equal(t, codes.Internal, grpcerr.Code(http.StatusBadGateway))
+}
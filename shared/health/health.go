@@ -0,0 +1,123 @@
+// Package health serves liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"decentralstore/shared/httperr"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check probes one dependency. The returned detail is shown in the readiness
+// response, e.g. the version of the dependency.
+type Check func(ctx context.Context) (detail string, err error)
+
+// Result is the outcome of one Check.
+type Result struct {
+	Status     string  `json:"status"`
+	Detail     string  `json:"detail,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// Report is the readiness response body.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs named readiness checks concurrently, each bounded by timeout.
+type Checker struct {
+	timeout time.Duration
+	names   []string
+	checks  map[string]Check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: make(map[string]Check)}
+}
+
+// Add registers check under name.
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks[name] = check
+}
+
+// Run executes all checks and reports unavailable if any of them fails.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.names))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range c.names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := run(ctx, check, c.timeout)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}(name, c.checks[name])
+	}
+	wg.Wait()
+
+	return report
+}
+
+func run(ctx context.Context, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check(ctx)
+	result := Result{
+		Status:     StatusOK,
+		Detail:     detail,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Live answers liveness probes. It checks no dependencies so that an outage of one of
+// them does not get the process restarted.
+func Live(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": StatusOK})
+}
+
+// Ready answers readiness probes with 200 when every check passes and 503 otherwise.
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := c.Run(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,75 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"decentralstore/shared/health"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func passing(detail string) health.Check {
+	return func(ctx context.Context) (string, error) { return detail, nil }
+}
+
+func TestReady_AllChecksPass(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", passing("version 16"))
+	checker.Add("cache", passing(""))
+
+	rr := httptest.NewRecorder()
+	checker.Ready(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report health.Report
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Equal(t, "version 16", report.Checks["database"].Detail)
+	assert.Equal(t, health.StatusOK, report.Checks["cache"].Status)
+}
+
+func TestReady_FailingCheck(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", passing("version 16"))
+	checker.Add("cache", func(ctx context.Context) (string, error) {
+		return "", errors.New("connection refused")
+	})
+
+	rr := httptest.NewRecorder()
+	checker.Ready(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var report health.Report
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["cache"].Error)
+	assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+}
+
+func TestRun_BoundsChecksByTimeout(t *testing.T) {
+	checker := health.NewChecker(20 * time.Millisecond)
+	checker.Add("database", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	report := checker.Run(context.Background())
+
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Contains(t, report.Checks["database"].Error, "deadline exceeded")
+}
+
+func TestLive(t *testing.T) {
+	rr := httptest.NewRecorder()
+	health.Live(rr, httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+}
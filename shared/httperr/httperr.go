@@ -0,0 +1,117 @@
+// Package httperr writes API errors as a JSON envelope:
+//
+//	{"error": {"code": "not_found", "message": "file not found", "requestId": "..."}}
+//
+// Requests rejected by validation also list what was wrong in details:
+//
+//	{"error": {"code": "invalid_request", "message": "...", "details": [{"field": "query.id", "message": "is required"}]}}
+//
+// Code is stable and meant for programs; Message is for humans and may change.
+package httperr
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"decentralstore/shared/logging"
+)
+
+// Error codes returned by the APIs. Each service documents the codes it uses in its
+// OpenAPI document.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal"
+	CodeNotImplemented   = "not_implemented"
+	CodeUnavailable      = "unavailable"
+
+	// file-service
+	CodeInvalidKeyword = "invalid_keyword"
+	CodeQuotaExceeded  = "quota_exceeded"
+
+	// blockchain-service
+	CodeInvalidSignature = "invalid_signature"
+	CodeSignatureExpired = "signature_expired"
+	CodeNonceMismatch    = "nonce_mismatch"
+)
+
+// Envelope is the body of every error response.
+type Envelope struct {
+	Error Body `json:"error"`
+}
+
+type Body struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+	// Details lists the individual problems of a request that failed validation.
+	Details []Detail `json:"details,omitempty"`
+}
+
+// Detail is one problem with a request, e.g. a missing parameter.
+type Detail struct {
+	// Field locates the problem: "query.id", "header.Content-Type", "body.name" or "body".
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error replies with message and the generic code for status. It is a drop-in
+// replacement for http.Error.
+func Error(w http.ResponseWriter, r *http.Request, message string, status int) {
+	Write(w, r, status, CodeForStatus(status), message)
+}
+
+// Write replies with status and an envelope carrying code and message.
+func Write(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	WriteDetails(w, r, status, code, message, nil)
+}
+
+// WriteDetails is like Write and also lists details in the envelope.
+func WriteDetails(w http.ResponseWriter, r *http.Request, status int, code, message string, details []Detail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Envelope{Error: Body{
+		Code:      code,
+		Message:   message,
+		RequestID: logging.RequestIDFromContext(r.Context()),
+		Details:   details,
+	}})
+}
+
+// CodeForStatus returns the generic code of an HTTP status.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 400 && status < 500 {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
@@ -0,0 +1,45 @@
+package httperr_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/logging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/download", nil)
+	req = req.WithContext(logging.WithRequestID(req.Context(), "req-1"))
+	rr := httptest.NewRecorder()
+
+	httperr.Error(rr, req, "Missing file ID or keyword", http.StatusBadRequest)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var envelope httperr.Envelope
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&envelope))
+	assert.Equal(t, httperr.Body{Code: httperr.CodeBadRequest, Message: "Missing file ID or keyword", RequestID: "req-1"}, envelope.Error)
+}
+
+func TestWriteDetails(t *testing.T) {
+	rr := httptest.NewRecorder()
+	details := []httperr.Detail{{Field: "query.id", Message: "is required"}}
+
+	httperr.WriteDetails(rr, httptest.NewRequest("GET", "/download", nil), http.StatusBadRequest, httperr.CodeInvalidRequest, "Invalid request", details)
+
+	var envelope httperr.Envelope
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&envelope))
+	assert.Equal(t, httperr.Body{Code: httperr.CodeInvalidRequest, Message: "Invalid request", Details: details}, envelope.Error)
+}
+
+func TestCodeForStatus(t *testing.T) {
+	assert.Equal(t, httperr.CodeRateLimited, httperr.CodeForStatus(http.StatusTooManyRequests))
+	assert.Equal(t, httperr.CodeBadRequest, httperr.CodeForStatus(http.StatusUnprocessableEntity))
+	assert.Equal(t, httperr.CodeInternal, httperr.CodeForStatus(http.StatusBadGateway))
+}
@@ -0,0 +1,50 @@
+// Package httpretry decides when and after how long the service clients retry a request.
+package httpretry
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy controls how idempotent requests are retried after network errors
+// and 429, 502, 503 or 504 responses.
+type Policy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is doubled after every attempt, up to MaxDelay, with full jitter.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultPolicy makes up to three attempts, waiting at most 5 seconds between them.
+var DefaultPolicy = Policy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// RetryableStatus reports whether a response with status is worth retrying.
+func RetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Delay returns how long to wait before attempt (counted from 1) is retried. A
+// Retry-After header in resp takes precedence when it asks for a longer wait.
+func (p Policy) Delay(attempt int, resp *http.Response) time.Duration {
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	wait := time.Duration(rand.Int64N(int64(backoff) + 1))
+
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			if retryAfter := time.Duration(seconds) * time.Second; retryAfter > wait {
+				wait = min(retryAfter, p.MaxDelay)
+			}
+		}
+	}
+	return wait
+}
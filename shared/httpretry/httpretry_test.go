@@ -0,0 +1,29 @@
+package httpretry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	policy := Policy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		assert.LessOrEqual(t, policy.Delay(attempt, nil), min(100*time.Millisecond<<(attempt-1), time.Second))
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	assert.Equal(t, time.Second, policy.Delay(1, resp), "Retry-After is capped at MaxDelay")
+	resp.Header.Set("Retry-After", "0")
+	assert.LessOrEqual(t, policy.Delay(1, resp), 100*time.Millisecond)
+}
+
+func TestRetryableStatus(t *testing.T) {
+	assert.True(t, RetryableStatus(http.StatusTooManyRequests))
+	assert.True(t, RetryableStatus(http.StatusServiceUnavailable))
+	assert.False(t, RetryableStatus(http.StatusInternalServerError))
+	assert.False(t, RetryableStatus(http.StatusNotFound))
+}
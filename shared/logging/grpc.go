@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDMetadata is the gRPC metadata key of the request ID; metadata keys are lower case.
+var requestIDMetadata = strings.ToLower(RequestIDHeader)
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware for unary calls.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx = withGRPCRequestID(ctx)
+		resp, err := handler(ctx, req)
+		logGRPC(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the gRPC counterpart of Middleware for streaming calls.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := withGRPCRequestID(stream.Context())
+		err := handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+		logGRPC(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// withGRPCRequestID attaches the well-formed request ID of the call metadata, or a new one,
+// to ctx and sends it back in the response header.
+func withGRPCRequestID(ctx context.Context) context.Context {
+	var id string
+	if ids := metadata.ValueFromIncomingContext(ctx, requestIDMetadata); len(ids) > 0 {
+		id = ids[0]
+	}
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+	return WithRequestID(ctx, id)
+}
+
+// logGRPC writes one access log line per call, like Middleware does per request.
+func logGRPC(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	}
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	FromContext(ctx).LogAttrs(ctx, level, "request",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("remote_addr", remoteAddr),
+	)
+}
+
+// serverStream replaces the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
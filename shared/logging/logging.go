@@ -0,0 +1,114 @@
+// Package logging configures structured JSON logging with secret redaction and
+// per-request correlation IDs.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted replaces the value of sensitive attributes and query parameters.
+const Redacted = "[REDACTED]"
+
+// sensitiveSuffixes are matched against attribute keys and query parameter names after
+// lower-casing and removing "_" and "-", so "deleteKeyword", "private_key" and
+// "X-Api-Key" are all caught.
+var sensitiveSuffixes = []string{
+	"keyword", "signature", "sig", "privatekey", "secret", "password", "token", "apikey", "authorization",
+}
+
+// ParseLevel parses debug, info, warn or error. An empty string means info.
+func ParseLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return level, nil
+}
+
+// New returns a JSON logger writing to w that redacts sensitive attributes.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}))
+}
+
+// Setup installs a logger for w as the default for both slog and the standard log
+// package, and returns the level so that it can be changed at runtime.
+func Setup(w io.Writer, levelName string) (*slog.LevelVar, error) {
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		return nil, err
+	}
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(level)
+	slog.SetDefault(New(w, levelVar))
+	return levelVar, nil
+}
+
+// IsSensitive reports whether values named name must not be logged.
+func IsSensitive(name string) bool {
+	normalized := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(normalized, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// RedactURL returns u as a string with sensitive query parameters masked.
+func RedactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	query := u.Query()
+	for name := range query {
+		if IsSensitive(name) {
+			query[name] = []string{Redacted}
+		}
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger annotated with the request ID and trace ID of ctx.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := RequestIDFromContext(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		logger = logger.With("trace_id", spanContext.TraceID().String())
+	}
+	return logger
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_RedactsSensitiveAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	logger.Info("uploaded", "deleteKeyword", "key-123", "private_key", "0xabc", "X-Api-Key", "dsk_secret", "cid", "QmTest")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, Redacted, entry["deleteKeyword"])
+	assert.Equal(t, Redacted, entry["private_key"])
+	assert.Equal(t, Redacted, entry["X-Api-Key"])
+	assert.Equal(t, "QmTest", entry["cid"])
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("debug")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestRedactURL(t *testing.T) {
+	u, _ := url.Parse("/download?id=123&keyword=secret&sig=abc")
+	assert.Equal(t, "/download?id=123&keyword=%5BREDACTED%5D&sig=%5BREDACTED%5D", RedactURL(u))
+
+	u, _ = url.Parse("/relay/store?signature=0xdeadbeef")
+	assert.Equal(t, "/relay/store?signature=%5BREDACTED%5D", RedactURL(u))
+}
+
+func TestMiddleware_PropagatesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(New(&buf, slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	// 下流サービスはTransport経由で同じIDを受け取る
+	var downstreamID string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamID = r.Header.Get(RequestIDHeader)
+	}))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), "POST", downstream.URL, nil)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+	handler := Middleware(mux, mux)
+
+	req := httptest.NewRequest("POST", "/upload?keyword=secret", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "req-42", rr.Header().Get(RequestIDHeader))
+	assert.Equal(t, "req-42", downstreamID)
+	assert.Contains(t, buf.String(), `"request_id":"req-42"`)
+	assert.Contains(t, buf.String(), `"route":"/upload"`)
+	assert.False(t, strings.Contains(buf.String(), "secret"), "query keywords must be redacted")
+
+	req = httptest.NewRequest("POST", "/upload", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n{\"injected\":true}")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Len(t, rr.Header().Get(RequestIDHeader), 32, "malformed IDs are replaced")
+}
@@ -0,0 +1,271 @@
+// Package openapi serves an OpenAPI 3 description of an HTTP API and validates
+// requests and responses against it.
+//
+// The documents are written by hand. They may only use the subset of OpenAPI that
+// the validator understands: exact paths without templates, query and header
+// parameters, JSON, form, multipart and binary bodies, and schemas built from type, format,
+// enum, pattern, minimum, maximum, minLength, required, properties,
+// additionalProperties, items, nullable and $ref to components.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"decentralstore/shared/httperr"
+)
+
+// Document is a parsed OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	raw []byte
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get"`
+	Head   *Operation `json:"head"`
+	Post   *Operation `json:"post"`
+	Put    *Operation `json:"put"`
+	Delete *Operation `json:"delete"`
+}
+
+// Operation returns the operation for method, or nil if the path does not support it.
+func (p *PathItem) Operation(method string) *Operation {
+	switch method {
+	case http.MethodGet:
+		return p.Get
+	case http.MethodHead:
+		return p.Head
+	case http.MethodPost:
+		return p.Post
+	case http.MethodPut:
+		return p.Put
+	case http.MethodDelete:
+		return p.Delete
+	}
+	return nil
+}
+
+// Operations returns the operations of the path keyed by HTTP method.
+func (p *PathItem) Operations() map[string]*Operation {
+	ops := make(map[string]*Operation)
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete} {
+		if op := p.Operation(method); op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*MediaType `json:"content"`
+}
+
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+	Responses  map[string]*Response  `json:"responses"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *Additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+
+	pattern *regexp.Regexp
+}
+
+// Additional is the value of additionalProperties: false, true or a schema.
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// Parse parses an OpenAPI document and checks that every reference resolves
+// and every pattern compiles.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	doc.raw = data
+
+	for path, item := range doc.Paths {
+		for method, op := range item.Operations() {
+			where := method + " " + path
+			for i, param := range op.Parameters {
+				resolved, err := doc.parameter(param)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", where, err)
+				}
+				op.Parameters[i] = resolved
+				if err := doc.check(resolved.Schema); err != nil {
+					return nil, fmt.Errorf("%s: parameter %s: %w", where, resolved.Name, err)
+				}
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					if err := doc.check(media.Schema); err != nil {
+						return nil, fmt.Errorf("%s: request body: %w", where, err)
+					}
+				}
+			}
+			if len(op.Responses) == 0 {
+				return nil, fmt.Errorf("%s: no responses", where)
+			}
+			for status, response := range op.Responses {
+				resolved, err := doc.response(response)
+				if err != nil {
+					return nil, fmt.Errorf("%s: response %s: %w", where, status, err)
+				}
+				op.Responses[status] = resolved
+				for _, media := range resolved.Content {
+					if err := doc.check(media.Schema); err != nil {
+						return nil, fmt.Errorf("%s: response %s: %w", where, status, err)
+					}
+				}
+			}
+		}
+	}
+	for name, schema := range doc.Components.Schemas {
+		if err := doc.check(schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	return &doc, nil
+}
+
+// Operation returns the operation for method and path, if the document describes it.
+func (d *Document) Operation(method, path string) (*Operation, bool) {
+	item, ok := d.Paths[path]
+	if !ok {
+		return nil, false
+	}
+	op := item.Operation(method)
+	return op, op != nil
+}
+
+// ServeHTTP serves the document as JSON.
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		httperr.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d.raw)
+}
+
+const componentPrefix = "#/components/"
+
+func (d *Document) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, ok := strings.CutPrefix(p.Ref, componentPrefix+"parameters/")
+	if resolved := d.Components.Parameters[name]; ok && resolved != nil {
+		return resolved, nil
+	}
+	return nil, fmt.Errorf("unresolved reference %s", p.Ref)
+}
+
+func (d *Document) response(r *Response) (*Response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, ok := strings.CutPrefix(r.Ref, componentPrefix+"responses/")
+	if resolved := d.Components.Responses[name]; ok && resolved != nil {
+		return resolved, nil
+	}
+	return nil, fmt.Errorf("unresolved reference %s", r.Ref)
+}
+
+func (d *Document) schema(s *Schema) (*Schema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+	name, ok := strings.CutPrefix(s.Ref, componentPrefix+"schemas/")
+	if resolved := d.Components.Schemas[name]; ok && resolved != nil {
+		return resolved, nil
+	}
+	return nil, fmt.Errorf("unresolved reference %s", s.Ref)
+}
+
+// check resolves the references below s and compiles its patterns. Referenced
+// component schemas are checked on their own.
+func (d *Document) check(s *Schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		_, err := d.schema(s)
+		return err
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		s.pattern = re
+	}
+	for _, prop := range s.Properties {
+		if err := d.check(prop); err != nil {
+			return err
+		}
+	}
+	if s.AdditionalProperties != nil {
+		if err := d.check(s.AdditionalProperties.Schema); err != nil {
+			return err
+		}
+	}
+	return d.check(s.Items)
+}
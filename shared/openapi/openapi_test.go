@@ -0,0 +1,93 @@
+package openapi_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/openapi"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDocument = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/items": {
+      "put": {
+        "operationId": "putItem",
+        "parameters": [{"$ref": "#/components/parameters/ID"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}
+        },
+        "responses": {"204": {"description": "Stored"}}
+      }
+    },
+    "/blobs": {
+      "post": {
+        "operationId": "postBlob",
+        "requestBody": {"content": {"application/octet-stream": {}}},
+        "responses": {"204": {"description": "Stored"}}
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "ID": {"name": "id", "in": "query", "required": true, "schema": {"type": "string", "pattern": "^[a-z]+$"}}
+    },
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "required": ["count"],
+        "properties": {"count": {"type": "integer", "minimum": 0}}
+      }
+    }
+  }
+}`
+
+func TestParse_UnresolvedReference(t *testing.T) {
+	_, err := openapi.Parse([]byte(`{"paths": {"/x": {"get": {"responses": {"200": {"$ref": "#/components/responses/Missing"}}}}}}`))
+	assert.ErrorContains(t, err, "unresolved reference")
+}
+
+func TestParse_InvalidPattern(t *testing.T) {
+	_, err := openapi.Parse([]byte(strings.Replace(testDocument, "^[a-z]+$", "[", 1)))
+	assert.Error(t, err)
+}
+
+func TestValidateRequest(t *testing.T) {
+	doc, err := openapi.Parse([]byte(testDocument))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPut, "/items?id=A1", strings.NewReader(`{"count": -1}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.Equal(t, []httperr.Detail{
+		{Field: "query.id", Message: "must match ^[a-z]+$"},
+		{Field: "body.count", Message: "must be at least 0"},
+	}, doc.ValidateRequest(req))
+
+	req = httptest.NewRequest(http.MethodPut, "/items?id=abc", strings.NewReader(`{"count": 2}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.Empty(t, doc.ValidateRequest(req))
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"count": 2}`, string(body), "the validated body is restored")
+}
+
+func TestValidateRequest_StreamsBinaryBody(t *testing.T) {
+	doc, err := openapi.Parse([]byte(testDocument))
+	require.NoError(t, err)
+
+	blob := strings.Repeat("x", 2<<20)
+	req := httptest.NewRequest(http.MethodPost, "/blobs", strings.NewReader(blob))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	assert.Empty(t, doc.ValidateRequest(req))
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Len(t, body, len(blob))
+}
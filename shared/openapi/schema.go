@@ -0,0 +1,139 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"time"
+
+	"decentralstore/shared/httperr"
+)
+
+// validate checks v, as decoded by a json.Decoder with UseNumber, against s and
+// appends a detail for every violation. field names the location of v, e.g. "body.name".
+func (d *Document) validate(s *Schema, v any, field string, details *[]httperr.Detail) {
+	s, err := d.schema(s)
+	if err != nil {
+		*details = append(*details, httperr.Detail{Field: field, Message: err.Error()})
+		return
+	}
+	fail := func(format string, args ...any) {
+		*details = append(*details, httperr.Detail{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if v == nil {
+		if !s.Nullable && s.Type != "" {
+			fail("must be %s, not null", article(s.Type))
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		d.validateObject(s, obj, field, details)
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				d.validate(s.Items, item, field+"["+strconv.Itoa(i)+"]", details)
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if s.MinLength != nil && len([]rune(str)) < *s.MinLength {
+			fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			fail("must match %s", s.Pattern)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				fail("must be an RFC 3339 date-time")
+			}
+		}
+	case "integer", "number":
+		num, ok := v.(json.Number)
+		if !ok {
+			fail("must be %s", article(s.Type))
+			return
+		}
+		value, ok := new(big.Float).SetString(string(num))
+		if !ok || (s.Type == "integer" && !value.IsInt()) {
+			fail("must be %s", article(s.Type))
+			return
+		}
+		if s.Minimum != nil && value.Cmp(big.NewFloat(*s.Minimum)) < 0 {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && value.Cmp(big.NewFloat(*s.Maximum)) > 0 {
+			fail("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("must be a boolean")
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(v) }) {
+		fail("must be one of %v", s.Enum)
+	}
+}
+
+func (d *Document) validateObject(s *Schema, obj map[string]any, field string, details *[]httperr.Detail) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			*details = append(*details, httperr.Detail{Field: join(field, name), Message: "is required"})
+		}
+	}
+
+	// Sorted so that the details come out in a stable order.
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if prop, ok := s.Properties[name]; ok {
+			d.validate(prop, obj[name], join(field, name), details)
+			continue
+		}
+		switch extra := s.AdditionalProperties; {
+		case extra == nil || (extra.Allowed && extra.Schema == nil):
+		case extra.Schema != nil:
+			d.validate(extra.Schema, obj[name], join(field, name), details)
+		default:
+			*details = append(*details, httperr.Detail{Field: join(field, name), Message: "is not allowed"})
+		}
+	}
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func article(typ string) string {
+	switch typ {
+	case "integer", "object", "array":
+		return "an " + typ
+	}
+	return "a " + typ
+}
@@ -0,0 +1,239 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"decentralstore/shared/httperr"
+)
+
+// maxValidatedBody is the largest JSON or form body the validator reads. Multipart and
+// other binary bodies, such as CAR files, are streamed to the handler without being read.
+const maxValidatedBody = 1 << 20
+
+// Middleware rejects requests that do not match the document with a 400 whose
+// envelope lists every problem. Paths and methods the document does not describe
+// are passed through, so the mux and handlers still answer them with 404 and 405.
+func (d *Document) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if details := d.ValidateRequest(r); len(details) > 0 {
+			httperr.WriteDetails(w, r, http.StatusBadRequest, httperr.CodeInvalidRequest,
+				"Request does not match the API specification", details)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ValidateRequest checks the parameters and body of r against its operation and
+// returns a detail for every problem. A body it reads is put back for the handler.
+func (d *Document) ValidateRequest(r *http.Request) []httperr.Detail {
+	op, ok := d.Operation(r.Method, r.URL.Path)
+	if !ok {
+		return nil
+	}
+
+	var details []httperr.Detail
+	query := r.URL.Query()
+	for _, param := range op.Parameters {
+		var values []string
+		switch param.In {
+		case "query":
+			values = query[param.Name]
+		case "header":
+			values = r.Header.Values(param.Name)
+		}
+		field := param.In + "." + param.Name
+		if len(values) == 0 || (param.In == "query" && values[0] == "" && param.Required) {
+			if param.Required {
+				details = append(details, httperr.Detail{Field: field, Message: "is required"})
+			}
+			continue
+		}
+		d.validate(param.Schema, d.coerce(param.Schema, values[0]), field, &details)
+	}
+
+	if op.RequestBody != nil {
+		d.validateBody(r, op.RequestBody, &details)
+	}
+	return details
+}
+
+func (d *Document) validateBody(r *http.Request, body *RequestBody, details *[]httperr.Detail) {
+	fail := func(field, message string) {
+		*details = append(*details, httperr.Detail{Field: field, Message: message})
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil && len(body.Content) == 1 {
+		// Clients often leave out the content type when only one is accepted.
+		for only := range body.Content {
+			mediaType, err = only, nil
+		}
+	}
+	media, ok := body.Content[mediaType]
+	if err != nil || !ok {
+		fail("header.Content-Type", "must be one of "+strings.Join(mediaTypes(body.Content), ", "))
+		return
+	}
+
+	if mediaType == "multipart/form-data" {
+		if params["boundary"] == "" {
+			fail("header.Content-Type", "must have a boundary")
+		}
+		return
+	}
+	if !isJSON(mediaType) && mediaType != "application/x-www-form-urlencoded" {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	switch {
+	case err != nil:
+		fail("body", "could not be read")
+		return
+	case len(data) > maxValidatedBody:
+		fail("body", fmt.Sprintf("must not be larger than %d bytes", maxValidatedBody))
+		return
+	case len(bytes.TrimSpace(data)) == 0:
+		if body.Required {
+			fail("body", "is required")
+		}
+		return
+	}
+
+	var value any
+	switch {
+	case isJSON(mediaType):
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			fail("body", "is not valid JSON")
+			return
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			fail("body", "is not a valid form")
+			return
+		}
+		value = d.formValue(media.Schema, form)
+	}
+	if media.Schema != nil {
+		d.validate(media.Schema, value, "body", details)
+	}
+}
+
+// formValue converts form fields to the types of the matching schema properties.
+func (d *Document) formValue(s *Schema, form url.Values) map[string]any {
+	if s != nil {
+		s, _ = d.schema(s)
+	}
+	obj := make(map[string]any, len(form))
+	for name, values := range form {
+		var prop *Schema
+		if s != nil {
+			prop = s.Properties[name]
+		}
+		obj[name] = d.coerce(prop, values[0])
+	}
+	return obj
+}
+
+// coerce converts a query, header or form value to the JSON type s expects, so that
+// validate can check it. Values that do not convert are left as strings and fail validation.
+func (d *Document) coerce(s *Schema, value string) any {
+	if s == nil {
+		return value
+	}
+	s, err := d.schema(s)
+	if err != nil {
+		return value
+	}
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// ValidateResponse checks that status is documented for the operation and that the
+// body matches the documented content. It is meant for contract tests.
+func (d *Document) ValidateResponse(method, path string, status int, header http.Header, body []byte) error {
+	op, ok := d.Operation(method, path)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	response := op.Responses[strconv.Itoa(status)]
+	if response == nil {
+		response = op.Responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if response == nil {
+		response = op.Responses["default"]
+	}
+	if response == nil {
+		return fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+	}
+
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("%s %s: status %d is documented without a body but has %d bytes", method, path, status, len(body))
+		}
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	media, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s: status %d has content type %q, want one of %s", method, path, status,
+			mediaType, strings.Join(mediaTypes(response.Content), ", "))
+	}
+	if media.Schema == nil || !isJSON(mediaType) || method == http.MethodHead {
+		return nil
+	}
+
+	var value any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("%s %s: status %d: body is not valid JSON: %w", method, path, status, err)
+	}
+	var details []httperr.Detail
+	d.validate(media.Schema, value, "body", &details)
+	errs := make([]error, len(details))
+	for i, detail := range details {
+		errs[i] = fmt.Errorf("%s %s: status %d: %s %s", method, path, status, detail.Field, detail.Message)
+	}
+	return errors.Join(errs...)
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func mediaTypes(content map[string]*MediaType) []string {
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
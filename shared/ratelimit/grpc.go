@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"decentralstore/shared/grpcerr"
+	"decentralstore/shared/httperr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// RetryAfterMetadata is the gRPC header metadata key that says how many seconds a
+// rate-limited caller has to wait, like the Retry-After header of HTTP.
+const RetryAfterMetadata = "retry-after"
+
+// GRPCKeyFunc identifies the client a gRPC call is accounted to.
+type GRPCKeyFunc func(ctx context.Context) string
+
+// UnaryServerInterceptor is the gRPC counterpart of Wrap: calls of the methods in classes,
+// keyed by full method name, are limited like requests of their class. Other methods and
+// a nil Limiter pass every call through.
+func (l *Limiter) UnaryServerInterceptor(classes map[string]string, key GRPCKeyFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.allowGRPC(ctx, classes[info.FullMethod], key); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the gRPC counterpart of Wrap for streaming calls; see
+// UnaryServerInterceptor.
+func (l *Limiter) StreamServerInterceptor(classes map[string]string, key GRPCKeyFunc) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.allowGRPC(stream.Context(), classes[info.FullMethod], key); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func (l *Limiter) allowGRPC(ctx context.Context, class string, key GRPCKeyFunc) error {
+	if l == nil || class == "" {
+		return nil
+	}
+	allowed, retryAfter := l.allow(ctx, class, func() string { return key(ctx) })
+	if allowed {
+		return nil
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(RetryAfterMetadata, retryAfterSeconds(retryAfter)))
+	return grpcerr.Error(http.StatusTooManyRequests, httperr.CodeRateLimited, "Rate limit exceeded")
+}
+
+// PeerIP is the gRPC counterpart of ClientIP: it returns the IP of the connection peer.
+func PeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// ThrottleStreamInterceptor is the gRPC counterpart of ThrottleBody and ThrottleResponse:
+// streaming calls receive messages at no more than receiveBytesPerSecond and send them at
+// no more than sendBytesPerSecond, measured by their encoded size. A non-positive rate
+// disables throttling in that direction.
+func ThrottleStreamInterceptor(receiveBytesPerSecond, sendBytesPerSecond int64) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		throttled := &throttledStream{ServerStream: stream}
+		if receiveBytesPerSecond > 0 {
+			throttled.receive = newPacer(stream.Context(), receiveBytesPerSecond)
+		}
+		if sendBytesPerSecond > 0 {
+			throttled.send = newPacer(stream.Context(), sendBytesPerSecond)
+		}
+		return handler(srv, throttled)
+	}
+}
+
+type throttledStream struct {
+	grpc.ServerStream
+	receive *pacer
+	send    *pacer
+}
+
+func (t *throttledStream) RecvMsg(m any) error {
+	if err := t.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if t.receive == nil {
+		return nil
+	}
+	return t.receive.wait(messageSize(m))
+}
+
+func (t *throttledStream) SendMsg(m any) error {
+	if err := t.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	if t.send == nil {
+		return nil
+	}
+	return t.send.wait(messageSize(m))
+}
+
+func messageSize(m any) int {
+	if msg, ok := m.(proto.Message); ok {
+		return proto.Size(msg)
+	}
+	return 0
+}
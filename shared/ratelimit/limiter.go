@@ -0,0 +1,153 @@
+// Package ratelimit implements per-client token-bucket rate limiting and bandwidth throttling.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"decentralstore/shared/httperr"
+	"decentralstore/shared/logging"
+)
+
+// Request classes with separate budgets.
+const (
+	ClassUpload   = "upload"
+	ClassDownload = "download"
+	ClassRead     = "read"
+	// ClassWrite covers state-changing requests, such as metadata writes that cost the
+	// service account gas.
+	ClassWrite = "write"
+	// ClassAuth covers sign-in, which allocates nonces and sessions.
+	ClassAuth = "auth"
+)
+
+// Limit is a token bucket: Burst requests at once, refilled at Rate requests per second.
+// The zero Limit disables limiting.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// ParseLimit parses "<n>/<unit>" where unit is s, m or h, e.g. "60/m".
+// The burst equals n. An empty string or "off" disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+
+	count, unit, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", s)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit unit in %q", s)
+	}
+
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: n}, nil
+}
+
+// Store takes tokens from buckets identified by key.
+type Store interface {
+	// Take removes one token from the bucket. When the bucket is empty it returns
+	// false and how long the caller has to wait for the next token.
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// KeyFunc identifies the client a request is accounted to.
+type KeyFunc func(r *http.Request) string
+
+// Limiter applies a Limit per request class and client.
+type Limiter struct {
+	store Store
+	key   KeyFunc
+
+	mu     sync.RWMutex
+	limits map[string]Limit
+}
+
+func NewLimiter(store Store, limits map[string]Limit, key KeyFunc) *Limiter {
+	return &Limiter{store: store, limits: limits, key: key}
+}
+
+// SetLimits replaces the limits of every class; requests already in flight keep the old ones.
+func (l *Limiter) SetLimits(limits map[string]Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+}
+
+func (l *Limiter) limit(class string) Limit {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.limits[class]
+}
+
+// Wrap rejects requests of class with 429 once the client's bucket is empty.
+// A nil Limiter or a disabled class passes every request through. If the store
+// fails the request is let through, so a Redis outage does not take the API down.
+func (l *Limiter) Wrap(class string, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, retryAfter := l.allow(r.Context(), class, func() string { return l.key(r) })
+		if !allowed {
+			w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+			httperr.Error(w, r, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token of class from the bucket of the client that key returns. It reports
+// false and how long to wait when the bucket is empty, and true when class is disabled or
+// the store fails.
+func (l *Limiter) allow(ctx context.Context, class string, key func() string) (bool, time.Duration) {
+	limit := l.limit(class)
+	if !limit.enabled() {
+		return true, 0
+	}
+	allowed, retryAfter, err := l.store.Take(ctx, "ratelimit:"+class+":"+key(), limit)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to check rate limit", "error", err)
+		return true, 0
+	}
+	return allowed, retryAfter
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// ClientIP returns the IP of the connection peer. Forwarding headers are ignored
+// because clients can set them freely.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
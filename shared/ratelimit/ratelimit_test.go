@@ -0,0 +1,180 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("60/m")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Rate: 1, Burst: 60}, limit)
+
+	limit, err = ParseLimit("off")
+	require.NoError(t, err)
+	assert.False(t, limit.enabled())
+
+	for _, invalid := range []string{"60", "0/s", "x/m", "10/d"} {
+		_, err := ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Unix(0, 0)
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := store.Take(ctx, "k", limit)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, retryAfter, err := store.Take(ctx, "k", limit)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+
+	allowed, _, _ = store.Take(ctx, "other", limit)
+	assert.True(t, allowed, "buckets are per key")
+
+	now = now.Add(time.Second)
+	allowed, _, _ = store.Take(ctx, "k", limit)
+	assert.True(t, allowed, "a token is refilled after one second")
+}
+
+func TestLimiter_Wrap(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), map[string]Limit{ClassUpload: {Rate: 0.5, Burst: 1}}, ClientIP)
+	handler := limiter.Wrap(ClassUpload, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/upload", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/upload", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+
+	unlimited := limiter.Wrap(ClassDownload, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 5; i++ {
+		rr = httptest.NewRecorder()
+		unlimited.ServeHTTP(rr, httptest.NewRequest("GET", "/download", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+}
+
+func TestLimiter_WrapKeysByClient(t *testing.T) {
+	key := func(r *http.Request) string { return r.Header.Get("X-Client") }
+	limiter := NewLimiter(NewMemoryStore(), map[string]Limit{ClassAuth: {Rate: 1, Burst: 1}}, key)
+	handler := limiter.Wrap(ClassAuth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	send := func(client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/auth/nonce", nil)
+		req.Header.Set("X-Client", client)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, send("a").Code)
+	rr := send("a")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, send("b").Code)
+
+	var nilLimiter *Limiter
+	assert.NotNil(t, nilLimiter.Wrap(ClassAuth, handler))
+}
+
+func TestLimiter_SetLimits(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), nil, ClientIP)
+	handler := limiter.Wrap(ClassWrite, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/delete", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	limiter.SetLimits(map[string]Limit{ClassWrite: {Rate: 0.5, Burst: 1}})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/delete", nil))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/delete", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "new limits apply to handlers wrapped before the change")
+}
+
+func TestThrottleBody(t *testing.T) {
+	var received []byte
+	handler := ThrottleBody(20*1024, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+	}))
+
+	payload := bytes.Repeat([]byte("x"), 4*1024)
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/upload", bytes.NewReader(payload)))
+
+	assert.Equal(t, payload, received)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestThrottleResponse(t *testing.T) {
+	payload := strings.Repeat("x", 4*1024)
+	handler := ThrottleResponse(20*1024, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, payload)
+	}))
+
+	rr := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/download", nil))
+
+	assert.Equal(t, payload, rr.Body.String())
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+// sendStream is a grpc.ServerStream that discards what is sent.
+type sendStream struct {
+	grpc.ServerStream
+	sent int
+}
+
+func (s *sendStream) Context() context.Context { return context.Background() }
+
+func (s *sendStream) SendMsg(m any) error {
+	s.sent++
+	return nil
+}
+
+func TestThrottleStreamInterceptor(t *testing.T) {
+	interceptor := ThrottleStreamInterceptor(0, 20*1024)
+	chunk := wrapperspb.Bytes(bytes.Repeat([]byte("x"), 1024))
+	stream := &sendStream{}
+
+	start := time.Now()
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, func(srv any, stream grpc.ServerStream) error {
+		for i := 0; i < 4; i++ {
+			if err := stream.SendMsg(chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, stream.sent)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
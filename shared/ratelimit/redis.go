@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeScript refills and takes from a bucket atomically. It uses the Redis clock so
+// that replicas with skewed clocks share one consistent view of every bucket.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1]) / 1000
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate) + 1000)
+return {allowed, wait}
+`)
+
+// RedisStore keeps buckets in Redis so that all replicas share the same limits.
+type RedisStore struct {
+	client redis.Scripter
+}
+
+func NewRedisStore(client redis.Scripter) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	result, err := takeScript.Run(ctx, s.client, []string{key}, limit.Rate, limit.Burst).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// minChunk is the smallest unit a throttled stream is read or written in.
+const minChunk = 1024
+
+// ThrottleBody limits how fast each request body is read to bytesPerSecond.
+// A non-positive rate disables throttling.
+func ThrottleBody(bytesPerSecond int64, next http.Handler) http.Handler {
+	if bytesPerSecond <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = &throttledReader{
+			ReadCloser: r.Body,
+			pacer:      newPacer(r.Context(), bytesPerSecond),
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ThrottleResponse limits how fast each response body is written to bytesPerSecond.
+// A non-positive rate disables throttling.
+func ThrottleResponse(bytesPerSecond int64, next http.Handler) http.Handler {
+	if bytesPerSecond <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&throttledWriter{
+			ResponseWriter: w,
+			pacer:          newPacer(r.Context(), bytesPerSecond),
+		}, r)
+	})
+}
+
+// pacer delays a stream so that its average rate stays at bytesPerSecond.
+type pacer struct {
+	ctx            context.Context
+	bytesPerSecond int64
+	chunk          int
+	start          time.Time
+	transferred    int64
+}
+
+func newPacer(ctx context.Context, bytesPerSecond int64) *pacer {
+	chunk := int(bytesPerSecond / 10)
+	if chunk < minChunk {
+		chunk = minChunk
+	}
+	return &pacer{ctx: ctx, bytesPerSecond: bytesPerSecond, chunk: chunk, start: time.Now()}
+}
+
+func (p *pacer) wait(n int) error {
+	p.transferred += int64(n)
+	due := time.Duration(float64(p.transferred) / float64(p.bytesPerSecond) * float64(time.Second))
+	delay := due - time.Since(p.start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+type throttledReader struct {
+	io.ReadCloser
+	pacer *pacer
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > t.pacer.chunk {
+		p = p[:t.pacer.chunk]
+	}
+	n, err := t.ReadCloser.Read(p)
+	if waitErr := t.pacer.wait(n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+type throttledWriter struct {
+	http.ResponseWriter
+	pacer *pacer
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > t.pacer.chunk {
+			chunk = chunk[:t.pacer.chunk]
+		}
+		n, err := t.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if err := t.pacer.wait(n); err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
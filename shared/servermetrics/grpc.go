@@ -0,0 +1,36 @@
+package servermetrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor is the gRPC counterpart of Instrument for unary calls. The server
+// only runs interceptors for registered methods, so the method label stays bounded.
+func (m *Requests) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeGRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the gRPC counterpart of Instrument for streaming calls.
+func (m *Requests) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		m.observeGRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (m *Requests) observeGRPC(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	m.GRPCRequests.WithLabelValues(method, code).Inc()
+	m.GRPCDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,48 @@
+// Package servermetrics records the HTTP and gRPC requests served by a service and
+// exports them, with the other Prometheus metrics of the process, on /metrics.
+package servermetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Requests holds the request metrics of one service.
+type Requests struct {
+	HTTPRequests *prometheus.CounterVec
+	HTTPDuration *prometheus.HistogramVec
+	GRPCRequests *prometheus.CounterVec
+	GRPCDuration *prometheus.HistogramVec
+}
+
+// NewRequests registers the request metrics under namespace, e.g. file_service_http_requests_total.
+// Registering a namespace twice panics, so each service creates its Requests once.
+func NewRequests(namespace string) *Requests {
+	return &Requests{
+		HTTPRequests: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+
+		HTTPDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+
+		GRPCRequests: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "gRPC calls by full method name and status code.",
+		}, []string{"method", "code"}),
+
+		GRPCDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "gRPC call latency by full method name and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+	}
+}
@@ -0,0 +1,30 @@
+package servermetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	m := NewRequests("test")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "File not found", http.StatusNotFound)
+	})
+	handler := m.Instrument(mux, mux)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/files?id=123", nil))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/files", "GET", "404")))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/no/such/route", nil))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues("unmatched", "GET", "404")))
+
+	// Made-up methods share one label value.
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("X-RANDOM-1", "/no/such/route", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("X-RANDOM-2", "/no/such/route", nil))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues("unmatched", "other", "404")))
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useInMemoryExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestEnd_RecordsError(t *testing.T) {
+	exporter := useInMemoryExporter(t)
+
+	_, span := Start(context.Background(), "ipfs.add")
+	End(span, errors.New("connection refused"))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "ipfs.add", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestMiddleware_ContinuesCallerTrace(t *testing.T) {
+	exporter := useInMemoryExporter(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/store", func(w http.ResponseWriter, r *http.Request) {})
+	handler := Middleware(mux, mux)
+
+	req := httptest.NewRequest("POST", "/store", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "POST /store", spans[0].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+}
+
+func TestTransport_PropagatesTraceContext(t *testing.T) {
+	exporter := useInMemoryExporter(t)
+
+	// 呼び出し先（blockchain-service相当）のサーバー
+	downstreamMux := http.NewServeMux()
+	downstreamMux.HandleFunc("/store", func(w http.ResponseWriter, r *http.Request) {})
+	downstream := httptest.NewServer(Middleware(downstreamMux, downstreamMux))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	upstreamMux := http.NewServeMux()
+	upstreamMux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), "POST", downstream.URL+"/store", nil)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+	upstream := httptest.NewServer(Middleware(upstreamMux, upstreamMux))
+	defer upstream.Close()
+
+	resp, err := http.Post(upstream.URL+"/upload", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	names := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans.Snapshots() {
+		names[span.Name()] = span
+	}
+	require.Contains(t, names, "POST /upload")
+	require.Contains(t, names, "POST /store")
+	assert.Equal(t, names["POST /upload"].SpanContext().TraceID(), names["POST /store"].SpanContext().TraceID(),
+		"the downstream span must join the caller's trace")
+}
@@ -0,0 +1,490 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Options configures a Dispatcher. Zero values select the defaults.
+type Options struct {
+	// Client sends the deliveries; the default times out after 10 seconds and only
+	// connects to public addresses unless AllowPrivateAddresses is set.
+	Client *http.Client
+	// MaxAttempts is the number of attempts before a delivery fails (default 8).
+	MaxAttempts int
+	// BaseDelay and MaxDelay bound the exponential backoff between attempts
+	// (default 1s and 1h).
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Workers is the number of concurrent deliveries (default 4).
+	Workers int
+	// PollInterval is how often the queue is scanned for due deliveries (default 1s).
+	PollInterval time.Duration
+	// AllowPrivateAddresses lets subscriptions send to loopback, link-local and private
+	// addresses. Only enable it when every subscriber may reach the internal network.
+	AllowPrivateAddresses bool
+}
+
+func (o Options) withDefaults() Options {
+	if o.Client == nil {
+		if o.AllowPrivateAddresses {
+			o.Client = &http.Client{Timeout: 10 * time.Second}
+		} else {
+			o.Client = publicClient(10 * time.Second)
+		}
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = time.Second
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = time.Hour
+	}
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	return o
+}
+
+const (
+	// batchSize is the number of deliveries taken per scan of the queue.
+	batchSize = 100
+	// claimLease is how long a delivery taken by one worker is hidden from the others.
+	// If the process stops while sending it, it is attempted again afterwards.
+	claimLease = 5 * time.Minute
+)
+
+// Dispatcher manages subscriptions and sends events to them in the background.
+// Deliveries wait in the queue of the Store until their next attempt is due, so with
+// a shared store retries survive restarts and are sent by whichever replica is up.
+type Dispatcher struct {
+	store Store
+	opts  Options
+	queue chan string
+	wake  chan struct{}
+
+	// ctx is canceled when Close gives up waiting, to abort in-flight requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+	closed chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher starts the delivery workers. Call Close to stop them.
+func NewDispatcher(store Store, opts Options) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		store:  store,
+		opts:   opts.withDefaults(),
+		queue:  make(chan string),
+		wake:   make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+		closed: make(chan struct{}),
+	}
+	d.wg.Add(1)
+	go d.poll()
+	for i := 0; i < d.opts.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Subscribe registers url for the events of owner and returns the subscription with
+// its secret. An empty events list subscribes to every event. URLs on addresses that
+// are not public are rejected unless AllowPrivateAddresses is set.
+func (d *Dispatcher) Subscribe(ctx context.Context, owner, rawURL string, events []string) (*Subscription, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("%w: owner is required", ErrInvalidSubscription)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	if !d.opts.AllowPrivateAddresses {
+		if err := checkHost(ctx, u.Hostname()); err != nil {
+			return nil, fmt.Errorf("%w: url must point to a public address", ErrInvalidSubscription)
+		}
+	}
+	if len(events) == 0 {
+		events = []string{"*"}
+	}
+	for _, event := range events {
+		if event == "" {
+			return nil, fmt.Errorf("%w: event types must not be empty", ErrInvalidSubscription)
+		}
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	sub := &Subscription{
+		ID:        id,
+		Owner:     owner,
+		URL:       u.String(),
+		Events:    events,
+		Secret:    "whsec_" + secret,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := d.store.SaveSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Subscriptions lists the subscriptions of owner.
+func (d *Dispatcher) Subscriptions(ctx context.Context, owner string) ([]*Subscription, error) {
+	return d.store.Subscriptions(ctx, owner)
+}
+
+// Unsubscribe deletes a subscription of owner and its delivery log.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, owner, id string) error {
+	sub, err := d.subscription(ctx, owner, id)
+	if err != nil {
+		return err
+	}
+	return d.store.DeleteSubscription(ctx, sub)
+}
+
+// Deliveries returns the delivery log of a subscription of owner, newest first.
+func (d *Dispatcher) Deliveries(ctx context.Context, owner, subscriptionID string) ([]*Delivery, error) {
+	if _, err := d.subscription(ctx, owner, subscriptionID); err != nil {
+		return nil, err
+	}
+	return d.store.Deliveries(ctx, subscriptionID)
+}
+
+// Replay sends the event of a delivery again as a new delivery.
+func (d *Dispatcher) Replay(ctx context.Context, owner, deliveryID string) (*Delivery, error) {
+	original, err := d.store.Delivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.subscription(ctx, owner, original.SubscriptionID); err != nil {
+		return nil, err
+	}
+
+	delivery, err := d.newDelivery(ctx, original.SubscriptionID, original.EventID, original.EventType, original.Payload)
+	if err != nil {
+		return nil, err
+	}
+	delivery.ReplayOf = original.ID
+	if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	if err := d.enqueue(ctx, delivery.ID); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Publish sends an event to every subscription of owner that wants eventType.
+// Failures are logged rather than returned, so that events never fail the operation
+// that caused them.
+func (d *Dispatcher) Publish(ctx context.Context, owner, eventType string, data any) {
+	if owner == "" {
+		return
+	}
+	if err := d.publish(ctx, owner, eventType, data); err != nil {
+		slog.Warn("Failed to publish webhook event", "owner", owner, "event", eventType, "error", err)
+	}
+}
+
+func (d *Dispatcher) publish(ctx context.Context, owner, eventType string, data any) error {
+	subs, err := d.store.Subscriptions(ctx, owner)
+	if err != nil {
+		return err
+	}
+
+	var event *Event
+	var payload []byte
+	for _, sub := range subs {
+		if !sub.Matches(eventType) {
+			continue
+		}
+		if event == nil {
+			id, err := randomHex(16)
+			if err != nil {
+				return err
+			}
+			event = &Event{ID: id, Type: eventType, CreatedAt: time.Now().UTC(), Data: data}
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+
+		delivery, err := d.newDelivery(ctx, sub.ID, event.ID, eventType, payload)
+		if err != nil {
+			return err
+		}
+		if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+			return err
+		}
+		if err := d.enqueue(ctx, delivery.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops scanning the queue and waits for the workers to finish the deliveries
+// they took, aborting them when ctx ends. Deliveries that are not due yet stay queued
+// in the store.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.once.Do(func() { close(d.closed) })
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) subscription(ctx context.Context, owner, id string) (*Subscription, error) {
+	sub, err := d.store.Subscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return sub, nil
+}
+
+func (d *Dispatcher) newDelivery(ctx context.Context, subscriptionID, eventID, eventType string, payload []byte) (*Delivery, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return &Delivery{
+		ID:             id,
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Status:         StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Payload:        payload,
+	}, nil
+}
+
+// enqueue queues a delivery to be attempted now.
+func (d *Dispatcher) enqueue(ctx context.Context, id string) error {
+	if err := d.store.Schedule(ctx, id, time.Now()); err != nil {
+		return err
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// poll hands the due deliveries to the workers until Close is called.
+func (d *Dispatcher) poll() {
+	defer d.wg.Done()
+	defer close(d.queue)
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.scan()
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-d.closed:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) scan() {
+	ctx := d.ctx
+	ids, err := d.store.Due(ctx, time.Now(), batchSize)
+	if err != nil {
+		slog.Warn("Failed to scan webhook queue", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		// Claim the delivery so that other replicas skip it. A replica that lists it at
+		// the same time may still claim it too, so a delivery may be sent twice.
+		claimed, err := d.store.Unschedule(ctx, id)
+		if err != nil || !claimed {
+			continue
+		}
+		if err := d.store.Schedule(ctx, id, time.Now().Add(claimLease)); err != nil {
+			slog.Warn("Failed to claim webhook delivery", "delivery", id, "error", err)
+			continue
+		}
+
+		select {
+		case d.queue <- id:
+		case <-d.closed:
+			// Leave the delivery to the next process rather than to the lease.
+			d.reschedule(id, time.Now())
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for id := range d.queue {
+		d.reschedule(id, d.deliver(id))
+	}
+}
+
+// reschedule queues a claimed delivery for its next attempt, or drops it from the queue
+// when next is the zero time.
+func (d *Dispatcher) reschedule(id string, next time.Time) {
+	var err error
+	if next.IsZero() {
+		_, err = d.store.Unschedule(d.ctx, id)
+	} else {
+		err = d.store.Schedule(d.ctx, id, next)
+	}
+	if err != nil {
+		slog.Warn("Failed to schedule webhook delivery", "delivery", id, "error", err)
+	}
+}
+
+// deliver makes an attempt at a delivery and returns when to make the next one, or the
+// zero time if the delivery is done.
+func (d *Dispatcher) deliver(id string) time.Time {
+	ctx := d.ctx
+	delivery, err := d.store.Delivery(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return time.Time{}
+	}
+	if err != nil {
+		slog.Warn("Failed to load webhook delivery", "delivery", id, "error", err)
+		return time.Now().Add(d.opts.BaseDelay)
+	}
+	if delivery.Status != StatusPending {
+		return time.Time{}
+	}
+
+	// The delivery log goes away with the subscription.
+	sub, err := d.store.Subscription(ctx, delivery.SubscriptionID)
+	if errors.Is(err, ErrNotFound) {
+		return time.Time{}
+	}
+	if err != nil {
+		slog.Warn("Failed to load webhook subscription", "delivery", id, "error", err)
+		return time.Now().Add(d.opts.BaseDelay)
+	}
+
+	delivery.Attempts++
+	delivery.NextAttemptAt = nil
+	delivery.LastStatusCode, err = d.send(ctx, sub, delivery)
+
+	switch {
+	case err == nil:
+		delivery.Status = StatusSucceeded
+		delivery.LastError = ""
+	case delivery.Attempts >= d.opts.MaxAttempts:
+		delivery.Status = StatusFailed
+		delivery.LastError = err.Error()
+	default:
+		delay := d.backoff(delivery.Attempts)
+		next := time.Now().UTC().Add(delay)
+		delivery.NextAttemptAt = &next
+		delivery.LastError = err.Error()
+	}
+	delivery.UpdatedAt = time.Now().UTC()
+
+	if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+		slog.Warn("Failed to save webhook delivery", "delivery", id, "error", err)
+	}
+	if delivery.NextAttemptAt == nil {
+		return time.Time{}
+	}
+	return *delivery.NextAttemptAt
+}
+
+// send POSTs the delivery and returns the response status. Non-2xx responses are errors.
+func (d *Dispatcher) send(ctx context.Context, sub *Subscription, delivery *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "decentralstore-webhooks")
+	req.Header.Set(HeaderID, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, time.Now(), delivery.Payload))
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay after the given number of attempts: BaseDelay doubled per
+// attempt, capped at MaxDelay, with up to 20% random jitter so receivers coming back
+// up are not hit by every retry at once.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.MaxDelay
+	if shift := attempts - 1; shift < 32 {
+		if scaled := d.opts.BaseDelay << shift; scaled > 0 && scaled < delay {
+			delay = scaled
+		}
+	}
+	if jitter := int64(delay / 5); jitter > 0 {
+		n, err := rand.Int(rand.Reader, big.NewInt(jitter))
+		if err == nil {
+			delay += time.Duration(n.Int64())
+		}
+	}
+	return delay
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,211 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisClient is the subset of *redis.Client that RedisStore uses.
+type RedisClient interface {
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd
+	ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd
+	ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+}
+
+type storedSubscription struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+type storedDelivery struct {
+	Delivery
+	Payload []byte `json:"payload"`
+}
+
+// RedisStore keeps subscriptions, deliveries and the queue in Redis, so every replica
+// sends the events of every subscription and pending deliveries survive restarts.
+//
+//	webhook:sub:<id>             -> JSON subscription
+//	webhook:owner:<owner>        -> set of subscription IDs
+//	webhook:delivery:<id>        -> JSON delivery, expires after deliveryTTL
+//	webhook:sub:<id>:deliveries  -> set of delivery IDs
+//	webhook:queue                -> sorted set of delivery IDs, scored by their next attempt in Unix milliseconds
+type RedisStore struct {
+	redis RedisClient
+}
+
+func NewRedisStore(redisClient RedisClient) *RedisStore {
+	return &RedisStore{redis: redisClient}
+}
+
+func (s *RedisStore) SaveSubscription(ctx context.Context, sub *Subscription) error {
+	data, err := json.Marshal(storedSubscription{Subscription: *sub, Secret: sub.Secret})
+	if err != nil {
+		return err
+	}
+	if err := s.redis.Set(ctx, subscriptionKey(sub.ID), string(data), 0).Err(); err != nil {
+		return fmt.Errorf("failed to save webhook subscription: %w", err)
+	}
+	if err := s.redis.SAdd(ctx, ownerKey(sub.Owner), sub.ID).Err(); err != nil {
+		return fmt.Errorf("failed to index webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Subscription(ctx context.Context, id string) (*Subscription, error) {
+	data, err := s.redis.Get(ctx, subscriptionKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+
+	var stored storedSubscription
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook subscription: %w", err)
+	}
+	sub := stored.Subscription
+	sub.Secret = stored.Secret
+	return &sub, nil
+}
+
+func (s *RedisStore) Subscriptions(ctx context.Context, owner string) ([]*Subscription, error) {
+	ids, err := s.redis.SMembers(ctx, ownerKey(owner)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	subs := make([]*Subscription, 0, len(ids))
+	for _, id := range ids {
+		sub, err := s.Subscription(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	sortSubscriptions(subs)
+	return subs, nil
+}
+
+func (s *RedisStore) DeleteSubscription(ctx context.Context, sub *Subscription) error {
+	if err := s.redis.SRem(ctx, ownerKey(sub.Owner), sub.ID).Err(); err != nil {
+		return fmt.Errorf("failed to unindex webhook subscription: %w", err)
+	}
+	if err := s.redis.Del(ctx, subscriptionKey(sub.ID), deliveriesKey(sub.ID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) SaveDelivery(ctx context.Context, delivery *Delivery) error {
+	data, err := json.Marshal(storedDelivery{Delivery: *delivery, Payload: delivery.Payload})
+	if err != nil {
+		return err
+	}
+	if err := s.redis.Set(ctx, deliveryKey(delivery.ID), string(data), deliveryTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+	if err := s.redis.SAdd(ctx, deliveriesKey(delivery.SubscriptionID), delivery.ID).Err(); err != nil {
+		return fmt.Errorf("failed to index webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Delivery(ctx context.Context, id string) (*Delivery, error) {
+	data, err := s.redis.Get(ctx, deliveryKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	var stored storedDelivery
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook delivery: %w", err)
+	}
+	delivery := stored.Delivery
+	delivery.Payload = stored.Payload
+	return &delivery, nil
+}
+
+// Deliveries also drops the IDs of expired deliveries from the index.
+func (s *RedisStore) Deliveries(ctx context.Context, subscriptionID string) ([]*Delivery, error) {
+	ids, err := s.redis.SMembers(ctx, deliveriesKey(subscriptionID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	deliveries := make([]*Delivery, 0, len(ids))
+	for _, id := range ids {
+		delivery, err := s.Delivery(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			s.redis.SRem(ctx, deliveriesKey(subscriptionID), id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+func (s *RedisStore) Schedule(ctx context.Context, deliveryID string, at time.Time) error {
+	if err := s.redis.ZAdd(ctx, queueKey, &redis.Z{Score: float64(at.UnixMilli()), Member: deliveryID}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Due(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	due := &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(now.UnixMilli(), 10), Count: int64(limit)}
+	ids, err := s.redis.ZRangeByScore(ctx, queueKey, due).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook queue: %w", err)
+	}
+	return ids, nil
+}
+
+func (s *RedisStore) Unschedule(ctx context.Context, deliveryID string) (bool, error) {
+	removed, err := s.redis.ZRem(ctx, queueKey, deliveryID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to unschedule webhook delivery: %w", err)
+	}
+	return removed > 0, nil
+}
+
+const queueKey = "webhook:queue"
+
+func subscriptionKey(id string) string {
+	return "webhook:sub:" + id
+}
+
+func ownerKey(owner string) string {
+	return "webhook:owner:" + owner
+}
+
+func deliveryKey(id string) string {
+	return "webhook:delivery:" + id
+}
+
+func deliveriesKey(subscriptionID string) string {
+	return "webhook:sub:" + subscriptionID + ":deliveries"
+}
@@ -0,0 +1,163 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeRedis is an in-memory webhook.RedisClient.
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	sets    map[string]map[string]struct{}
+	zsets   map[string]map[string]float64
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		strings: make(map[string]string),
+		sets:    make(map[string]map[string]struct{}),
+		zsets:   make(map[string]map[string]float64),
+	}
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.strings[key] = fmt.Sprint(value)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.strings[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (f *fakeRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := f.strings[key]; ok {
+			delete(f.strings, key)
+			deleted++
+		}
+		if _, ok := f.sets[key]; ok {
+			delete(f.sets, key)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func (f *fakeRedis) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	set, ok := f.sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		f.sets[key] = set
+	}
+	var added int64
+	for _, m := range members {
+		member := fmt.Sprint(m)
+		if _, exists := set[member]; !exists {
+			set[member] = struct{}{}
+			added++
+		}
+	}
+	return redis.NewIntResult(added, nil)
+}
+
+func (f *fakeRedis) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var removed int64
+	for _, m := range members {
+		member := fmt.Sprint(m)
+		if _, exists := f.sets[key][member]; exists {
+			delete(f.sets[key], member)
+			removed++
+		}
+	}
+	return redis.NewIntResult(removed, nil)
+}
+
+func (f *fakeRedis) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	members := make([]string, 0, len(f.sets[key]))
+	for member := range f.sets[key] {
+		members = append(members, member)
+	}
+	return redis.NewStringSliceResult(members, nil)
+}
+
+func (f *fakeRedis) ZAdd(ctx context.Context, key string, members ...*redis.Z) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zset, ok := f.zsets[key]
+	if !ok {
+		zset = make(map[string]float64)
+		f.zsets[key] = zset
+	}
+	var added int64
+	for _, z := range members {
+		member := fmt.Sprint(z.Member)
+		if _, exists := zset[member]; !exists {
+			added++
+		}
+		zset[member] = z.Score
+	}
+	return redis.NewIntResult(added, nil)
+}
+
+// ZRangeByScore only supports a Min of "-inf" and a numeric Max.
+func (f *fakeRedis) ZRangeByScore(ctx context.Context, key string, opt *redis.ZRangeBy) *redis.StringSliceCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	max, err := strconv.ParseFloat(opt.Max, 64)
+	if err != nil {
+		return redis.NewStringSliceResult(nil, err)
+	}
+
+	zset := f.zsets[key]
+	var members []string
+	for member, score := range zset {
+		if score <= max {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return zset[members[i]] < zset[members[j]]
+	})
+	if opt.Count > 0 && int64(len(members)) > opt.Count {
+		members = members[:opt.Count]
+	}
+	return redis.NewStringSliceResult(members, nil)
+}
+
+func (f *fakeRedis) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var removed int64
+	for _, m := range members {
+		member := fmt.Sprint(m)
+		if _, exists := f.zsets[key][member]; exists {
+			delete(f.zsets[key], member)
+			removed++
+		}
+	}
+	return redis.NewIntResult(removed, nil)
+}
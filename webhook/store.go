@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// deliveryTTL is how long deliveries stay in the log.
+const deliveryTTL = 7 * 24 * time.Hour
+
+// Store persists subscriptions and the delivery log.
+type Store interface {
+	SaveSubscription(ctx context.Context, sub *Subscription) error
+	// Subscription returns ErrNotFound for unknown IDs.
+	Subscription(ctx context.Context, id string) (*Subscription, error)
+	Subscriptions(ctx context.Context, owner string) ([]*Subscription, error)
+	DeleteSubscription(ctx context.Context, sub *Subscription) error
+
+	SaveDelivery(ctx context.Context, delivery *Delivery) error
+	// Delivery returns ErrNotFound for unknown or expired IDs.
+	Delivery(ctx context.Context, id string) (*Delivery, error)
+	// Deliveries returns the logged deliveries of a subscription, newest first.
+	Deliveries(ctx context.Context, subscriptionID string) ([]*Delivery, error)
+
+	// Schedule queues a delivery to be attempted at the given time, replacing the time
+	// it was queued for before.
+	Schedule(ctx context.Context, deliveryID string, at time.Time) error
+	// Due returns up to limit queued deliveries whose time has come, earliest first.
+	Due(ctx context.Context, now time.Time, limit int) ([]string, error)
+	// Unschedule removes a delivery from the queue. It reports false if the delivery
+	// was not queued, e.g. because another worker took it first.
+	Unschedule(ctx context.Context, deliveryID string) (bool, error)
+}
+
+// MemoryStore keeps subscriptions, deliveries and the queue in process memory. Expired
+// deliveries are dropped whenever a delivery is saved.
+type MemoryStore struct {
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+	deliveries    map[string]Delivery
+	queue         map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		subscriptions: make(map[string]Subscription),
+		deliveries:    make(map[string]Delivery),
+		queue:         make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) SaveSubscription(ctx context.Context, sub *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[sub.ID] = *sub
+	return nil
+}
+
+func (s *MemoryStore) Subscription(ctx context.Context, id string) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &sub, nil
+}
+
+func (s *MemoryStore) Subscriptions(ctx context.Context, owner string) ([]*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var subs []*Subscription
+	for _, sub := range s.subscriptions {
+		if sub.Owner == owner {
+			sub := sub
+			subs = append(subs, &sub)
+		}
+	}
+	sortSubscriptions(subs)
+	return subs, nil
+}
+
+func (s *MemoryStore) DeleteSubscription(ctx context.Context, sub *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, sub.ID)
+	for id, delivery := range s.deliveries {
+		if delivery.SubscriptionID == sub.ID {
+			delete(s.deliveries, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) SaveDelivery(ctx context.Context, delivery *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, logged := range s.deliveries {
+		if time.Since(logged.CreatedAt) > deliveryTTL {
+			delete(s.deliveries, id)
+		}
+	}
+	s.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (s *MemoryStore) Delivery(ctx context.Context, id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &delivery, nil
+}
+
+func (s *MemoryStore) Deliveries(ctx context.Context, subscriptionID string) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deliveries []*Delivery
+	for _, delivery := range s.deliveries {
+		if delivery.SubscriptionID == subscriptionID {
+			delivery := delivery
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+func (s *MemoryStore) Schedule(ctx context.Context, deliveryID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue[deliveryID] = at
+	return nil
+}
+
+func (s *MemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []string
+	for id, at := range s.queue {
+		if !at.After(now) {
+			due = append(due, id)
+		}
+	}
+	slices.SortFunc(due, func(a, b string) int {
+		return s.queue[a].Compare(s.queue[b])
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (s *MemoryStore) Unschedule(ctx context.Context, deliveryID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.queue[deliveryID]
+	delete(s.queue, deliveryID)
+	return ok, nil
+}
+
+func sortSubscriptions(subs []*Subscription) {
+	slices.SortFunc(subs, func(a, b *Subscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+// sortDeliveries orders deliveries newest first.
+func sortDeliveries(deliveries []*Delivery) {
+	slices.SortFunc(deliveries, func(a, b *Delivery) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+}
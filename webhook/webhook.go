@@ -0,0 +1,139 @@
+// Package webhook notifies subscribers of lifecycle events over HTTP.
+//
+// Every delivery is a POST of the event as JSON, signed with the subscription secret:
+//
+//	X-Webhook-Id:        the delivery ID, stable across retries
+//	X-Webhook-Event:     the event type, e.g. "file.uploaded" or "metadata.confirmed"
+//	X-Webhook-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+//
+// Receivers should check the signature with Verify and reject old timestamps.
+// Failed deliveries are retried with exponential backoff, and every delivery is kept
+// in a log from which it can be replayed.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request headers of a delivery.
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Delivery states.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+var (
+	// ErrNotFound is returned for subscriptions and deliveries that do not exist or
+	// belong to another owner.
+	ErrNotFound = errors.New("webhook not found")
+	// ErrInvalidSubscription is returned when a subscription has no valid URL or events.
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+	// ErrInvalidSignature is returned by Verify.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Subscription sends the events of one owner to a URL.
+type Subscription struct {
+	ID string `json:"id"`
+	// Owner identifies whose events are delivered, such as a tenant or a wallet address.
+	Owner string `json:"owner"`
+	URL   string `json:"url"`
+	// Events lists the event types to deliver; "*" matches every type.
+	Events []string `json:"events"`
+	// Secret signs the deliveries. It is only shown when the subscription is created.
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Matches reports whether the subscription wants events of eventType.
+func (s *Subscription) Matches(eventType string) bool {
+	for _, e := range s.Events {
+		if e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the body of a delivery.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// Delivery is one event sent to one subscription, including its retries.
+type Delivery struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscriptionId"`
+	EventID        string `json:"eventId"`
+	EventType      string `json:"eventType"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	// LastStatusCode and LastError describe the most recent failed attempt.
+	LastStatusCode int        `json:"lastStatusCode,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	// ReplayOf is the ID of the delivery this one replays.
+	ReplayOf  string    `json:"replayOf,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Payload is the signed request body.
+	Payload []byte `json:"-"`
+}
+
+// Sign returns the X-Webhook-Signature header value for body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + signature(secret, t, body)
+}
+
+// Verify checks an X-Webhook-Signature header value against body and rejects
+// signatures older than tolerance.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t, v1 string
+	for _, field := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			v1 = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || v1 == "" {
+		return ErrInvalidSignature
+	}
+	if now.Sub(time.Unix(seconds, 0)) > tolerance {
+		return fmt.Errorf("%w: timestamp too old", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(v1), []byte(signature(secret, t, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
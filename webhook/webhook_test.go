@@ -0,0 +1,343 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"decentralstore/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver records the requests it gets and fails the first failures of them.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+	got      chan struct{}
+}
+
+func newReceiver(t *testing.T, failures int) (*receiver, *httptest.Server) {
+	rec := &receiver{failures: failures, got: make(chan struct{}, 16)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, r)
+		rec.bodies = append(rec.bodies, body)
+		fail := len(rec.requests) <= rec.failures
+		rec.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		rec.got <- struct{}{}
+	}))
+	t.Cleanup(server.Close)
+	return rec, server
+}
+
+func (rec *receiver) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-rec.got:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d requests", i, n)
+		}
+	}
+}
+
+func newDispatcher(t *testing.T, store webhook.Store) *webhook.Dispatcher {
+	d := webhook.NewDispatcher(store, webhook.Options{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, Workers: 1, PollInterval: 10 * time.Millisecond, AllowPrivateAddresses: true})
+	t.Cleanup(func() { d.Close(context.Background()) })
+	return d
+}
+
+// waitStatus polls the delivery log until the newest delivery has the status.
+func waitStatus(t *testing.T, d *webhook.Dispatcher, owner, subID, status string) *webhook.Delivery {
+	t.Helper()
+	var deliveries []*webhook.Delivery
+	require.Eventually(t, func() bool {
+		var err error
+		deliveries, err = d.Deliveries(context.Background(), owner, subID)
+		require.NoError(t, err)
+		return len(deliveries) > 0 && deliveries[0].Status == status
+	}, 5*time.Second, 10*time.Millisecond)
+	return deliveries[0]
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"file.uploaded"}`)
+	header := webhook.Sign("whsec_test", now, body)
+
+	assert.NoError(t, webhook.Verify("whsec_test", header, body, time.Minute, now.Add(30*time.Second)))
+	assert.ErrorIs(t, webhook.Verify("whsec_other", header, body, time.Minute, now), webhook.ErrInvalidSignature)
+	assert.ErrorIs(t, webhook.Verify("whsec_test", header, []byte("{}"), time.Minute, now), webhook.ErrInvalidSignature)
+	assert.ErrorIs(t, webhook.Verify("whsec_test", header, body, time.Minute, now.Add(2*time.Minute)), webhook.ErrInvalidSignature)
+	assert.ErrorIs(t, webhook.Verify("whsec_test", "garbage", body, time.Minute, now), webhook.ErrInvalidSignature)
+}
+
+func TestDispatcher_Subscribe_Invalid(t *testing.T) {
+	d := newDispatcher(t, webhook.NewMemoryStore())
+	ctx := context.Background()
+
+	for _, rawURL := range []string{"", "ftp://example.com", "/relative", "http://"} {
+		_, err := d.Subscribe(ctx, "acme", rawURL, nil)
+		assert.ErrorIs(t, err, webhook.ErrInvalidSubscription, rawURL)
+	}
+	_, err := d.Subscribe(ctx, "acme", "https://example.com/hook", []string{""})
+	assert.ErrorIs(t, err, webhook.ErrInvalidSubscription)
+}
+
+func TestDispatcher_Subscribe_PrivateAddress(t *testing.T) {
+	d := webhook.NewDispatcher(webhook.NewMemoryStore(), webhook.Options{})
+	t.Cleanup(func() { d.Close(context.Background()) })
+	ctx := context.Background()
+
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://100.64.0.1/hook",
+		"http://198.18.0.1/hook",
+		"http://[fd00::1]/hook",
+	} {
+		_, err := d.Subscribe(ctx, "acme", rawURL, nil)
+		assert.ErrorIs(t, err, webhook.ErrInvalidSubscription, rawURL)
+	}
+	_, err := d.Subscribe(ctx, "acme", "https://93.184.215.14/hook", nil)
+	assert.NoError(t, err)
+}
+
+func TestDispatcher_RefusesToDialPrivateAddress(t *testing.T) {
+	rec, server := newReceiver(t, 0)
+	store := webhook.NewMemoryStore()
+	d := webhook.NewDispatcher(store, webhook.Options{MaxAttempts: 1, Workers: 1})
+	t.Cleanup(func() { d.Close(context.Background()) })
+	ctx := context.Background()
+
+	// The hosts resolved to public addresses when they were subscribed and no longer do.
+	urls := []string{
+		server.URL,
+		"http://0.1.2.3/hook",
+		"http://100.64.0.1/hook",
+		"http://192.0.0.8/hook",
+		"http://198.18.0.1/hook",
+		"http://240.0.0.1/hook",
+		"http://[64:ff9b::a00:1]/hook",
+		"http://[fd00::1]/hook",
+	}
+	for i, rawURL := range urls {
+		sub := &webhook.Subscription{ID: fmt.Sprint("s", i), Owner: "acme", URL: rawURL, Events: []string{"*"}, Secret: "whsec_x"}
+		require.NoError(t, store.SaveSubscription(ctx, sub))
+	}
+	d.Publish(ctx, "acme", "file.uploaded", nil)
+
+	for i, rawURL := range urls {
+		failed := waitStatus(t, d, "acme", fmt.Sprint("s", i), webhook.StatusFailed)
+		assert.Contains(t, failed.LastError, "not public", rawURL)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Empty(t, rec.requests)
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	rec, server := newReceiver(t, 0)
+	d := newDispatcher(t, webhook.NewMemoryStore())
+	ctx := context.Background()
+
+	sub, err := d.Subscribe(ctx, "acme", server.URL, []string{"file.uploaded"})
+	require.NoError(t, err)
+	assert.Contains(t, sub.Secret, "whsec_")
+
+	d.Publish(ctx, "acme", "file.deleted", nil)
+	d.Publish(ctx, "other", "file.uploaded", nil)
+	d.Publish(ctx, "acme", "file.uploaded", map[string]string{"id": "42"})
+	rec.wait(t, 1)
+
+	delivery := waitStatus(t, d, "acme", sub.ID, webhook.StatusSucceeded)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.LastStatusCode)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	require.Len(t, rec.requests, 1, "only matching events of the owner are delivered")
+	req, body := rec.requests[0], rec.bodies[0]
+	assert.Equal(t, delivery.ID, req.Header.Get(webhook.HeaderID))
+	assert.Equal(t, "file.uploaded", req.Header.Get(webhook.HeaderEvent))
+	assert.NoError(t, webhook.Verify(sub.Secret, req.Header.Get(webhook.HeaderSignature), body, time.Minute, time.Now()))
+
+	var event webhook.Event
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "file.uploaded", event.Type)
+	assert.Equal(t, map[string]any{"id": "42"}, event.Data)
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	rec, server := newReceiver(t, 2)
+	d := newDispatcher(t, webhook.NewMemoryStore())
+	ctx := context.Background()
+
+	sub, err := d.Subscribe(ctx, "acme", server.URL, nil)
+	require.NoError(t, err)
+
+	d.Publish(ctx, "acme", "file.uploaded", nil)
+	rec.wait(t, 3)
+
+	delivery := waitStatus(t, d, "acme", sub.ID, webhook.StatusSucceeded)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	ids := map[string]bool{}
+	for _, req := range rec.requests {
+		ids[req.Header.Get(webhook.HeaderID)] = true
+	}
+	assert.Len(t, ids, 1, "retries reuse the delivery ID")
+}
+
+func TestDispatcher_RetriesSurviveRestart(t *testing.T) {
+	rec, server := newReceiver(t, 1)
+	store := webhook.NewMemoryStore()
+	first := webhook.NewDispatcher(store, webhook.Options{BaseDelay: time.Second, Workers: 1, AllowPrivateAddresses: true})
+	ctx := context.Background()
+
+	sub, err := first.Subscribe(ctx, "acme", server.URL, nil)
+	require.NoError(t, err)
+	first.Publish(ctx, "acme", "file.uploaded", nil)
+	rec.wait(t, 1)
+	require.NoError(t, first.Close(ctx))
+
+	second := newDispatcher(t, store)
+	rec.wait(t, 1)
+	delivery := waitStatus(t, second, "acme", sub.ID, webhook.StatusSucceeded)
+	assert.Equal(t, 2, delivery.Attempts)
+}
+
+func TestDispatcher_GivesUpAndReplays(t *testing.T) {
+	rec, server := newReceiver(t, 3)
+	d := newDispatcher(t, webhook.NewMemoryStore())
+	ctx := context.Background()
+
+	sub, err := d.Subscribe(ctx, "acme", server.URL, nil)
+	require.NoError(t, err)
+
+	d.Publish(ctx, "acme", "file.deleted", nil)
+	rec.wait(t, 3)
+	failed := waitStatus(t, d, "acme", sub.ID, webhook.StatusFailed)
+	assert.Equal(t, 3, failed.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, failed.LastStatusCode)
+	assert.NotEmpty(t, failed.LastError)
+
+	_, err = d.Replay(ctx, "other", failed.ID)
+	assert.ErrorIs(t, err, webhook.ErrNotFound, "deliveries of other owners are hidden")
+
+	replay, err := d.Replay(ctx, "acme", failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, failed.ID, replay.ReplayOf)
+	assert.Equal(t, failed.EventID, replay.EventID)
+	rec.wait(t, 1)
+
+	delivered := waitStatus(t, d, "acme", sub.ID, webhook.StatusSucceeded)
+	assert.Equal(t, replay.ID, delivered.ID)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Equal(t, rec.bodies[0], rec.bodies[3], "a replay sends the original event")
+}
+
+func TestDispatcher_Unsubscribe(t *testing.T) {
+	d := newDispatcher(t, webhook.NewMemoryStore())
+	ctx := context.Background()
+
+	sub, err := d.Subscribe(ctx, "acme", "https://example.com/hook", nil)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, d.Unsubscribe(ctx, "other", sub.ID), webhook.ErrNotFound)
+	require.NoError(t, d.Unsubscribe(ctx, "acme", sub.ID))
+
+	subs, err := d.Subscriptions(ctx, "acme")
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+	_, err = d.Deliveries(ctx, "acme", sub.ID)
+	assert.ErrorIs(t, err, webhook.ErrNotFound)
+}
+
+func TestRedisStore(t *testing.T) {
+	store := webhook.NewRedisStore(newFakeRedis())
+	ctx := context.Background()
+
+	sub := &webhook.Subscription{ID: "s1", Owner: "acme", URL: "https://example.com", Events: []string{"*"}, Secret: "whsec_x"}
+	require.NoError(t, store.SaveSubscription(ctx, sub))
+
+	loaded, err := store.Subscription(ctx, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, sub, loaded, "the secret is stored with the subscription")
+
+	subs, err := store.Subscriptions(ctx, "acme")
+	require.NoError(t, err)
+	assert.Len(t, subs, 1)
+
+	older := &webhook.Delivery{ID: "d1", SubscriptionID: "s1", Status: webhook.StatusFailed, CreatedAt: time.Unix(1, 0), Payload: []byte(`{"a":1}`)}
+	newer := &webhook.Delivery{ID: "d2", SubscriptionID: "s1", Status: webhook.StatusPending, CreatedAt: time.Unix(2, 0)}
+	require.NoError(t, store.SaveDelivery(ctx, older))
+	require.NoError(t, store.SaveDelivery(ctx, newer))
+
+	deliveries, err := store.Deliveries(ctx, "s1")
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "d2", deliveries[0].ID)
+	assert.Equal(t, []byte(`{"a":1}`), deliveries[1].Payload)
+
+	require.NoError(t, store.DeleteSubscription(ctx, sub))
+	_, err = store.Subscription(ctx, "s1")
+	assert.ErrorIs(t, err, webhook.ErrNotFound)
+	subs, err = store.Subscriptions(ctx, "acme")
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+
+	now := time.Now()
+	require.NoError(t, store.Schedule(ctx, "d1", now.Add(-time.Second)))
+	require.NoError(t, store.Schedule(ctx, "d2", now.Add(-2*time.Second)))
+	require.NoError(t, store.Schedule(ctx, "d3", now.Add(time.Minute)))
+	due, err := store.Due(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d2", "d1"}, due)
+
+	claimed, err := store.Unschedule(ctx, "d2")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = store.Unschedule(ctx, "d2")
+	require.NoError(t, err)
+	assert.False(t, claimed, "a delivery is claimed once")
+}
+
+func TestMemoryStore_DeleteSubscription(t *testing.T) {
+	store := webhook.NewMemoryStore()
+	ctx := context.Background()
+
+	sub := &webhook.Subscription{ID: "s1", Owner: "acme", URL: "https://example.com", Events: []string{"*"}}
+	require.NoError(t, store.SaveSubscription(ctx, sub))
+	require.NoError(t, store.SaveDelivery(ctx, &webhook.Delivery{ID: "d1", SubscriptionID: "s1", CreatedAt: time.Now()}))
+
+	require.NoError(t, store.DeleteSubscription(ctx, sub))
+	_, err := store.Subscription(ctx, "s1")
+	assert.ErrorIs(t, err, webhook.ErrNotFound)
+	_, err = store.Delivery(ctx, "d1")
+	assert.ErrorIs(t, err, webhook.ErrNotFound, "the delivery log goes away with the subscription")
+}